@@ -2,7 +2,9 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
+	"log"
 	"os"
 	"time"
 
@@ -10,9 +12,26 @@ import (
 	"github.com/yuyz0112/sunmao-ui-go-binding/pkg/sunmao"
 )
 
+var (
+	record  = flag.String("record", "", "record websocket frames to the file")
+	replay  = flag.String("replay", "", "replay a recorded file through the handlers and exit")
+	inspect = flag.Bool("inspect", false, "serve the websocket frame inspector")
+	static  = flag.String("static", "", "export a static bundle to the dir and exit")
+)
+
 func main() {
+	flag.Parse()
+
+	opts := []runtime.Option{}
+	if *record != "" {
+		opts = append(opts, runtime.WithRecorder(*record))
+	}
+	if *inspect {
+		opts = append(opts, runtime.WithInspector())
+	}
+
 	// init the runtime
-	r := runtime.New("ui", "patch")
+	r := runtime.New("ui", "patch", opts...)
 	// init an App builder, use a lib
 	b := sunmao.NewChakraUIApp()
 
@@ -124,12 +143,14 @@ func main() {
 	}()
 
 	// add any server function as an API
-	r.Handle("debug", func(m *runtime.Message, connId int) error {
+	if err := r.Handle("debug", func(m *runtime.Message, connId int) error {
 		fmt.Println("debug >", m, "from >", connId)
 		return nil
-	})
+	}); err != nil {
+		log.Fatalln(err)
+	}
 
-	r.Handle("writeFile", func(m *runtime.Message, connId int) error {
+	if err := r.Handle("writeFile", func(m *runtime.Message, connId int) error {
 		content, _ := json.Marshal(m.Params)
 		err := os.WriteFile("test", content, 777)
 		if err != nil {
@@ -146,7 +167,9 @@ func main() {
 		}, &connId)
 
 		return nil
-	})
+	}); err != nil {
+		log.Fatalln(err)
+	}
 
 	b.Component(b.NewButton().Content("click to debug").
 		OnClick(&sunmao.ServerHandler{
@@ -160,6 +183,20 @@ func main() {
 	r.LoadModule(fileTypeModule)
 	r.LoadApp(b.AppBuilder)
 
+	if *replay != "" {
+		if err := r.Replay(*replay); err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
+
+	if *static != "" {
+		if err := r.ExportStatic(*static); err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
+
 	// start the runtime
 	r.Run()
 }
@@ -0,0 +1,127 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// AccessLog configures WithAccessLog.
+type AccessLog struct {
+	// Out defaults to os.Stdout.
+	Out io.Writer
+	// Format is "json", one object per line, or "text".
+	Format string
+	// SampleRate logs the given share of requests, 0 logs all of them.
+	// Server errors are always logged.
+	SampleRate float64
+	// Exclude skips paths relative to the base path, patterns ending in
+	// / match by prefix, e.g. "/healthz" or "/assets/".
+	Exclude []string
+	// file is set when the runtime opened Out itself, Shutdown closes it
+	file io.Closer
+}
+
+// AccessLogEntry is one logged request. Websocket upgrades are logged once
+// the connection closed, their latency is the connection's lifetime.
+type AccessLogEntry struct {
+	Time      time.Time `json:"time"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Status    int       `json:"status"`
+	LatencyMs float64   `json:"latencyMs"`
+	Bytes     int64     `json:"bytes"`
+	IP        string    `json:"ip"`
+	Upgrade   bool      `json:"upgrade,omitempty"`
+}
+
+func (e *AccessLogEntry) text() string {
+	upgrade := ""
+	if e.Upgrade {
+		upgrade = " upgrade"
+	}
+	return fmt.Sprintf("%v %v %v %v %v %.3fms %vB%v\n",
+		e.Time.Format(time.RFC3339), e.IP, e.Method, e.Path, e.Status, e.LatencyMs, e.Bytes, upgrade)
+}
+
+type accessLogger struct {
+	AccessLog
+	mu sync.Mutex
+}
+
+// WithAccessLog logs every request, after the error handler wrote its
+// response so failed requests show their final status.
+func WithAccessLog(cfg AccessLog) Option {
+	return func(r *Runtime) {
+		if cfg.Out == nil {
+			cfg.Out = os.Stdout
+		}
+		r.accessLog = &accessLogger{AccessLog: cfg}
+	}
+}
+
+func (l *accessLogger) write(e *AccessLogEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.Format == "json" {
+		json.NewEncoder(l.Out).Encode(e)
+		return
+	}
+	io.WriteString(l.Out, e.text())
+}
+
+func (l *accessLogger) close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	// later requests of a draining server must not write to the closed file
+	l.Out = io.Discard
+	return err
+}
+
+func (r *Runtime) accessLogMiddleware() echo.MiddlewareFunc {
+	l := r.accessLog
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if matchPath(l.Exclude, strings.TrimPrefix(req.URL.Path, r.basePath)) {
+				return next(c)
+			}
+
+			start := time.Now()
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+
+			res := c.Response()
+			if l.SampleRate > 0 && res.Status < http.StatusInternalServerError && rand.Float64() >= l.SampleRate {
+				return nil
+			}
+			l.write(&AccessLogEntry{
+				Time:      start,
+				Method:    req.Method,
+				Path:      req.URL.Path,
+				Status:    res.Status,
+				LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+				Bytes:     res.Size,
+				IP:        c.RealIP(),
+				Upgrade:   strings.EqualFold(req.Header.Get(echo.HeaderUpgrade), "websocket"),
+			})
+			return nil
+		}
+	}
+}
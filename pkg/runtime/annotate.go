@@ -0,0 +1,161 @@
+package runtime
+
+import "sync"
+
+// Annotation is a labeled box on an image. X, Y, W and H are fractions of
+// the image size, so boxes stay in place however the image is scaled.
+type Annotation struct {
+	Id    string  `json:"id"`
+	Label string  `json:"label,omitempty"`
+	X     float64 `json:"x"`
+	Y     float64 `json:"y"`
+	W     float64 `json:"w"`
+	H     float64 `json:"h"`
+	// Color is a css color, the theme color by default.
+	Color string `json:"color,omitempty"`
+	Data  any    `json:"data,omitempty"`
+}
+
+// AnnotatorCallbacks receive what users do on an image, nil callbacks are
+// skipped and a nil OnDraw disables drawing.
+type AnnotatorCallbacks struct {
+	// OnClick gets the clicked point, hit is the topmost box under it or
+	// nil.
+	OnClick func(conn *Conn, x, y float64, hit *Annotation) error
+	// OnDraw gets the box a user drew, without id or label. The returned
+	// annotation, if any, is added for every client.
+	OnDraw func(conn *Conn, box Annotation) (*Annotation, error)
+}
+
+type annotatorState struct {
+	Src         string       `json:"src"`
+	Annotations []Annotation `json:"annotations"`
+}
+
+// ImageAnnotator is the ServerState behind sunmao.NewImageAnnotator.
+type ImageAnnotator struct {
+	*ServerState
+	mu        sync.Mutex
+	state     annotatorState
+	callbacks AnnotatorCallbacks
+}
+
+// NewImageAnnotator serves src and its annotations to the annotators bound
+// to id. src may be empty when the component's src is bound to an
+// ImageSource instead.
+func NewImageAnnotator(r *Runtime, id, src string, callbacks AnnotatorCallbacks) (*ImageAnnotator, error) {
+	a := &ImageAnnotator{
+		ServerState: r.NewServerState(id, &annotatorState{Src: src, Annotations: []Annotation{}}),
+		state:       annotatorState{Src: src, Annotations: []Annotation{}},
+		callbacks:   callbacks,
+	}
+
+	if err := r.Handle(id+"/click", a.handleClick); err != nil {
+		return nil, err
+	}
+	return a, r.Handle(id+"/draw", a.handleDraw)
+}
+
+// SetImage shows another image with its annotations, e.g. the next sample
+// to label.
+func (a *ImageAnnotator) SetImage(src string, annotations []Annotation) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.state = annotatorState{Src: src, Annotations: append([]Annotation{}, annotations...)}
+	return a.push()
+}
+
+// SetAnnotations replaces the boxes of the current image.
+func (a *ImageAnnotator) SetAnnotations(annotations []Annotation) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.state.Annotations = append([]Annotation{}, annotations...)
+	return a.push()
+}
+
+// PutAnnotation adds annotation, or replaces the one with the same id.
+func (a *ImageAnnotator) PutAnnotation(annotation Annotation) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.state.Annotations = append(withoutAnnotation(a.state.Annotations, annotation.Id), annotation)
+	return a.push()
+}
+
+// RemoveAnnotation deletes the annotation with id.
+func (a *ImageAnnotator) RemoveAnnotation(id string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.state.Annotations = withoutAnnotation(a.state.Annotations, id)
+	return a.push()
+}
+
+// Annotations returns the boxes of the current image.
+func (a *ImageAnnotator) Annotations() []Annotation {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.state.Annotations
+}
+
+func (a *ImageAnnotator) push() error {
+	state := a.state
+	return a.SetState(&state, nil)
+}
+
+func (a *ImageAnnotator) handleClick(m *Message, connId int) error {
+	if a.callbacks.OnClick == nil {
+		return nil
+	}
+	params, err := decodeParams[struct {
+		X float64 `json:"x"`
+		Y float64 `json:"y"`
+	}](m)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	var hit *Annotation
+	// later boxes are drawn on top
+	for i := len(a.state.Annotations) - 1; i >= 0; i-- {
+		b := a.state.Annotations[i]
+		if params.X >= b.X && params.X <= b.X+b.W && params.Y >= b.Y && params.Y <= b.Y+b.H {
+			hit = &b
+			break
+		}
+	}
+	a.mu.Unlock()
+
+	return a.callbacks.OnClick(a.r.conns.get(connId), params.X, params.Y, hit)
+}
+
+func (a *ImageAnnotator) handleDraw(m *Message, connId int) error {
+	if a.callbacks.OnDraw == nil {
+		return &ActionError{Code: ForbiddenCode, Message: "drawing is disabled"}
+	}
+	box, err := decodeParams[Annotation](m)
+	if err != nil {
+		return err
+	}
+	box.Id, box.Label, box.Data = "", "", nil
+
+	added, err := a.callbacks.OnDraw(a.r.conns.get(connId), box)
+	if err != nil || added == nil {
+		return err
+	}
+	return a.PutAnnotation(*added)
+}
+
+func withoutAnnotation(annotations []Annotation, id string) []Annotation {
+	kept := make([]Annotation, 0, len(annotations))
+	for _, a := range annotations {
+		if a.Id != id {
+			kept = append(kept, a)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,89 @@
+package runtime
+
+import "sync"
+
+type AnnounceLevel string
+
+const (
+	AnnounceInfo    AnnounceLevel = "info"
+	AnnounceWarning AnnounceLevel = "warning"
+	AnnounceError   AnnounceLevel = "error"
+)
+
+const maintenanceMessage = "The application is under maintenance, changes are paused for a moment."
+
+type announcement struct {
+	mu          sync.RWMutex
+	level       AnnounceLevel
+	message     string
+	maintenance bool
+	// saved is the banner maintenance mode replaced
+	savedLevel   AnnounceLevel
+	savedMessage string
+}
+
+// Announce shows a banner on every client, including ones connecting
+// later. An empty message removes it.
+func (r *Runtime) Announce(level AnnounceLevel, message string) error {
+	r.announcement.mu.Lock()
+	r.announcement.level = level
+	r.announcement.message = message
+	r.announcement.mu.Unlock()
+
+	return r.send(map[string]interface{}{
+		"type":    "Announce",
+		"level":   level,
+		"message": message,
+	}, nil)
+}
+
+// MaintenanceMode rejects new Actions with a friendly message and shows
+// a banner while on, so changes can roll out during business hours. The
+// banner shown before comes back once it is off, unless another one was
+// announced meanwhile.
+func (r *Runtime) MaintenanceMode(on bool) error {
+	a := r.announcement
+	a.mu.Lock()
+	if on == a.maintenance {
+		a.mu.Unlock()
+		return nil
+	}
+	a.maintenance = on
+	level, message := AnnounceWarning, maintenanceMessage
+	if on {
+		a.savedLevel, a.savedMessage = a.level, a.message
+	} else {
+		level, message = a.level, a.message
+		if message == maintenanceMessage {
+			level, message = a.savedLevel, a.savedMessage
+		}
+		a.savedLevel, a.savedMessage = "", ""
+	}
+	a.mu.Unlock()
+
+	return r.Announce(level, message)
+}
+
+func (r *Runtime) inMaintenance() bool {
+	r.announcement.mu.RLock()
+	defer r.announcement.mu.RUnlock()
+
+	return r.announcement.maintenance
+}
+
+// announceTo catches a new connection up with the current banner.
+func (r *Runtime) announceTo(conn *Conn) {
+	r.announcement.mu.RLock()
+	level, message := r.announcement.level, r.announcement.message
+	r.announcement.mu.RUnlock()
+
+	if message == "" {
+		return
+	}
+	connId := conn.Id
+	r.send(map[string]interface{}{
+		"type":    "Announce",
+		"level":   level,
+		"message": message,
+	}, &connId)
+}
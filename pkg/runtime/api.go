@@ -0,0 +1,118 @@
+package runtime
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const actionAPIPrefix = "/api/actions/"
+
+// APIConnId is the connId handlers receive for calls made through the
+// action api, it never matches a websocket connection.
+const APIConnId = 0
+
+type ActionRequest struct {
+	Params any             `json:"params"`
+	Store  json.RawMessage `json:"store"`
+}
+
+// WithAPITokens exposes every handler at POST /api/actions/:handler for
+// callers presenting one of tokens as a bearer token. Unknown handlers
+// answer 404, ActionErrors a status matching their code such as 400 for
+// invalid params or 429 when rate limited.
+func WithAPITokens(tokens ...string) Option {
+	return func(r *Runtime) {
+		r.apiTokens = append(r.apiTokens, tokens...)
+	}
+}
+
+func (r *Runtime) validAPIToken(c echo.Context) bool {
+	token := strings.TrimPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+	if token == "" {
+		return false
+	}
+
+	valid := false
+	for _, t := range r.apiTokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(t)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
+
+func (r *Runtime) registerActionAPI() {
+	r.authExempt = append(r.authExempt, actionAPIPrefix)
+	r.router.POST(actionAPIPrefix+":handler", func(c echo.Context) error {
+		if !r.validAPIToken(c) {
+			return echo.ErrUnauthorized
+		}
+
+		req := &ActionRequest{}
+		if err := c.Bind(req); err != nil {
+			return err
+		}
+
+		msg := &Message{
+			Type:      "Action",
+			Handler:   c.Param("handler"),
+			Params:    req.Params,
+			RequestId: c.Request().Header.Get(echo.HeaderXRequestID),
+			storeSize: len(req.Store),
+		}
+		if req.Params != nil {
+			params, err := json.Marshal(req.Params)
+			if err != nil {
+				return err
+			}
+			msg.paramsSize = len(params)
+		}
+		if len(req.Store) > 0 {
+			if err := json.Unmarshal(req.Store, &msg.Store); err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+			}
+		}
+
+		err := r.callHandler(msg, APIConnId)
+		c.Response().Header().Set(echo.HeaderXRequestID, msg.RequestId)
+		if err == errUnknownHandler {
+			return echo.NewHTTPError(http.StatusNotFound, err.Error())
+		}
+		if actionErr := actionErrorOf(err); actionErr != nil {
+			return echo.NewHTTPError(actionStatus(actionErr.Code), actionErr)
+		}
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"ok": true,
+		})
+	})
+}
+
+// actionStatus maps the codes of the runtime's ActionErrors to an http
+// status, codes of the app's handlers are rejections of the request.
+func actionStatus(code string) int {
+	switch code {
+	case "invalid_params", "edit_rejected", "password_mismatch":
+		return http.StatusBadRequest
+	case "invalid_credentials":
+		return http.StatusUnauthorized
+	case ForbiddenCode, "vetoed":
+		return http.StatusForbidden
+	case "busy":
+		return http.StatusConflict
+	case "params_too_large", "store_too_large":
+		return http.StatusRequestEntityTooLarge
+	case "rate_limited", "superseded", "too_many_uploads":
+		return http.StatusTooManyRequests
+	case "maintenance":
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusUnprocessableEntity
+}
@@ -0,0 +1,135 @@
+package runtime
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// vite emits assets as [name].[hash].[ext]
+var hashedAsset = regexp.MustCompile(`\.[0-9a-f]{8}\.[a-z0-9]+$`)
+
+var precompressedEncodings = []struct {
+	encoding string
+	ext      string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+func (r *Runtime) assetPath(name string) string {
+	return filepath.Join(fmt.Sprintf("%v/dist/assets", r.uiDir), filepath.FromSlash(path.Clean("/"+name)))
+}
+
+// precompressedAsset looks for a .br or .gz sibling of the requested asset
+// that the client accepts.
+func (r *Runtime) precompressedAsset(c echo.Context) (string, string, bool) {
+	if !strings.HasSuffix(c.Path(), "/assets/*") {
+		return "", "", false
+	}
+	name := c.Param("*")
+
+	accept := parseAcceptEncoding(c.Request().Header.Get(echo.HeaderAcceptEncoding))
+	file, encoding, best := "", "", 0.0
+	for _, p := range precompressedEncodings {
+		// on equal weights the earlier, smaller encoding wins
+		q := accept.weight(p.encoding)
+		if q <= best {
+			continue
+		}
+		f := r.assetPath(name) + p.ext
+		if info, err := os.Stat(f); err == nil && !info.IsDir() {
+			file, encoding, best = f, p.encoding, q
+		}
+	}
+	return file, encoding, file != ""
+}
+
+// acceptEncoding maps the codings of an Accept-Encoding header to their
+// q-value.
+type acceptEncoding map[string]float64
+
+func parseAcceptEncoding(header string) acceptEncoding {
+	accept := acceptEncoding{}
+	for _, token := range strings.Split(header, ",") {
+		params := strings.Split(token, ";")
+		coding := strings.ToLower(strings.TrimSpace(params[0]))
+		if coding == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range params[1:] {
+			k, v, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if !ok || strings.ToLower(strings.TrimSpace(k)) != "q" {
+				continue
+			}
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				q = f
+			}
+		}
+		accept[coding] = q
+	}
+	return accept
+}
+
+// weight is the q-value of coding, falling back to the "*" wildcard. Zero
+// means the coding is not acceptable.
+func (a acceptEncoding) weight(coding string) float64 {
+	if q, ok := a[coding]; ok {
+		return q
+	}
+	return a["*"]
+}
+
+func (r *Runtime) gzipMiddleware() echo.MiddlewareFunc {
+	return middleware.GzipWithConfig(middleware.GzipConfig{
+		Skipper: func(c echo.Context) bool {
+			_, _, ok := r.precompressedAsset(c)
+			return ok
+		},
+	})
+}
+
+func (r *Runtime) serveAsset(c echo.Context) error {
+	name := c.Param("*")
+	header := c.Response().Header()
+
+	header.Add(echo.HeaderVary, echo.HeaderAcceptEncoding)
+	if hashedAsset.MatchString(name) {
+		header.Set(echo.HeaderCacheControl, "public, max-age=31536000, immutable")
+	} else {
+		header.Set(echo.HeaderCacheControl, "no-cache")
+	}
+
+	file, encoding, ok := r.precompressedAsset(c)
+	if !ok {
+		return c.File(r.assetPath(name))
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if t := mime.TypeByExtension(path.Ext(name)); t != "" {
+		header.Set(echo.HeaderContentType, t)
+	}
+	header.Set(echo.HeaderContentEncoding, encoding)
+	http.ServeContent(c.Response(), c.Request(), name, info.ModTime(), f)
+	return nil
+}
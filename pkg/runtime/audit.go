@@ -0,0 +1,135 @@
+package runtime
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// AuditEntry records one Action.
+type AuditEntry struct {
+	Time      time.Time `json:"time"`
+	RequestId string    `json:"requestId"`
+	Handler   string    `json:"handler"`
+	ConnId    int       `json:"connId"`
+	// UserId is empty for anonymous connections.
+	UserId string `json:"userId,omitempty"`
+	// ParamsDigest is the sha256 of the redacted params, so entries can
+	// be matched to payloads without storing them.
+	ParamsDigest string `json:"paramsDigest"`
+	// Result is "ok", "rejected" for action errors and vetoes, or "error".
+	Result  string        `json:"result"`
+	Error   string        `json:"error,omitempty"`
+	Latency time.Duration `json:"latency"`
+}
+
+// AuditSink stores audit entries, e.g. in a file or a database table.
+type AuditSink interface {
+	Record(entry *AuditEntry) error
+}
+
+// AuditFunc adapts a function to AuditSink.
+type AuditFunc func(entry *AuditEntry) error
+
+func (f AuditFunc) Record(entry *AuditEntry) error {
+	return f(entry)
+}
+
+// Redactor replaces sensitive fields of the params of handler before they
+// are digested.
+type Redactor func(handler string, params any) any
+
+type audit struct {
+	sink    AuditSink
+	redacts []Redactor
+}
+
+// WithAudit records every Action to sink after running the redactors.
+func WithAudit(sink AuditSink, redacts ...Redactor) Option {
+	return func(r *Runtime) {
+		r.audit = &audit{sink: sink, redacts: redacts}
+	}
+}
+
+// RedactFields blanks top level param fields such as "password" for every
+// handler.
+func RedactFields(fields ...string) Redactor {
+	return func(handler string, params any) any {
+		m, ok := params.(map[string]any)
+		if !ok {
+			return params
+		}
+		redacted := make(map[string]any, len(m))
+		for k, v := range m {
+			redacted[k] = v
+		}
+		for _, f := range fields {
+			if _, ok := redacted[f]; ok {
+				redacted[f] = "[redacted]"
+			}
+		}
+		return redacted
+	}
+}
+
+func (r *Runtime) auditAction(msg *Message, connId int, latency time.Duration, err error) {
+	params := msg.Params
+	for _, redact := range r.audit.redacts {
+		params = redact(msg.Handler, params)
+	}
+	buf, _ := json.Marshal(params)
+	sum := sha256.Sum256(buf)
+
+	entry := &AuditEntry{
+		Time:         time.Now(),
+		RequestId:    msg.RequestId,
+		Handler:      msg.Handler,
+		ConnId:       connId,
+		ParamsDigest: hex.EncodeToString(sum[:]),
+		Result:       "ok",
+		Latency:      latency,
+	}
+	if conn := r.conns.get(connId); conn != nil && conn.Identity != nil {
+		entry.UserId = conn.Identity.Id
+	}
+	if err != nil {
+		entry.Result = "error"
+		if actionErrorOf(err) != nil {
+			entry.Result = "rejected"
+		}
+		entry.Error = err.Error()
+	}
+
+	if err := r.audit.sink.Record(entry); err != nil {
+		r.e.Logger.Error(err)
+	}
+}
+
+// FileAuditSink appends entries to a file as JSON lines.
+type FileAuditSink struct {
+	mu  sync.Mutex
+	f   *os.File
+	enc *json.Encoder
+}
+
+func NewFileAuditSink(path string) (*FileAuditSink, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileAuditSink{f: f, enc: json.NewEncoder(f)}, nil
+}
+
+func (s *FileAuditSink) Record(entry *AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.enc.Encode(entry)
+}
+
+func (s *FileAuditSink) Close() error {
+	return s.f.Close()
+}
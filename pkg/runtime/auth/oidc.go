@@ -0,0 +1,353 @@
+// Package auth implements single sign-on for the runtime.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/yuyz0112/sunmao-ui-go-binding/pkg/runtime"
+)
+
+const stateCookie = "sunmao_oidc_state"
+
+type OIDCConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL must point to the callback route, e.g.
+	// https://tools.example.com/auth/callback
+	RedirectURL string
+	// Scopes defaults to openid, profile and email.
+	Scopes []string
+	// RolesClaim names the id token claim copied into Identity.Roles.
+	RolesClaim string
+	// Prefix of the login, callback and logout routes, defaults to /auth.
+	Prefix string
+}
+
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+	EndSessionEndpoint    string `json:"end_session_endpoint"`
+}
+
+// OIDC implements the authorization code flow against an OpenID provider.
+type OIDC struct {
+	cfg       OIDCConfig
+	discovery discovery
+	keys      *keySet
+	client    *http.Client
+}
+
+func NewOIDC(ctx context.Context, cfg OIDCConfig) (*OIDC, error) {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "profile", "email"}
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = "/auth"
+	}
+
+	o := &OIDC{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+
+	err := o.getJSON(ctx, strings.TrimSuffix(cfg.Issuer, "/")+"/.well-known/openid-configuration", &o.discovery)
+	if err != nil {
+		return nil, err
+	}
+	if o.discovery.Issuer != cfg.Issuer {
+		return nil, fmt.Errorf("issuer mismatch: %v", o.discovery.Issuer)
+	}
+
+	o.keys = newKeySet(o.discovery.JwksURI, o.getJSON)
+	return o, nil
+}
+
+func (o *OIDC) getJSON(ctx context.Context, u string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %v: %v", u, res.Status)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+func (o *OIDC) LoginPath() string {
+	return o.cfg.Prefix + "/login"
+}
+
+// LogoutPath only takes same origin POST requests, e.g. from a form, so
+// other sites can not log users out.
+func (o *OIDC) LogoutPath() string {
+	return o.cfg.Prefix + "/logout"
+}
+
+// RequireAuth gates the app behind the login route of o.
+func (o *OIDC) RequireAuth(public ...string) runtime.Option {
+	return runtime.RequireAuth(o.LoginPath(), append(public, o.cfg.Prefix+"/")...)
+}
+
+// Register adds the login, callback and logout routes to r.
+func (o *OIDC) Register(r *runtime.Runtime) {
+	g := r.Router()
+
+	g.GET(o.cfg.Prefix+"/login", func(c echo.Context) error {
+		state, err := randomString()
+		if err != nil {
+			return err
+		}
+		nonce, err := randomString()
+		if err != nil {
+			return err
+		}
+
+		buf, err := json.Marshal(&pendingLogin{
+			State:    state,
+			Nonce:    nonce,
+			ReturnTo: safeReturnTo(c.QueryParam("return_to"), r.BasePath()+"/"),
+		})
+		if err != nil {
+			return err
+		}
+		c.SetCookie(&http.Cookie{
+			Name:     stateCookie,
+			Value:    url.QueryEscape(string(buf)),
+			Path:     "/",
+			MaxAge:   600,
+			HttpOnly: true,
+			Secure:   c.Scheme() == "https",
+			SameSite: http.SameSiteLaxMode,
+		})
+
+		q := url.Values{}
+		q.Set("response_type", "code")
+		q.Set("client_id", o.cfg.ClientID)
+		q.Set("redirect_uri", o.cfg.RedirectURL)
+		q.Set("scope", strings.Join(o.cfg.Scopes, " "))
+		q.Set("state", state)
+		q.Set("nonce", nonce)
+		return c.Redirect(http.StatusFound, o.discovery.AuthorizationEndpoint+"?"+q.Encode())
+	})
+
+	g.GET(o.cfg.Prefix+"/callback", func(c echo.Context) error {
+		pending, err := readPendingLogin(c)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if c.QueryParam("state") != pending.State {
+			return echo.NewHTTPError(http.StatusBadRequest, "state mismatch")
+		}
+		// the state and nonce are single use
+		c.SetCookie(&http.Cookie{
+			Name:     stateCookie,
+			Path:     "/",
+			MaxAge:   -1,
+			HttpOnly: true,
+			Secure:   c.Scheme() == "https",
+			SameSite: http.SameSiteLaxMode,
+		})
+		if e := c.QueryParam("error"); e != "" {
+			return echo.NewHTTPError(http.StatusUnauthorized, e)
+		}
+
+		rawIdToken, err := o.exchange(c.Request().Context(), c.QueryParam("code"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+		}
+
+		claims, err := o.verify(c.Request().Context(), rawIdToken, pending.Nonce)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+		}
+
+		if err := r.Login(c, o.identity(claims)); err != nil {
+			return err
+		}
+		return c.Redirect(http.StatusFound, pending.ReturnTo)
+	})
+
+	// WithCSRF checks the token of the logout as well, the origin check
+	// protects apps without it
+	g.POST(o.LogoutPath(), func(c echo.Context) error {
+		if !sameOrigin(c) {
+			return echo.NewHTTPError(http.StatusForbidden, "cross site logout")
+		}
+		if err := r.Logout(c); err != nil {
+			return err
+		}
+		if o.discovery.EndSessionEndpoint != "" {
+			return c.Redirect(http.StatusSeeOther, o.discovery.EndSessionEndpoint)
+		}
+		return c.Redirect(http.StatusSeeOther, r.BasePath()+"/")
+	})
+}
+
+type pendingLogin struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	ReturnTo string `json:"returnTo"`
+}
+
+func readPendingLogin(c echo.Context) (*pendingLogin, error) {
+	cookie, err := c.Cookie(stateCookie)
+	if err != nil {
+		return nil, errors.New("login was not started")
+	}
+
+	raw, err := url.QueryUnescape(cookie.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &pendingLogin{}
+	return p, json.Unmarshal([]byte(raw), p)
+}
+
+// safeReturnTo only allows redirects back to a local path, anything else
+// is replaced by fallback. Browsers read a backslash as a slash and drop
+// tabs and newlines, so "/\evil.com" would leave the site.
+func safeReturnTo(returnTo string, fallback string) string {
+	if !strings.HasPrefix(returnTo, "/") || strings.ContainsAny(returnTo, "\\\t\r\n") {
+		return fallback
+	}
+	u, err := url.Parse(returnTo)
+	if err != nil || u.Scheme != "" || u.Host != "" || strings.HasPrefix(u.Path, "//") {
+		return fallback
+	}
+	return returnTo
+}
+
+// sameOrigin reports whether a browser sent the request from this site.
+// Requests without the fetch metadata or Origin headers do not come from
+// a cross site page of a current browser.
+func sameOrigin(c echo.Context) bool {
+	h := c.Request().Header
+	if site := h.Get("Sec-Fetch-Site"); site != "" {
+		return site == "same-origin" || site == "none"
+	}
+	origin := h.Get(echo.HeaderOrigin)
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == c.Request().Host
+}
+
+func randomString() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func (o *OIDC) exchange(ctx context.Context, code string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", o.cfg.RedirectURL)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(o.cfg.ClientID), url.QueryEscape(o.cfg.ClientSecret))
+
+	res, err := o.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	token := struct {
+		IdToken string `json:"id_token"`
+		Error   string `json:"error"`
+	}{}
+	if err := json.NewDecoder(res.Body).Decode(&token); err != nil {
+		return "", err
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint: %v %v", res.Status, token.Error)
+	}
+	if token.IdToken == "" {
+		return "", errors.New("token response has no id_token")
+	}
+	return token.IdToken, nil
+}
+
+func (o *OIDC) verify(ctx context.Context, rawIdToken string, nonce string) (map[string]any, error) {
+	claims, err := o.keys.verify(ctx, rawIdToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims["iss"] != o.discovery.Issuer {
+		return nil, errors.New("unexpected issuer")
+	}
+	if !hasAudience(claims["aud"], o.cfg.ClientID) {
+		return nil, errors.New("unexpected audience")
+	}
+	if exp, ok := claims["exp"].(float64); !ok || time.Now().Unix() > int64(exp) {
+		return nil, errors.New("id token expired")
+	}
+	if claims["nonce"] != nonce {
+		return nil, errors.New("nonce mismatch")
+	}
+	return claims, nil
+}
+
+func hasAudience(aud any, clientId string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == clientId
+	case []any:
+		for _, a := range v {
+			if a == clientId {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (o *OIDC) identity(claims map[string]any) *runtime.Identity {
+	identity := &runtime.Identity{
+		Claims: claims,
+	}
+	identity.Id, _ = claims["sub"].(string)
+	identity.Name, _ = claims["name"].(string)
+	if identity.Name == "" {
+		identity.Name, _ = claims["email"].(string)
+	}
+
+	if roles, ok := claims[o.cfg.RolesClaim].([]any); ok && o.cfg.RolesClaim != "" {
+		for _, role := range roles {
+			if s, ok := role.(string); ok {
+				identity.Roles = append(identity.Roles, s)
+			}
+		}
+	}
+	return identity
+}
@@ -0,0 +1,89 @@
+package runtime
+
+import "time"
+
+// SlowClientPolicy decides what happens to a message for a client whose
+// outbound queue is full.
+type SlowClientPolicy int
+
+const (
+	// SlowClientBlock waits for room in the queue, stalling the sender.
+	SlowClientBlock SlowClientPolicy = iota
+	// SlowClientDropOldest discards the oldest queued message.
+	SlowClientDropOldest
+	// SlowClientSkip discards the new message.
+	SlowClientSkip
+	// SlowClientDisconnect closes the connection, a client with a
+	// reconnect policy comes back and is served fresh.
+	SlowClientDisconnect
+)
+
+type backpressure struct {
+	queueSize    int
+	writeTimeout time.Duration
+	policy       SlowClientPolicy
+}
+
+// WithBackpressure bounds every connection's outbound queue to queueSize
+// frames, gives each write writeTimeout to complete and applies policy
+// to clients that fall behind, so one slow link can not stall broadcasts
+// for everyone else.
+func WithBackpressure(queueSize int, writeTimeout time.Duration, policy SlowClientPolicy) Option {
+	return func(r *Runtime) {
+		if queueSize <= 0 {
+			queueSize = outboundQueueSize
+		}
+		r.backpressure = &backpressure{
+			queueSize:    queueSize,
+			writeTimeout: writeTimeout,
+			policy:       policy,
+		}
+	}
+}
+
+func (r *Runtime) outboundQueueSize() int {
+	if r.backpressure == nil {
+		return outboundQueueSize
+	}
+	return r.backpressure.queueSize
+}
+
+func (r *Runtime) slowClientPolicy() SlowClientPolicy {
+	if r.backpressure == nil {
+		return SlowClientBlock
+	}
+	return r.backpressure.policy
+}
+
+// writeDeadline is zero, no deadline, without a write timeout.
+func (r *Runtime) writeDeadline() time.Time {
+	if r.backpressure == nil || r.backpressure.writeTimeout <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(r.backpressure.writeTimeout)
+}
+
+// pushFull applies a policy other than SlowClientBlock to a message for a
+// full queue and reports whether it was queued, callers hold c.mu. Whole
+// messages are dropped, never single chunks of one.
+func (c *Conn) pushFull(msg outMessage) (bool, error) {
+	switch c.policy {
+	case SlowClientDropOldest:
+		select {
+		case <-c.out:
+		default:
+		}
+		select {
+		case c.out <- msg:
+			return true, nil
+		default:
+			// the writer is gone or the queue refilled, drop this one
+			return false, nil
+		}
+	case SlowClientDisconnect:
+		// the read loop notices the closed socket and cleans up
+		c.ws.Close()
+		return false, errConnClosed
+	}
+	return false, nil
+}
@@ -0,0 +1,32 @@
+package runtime
+
+import (
+	"bytes"
+	"strings"
+)
+
+// WithBasePath serves the app under a sub path such as "/tools/myapp",
+// for deployments behind a reverse proxy that does not strip the prefix.
+func WithBasePath(basePath string) Option {
+	return func(r *Runtime) {
+		r.basePath = "/" + strings.Trim(basePath, "/")
+		if r.basePath == "/" {
+			r.basePath = ""
+		}
+	}
+}
+
+// BasePath is the path the app is served under, including the prefix of
+// LoadAppAt, or "" at the root.
+func (r *Runtime) BasePath() string {
+	return r.basePath
+}
+
+// rewriteAssetUrls prefixes the absolute asset urls vite emits in the
+// html entries with the base path.
+func rewriteAssetUrls(html []byte, basePath string) []byte {
+	for _, attr := range []string{`src="`, `href="`} {
+		html = bytes.ReplaceAll(html, []byte(attr+"/assets/"), []byte(attr+basePath+"/assets/"))
+	}
+	return html
+}
@@ -0,0 +1,123 @@
+package runtime
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type cachedPage struct {
+	html    []byte
+	etag    string
+	modTime time.Time
+}
+
+type pageCache struct {
+	mu    sync.RWMutex
+	pages map[string]*cachedPage
+}
+
+// page returns the rendered html entry, rendering it at most once until
+// the cache is invalidated.
+func (r *Runtime) page(name string) (*cachedPage, error) {
+	r.pageCache.mu.RLock()
+	p, ok := r.pageCache.pages[name]
+	r.pageCache.mu.RUnlock()
+	if ok {
+		return p, nil
+	}
+
+	r.pageCache.mu.Lock()
+	defer r.pageCache.mu.Unlock()
+
+	if p, ok := r.pageCache.pages[name]; ok {
+		return p, nil
+	}
+
+	options, err := r.uiOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	if err := r.renderPage(buf, name, options); err != nil {
+		return nil, err
+	}
+	html := buf.Bytes()
+	if name == "index.html" {
+		if html, err = r.renderHead(html, r.indexData()); err != nil {
+			return nil, err
+		}
+	}
+
+	sum := sha1.Sum(html)
+	p = &cachedPage{
+		html:    html,
+		etag:    `"` + hex.EncodeToString(sum[:]) + `"`,
+		modTime: time.Now(),
+	}
+	r.pageCache.pages[name] = p
+	return p, nil
+}
+
+func (r *Runtime) invalidatePages() {
+	r.pageCache.mu.Lock()
+	defer r.pageCache.mu.Unlock()
+
+	r.pageCache.pages = map[string]*cachedPage{}
+}
+
+// WithStreamingPages disables the page cache and encodes the options
+// payload directly into every response, trading CPU for peak memory.
+func WithStreamingPages() Option {
+	return func(r *Runtime) {
+		r.streamPages = true
+	}
+}
+
+func (r *Runtime) servePage(c echo.Context, name string) error {
+	// the head template needs the whole page, so an index is not streamed
+	// once it is customized
+	if name == "index.html" && (len(r.hooks.hydrate) > 0 || len(r.hooks.index) > 0 ||
+		r.flags != nil || (r.streamPages && r.headTemplate != nil)) {
+		return r.serveHydrated(c, name)
+	}
+
+	if r.streamPages {
+		options, err := r.uiOptions()
+		if err != nil {
+			return err
+		}
+		if r.cspNonce {
+			// the nonce is added to the rendered page, it is not streamed
+			buf := &bytes.Buffer{}
+			if err := r.renderPage(buf, name, options); err != nil {
+				return err
+			}
+			return r.servePageWithNonce(c, &cachedPage{html: buf.Bytes()})
+		}
+
+		c.Response().Header().Set(echo.HeaderContentType, echo.MIMETextHTMLCharsetUTF8)
+		c.Response().WriteHeader(http.StatusOK)
+		return r.renderPage(c.Response(), name, options)
+	}
+
+	p, err := r.page(name)
+	if err != nil {
+		return err
+	}
+
+	if r.cspNonce {
+		return r.servePageWithNonce(c, p)
+	}
+
+	c.Response().Header().Set("ETag", p.etag)
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-cache")
+	http.ServeContent(c.Response(), c.Request(), name, p.modTime, bytes.NewReader(p.html))
+	return nil
+}
@@ -0,0 +1,158 @@
+package runtime
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+type CalendarEvent struct {
+	Id    string    `json:"id"`
+	Title string    `json:"title"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// Color is a css color, the theme color by default.
+	Color string `json:"color,omitempty"`
+}
+
+// CalendarCallbacks answer what users do on a calendar, a nil callback
+// disables the interaction.
+type CalendarCallbacks struct {
+	OnClick func(conn *Conn, event CalendarEvent) error
+	// OnCreate runs for a click on an empty day of the month view or an
+	// empty hour of the week view. The returned event, if any, is added.
+	OnCreate func(conn *Conn, start, end time.Time) (*CalendarEvent, error)
+}
+
+// Calendar is the ServerState behind sunmao.NewCalendar, changes of its
+// events are pushed to every client.
+type Calendar struct {
+	*ServerState
+	mu        sync.Mutex
+	events    []CalendarEvent
+	callbacks CalendarCallbacks
+}
+
+type calendarClick struct {
+	Event string `json:"event"`
+}
+
+type calendarCreate struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// NewCalendar serves events to the calendars bound to id, pages served
+// after a change show the changed events.
+func NewCalendar(r *Runtime, id string, events []CalendarEvent, callbacks CalendarCallbacks) (*Calendar, error) {
+	c := &Calendar{
+		ServerState: r.NewServerState(id, sortEvents(events)),
+		events:      sortEvents(events),
+		callbacks:   callbacks,
+	}
+	c.ServeLatest()
+
+	if err := r.Handle(id+"/click", c.handleClick, ParamsOf[calendarClick]()); err != nil {
+		return nil, err
+	}
+	return c, r.Handle(id+"/create", c.handleCreate)
+}
+
+// Events returns the events ordered by start.
+func (c *Calendar) Events() []CalendarEvent {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.events
+}
+
+// SetEvents replaces all events.
+func (c *Calendar) SetEvents(events []CalendarEvent) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.events = sortEvents(events)
+	return c.SetState(c.events, nil)
+}
+
+// Put adds event, or replaces the event with the same id.
+func (c *Calendar) Put(event CalendarEvent) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.events = sortEvents(append(withoutEvent(c.events, event.Id), event))
+	return c.SetState(c.events, nil)
+}
+
+// Remove deletes the event with id.
+func (c *Calendar) Remove(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.events = withoutEvent(c.events, id)
+	return c.SetState(c.events, nil)
+}
+
+func (c *Calendar) handleClick(m *Message, connId int) error {
+	click, err := decodeParams[calendarClick](m)
+	if err != nil {
+		return err
+	}
+	if c.callbacks.OnClick == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	var event *CalendarEvent
+	for i := range c.events {
+		if c.events[i].Id == click.Event {
+			e := c.events[i]
+			event = &e
+		}
+	}
+	c.mu.Unlock()
+
+	if event == nil {
+		return fmt.Errorf("no event %v", click.Event)
+	}
+	return c.callbacks.OnClick(c.r.conns.get(connId), *event)
+}
+
+func (c *Calendar) handleCreate(m *Message, connId int) error {
+	create, err := decodeParams[calendarCreate](m)
+	if err != nil {
+		return err
+	}
+	if c.callbacks.OnCreate == nil {
+		return &ActionError{Code: ForbiddenCode, Message: "events can not be created"}
+	}
+	if !create.End.After(create.Start) {
+		return fmt.Errorf("event ends before it starts")
+	}
+
+	event, err := c.callbacks.OnCreate(c.r.conns.get(connId), create.Start, create.End)
+	if err != nil || event == nil {
+		return err
+	}
+	return c.Put(*event)
+}
+
+func withoutEvent(events []CalendarEvent, id string) []CalendarEvent {
+	kept := make([]CalendarEvent, 0, len(events))
+	for _, e := range events {
+		if e.Id != id {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
+
+// sortEvents returns a sorted copy, the client lays days out in order.
+func sortEvents(events []CalendarEvent) []CalendarEvent {
+	sorted := append([]CalendarEvent{}, events...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Start.Before(sorted[j].Start)
+	})
+	return sorted
+}
@@ -0,0 +1,146 @@
+// Package cdc brings database changes to the runtime. It decodes the change
+// events of Postgres LISTEN/NOTIFY triggers and Debezium connectors without
+// depending on a driver or broker client: feed it the payloads from the one
+// the app already uses.
+package cdc
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/yuyz0112/sunmao-ui-go-binding/pkg/runtime"
+)
+
+type Op string
+
+const (
+	Insert Op = "insert"
+	Update Op = "update"
+	Delete Op = "delete"
+)
+
+// Change is a row written to a table. Before is nil for inserts and After
+// is nil for deletes.
+type Change struct {
+	Table  string
+	Op     Op
+	Before map[string]any
+	After  map[string]any
+}
+
+// Row is the row after the change, or the deleted one.
+func (c *Change) Row() map[string]any {
+	if c.After != nil {
+		return c.After
+	}
+	return c.Before
+}
+
+// Bridge hands decoded changes to the handlers of their table.
+type Bridge struct {
+	mu       sync.RWMutex
+	handlers map[string][]func(Change) error
+}
+
+func NewBridge() *Bridge {
+	return &Bridge{handlers: map[string][]func(Change) error{}}
+}
+
+// On runs fn for every change of table, "*" for the changes of any table.
+func (b *Bridge) On(table string, fn func(Change) error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.handlers[table] = append(b.handlers[table], fn)
+}
+
+// Apply runs the handlers of the change's table, the first error ends it.
+func (b *Bridge) Apply(c Change) error {
+	b.mu.RLock()
+	handlers := append(append([]func(Change) error{}, b.handlers[c.Table]...), b.handlers["*"]...)
+	b.mu.RUnlock()
+
+	for _, fn := range handlers {
+		if err := fn(c); err != nil {
+			return fmt.Errorf("cdc %v %v: %w", c.Table, c.Op, err)
+		}
+	}
+	return nil
+}
+
+// Table mirrors the rows of table into state as a list ordered by key, so
+// a table component bound to it follows inserts, updates and deletes.
+// Start it with the rows read from the database, the changes since then
+// are applied on top. Clients served later get the current rows.
+func (b *Bridge) Table(table, key string, state *runtime.ServerState, rows []map[string]any) error {
+	state.ServeLatest()
+	t := &tableMirror{key: key, state: state, rows: map[string]map[string]any{}}
+	for _, row := range rows {
+		t.rows[fmt.Sprint(row[key])] = row
+	}
+	b.On(table, t.apply)
+	return t.push()
+}
+
+type tableMirror struct {
+	mu    sync.Mutex
+	key   string
+	state *runtime.ServerState
+	rows  map[string]map[string]any
+}
+
+func (t *tableMirror) apply(c Change) error {
+	row := c.Row()
+	if row == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if c.Op == Delete {
+		delete(t.rows, fmt.Sprint(row[t.key]))
+	} else {
+		// an update may change the key itself
+		if c.Before != nil {
+			delete(t.rows, fmt.Sprint(c.Before[t.key]))
+		}
+		t.rows[fmt.Sprint(row[t.key])] = row
+	}
+	return t.pushLocked()
+}
+
+func (t *tableMirror) push() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.pushLocked()
+}
+
+func (t *tableMirror) pushLocked() error {
+	keys := make([]string, 0, len(t.rows))
+	for k := range t.rows {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return lessKey(keys[i], keys[j])
+	})
+
+	rows := make([]map[string]any, len(keys))
+	for i, k := range keys {
+		rows[i] = t.rows[k]
+	}
+	return t.state.SetState(rows, nil)
+}
+
+// lessKey orders numeric keys by value and other keys as strings.
+func lessKey(a, b string) bool {
+	x, errA := strconv.ParseFloat(a, 64)
+	y, errB := strconv.ParseFloat(b, 64)
+	if errA == nil && errB == nil {
+		return x < y
+	}
+	return a < b
+}
@@ -0,0 +1,66 @@
+package cdc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// debeziumOps maps the op codes of Debezium, snapshot reads count as
+// inserts.
+var debeziumOps = map[string]Op{
+	"c": Insert,
+	"r": Insert,
+	"u": Update,
+	"d": Delete,
+}
+
+type debeziumPayload struct {
+	Op     string         `json:"op"`
+	Before map[string]any `json:"before"`
+	After  map[string]any `json:"after"`
+	Source struct {
+		Table string `json:"table"`
+	} `json:"source"`
+}
+
+// DecodeDebezium reads the value of a Debezium change event, with or
+// without the schema envelope of the json converter. ok is false for
+// tombstones and messages without a row change, such as truncates.
+func DecodeDebezium(value []byte) (c Change, ok bool, err error) {
+	value = bytes.TrimSpace(value)
+	if len(value) == 0 || bytes.Equal(value, []byte("null")) {
+		return Change{}, false, nil
+	}
+
+	envelope := struct {
+		Payload json.RawMessage `json:"payload"`
+	}{}
+	if err := json.Unmarshal(value, &envelope); err != nil {
+		return Change{}, false, fmt.Errorf("decode debezium event: %w", err)
+	}
+	if len(envelope.Payload) > 0 {
+		value = envelope.Payload
+	}
+
+	p := debeziumPayload{}
+	if err := json.Unmarshal(value, &p); err != nil {
+		return Change{}, false, fmt.Errorf("decode debezium event: %w", err)
+	}
+	op, ok := debeziumOps[p.Op]
+	if !ok {
+		return Change{}, false, nil
+	}
+	return Change{Table: p.Source.Table, Op: op, Before: p.Before, After: p.After}, true, nil
+}
+
+// ApplyDebezium decodes value and applies it, skipped events are no error.
+// Call it with the message values a Kafka consumer reads from the
+// connector's topics.
+func (b *Bridge) ApplyDebezium(value []byte) error {
+	c, ok, err := DecodeDebezium(value)
+	if err != nil || !ok {
+		return err
+	}
+	return b.Apply(c)
+}
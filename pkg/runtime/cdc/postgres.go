@@ -0,0 +1,73 @@
+package cdc
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// NotifyTrigger creates a trigger sending every row change of table as a
+// Postgres NOTIFY on channel, in the format DecodeNotify reads. Changes
+// carry the table name without its schema. Payloads
+// are limited to 8000 bytes by Postgres, wide rows should notify their key
+// only and be read again.
+func NotifyTrigger(table, channel string) string {
+	fn := strings.ReplaceAll(table, ".", "_") + "_notify"
+	return fmt.Sprintf(`CREATE OR REPLACE FUNCTION %[1]v() RETURNS trigger AS $$
+BEGIN
+  PERFORM pg_notify('%[2]v', json_build_object(
+    'table', TG_TABLE_NAME,
+    'op', lower(TG_OP),
+    'before', CASE WHEN TG_OP <> 'INSERT' THEN row_to_json(OLD) END,
+    'after', CASE WHEN TG_OP <> 'DELETE' THEN row_to_json(NEW) END
+  )::text);
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS %[1]v ON %[3]v;
+CREATE TRIGGER %[1]v AFTER INSERT OR UPDATE OR DELETE ON %[3]v
+  FOR EACH ROW EXECUTE FUNCTION %[1]v();
+`, fn, channel, table)
+}
+
+// DecodeNotify reads the payload of a notification sent by NotifyTrigger.
+func DecodeNotify(payload string) (Change, error) {
+	v := struct {
+		Table  string         `json:"table"`
+		Op     Op             `json:"op"`
+		Before map[string]any `json:"before"`
+		After  map[string]any `json:"after"`
+	}{}
+	if err := json.Unmarshal([]byte(payload), &v); err != nil {
+		return Change{}, fmt.Errorf("decode notify payload: %w", err)
+	}
+	switch v.Op {
+	case Insert, Update, Delete:
+	default:
+		return Change{}, fmt.Errorf("unknown op %q", v.Op)
+	}
+	return Change{Table: v.Table, Op: v.Op, Before: v.Before, After: v.After}, nil
+}
+
+// Notification is a NOTIFY delivered by a Postgres driver, such as the
+// Channel and Extra of a *pq.Notification or the Channel and Payload of a
+// *pgconn.Notification.
+type Notification struct {
+	Channel string
+	Payload string
+}
+
+// Listen applies the notifications of ch until it is closed. Payloads that
+// fail to decode or apply go to onError, which may be nil.
+func (b *Bridge) Listen(ch <-chan Notification, onError func(n Notification, err error)) {
+	for n := range ch {
+		c, err := DecodeNotify(n.Payload)
+		if err == nil {
+			err = b.Apply(c)
+		}
+		if err != nil && onError != nil {
+			onError(n, err)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package runtime
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// ChunkProgress reports how many chunks of a message were written to the
+// connection so far.
+type ChunkProgress func(conn *Conn, messageId string, written, total int)
+
+var chunkSeq uint64
+
+// WithChunking splits outbound messages larger than size bytes into Chunk
+// frames the client reassembles, so multi megabyte states pass proxies and
+// frame limits. progress may be nil.
+func WithChunking(size int, progress ChunkProgress) Option {
+	return func(r *Runtime) {
+		r.chunkSize = size
+		r.chunkProgress = progress
+	}
+}
+
+// splitChunks cuts payload into pieces of at most size bytes without
+// splitting a utf-8 sequence.
+func splitChunks(payload []byte, size int) [][]byte {
+	chunks := [][]byte{}
+	for len(payload) > size {
+		cut := size
+		for cut > 0 && !utf8.RuneStart(payload[cut]) {
+			cut--
+		}
+		if cut == 0 {
+			cut = size
+		}
+		chunks = append(chunks, payload[:cut])
+		payload = payload[cut:]
+	}
+	return append(chunks, payload)
+}
+
+// enqueueChunked queues the chunks as one message, a message sent later
+// can not overtake them and slow client policies drop them together.
+func (r *Runtime) enqueueChunked(conn *Conn, payload []byte) error {
+	id := strconv.FormatUint(atomic.AddUint64(&chunkSeq, 1), 36)
+	chunks := splitChunks(payload, r.chunkSize)
+	frames := make([][]byte, len(chunks))
+	written := make([]func(), len(chunks))
+	for i, chunk := range chunks {
+		frame, err := json.Marshal(map[string]interface{}{
+			"type":  "Chunk",
+			"id":    id,
+			"index": i,
+			"total": len(chunks),
+			"data":  string(chunk),
+		})
+		if err != nil {
+			return err
+		}
+		frames[i] = frame
+
+		if r.chunkProgress != nil {
+			i := i
+			written[i] = func() { r.chunkProgress(conn, id, i+1, len(chunks)) }
+		}
+	}
+	return conn.enqueueFrames(frames, written)
+}
+
+// enqueueMessage sends payload as is or in chunks, depending on its size.
+func (r *Runtime) enqueueMessage(conn *Conn, payload []byte) error {
+	if r.chunkSize > 0 && len(payload) > r.chunkSize {
+		return r.enqueueChunked(conn, payload)
+	}
+	return conn.enqueue(payload)
+}
@@ -0,0 +1,35 @@
+package runtime
+
+// ClientError is a render error caught by an error boundary on the client.
+type ClientError struct {
+	// ComponentId is the error boundary that caught the error.
+	ComponentId    string `json:"componentId"`
+	Message        string `json:"message"`
+	Stack          string `json:"stack"`
+	ComponentStack string `json:"componentStack"`
+}
+
+func (e *ClientError) Error() string {
+	return "client error in " + e.ComponentId + ": " + e.Message
+}
+
+// OnClientError runs for every error an error boundary caught. Without a
+// hook the errors are logged.
+func (r *Runtime) OnClientError(fn func(conn *Conn, e *ClientError)) {
+	r.hooks.clientError = append(r.hooks.clientError, fn)
+}
+
+func (r *Runtime) clientError(conn *Conn, params any) {
+	e, err := decodeValue[ClientError](params)
+	if err != nil {
+		return
+	}
+
+	if len(r.hooks.clientError) == 0 {
+		r.e.Logger.Error(&e)
+		return
+	}
+	for _, fn := range r.hooks.clientError {
+		fn(conn, &e)
+	}
+}
@@ -0,0 +1,46 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+type MarshalFunc func(v any) ([]byte, error)
+
+type UnmarshalFunc func(data []byte, v any) error
+
+var codec = struct {
+	custom    bool
+	marshal   MarshalFunc
+	unmarshal UnmarshalFunc
+}{
+	marshal:   json.Marshal,
+	unmarshal: json.Unmarshal,
+}
+
+// SetJSONCodec swaps encoding/json for a faster compatible implementation
+// such as sonic or go-json, for websocket frames and the options payload.
+// Call it before New, it is not safe to change while serving.
+func SetJSONCodec(marshal MarshalFunc, unmarshal UnmarshalFunc) {
+	codec.custom = true
+	codec.marshal = marshal
+	codec.unmarshal = unmarshal
+}
+
+func encodeOptions(w io.Writer, options map[string]interface{}) error {
+	if !codec.custom {
+		// the encoder escapes html by default
+		return json.NewEncoder(w).Encode(options)
+	}
+
+	// other codecs do not reliably escape html, escape after encoding
+	buf, err := codec.marshal(options)
+	if err != nil {
+		return err
+	}
+	escaped := &bytes.Buffer{}
+	json.HTMLEscape(escaped, buf)
+	_, err = escaped.WriteTo(w)
+	return err
+}
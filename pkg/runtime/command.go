@@ -0,0 +1,384 @@
+package runtime
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// commandFlushInterval batches the output lines sent to the log views
+const commandFlushInterval = 100 * time.Millisecond
+
+// maxCommandLine is the longest output line, longer lines end the output
+const maxCommandLine = 1 << 20
+
+// CommandFunc builds the command of a run from the params of the start
+// action. Do not start it, the runner does.
+type CommandFunc func(conn *Conn, params map[string]any) (*exec.Cmd, error)
+
+// LogOpen opens the stream of a run from the params of the start action.
+type LogOpen func(ctx context.Context, conn *Conn, params map[string]any) (io.ReadCloser, error)
+
+// CommandState is the state of a connection's run, see
+// sunmao.CommandRunning.
+type CommandState struct {
+	Running bool `json:"running"`
+	// Run counts the runs of the connection, log views clear on a new one.
+	Run      int    `json:"run"`
+	ExitCode *int   `json:"exitCode,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// LogLine is a line of output shown by the binding/v1/logView component.
+type LogLine struct {
+	// Stream is "stdout" or "stderr".
+	Stream string `json:"stream"`
+	Text   string `json:"text"`
+}
+
+// CommandRunner is the ServerState behind sunmao.NewLogView, it runs a
+// command per start action and streams its output to the log views of the
+// starting connection.
+type CommandRunner struct {
+	*ServerState
+	launch func(conn *Conn, params map[string]any) (*commandJob, error)
+	// slots holds a token per running command, nil for no limit
+	slots chan struct{}
+	mu    sync.Mutex
+	runs  map[int]*commandRun
+}
+
+// commandJob is a started run, wait returns how it ended once its streams
+// were drained.
+type commandJob struct {
+	streams []commandStream
+	kill    func()
+	wait    func() (exitCode *int, err error)
+}
+
+type commandStream struct {
+	name string
+	r    io.ReadCloser
+}
+
+func startCommand(cmd *exec.Cmd) (*commandJob, error) {
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &commandJob{
+		streams: []commandStream{{name: "stdout", r: stdout}, {name: "stderr", r: stderr}},
+		kill: func() {
+			_ = killProcessGroup(cmd)
+		},
+		wait: func() (*int, error) {
+			err := cmd.Wait()
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				// a non zero exit is reported by its code
+				err = nil
+			}
+			if code := cmd.ProcessState.ExitCode(); code >= 0 {
+				return &code, err
+			}
+			return nil, err
+		},
+	}, nil
+}
+
+type commandRun struct {
+	run    int
+	cancel chan struct{}
+	once   sync.Once
+}
+
+func (c *commandRun) stop() {
+	c.once.Do(func() { close(c.cancel) })
+}
+
+// NewCommandRunner registers the handlers id/start and id/cancel. A
+// connection runs one command at a time and at most limit commands run
+// at once, 0 for no limit; starts beyond that fail. Commands run in a
+// process group of their own, which is killed when they are cancelled or
+// their connection closes.
+func NewCommandRunner(r *Runtime, id string, build CommandFunc, limit int) (*CommandRunner, error) {
+	return newCommandRunner(r, id, func(conn *Conn, params map[string]any) (*commandJob, error) {
+		cmd, err := build(conn, params)
+		if err != nil {
+			return nil, err
+		}
+		return startCommand(cmd)
+	}, limit)
+}
+
+// NewLogStream is a CommandRunner following the stream open returns, such
+// as the body of a followed log, until it ends or the client cancels. ctx
+// is cancelled then too.
+func NewLogStream(r *Runtime, id string, open LogOpen, limit int) (*CommandRunner, error) {
+	return newCommandRunner(r, id, func(conn *Conn, params map[string]any) (*commandJob, error) {
+		ctx, cancel := context.WithCancel(context.Background())
+		stream, err := open(ctx, conn, params)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		return &commandJob{
+			streams: []commandStream{{name: "stdout", r: stream}},
+			kill:    cancel,
+			wait: func() (*int, error) {
+				cancel()
+				return nil, stream.Close()
+			},
+		}, nil
+	}, limit)
+}
+
+func newCommandRunner(r *Runtime, id string, launch func(conn *Conn, params map[string]any) (*commandJob, error), limit int) (*CommandRunner, error) {
+	c := &CommandRunner{
+		ServerState: r.NewServerState(id, &CommandState{}),
+		launch:      launch,
+		runs:        map[int]*commandRun{},
+	}
+	if limit > 0 {
+		c.slots = make(chan struct{}, limit)
+	}
+
+	r.OnDisconnected(func(conn *Conn, _ CloseReason) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		if run, ok := c.runs[conn.Id]; ok {
+			run.stop()
+			delete(c.runs, conn.Id)
+		}
+	})
+
+	if err := r.Handle(id+"/start", c.handleStart); err != nil {
+		return nil, err
+	}
+	return c, r.Handle(id+"/cancel", func(m *Message, connId int) error {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		if run, ok := c.runs[connId]; ok {
+			run.stop()
+		}
+		return nil
+	})
+}
+
+func (c *CommandRunner) handleStart(m *Message, connId int) error {
+	conn := c.r.conns.get(connId)
+	if conn == nil {
+		return nil
+	}
+	params, _ := m.Params.(map[string]any)
+
+	c.mu.Lock()
+	prev, running := c.runs[connId]
+	if running {
+		select {
+		case <-prev.cancel:
+		default:
+			c.mu.Unlock()
+			return &ActionError{Code: "busy", Message: "a command is already running"}
+		}
+	}
+	if c.slots != nil {
+		select {
+		case c.slots <- struct{}{}:
+		default:
+			c.mu.Unlock()
+			return &ActionError{Code: "busy", Message: "too many commands are running, try again later"}
+		}
+	}
+	// the run is taken before launching, a cancel or disconnect meanwhile
+	// stops it once launched
+	run := &commandRun{run: 1, cancel: make(chan struct{})}
+	if prev != nil {
+		run.run = prev.run + 1
+	}
+	c.runs[connId] = run
+	c.mu.Unlock()
+
+	return c.start(conn, params, run, prev)
+}
+
+// start launches run for conn without holding c.mu, launching may open a
+// remote stream. Action errors of the launch are returned and leave prev
+// as the last run, other ones are shown like a failed run.
+func (c *CommandRunner) start(conn *Conn, params map[string]any, run, prev *commandRun) error {
+	connId := conn.Id
+	job, err := c.launch(conn, params)
+	if err != nil {
+		run.stop()
+		if c.slots != nil {
+			<-c.slots
+		}
+	}
+	var actionErr *ActionError
+	if errors.As(err, &actionErr) {
+		c.mu.Lock()
+		if c.runs[connId] == run {
+			if prev != nil {
+				c.runs[connId] = prev
+			} else {
+				delete(c.runs, connId)
+			}
+		}
+		c.mu.Unlock()
+		return err
+	}
+	if err != nil {
+		c.push(connId, &CommandState{Run: run.run, Error: err.Error()})
+		return nil
+	}
+	c.push(connId, &CommandState{Running: true, Run: run.run})
+
+	out := &commandOutput{r: c.r, id: c.Id, connId: connId, run: run.run, cancel: run.cancel}
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-run.cancel:
+			job.kill()
+			// children which left the process group may keep the pipes
+			// open
+			for _, s := range job.streams {
+				_ = s.r.Close()
+			}
+		case <-done:
+		}
+	}()
+	go func() {
+		if c.slots != nil {
+			defer func() { <-c.slots }()
+		}
+		wg := sync.WaitGroup{}
+		wg.Add(len(job.streams))
+		for _, s := range job.streams {
+			go out.read(s.name, s.r, &wg)
+		}
+		flushDone := out.flushEvery(commandFlushInterval)
+		// the pipes must be drained before Wait closes them
+		wg.Wait()
+		exitCode, err := job.wait()
+		close(done)
+		flushDone()
+
+		state := &CommandState{Run: run.run, ExitCode: exitCode}
+		select {
+		case <-run.cancel:
+			state.Error = "cancelled"
+		default:
+			if err != nil {
+				state.Error = err.Error()
+			}
+		}
+		run.stop()
+		c.push(connId, state)
+	}()
+	return nil
+}
+
+func (c *CommandRunner) push(connId int, state *CommandState) {
+	if err := c.SetState(state, &connId); err != nil && err != errConnClosed {
+		c.r.e.Logger.Error(err)
+	}
+}
+
+// commandOutput batches the lines of a run for its connection.
+type commandOutput struct {
+	r       *Runtime
+	id      string
+	connId  int
+	run     int
+	cancel  <-chan struct{}
+	mu      sync.Mutex
+	pending []LogLine
+}
+
+func (o *commandOutput) cancelled() bool {
+	select {
+	case <-o.cancel:
+		return true
+	default:
+		return false
+	}
+}
+
+func (o *commandOutput) read(stream string, pipe io.Reader, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	scanner := bufio.NewScanner(pipe)
+	scanner.Buffer(make([]byte, 64<<10), maxCommandLine)
+	for scanner.Scan() {
+		o.mu.Lock()
+		o.pending = append(o.pending, LogLine{Stream: stream, Text: scanner.Text()})
+		o.mu.Unlock()
+	}
+	if err := scanner.Err(); err != nil && !o.cancelled() {
+		o.mu.Lock()
+		o.pending = append(o.pending, LogLine{Stream: stream, Text: err.Error()})
+		o.mu.Unlock()
+		// keep draining, a blocked pipe would block the command
+		_, _ = io.Copy(io.Discard, pipe)
+	}
+}
+
+// flushEvery sends the pending lines every interval until the returned
+// func, which sends the rest, is called.
+func (o *commandOutput) flushEvery(interval time.Duration) func() {
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				o.flush()
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-stopped
+		o.flush()
+	}
+}
+
+func (o *commandOutput) flush() {
+	o.mu.Lock()
+	lines := o.pending
+	o.pending = nil
+	o.mu.Unlock()
+
+	if len(lines) == 0 {
+		return
+	}
+	err := o.r.send(map[string]interface{}{
+		"type":  "LogLines",
+		"log":   o.id,
+		"run":   o.run,
+		"lines": lines,
+	}, &o.connId)
+	if err != nil && err != errConnClosed {
+		o.r.e.Logger.Error(err)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package runtime
+
+import "os/exec"
+
+// setProcessGroup is unix only, elsewhere only the command itself is
+// killed.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
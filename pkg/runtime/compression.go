@@ -0,0 +1,39 @@
+package runtime
+
+import (
+	"compress/flate"
+
+	"github.com/gorilla/websocket"
+)
+
+type compression struct {
+	level     int
+	threshold int
+}
+
+// WithCompression negotiates permessage-deflate with clients and
+// compresses outbound frames of at least threshold bytes at level, a
+// flate level from 1 to 9. Small frames are cheaper sent as they are.
+func WithCompression(level int, threshold int) Option {
+	return func(r *Runtime) {
+		if level < flate.BestSpeed || level > flate.BestCompression {
+			level = flate.DefaultCompression
+		}
+		r.compression = &compression{level: level, threshold: threshold}
+	}
+}
+
+func (r *Runtime) upgrader() *websocket.Upgrader {
+	if r.compression == nil {
+		return &upgrader
+	}
+	return &websocket.Upgrader{EnableCompression: true}
+}
+
+// compress prepares the next write, it is only called by the writer
+// goroutine of the connection.
+func (r *Runtime) compress(ws *websocket.Conn, size int) {
+	if r.compression != nil {
+		ws.EnableWriteCompression(size >= r.compression.threshold)
+	}
+}
@@ -0,0 +1,243 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/gommon/log"
+	"gopkg.in/yaml.v3"
+)
+
+type TLSConfig struct {
+	CertFile string `json:"certFile" yaml:"certFile"`
+	KeyFile  string `json:"keyFile" yaml:"keyFile"`
+}
+
+type LimitsConfig struct {
+	// MaxMessageSize caps inbound websocket messages in bytes.
+	MaxMessageSize int64 `json:"maxMessageSize" yaml:"maxMessageSize"`
+}
+
+type AccessLogConfig struct {
+	// File is appended to, stdout when empty.
+	File       string   `json:"file" yaml:"file"`
+	Format     string   `json:"format" yaml:"format"`
+	SampleRate float64  `json:"sampleRate" yaml:"sampleRate"`
+	Exclude    []string `json:"exclude" yaml:"exclude"`
+}
+
+// Config holds the deployment settings of a Runtime, see LoadConfig.
+type Config struct {
+	Addr     string       `json:"addr" yaml:"addr"`
+	TLS      *TLSConfig   `json:"tls" yaml:"tls"`
+	BasePath string       `json:"basePath" yaml:"basePath"`
+	UIDir    string       `json:"uiDir" yaml:"uiDir"`
+	PatchDir string       `json:"patchDir" yaml:"patchDir"`
+	Limits   LimitsConfig `json:"limits" yaml:"limits"`
+	// LogLevel is one of debug, info, warn, error and off.
+	LogLevel string `json:"logLevel" yaml:"logLevel"`
+	// DevMode serves the websocket frame inspector.
+	DevMode bool `json:"devMode" yaml:"devMode"`
+	// H2C serves cleartext HTTP/2, for use behind a TLS terminating proxy.
+	H2C bool `json:"h2c" yaml:"h2c"`
+	// AccessLog enables request logging when set.
+	AccessLog *AccessLogConfig `json:"accessLog" yaml:"accessLog"`
+	// TrustedProxies are CIDRs whose X-Forwarded-* headers are honored.
+	TrustedProxies []string `json:"trustedProxies" yaml:"trustedProxies"`
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		Addr:     ":8999",
+		UIDir:    "ui",
+		PatchDir: "patch",
+		LogLevel: "error",
+	}
+}
+
+// LoadConfig reads a yaml or json file on top of DefaultConfig, then
+// applies SUNMAO_* environment variables. An empty path only reads the
+// environment.
+func LoadConfig(path string) (*Config, error) {
+	cfg := DefaultConfig()
+
+	if path != "" {
+		buf, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		switch filepath.Ext(path) {
+		case ".yaml", ".yml":
+			err = yaml.Unmarshal(buf, cfg)
+		case ".json":
+			err = json.Unmarshal(buf, cfg)
+		default:
+			err = fmt.Errorf("unknown config format %v", path)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return cfg, cfg.applyEnv()
+}
+
+func (cfg *Config) applyEnv() error {
+	strs := map[string]*string{
+		"SUNMAO_ADDR":      &cfg.Addr,
+		"SUNMAO_BASE_PATH": &cfg.BasePath,
+		"SUNMAO_UI_DIR":    &cfg.UIDir,
+		"SUNMAO_PATCH_DIR": &cfg.PatchDir,
+		"SUNMAO_LOG_LEVEL": &cfg.LogLevel,
+	}
+	for k, p := range strs {
+		if v, ok := os.LookupEnv(k); ok {
+			*p = v
+		}
+	}
+
+	if v, ok := os.LookupEnv("SUNMAO_DEV_MODE"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("SUNMAO_DEV_MODE: %w", err)
+		}
+		cfg.DevMode = b
+	}
+
+	if v, ok := os.LookupEnv("SUNMAO_H2C"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("SUNMAO_H2C: %w", err)
+		}
+		cfg.H2C = b
+	}
+
+	if v, ok := os.LookupEnv("SUNMAO_MAX_MESSAGE_SIZE"); ok {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("SUNMAO_MAX_MESSAGE_SIZE: %w", err)
+		}
+		cfg.Limits.MaxMessageSize = n
+	}
+
+	if v, ok := os.LookupEnv("SUNMAO_TRUSTED_PROXIES"); ok {
+		cfg.TrustedProxies = strings.Split(v, ",")
+	}
+
+	cert, certOk := os.LookupEnv("SUNMAO_TLS_CERT_FILE")
+	key, keyOk := os.LookupEnv("SUNMAO_TLS_KEY_FILE")
+	if certOk || keyOk {
+		cfg.TLS = &TLSConfig{CertFile: cert, KeyFile: key}
+	}
+
+	return nil
+}
+
+func parseLogLevel(level string) (log.Lvl, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return log.DEBUG, nil
+	case "info":
+		return log.INFO, nil
+	case "warn":
+		return log.WARN, nil
+	case "error", "":
+		return log.ERROR, nil
+	case "off":
+		return log.OFF, nil
+	}
+	return 0, fmt.Errorf("unknown log level %v", level)
+}
+
+// Options translates cfg into runtime options.
+func (cfg *Config) Options() ([]Option, error) {
+	level, err := parseLogLevel(cfg.LogLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []Option{
+		WithAddr(cfg.Addr),
+		WithBasePath(cfg.BasePath),
+		WithMaxMessageSize(cfg.Limits.MaxMessageSize),
+		func(r *Runtime) {
+			r.e.Logger.SetLevel(level)
+		},
+	}
+	if cfg.TLS != nil {
+		opts = append(opts, WithTLS(cfg.TLS.CertFile, cfg.TLS.KeyFile))
+	}
+	if cfg.DevMode {
+		opts = append(opts, WithInspector())
+	}
+	if cfg.H2C {
+		opts = append(opts, WithH2C())
+	}
+	if len(cfg.TrustedProxies) > 0 {
+		proxies := make([]CIDR, 0, len(cfg.TrustedProxies))
+		for _, p := range cfg.TrustedProxies {
+			prefix, err := netip.ParsePrefix(strings.TrimSpace(p))
+			if err != nil {
+				return nil, fmt.Errorf("trusted proxy: %w", err)
+			}
+			proxies = append(proxies, prefix)
+		}
+		opts = append(opts, WithTrustedProxies(proxies...))
+	}
+	if l := cfg.AccessLog; l != nil {
+		if l.Format != "" && l.Format != "text" && l.Format != "json" {
+			return nil, fmt.Errorf("unknown access log format %v", l.Format)
+		}
+		accessLog := AccessLog{
+			Format:     l.Format,
+			SampleRate: l.SampleRate,
+			Exclude:    l.Exclude,
+		}
+		if l.File != "" {
+			f, err := os.OpenFile(l.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+			if err != nil {
+				return nil, err
+			}
+			accessLog.Out = f
+			accessLog.file = f
+		}
+		opts = append(opts, WithAccessLog(accessLog))
+	}
+	return opts, nil
+}
+
+// NewFromConfig creates a Runtime from cfg, opts are applied after the
+// ones derived from cfg.
+func NewFromConfig(cfg *Config, opts ...Option) (*Runtime, error) {
+	cfgOpts, err := cfg.Options()
+	if err != nil {
+		return nil, err
+	}
+	return New(cfg.UIDir, cfg.PatchDir, append(cfgOpts, opts...)...), nil
+}
+
+func WithAddr(addr string) Option {
+	return func(r *Runtime) {
+		r.addr = addr
+	}
+}
+
+func WithTLS(certFile, keyFile string) Option {
+	return func(r *Runtime) {
+		r.tls = &TLSConfig{CertFile: certFile, KeyFile: keyFile}
+	}
+}
+
+// WithMaxMessageSize closes websocket connections sending messages larger
+// than n bytes, zero means unlimited.
+func WithMaxMessageSize(n int64) Option {
+	return func(r *Runtime) {
+		r.maxMessageSize = n
+	}
+}
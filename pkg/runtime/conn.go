@@ -0,0 +1,389 @@
+package runtime
+
+import (
+	"encoding/binary"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+)
+
+const outboundQueueSize = 256
+
+var errConnClosed = errors.New("connection closed")
+
+// Conn is a websocket connection of a client.
+type Conn struct {
+	Id int
+	// Identity is resolved from the session when the websocket is upgraded,
+	// it is nil for anonymous clients.
+	Identity *Identity
+	// TabId is minted by the client once per page load and kept across
+	// reconnects, it tells apart the tabs of one user.
+	TabId string
+	// CloseReason is set right before the disconnected hook runs.
+	CloseReason *CloseReason
+	ws          *websocket.Conn
+	hidden      atomic.Bool
+	// requestId is the Action being handled on the read goroutine
+	requestId atomic.Value
+	// session is the session cookie the websocket was upgraded with, Logout
+	// closes the connections of the cleared session
+	session string
+
+	prefMu sync.RWMutex
+	prefs  map[string]any
+
+	// versions are the ServerState versions pushed to this connection alone,
+	// by state id
+	versionMu sync.Mutex
+	versions  map[string]uint64
+
+	limits connLimits
+
+	// mu orders seq assignment with queueing, so frames leave the single
+	// writer goroutine in the order their seq was assigned
+	mu  sync.Mutex
+	seq uint64
+	out chan outMessage
+	// room is signalled by the writer whenever it takes a message off out
+	room   chan struct{}
+	done   chan struct{}
+	policy SlowClientPolicy
+
+	// deferred calls run on the read loop between incoming messages
+	deferred chan func()
+}
+
+type outFrame struct {
+	binary bool
+	data   []byte
+	// written runs once the frame left the writer, if set
+	written func()
+}
+
+// outMessage is one entry of the outbound queue. The chunks of a large
+// message are a single entry, so they are queued and dropped together.
+type outMessage []outFrame
+
+// CloseReason tells why a connection ended.
+type CloseReason struct {
+	// Code is the websocket close code, 1006 when the connection dropped
+	// without a close frame.
+	Code int
+	Text string
+	// Err is the underlying read error for abnormal closures.
+	Err error
+}
+
+// Normal reports whether the client closed the connection on purpose,
+// e.g. by navigating away.
+func (r *CloseReason) Normal() bool {
+	return r.Code == websocket.CloseNormalClosure || r.Code == websocket.CloseGoingAway
+}
+
+func closeReasonOf(err error) *CloseReason {
+	closeErr := &websocket.CloseError{}
+	if errors.As(err, &closeErr) {
+		return &CloseReason{Code: closeErr.Code, Text: closeErr.Text}
+	}
+	return &CloseReason{Code: websocket.CloseAbnormalClosure, Err: err}
+}
+
+// withSeq prepends the seq field to an encoded json object.
+func withSeq(seq uint64, payload []byte) []byte {
+	buf := make([]byte, 0, len(payload)+24)
+	buf = append(buf, `{"seq":`...)
+	buf = strconv.AppendUint(buf, seq, 10)
+	if len(payload) > 2 {
+		buf = append(buf, ',')
+	}
+	return append(buf, payload[1:]...)
+}
+
+// enqueue stamps the next seq on an encoded message and hands it to the
+// writer goroutine. The client applies frames in seq order.
+func (c *Conn) enqueue(payload []byte) error {
+	return c.enqueueFrame(payload, nil)
+}
+
+func (c *Conn) enqueueFrame(payload []byte, written func()) error {
+	return c.queue(func(seq uint64) outMessage {
+		return outMessage{{data: withSeq(seq, payload), written: written}}
+	})
+}
+
+// enqueueFrames queues the payloads as one message whose frames take
+// consecutive seqs, written[i] may be set for payloads[i].
+func (c *Conn) enqueueFrames(payloads [][]byte, written []func()) error {
+	return c.queue(func(seq uint64) outMessage {
+		msg := make(outMessage, len(payloads))
+		for i, payload := range payloads {
+			msg[i] = outFrame{data: withSeq(seq+uint64(i), payload), written: written[i]}
+		}
+		return msg
+	})
+}
+
+// enqueueBinary sends a json header followed by raw bytes in one binary
+// frame. The header is prefixed by its length as a big endian uint32 and
+// takes part in the seq order like any text frame.
+func (c *Conn) enqueueBinary(header []byte, body []byte) error {
+	return c.queue(func(seq uint64) outMessage {
+		header := withSeq(seq, header)
+		buf := make([]byte, 4, 4+len(header)+len(body))
+		binary.BigEndian.PutUint32(buf, uint32(len(header)))
+		buf = append(buf, header...)
+		buf = append(buf, body...)
+		return outMessage{{binary: true, data: buf}}
+	})
+}
+
+// queue hands the message stamp builds from the next seq to the writer
+// goroutine. A full queue is handled by the slow client policy, blocking
+// waits for room without holding c.mu, the message is stamped again once
+// there is room.
+func (c *Conn) queue(stamp func(seq uint64) outMessage) error {
+	for {
+		c.mu.Lock()
+		msg := stamp(c.seq + 1)
+		select {
+		case c.out <- msg:
+			c.seq += uint64(len(msg))
+			c.mu.Unlock()
+			return nil
+		case <-c.done:
+			c.mu.Unlock()
+			return errConnClosed
+		default:
+		}
+		if c.policy != SlowClientBlock {
+			queued, err := c.pushFull(msg)
+			if queued {
+				c.seq += uint64(len(msg))
+			}
+			c.mu.Unlock()
+			return err
+		}
+		c.mu.Unlock()
+
+		select {
+		case <-c.room:
+		case <-c.done:
+			return errConnClosed
+		}
+	}
+}
+
+func (r *Runtime) writeLoop(conn *Conn) {
+	for {
+		select {
+		case msg := <-conn.out:
+			select {
+			case conn.room <- struct{}{}:
+			default:
+			}
+			for _, frame := range msg {
+				if !r.writeFrame(conn, frame) {
+					// the read loop notices the broken connection and
+					// cleans up
+					conn.ws.Close()
+					return
+				}
+			}
+		case <-conn.done:
+			return
+		}
+	}
+}
+
+func (r *Runtime) writeFrame(conn *Conn, frame outFrame) bool {
+	messageType := websocket.TextMessage
+	if frame.binary {
+		messageType = websocket.BinaryMessage
+	}
+	r.compress(conn.ws, len(frame.data))
+	conn.ws.SetWriteDeadline(r.writeDeadline())
+	if err := conn.ws.WriteMessage(messageType, frame.data); err != nil {
+		return false
+	}
+	r.metrics.MessageSent(conn.Id, len(frame.data))
+	if !frame.binary {
+		r.trace(directionOut, conn.Id, frame.data)
+	}
+	if frame.written != nil {
+		frame.written()
+	}
+	return true
+}
+
+// deferCall runs fn on the read loop of c, it is dropped once c closed.
+func (c *Conn) deferCall(fn func()) {
+	select {
+	case c.deferred <- fn:
+	case <-c.done:
+	}
+}
+
+type connSet struct {
+	mu    sync.RWMutex
+	conns map[int]*Conn
+	// owner indexes the connections of its tenants too, the handlers and
+	// hooks they share capture the owner and look connections up on it
+	owner   *connSet
+	tenants map[int]*Conn
+}
+
+func newConnSet() *connSet {
+	return &connSet{conns: map[int]*Conn{}, tenants: map[int]*Conn{}}
+}
+
+// newTenantConnSet returns the connections of a tenant of owner.
+func newTenantConnSet(owner *connSet) *connSet {
+	s := newConnSet()
+	s.owner = owner
+	return s
+}
+
+func (s *connSet) get(connId int) *Conn {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if conn, ok := s.conns[connId]; ok {
+		return conn
+	}
+	return s.tenants[connId]
+}
+
+func (s *connSet) add(conn *Conn) {
+	s.mu.Lock()
+	s.conns[conn.Id] = conn
+	s.mu.Unlock()
+
+	if s.owner != nil {
+		s.owner.mu.Lock()
+		s.owner.tenants[conn.Id] = conn
+		s.owner.mu.Unlock()
+	}
+}
+
+func (s *connSet) remove(connId int) {
+	s.mu.Lock()
+	delete(s.conns, connId)
+	s.mu.Unlock()
+
+	if s.owner != nil {
+		s.owner.mu.Lock()
+		delete(s.owner.tenants, connId)
+		s.owner.mu.Unlock()
+	}
+}
+
+// list returns a snapshot, so callers may block on a connection without
+// holding the lock. The connections of tenants are not listed, broadcasts
+// stay within one hostname.
+func (s *connSet) list() []*Conn {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	conns := make([]*Conn, 0, len(s.conns))
+	for _, conn := range s.conns {
+		conns = append(conns, conn)
+	}
+	return conns
+}
+
+// Conn returns the connection with connId, or nil if it is closed.
+func (r *Runtime) Conn(connId int) *Conn {
+	return r.conns.get(connId)
+}
+
+func (r *Runtime) serveWs(c echo.Context) error {
+	ws, err := r.upgrader().Upgrade(c.Response(), c.Request(), nil)
+	if err != nil {
+		return err
+	}
+	if r.compression != nil {
+		ws.SetCompressionLevel(r.compression.level)
+	}
+	if r.maxMessageSize > 0 {
+		ws.SetReadLimit(r.maxMessageSize)
+	}
+	connId := int(atomic.AddInt64(r.connSeq, 1))
+	conn := &Conn{
+		Id:       connId,
+		Identity: r.Identity(c),
+		TabId:    c.QueryParam("tab"),
+		ws:       ws,
+		session:  sessionOf(c),
+		out:      make(chan outMessage, r.outboundQueueSize()),
+		room:     make(chan struct{}, 1),
+		deferred: make(chan func()),
+		done:     make(chan struct{}),
+		policy:   r.slowClientPolicy(),
+	}
+	r.conns.add(conn)
+	go r.writeLoop(conn)
+	defer func() {
+		r.conns.remove(connId)
+		conn.limits.stop()
+		close(conn.done)
+		ws.Close()
+	}()
+
+	r.resume(conn, c.QueryParam("resume"))
+	r.metrics.ConnOpened(conn)
+	for _, fn := range r.hooks.connected {
+		fn(conn)
+	}
+
+	// messages are read on their own goroutine, so deferred calls can join
+	// the loop between them
+	in := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		for {
+			_, msgBytes, err := ws.ReadMessage()
+			if err != nil {
+				readErr <- err
+				return
+			}
+			select {
+			case in <- msgBytes:
+			case <-conn.done:
+				return
+			}
+		}
+	}()
+
+	// every way out of the read loop, a close frame, a timeout, a read
+	// limit violation or a dropped tcp connection, ends up here once
+	for conn.CloseReason == nil {
+		select {
+		case msgBytes := <-in:
+			r.trace(directionIn, connId, msgBytes)
+			r.dispatch(msgBytes, connId)
+		case fn := <-conn.deferred:
+			fn()
+		case err := <-readErr:
+			if errors.Is(err, websocket.ErrReadLimit) {
+				r.invalidMessage(connId, InvalidTooLarge, err, nil)
+			}
+			conn.CloseReason = closeReasonOf(err)
+			if !conn.CloseReason.Normal() {
+				c.Logger().Error(err)
+			}
+		}
+	}
+
+	r.metrics.ConnClosed(conn, *conn.CloseReason)
+	for _, fn := range r.hooks.disconnected {
+		fn(conn, *conn.CloseReason)
+	}
+	r.userStates.disconnected(r, conn)
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package runtime
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+const csrfCookie = "sunmao_csrf"
+
+// WithCSRF requires state changing requests to echo the token of the
+// csrf cookie in the X-CSRF-Token header. The ui reads the cookie named in
+// the options payload. Token authenticated routes such as the action api
+// and webhooks are not checked.
+func WithCSRF() Option {
+	return func(r *Runtime) {
+		r.csrf = true
+	}
+}
+
+func (r *Runtime) csrfMiddleware() echo.MiddlewareFunc {
+	path := r.basePath
+	if path == "" {
+		path = "/"
+	}
+
+	return middleware.CSRFWithConfig(middleware.CSRFConfig{
+		Skipper: func(c echo.Context) bool {
+			// exemptions are relative to the app serving the request
+			m := r.mountOf(c.Request().URL.Path)
+			return matchPath(m.authExempt, strings.TrimPrefix(c.Request().URL.Path, m.basePath))
+		},
+		// html forms such as the login page send the token as a field
+		TokenLookup:    "header:" + echo.HeaderXCSRFToken + ",form:" + csrfFormField,
+		CookieName:     csrfCookie,
+		CookiePath:     path,
+		CookieSameSite: http.SameSiteStrictMode,
+	})
+}
@@ -0,0 +1,11 @@
+package runtime
+
+import "github.com/yuyz0112/sunmao-ui-go-binding/pkg/sunmao"
+
+// DeclareComponents passes custom component declarations to the client,
+// which reports every declared component missing an implementation when
+// it loads.
+func (r *Runtime) DeclareComponents(components ...sunmao.CustomComponent) {
+	r.customComponents = append(r.customComponents, components...)
+	r.invalidatePages()
+}
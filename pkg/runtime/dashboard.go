@@ -0,0 +1,106 @@
+package runtime
+
+import "sync"
+
+// Widget is a card of a dashboard.
+type Widget struct {
+	Key   string `json:"key"`
+	Title string `json:"title"`
+	// Kind picks the renderer of the data: "stat" for a {value, caption},
+	// "bars" and "line" for a series of {label, value}, "list" for strings
+	// or {title, description} rows. Other data is shown as json.
+	Kind string `json:"kind"`
+	// W and H are the default size in grid cells.
+	W int `json:"w"`
+	H int `json:"h"`
+	// Data loads the widget for a connection, it runs when the app was
+	// served and on every Refresh.
+	Data func(conn *Conn) (any, error) `json:"-"`
+}
+
+// WidgetLayout is the place of a widget a user arranged, widgets are laid
+// out in order.
+type WidgetLayout struct {
+	Key string `json:"key"`
+	W   int    `json:"w"`
+	H   int    `json:"h"`
+}
+
+type dashboardState struct {
+	Widgets []Widget          `json:"widgets"`
+	Data    map[string]any    `json:"data"`
+	Errors  map[string]string `json:"errors"`
+}
+
+// Dashboard is the ServerState behind sunmao.NewDashboard.
+type Dashboard struct {
+	*ServerState
+	mu      sync.RWMutex
+	widgets []Widget
+}
+
+// NewDashboard serves widgets to the dashboards bound to id. The layout
+// users arrange is stored as a client preference, read it with Layout.
+func NewDashboard(r *Runtime, id string, widgets ...Widget) *Dashboard {
+	d := &Dashboard{
+		ServerState: r.NewServerState(id, &dashboardState{Widgets: widgets, Data: map[string]any{}, Errors: map[string]string{}}),
+		widgets:     widgets,
+	}
+	r.Preference(d.preferenceKey(), []WidgetLayout{})
+
+	r.OnAppServed(func(conn *Conn) {
+		d.push(conn)
+	})
+	return d
+}
+
+// SetWidgets replaces the widgets and loads them for every connection.
+func (d *Dashboard) SetWidgets(widgets ...Widget) {
+	d.mu.Lock()
+	d.widgets = widgets
+	d.mu.Unlock()
+
+	d.Refresh()
+}
+
+// Refresh loads the data of every widget again for every connection.
+func (d *Dashboard) Refresh() {
+	for _, conn := range d.r.conns.list() {
+		d.push(conn)
+	}
+}
+
+// Layout returns the arrangement the user of conn saved, empty until the
+// user moved or resized a widget.
+func (d *Dashboard) Layout(conn *Conn) ([]WidgetLayout, error) {
+	return DecodePreference[[]WidgetLayout](conn, d.preferenceKey())
+}
+
+func (d *Dashboard) preferenceKey() string {
+	return "dashboard:" + d.Id
+}
+
+func (d *Dashboard) push(conn *Conn) {
+	d.mu.RLock()
+	widgets := d.widgets
+	d.mu.RUnlock()
+
+	state := &dashboardState{Widgets: widgets, Data: map[string]any{}, Errors: map[string]string{}}
+	for _, w := range widgets {
+		if w.Data == nil {
+			continue
+		}
+		// a failing widget shows its error, the others still load
+		data, err := w.Data(conn)
+		if err != nil {
+			state.Errors[w.Key] = err.Error()
+			continue
+		}
+		state.Data[w.Key] = data
+	}
+
+	connId := conn.Id
+	if err := d.SetState(state, &connId); err != nil && err != errConnClosed {
+		d.r.e.Logger.Error(err)
+	}
+}
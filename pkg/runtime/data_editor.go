@@ -0,0 +1,113 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/yuyz0112/sunmao-ui-go-binding/pkg/sunmao"
+	"gopkg.in/yaml.v3"
+)
+
+type dataEditorState struct {
+	Value any `json:"value"`
+	// YAML is the value rendered on the server, the client has no yaml
+	// library
+	YAML   string                 `json:"yaml"`
+	Schema map[string]interface{} `json:"schema,omitempty"`
+}
+
+// DataEditor is the ServerState behind sunmao.NewDataEditor.
+type DataEditor struct {
+	*ServerState
+	// PerConnection shows an accepted value to the saving connection only,
+	// for values other users must not see such as request bodies.
+	PerConnection bool
+	schema        map[string]interface{}
+}
+
+// NewDataEditor shows value in the data editors bound to id. Saved edits
+// are parsed as JSON or YAML and checked against schema, if not nil, on
+// the client and again on the server before onSave runs. A value onSave
+// accepted is shown on every client, unless PerConnection is set.
+func NewDataEditor(r *Runtime, id string, value any, schema map[string]interface{}, onSave func(conn *Conn, value any) error) (*DataEditor, error) {
+	initState, err := editorState(value, schema)
+	if err != nil {
+		return nil, err
+	}
+	d := &DataEditor{
+		ServerState: r.NewServerState(id, initState),
+		schema:      schema,
+	}
+
+	return d, r.Handle(id+"/save", func(m *Message, connId int) error {
+		params, err := decodeParams[struct {
+			Text   string `json:"text"`
+			Format string `json:"format"`
+		}](m)
+		if err != nil {
+			return err
+		}
+		value, err := parseEdit(params.Text, params.Format)
+		if err != nil {
+			return &ActionError{Code: "invalid_params", Message: err.Error()}
+		}
+		if d.schema != nil {
+			if err := sunmao.ValidateSchema(d.schema, value); err != nil {
+				return &ActionError{Code: "invalid_params", Message: err.Error()}
+			}
+		}
+		if onSave != nil {
+			if err := onSave(r.conns.get(connId), value); err != nil {
+				return err
+			}
+		}
+		if d.PerConnection {
+			return d.Set(value, &connId)
+		}
+		return d.Set(value, nil)
+	})
+}
+
+// Set shows value, a nil connId shows it on every client.
+func (d *DataEditor) Set(value any, connId *int) error {
+	state, err := editorState(value, d.schema)
+	if err != nil {
+		return err
+	}
+	return d.SetState(state, connId)
+}
+
+func editorState(value any, schema map[string]interface{}) (*dataEditorState, error) {
+	buf, err := yaml.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return &dataEditorState{Value: value, YAML: string(buf), Schema: schema}, nil
+}
+
+// parseEdit decodes the edited text into the types encoding/json uses, so
+// yaml and json edits validate and reach onSave alike.
+func parseEdit(text, format string) (any, error) {
+	var value any
+	switch format {
+	case "json":
+		if err := json.Unmarshal([]byte(text), &value); err != nil {
+			return nil, fmt.Errorf("invalid json: %w", err)
+		}
+		return value, nil
+	case "yaml":
+		if err := yaml.Unmarshal([]byte(text), &value); err != nil {
+			return nil, fmt.Errorf("invalid yaml: %w", err)
+		}
+		buf, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid yaml: %w", err)
+		}
+		value = nil
+		if err := json.Unmarshal(buf, &value); err != nil {
+			return nil, err
+		}
+		return value, nil
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
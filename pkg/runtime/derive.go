@@ -0,0 +1,99 @@
+package runtime
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// Value returns the state last set for every client, the initial state
+// before the first broadcast SetState. Values set for single connections
+// are not kept.
+func (s *ServerState) Value() any {
+	s.valueMu.RLock()
+	defer s.valueMu.RUnlock()
+
+	return s.value
+}
+
+// observe runs fn with every value broadcast from then on.
+func (s *ServerState) observe(fn func(value any)) {
+	s.valueMu.Lock()
+	defer s.valueMu.Unlock()
+
+	s.observers = append(s.observers, fn)
+}
+
+func (s *ServerState) changed(value any) {
+	s.valueMu.Lock()
+	s.value = value
+	observers := append([]func(any){}, s.observers...)
+	s.valueMu.Unlock()
+
+	for _, fn := range observers {
+		fn(value)
+	}
+}
+
+// ServeLatest pushes the last broadcast value to clients served after it,
+// the app carries the initial state only. Sync and Derive call it, call it
+// for states broadcast from anywhere else. The hook is registered once per
+// state and serves as long as one caller did not release it.
+func (s *ServerState) ServeLatest() (release func()) {
+	s.latestOnce.Do(func() {
+		s.r.OnAppServed(func(conn *Conn) {
+			if atomic.LoadInt32(&s.latest) == 0 || s.Version() == 0 {
+				return
+			}
+			if err := s.serveBroadcast(s.Value(), conn.Id); err != nil && err != errConnClosed {
+				s.r.e.Logger.Error(err)
+			}
+		})
+	})
+	atomic.AddInt32(&s.latest, 1)
+
+	once := sync.Once{}
+	return func() {
+		once.Do(func() { atomic.AddInt32(&s.latest, -1) })
+	}
+}
+
+// Derive serves the state id computed by fn from the values of deps, in
+// their order. It is computed once right away and again whenever one of
+// deps is broadcast a new value, then pushed to every client unless the
+// result did not change. Clients served later get the latest result.
+// Derived states may depend on each other, add them with AsComponent like
+// any other state.
+func (r *Runtime) Derive(id string, fn func(deps ...any) any, deps ...*ServerState) *ServerState {
+	values := func() []any {
+		v := make([]any, len(deps))
+		for i, dep := range deps {
+			v[i] = dep.Value()
+		}
+		return v
+	}
+
+	s := r.NewServerState(id, fn(values()...))
+
+	// recomputing in order keeps a slow computation from overwriting the
+	// result of a newer one
+	mu := sync.Mutex{}
+	recompute := func(any) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		next := fn(values()...)
+		if reflect.DeepEqual(next, s.Value()) {
+			return
+		}
+		if err := s.SetState(next, nil); err != nil && err != errConnClosed {
+			r.e.Logger.Errorf("derive %v: %v", id, err)
+		}
+	}
+	for _, dep := range deps {
+		dep.observe(recompute)
+	}
+	// derived states keep serving for the life of the runtime
+	s.ServeLatest()
+	return s
+}
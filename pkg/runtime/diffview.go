@@ -0,0 +1,275 @@
+package runtime
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DiffLine is a line of a DiffFile. Kind is " " for context, "-" for a
+// removed and "+" for an added line, Old and New are 1 based line numbers,
+// 0 on the side the line is missing from.
+type DiffLine struct {
+	Kind string `json:"kind"`
+	Old  int    `json:"old,omitempty"`
+	New  int    `json:"new,omitempty"`
+	Text string `json:"text"`
+}
+
+// DiffFile is the diff of one file, Language picks the syntax
+// highlighting and is guessed from the name when empty.
+type DiffFile struct {
+	Name     string     `json:"name,omitempty"`
+	Language string     `json:"language,omitempty"`
+	Lines    []DiffLine `json:"lines"`
+}
+
+// DiffView is the ServerState behind sunmao.NewDiffViewer.
+type DiffView struct {
+	*ServerState
+}
+
+// NewDiffView serves the diffs shown by the diff viewers bound to id.
+func NewDiffView(r *Runtime, id string) *DiffView {
+	return &DiffView{ServerState: r.NewServerState(id, []DiffFile{})}
+}
+
+// Compare shows the line diff of two versions of a file, a nil connId
+// shows it on every client.
+func (d *DiffView) Compare(name, before, after string, connId *int) error {
+	return d.SetState([]DiffFile{{Name: name, Lines: LineDiff(before, after)}}, connId)
+}
+
+// Unified shows a patch in the unified format, as printed by git diff.
+func (d *DiffView) Unified(patch string, connId *int) error {
+	files, err := ParseUnifiedDiff(patch)
+	if err != nil {
+		return err
+	}
+	return d.SetState(files, connId)
+}
+
+// maxDiffEdits bounds the edit distance LineDiff searches, the trace it
+// keeps grows with its square
+const maxDiffEdits = 1000
+
+// LineDiff compares before and after line by line with the Myers algorithm,
+// so the result is a shortest edit script. Versions more than
+// maxDiffEdits lines apart are shown as all of before replaced by all of
+// after.
+func LineDiff(before, after string) []DiffLine {
+	a, b := splitLines(before), splitLines(after)
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	// trace keeps v[-dist..dist] of every round to walk the edit script
+	// back, the rounds before never reach further
+	trace := [][]int{}
+	found := false
+
+search:
+	for dist := 0; dist <= max; dist++ {
+		if dist > maxDiffEdits {
+			break
+		}
+		trace = append(trace, append([]int{}, v[offset-dist:offset+dist+1]...))
+		for k := -dist; k <= dist; k += 2 {
+			var x int
+			if k == -dist || (k != dist && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break search
+			}
+		}
+	}
+	if !found {
+		return replaceLines(a, b)
+	}
+
+	lines := []DiffLine{}
+	x, y := n, m
+	for dist := len(trace) - 1; dist >= 0; dist-- {
+		v := trace[dist]
+		k := x - y
+		var prevK int
+		if k == -dist || (k != dist && v[dist+k-1] < v[dist+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := 0
+		if dist > 0 {
+			prevX = v[dist+prevK]
+		}
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			lines = append(lines, DiffLine{Kind: " ", Old: x + 1, New: y + 1, Text: a[x]})
+		}
+		if dist == 0 {
+			break
+		}
+		if x == prevX {
+			y--
+			lines = append(lines, DiffLine{Kind: "+", New: y + 1, Text: b[y]})
+		} else {
+			x--
+			lines = append(lines, DiffLine{Kind: "-", Old: x + 1, Text: a[x]})
+		}
+	}
+
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+	return lines
+}
+
+func replaceLines(a, b []string) []DiffLine {
+	lines := make([]DiffLine, 0, len(a)+len(b))
+	for i, line := range a {
+		lines = append(lines, DiffLine{Kind: "-", Old: i + 1, Text: line})
+	}
+	for i, line := range b {
+		lines = append(lines, DiffLine{Kind: "+", New: i + 1, Text: line})
+	}
+	return lines
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// ParseUnifiedDiff reads the files of a patch in the unified format, the
+// lines between hunks are not part of the result.
+func ParseUnifiedDiff(patch string) ([]DiffFile, error) {
+	files := []DiffFile{}
+	var file *DiffFile
+	var h hunk
+	for i, line := range splitLines(patch) {
+		// inside a hunk every line belongs to it, even one looking like
+		// a header such as a removed "-- comment"
+		if h.oldLeft > 0 || h.newLeft > 0 {
+			if err := h.add(file, line); err != nil {
+				return nil, fmt.Errorf("line %v: %w", i+1, err)
+			}
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "--- "):
+			files = append(files, DiffFile{Name: diffName(line[4:]), Lines: []DiffLine{}})
+			file = &files[len(files)-1]
+		case strings.HasPrefix(line, "+++ ") && file != nil:
+			if name := diffName(line[4:]); name != "" {
+				file.Name = name
+			}
+		case strings.HasPrefix(line, "@@"):
+			if file == nil {
+				// a bare hunk, as printed for two strings
+				files = append(files, DiffFile{Lines: []DiffLine{}})
+				file = &files[len(files)-1]
+			}
+			var err error
+			if h, err = parseHunk(line); err != nil {
+				return nil, fmt.Errorf("line %v: %w", i+1, err)
+			}
+		}
+	}
+	return files, nil
+}
+
+type hunk struct {
+	oldLine, newLine int
+	oldLeft, newLeft int
+}
+
+// parseHunk reads the header "@@ -line,count +line,count @@".
+func parseHunk(line string) (hunk, error) {
+	h := hunk{}
+	fields := strings.Fields(line)
+	if len(fields) < 3 || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+		return h, fmt.Errorf("invalid hunk header %q", line)
+	}
+	var err error
+	if h.oldLine, h.oldLeft, err = hunkRange(fields[1][1:]); err != nil {
+		return h, fmt.Errorf("invalid hunk header %q", line)
+	}
+	if h.newLine, h.newLeft, err = hunkRange(fields[2][1:]); err != nil {
+		return h, fmt.Errorf("invalid hunk header %q", line)
+	}
+	return h, nil
+}
+
+// hunkRange reads "line,count", the count is 1 when left out.
+func hunkRange(s string) (int, int, error) {
+	parts := strings.SplitN(s, ",", 2)
+	line, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	count := 1
+	if len(parts) == 2 {
+		if count, err = strconv.Atoi(parts[1]); err != nil {
+			return 0, 0, err
+		}
+	}
+	// an empty side is numbered by the line before it
+	if count == 0 {
+		line++
+	}
+	return line, count, nil
+}
+
+func (h *hunk) add(file *DiffFile, line string) error {
+	switch {
+	case strings.HasPrefix(line, "\\"):
+		// "\ No newline at end of file"
+	case strings.HasPrefix(line, "+") && h.newLeft > 0:
+		file.Lines = append(file.Lines, DiffLine{Kind: "+", New: h.newLine, Text: line[1:]})
+		h.newLine++
+		h.newLeft--
+	case strings.HasPrefix(line, "-") && h.oldLeft > 0:
+		file.Lines = append(file.Lines, DiffLine{Kind: "-", Old: h.oldLine, Text: line[1:]})
+		h.oldLine++
+		h.oldLeft--
+	case (line == "" || strings.HasPrefix(line, " ")) && h.oldLeft > 0 && h.newLeft > 0:
+		text := line
+		if text != "" {
+			text = text[1:]
+		}
+		file.Lines = append(file.Lines, DiffLine{Kind: " ", Old: h.oldLine, New: h.newLine, Text: text})
+		h.oldLine++
+		h.newLine++
+		h.oldLeft--
+		h.newLeft--
+	default:
+		return fmt.Errorf("unexpected line %q in hunk", line)
+	}
+	return nil
+}
+
+// diffName drops the a/ and b/ prefixes of git, /dev/null is no name.
+func diffName(s string) string {
+	name := strings.SplitN(s, "\t", 2)[0]
+	if name == "/dev/null" {
+		return ""
+	}
+	if strings.HasPrefix(name, "a/") || strings.HasPrefix(name, "b/") {
+		return name[2:]
+	}
+	return name
+}
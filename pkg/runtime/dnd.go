@@ -0,0 +1,20 @@
+package runtime
+
+// DropEvent is sent by the binding/v1/droppable trait.
+type DropEvent struct {
+	SourceId string `json:"sourceId"`
+	TargetId string `json:"targetId"`
+	// Payload is the value given to Draggable on the source.
+	Payload any `json:"payload"`
+	// Parameters are the ServerHandler parameters of the target.
+	Parameters any `json:"parameters"`
+}
+
+// DecodeDrop reads the DropEvent out of a droppable handler call.
+func DecodeDrop(m *Message) (*DropEvent, error) {
+	e, err := decodeParams[DropEvent](m)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
@@ -0,0 +1,202 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/yuyz0112/sunmao-ui-go-binding/pkg/runtime"
+	"github.com/yuyz0112/sunmao-ui-go-binding/pkg/sunmao"
+)
+
+const (
+	// refreshDelay coalesces the events of a compose up into one listing
+	refreshDelay = 200 * time.Millisecond
+	// retryDelay waits before following the events again, e.g. after the
+	// engine restarted
+	retryDelay = 5 * time.Second
+)
+
+var errAuthorizeRequired = errors.New("docker: authorize is required")
+
+// Authorize decides whether conn may run action, "logs", "start", "stop"
+// or "restart", on container. Container ids and names come from the
+// client and may name any container of the engine, not only the listed
+// ones.
+type Authorize func(conn *runtime.Conn, action, container string) error
+
+// Row is the table row of a container.
+func Row(c Container) map[string]any {
+	id := c.Id
+	if len(id) > 12 {
+		id = id[:12]
+	}
+	return map[string]any{
+		"id":      id,
+		"name":    c.Name(),
+		"image":   c.Image,
+		"state":   c.State,
+		"status":  c.Status,
+		"created": time.Unix(c.Created, 0).UTC().Format(time.RFC3339),
+	}
+}
+
+// Columns are the columns of Row, rows are keyed by "id".
+func Columns() []*sunmao.ArcoTableColumn {
+	cols := []*sunmao.ArcoTableColumn{}
+	for _, name := range []string{"name", "image", "state", "status", "created"} {
+		cols = append(cols, &sunmao.ArcoTableColumn{Title: name, DataIndex: name, Sorter: true, Filter: name == "state"})
+	}
+	return cols
+}
+
+// Watch keeps state at the rows of the containers, listed again whenever
+// the engine reports a container event, until ctx is done. Clients served
+// meanwhile get the last listing. Failures go to onError, which may be
+// nil, and are retried. Run it in a goroutine.
+func (c *Client) Watch(ctx context.Context, state *runtime.ServerState, onError func(error)) {
+	release := state.ServeLatest()
+	defer release()
+
+	report := func(err error) {
+		if err != nil && onError != nil && ctx.Err() == nil {
+			onError(err)
+		}
+	}
+	refresh := func() {
+		containers, err := c.Containers(ctx)
+		if err != nil {
+			report(err)
+			return
+		}
+		rows := make([]map[string]any, len(containers))
+		for i, container := range containers {
+			rows[i] = Row(container)
+		}
+		report(state.SetState(rows, nil))
+	}
+
+	pending := make(chan struct{}, 1)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-pending:
+				time.Sleep(refreshDelay)
+				// the events meanwhile are covered by this listing
+				select {
+				case <-pending:
+				default:
+				}
+				refresh()
+			}
+		}
+	}()
+
+	for {
+		// list first, the events only tell what changed since
+		refresh()
+		err := c.Events(ctx, func(Event) {
+			select {
+			case pending <- struct{}{}:
+			default:
+			}
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		report(err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryDelay):
+		}
+	}
+}
+
+// FollowLogs serves followed container logs from their last tail lines
+// under id, start one with Follow and show it with sunmao.NewLogView(id).
+// At most limit logs are followed at once, 0 for no limit. authorize is
+// required.
+func (c *Client) FollowLogs(r *runtime.Runtime, id string, tail, limit int, authorize Authorize) (*runtime.CommandRunner, error) {
+	if authorize == nil {
+		return nil, errAuthorizeRequired
+	}
+	return runtime.NewLogStream(r, id, func(ctx context.Context, conn *runtime.Conn, params map[string]any) (io.ReadCloser, error) {
+		container, err := check(conn, params, "logs", authorize)
+		if err != nil {
+			return nil, err
+		}
+		return c.Logs(ctx, container, tail)
+	}, limit)
+}
+
+// Follow follows the log of container, an id or name or an expression
+// such as "{{ containers.selectedItem.id }}", in the log view of id.
+func Follow(id, container string) *sunmao.ServerHandler {
+	return sunmao.StartCommand(id, map[string]interface{}{"container": container})
+}
+
+// Actions registers the handlers prefix/start, prefix/stop and
+// prefix/restart, call them with Action. Stop and restart ask for
+// confirmation first, which only guards against slips, authorize is
+// required and decides who may run them.
+func (c *Client) Actions(r *runtime.Runtime, prefix string, authorize Authorize) error {
+	if authorize == nil {
+		return errAuthorizeRequired
+	}
+	actions := []struct {
+		name    string
+		run     func(ctx context.Context, id string) error
+		confirm string
+	}{
+		{"start", c.Start, ""},
+		{"stop", c.Stop, "Stop container {container}?"},
+		{"restart", c.Restart, "Restart container {container}?"},
+	}
+	for _, a := range actions {
+		a := a
+		opts := []runtime.HandlerOption{}
+		if a.confirm != "" {
+			opts = append(opts, runtime.Confirm(a.confirm))
+		}
+		err := r.Handle(prefix+"/"+a.name, func(m *runtime.Message, connId int) error {
+			params, _ := m.Params.(map[string]any)
+			container, err := check(r.Conn(connId), params, a.name, authorize)
+			if err != nil {
+				return err
+			}
+			// the table follows through the engine's events
+			return a.run(context.Background(), container)
+		}, opts...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Action calls the handler action, "start", "stop" or "restart", of the
+// Actions under prefix for container.
+func Action(prefix, action, container string) *sunmao.ServerHandler {
+	return &sunmao.ServerHandler{
+		Name:       prefix + "/" + action,
+		Parameters: map[string]interface{}{"container": container},
+	}
+}
+
+func check(conn *runtime.Conn, params map[string]any, action string, authorize Authorize) (string, error) {
+	container, _ := params["container"].(string)
+	if container == "" {
+		return "", &runtime.ActionError{Code: "invalid_params", Message: "container is required"}
+	}
+	if conn == nil {
+		return "", errors.New("docker: connection closed")
+	}
+	if err := authorize(conn, action, container); err != nil {
+		return "", &runtime.ActionError{Code: "forbidden", Message: err.Error()}
+	}
+	return container, nil
+}
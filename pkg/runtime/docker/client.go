@@ -0,0 +1,234 @@
+// Package docker binds a Docker engine to the runtime: a container table
+// following the engine's events, container logs in log views and start,
+// stop and restart handlers asking for confirmation. It talks to the
+// engine API directly, no docker client module is needed.
+package docker
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultHost is the socket of a local engine.
+const DefaultHost = "unix:///var/run/docker.sock"
+
+// Container is an entry of the engine's container list.
+type Container struct {
+	Id      string
+	Names   []string
+	Image   string
+	State   string
+	Status  string
+	Created int64
+}
+
+// Name is the first name of the container without its leading slash.
+func (c Container) Name() string {
+	if len(c.Names) == 0 {
+		return c.Id
+	}
+	return strings.TrimPrefix(c.Names[0], "/")
+}
+
+// Client calls the engine API.
+type Client struct {
+	http *http.Client
+	base string
+}
+
+// NewClient connects to host, a unix:// socket such as DefaultHost or a
+// tcp:// or http:// address. TLS engines need a client with their
+// certificates, use NewClientWith then.
+func NewClient(host string) (*Client, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("docker host %q: %w", host, err)
+	}
+	switch u.Scheme {
+	case "unix":
+		path := u.Path
+		dialer := &net.Dialer{}
+		return NewClientWith(&http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, "unix", path)
+			},
+		}}, "http://docker"), nil
+	case "tcp", "http":
+		return NewClientWith(http.DefaultClient, "http://"+u.Host), nil
+	}
+	return nil, fmt.Errorf("docker host %q: unsupported scheme", host)
+}
+
+// NewClientWith calls the engine at base, e.g. "https://docker:2376",
+// with client.
+func NewClientWith(client *http.Client, base string) *Client {
+	return &Client{http: client, base: strings.TrimSuffix(base, "/")}
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values) (*http.Response, error) {
+	target := c.base + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("docker %v %v: %w", method, path, err)
+	}
+	// 304 is a start of a running or a stop of a stopped container
+	if res.StatusCode >= 400 {
+		defer res.Body.Close()
+		var body struct {
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(res.Body).Decode(&body)
+		if body.Message == "" {
+			body.Message = res.Status
+		}
+		return nil, fmt.Errorf("docker %v %v: %v", method, path, body.Message)
+	}
+	return res, nil
+}
+
+// Containers lists the containers, stopped ones too.
+func (c *Client) Containers(ctx context.Context) ([]Container, error) {
+	res, err := c.do(ctx, http.MethodGet, "/containers/json", url.Values{"all": {"1"}})
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	containers := []Container{}
+	return containers, json.NewDecoder(res.Body).Decode(&containers)
+}
+
+// Start, Stop and Restart run the action on the container with id or
+// name.
+func (c *Client) Start(ctx context.Context, id string) error {
+	return c.action(ctx, id, "start")
+}
+
+func (c *Client) Stop(ctx context.Context, id string) error {
+	return c.action(ctx, id, "stop")
+}
+
+func (c *Client) Restart(ctx context.Context, id string) error {
+	return c.action(ctx, id, "restart")
+}
+
+func (c *Client) action(ctx context.Context, id, action string) error {
+	res, err := c.do(ctx, http.MethodPost, "/containers/"+url.PathEscape(id)+"/"+action, nil)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+// Logs follows the output of a container from its last tail lines.
+// Containers without a tty multiplex stdout and stderr, both are read as
+// one stream.
+func (c *Client) Logs(ctx context.Context, id string, tail int) (io.ReadCloser, error) {
+	res, err := c.do(ctx, http.MethodGet, "/containers/"+url.PathEscape(id)+"/json", nil)
+	if err != nil {
+		return nil, err
+	}
+	var inspect struct {
+		Config struct {
+			Tty bool
+		}
+	}
+	err = json.NewDecoder(res.Body).Decode(&inspect)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err = c.do(ctx, http.MethodGet, "/containers/"+url.PathEscape(id)+"/logs", url.Values{
+		"follow": {"1"},
+		"stdout": {"1"},
+		"stderr": {"1"},
+		"tail":   {fmt.Sprint(tail)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if inspect.Config.Tty {
+		return res.Body, nil
+	}
+	return &demux{r: res.Body}, nil
+}
+
+// demux strips the frame headers of a multiplexed stream: a byte naming
+// the stream, three zero bytes and the big endian frame size.
+type demux struct {
+	r         io.ReadCloser
+	remaining uint32
+}
+
+func (d *demux) Read(p []byte) (int, error) {
+	for d.remaining == 0 {
+		header := [8]byte{}
+		if _, err := io.ReadFull(d.r, header[:]); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				err = io.EOF
+			}
+			return 0, err
+		}
+		d.remaining = binary.BigEndian.Uint32(header[4:])
+	}
+	if uint32(len(p)) > d.remaining {
+		p = p[:d.remaining]
+	}
+	n, err := d.r.Read(p)
+	d.remaining -= uint32(n)
+	return n, err
+}
+
+func (d *demux) Close() error {
+	return d.r.Close()
+}
+
+// Event is a container event of the engine, such as start or die.
+type Event struct {
+	Action string `json:"Action"`
+	Actor  struct {
+		ID         string            `json:"ID"`
+		Attributes map[string]string `json:"Attributes"`
+	} `json:"Actor"`
+}
+
+// Events calls fn for every container event until ctx is done or the
+// stream fails.
+func (c *Client) Events(ctx context.Context, fn func(Event)) error {
+	filters, err := json.Marshal(map[string][]string{"type": {"container"}})
+	if err != nil {
+		return err
+	}
+	res, err := c.do(ctx, http.MethodGet, "/events", url.Values{"filters": {string(filters)}})
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	decoder := json.NewDecoder(res.Body)
+	for {
+		var e Event
+		if err := decoder.Decode(&e); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		fn(e)
+	}
+}
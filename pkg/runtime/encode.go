@@ -0,0 +1,44 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+)
+
+// payloads above this size are not returned to the pool, so one huge
+// snapshot does not pin its buffer forever
+const maxPooledBuffer = 1 << 20
+
+var bufferPool = sync.Pool{
+	New: func() any {
+		return &bytes.Buffer{}
+	},
+}
+
+// withEncoded encodes message once into a pooled buffer and hands it to
+// fn. The payload is only valid during fn, enqueue copies it while adding
+// the seq of each connection.
+func withEncoded(message any, fn func(payload []byte) error) error {
+	if codec.custom {
+		payload, err := codec.marshal(message)
+		if err != nil {
+			return err
+		}
+		return fn(payload)
+	}
+
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer func() {
+		if buf.Cap() <= maxPooledBuffer {
+			bufferPool.Put(buf)
+		}
+	}()
+
+	if err := json.NewEncoder(buf).Encode(message); err != nil {
+		return err
+	}
+	// drop the newline written by Encode
+	return fn(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+}
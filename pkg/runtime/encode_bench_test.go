@@ -0,0 +1,84 @@
+package runtime
+
+import (
+	"fmt"
+	"testing"
+)
+
+var benchMessage = map[string]interface{}{
+	"type":        "UiMethod",
+	"componentId": "table",
+	"name":        "setValue",
+	"parameters": map[string]interface{}{
+		"key":   "state",
+		"value": []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+	},
+}
+
+// benchConns adds n connections whose queues are drained right away, the
+// returned func stops the drains.
+func benchConns(r *Runtime, n int) func() {
+	done := make(chan struct{})
+	for i := 0; i < n; i++ {
+		conn := &Conn{
+			Id:   i + 1,
+			out:  make(chan outMessage, outboundQueueSize),
+			room: make(chan struct{}, 1),
+			done: done,
+		}
+		r.conns.add(conn)
+		go func() {
+			for {
+				select {
+				case <-conn.out:
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+	return func() { close(done) }
+}
+
+func BenchmarkSendBroadcast(b *testing.B) {
+	for _, n := range []int{1, 10, 100, 1000} {
+		b.Run(fmt.Sprintf("conns=%v", n), func(b *testing.B) {
+			r := New(b.TempDir(), b.TempDir())
+			stop := benchConns(r, n)
+			defer stop()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := r.send(benchMessage, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkWithEncoded(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := withEncoded(benchMessage, func(payload []byte) error { return nil }); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWithSeq(b *testing.B) {
+	var payload []byte
+	if err := withEncoded(benchMessage, func(p []byte) error {
+		payload = append([]byte{}, p...)
+		return nil
+	}); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		withSeq(uint64(i), payload)
+	}
+}
@@ -0,0 +1,47 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ActionError is reported back to the client that sent the action, unlike
+// other handler errors which are only logged.
+type ActionError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *ActionError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Code, e.Message)
+}
+
+func actionErrorOf(err error) *ActionError {
+	if err == nil {
+		return nil
+	}
+
+	actionErr := &ActionError{}
+	if errors.As(err, &actionErr) {
+		return actionErr
+	}
+
+	veto := &VetoError{}
+	if errors.As(err, &veto) {
+		return &ActionError{Code: "vetoed", Message: veto.Err.Error()}
+	}
+	return nil
+}
+
+func (r *Runtime) sendActionError(connId int, msg *Message, err *ActionError) {
+	sendErr := r.send(map[string]interface{}{
+		"type":      "ActionError",
+		"handler":   msg.Handler,
+		"code":      err.Code,
+		"message":   err.Message,
+		"requestId": msg.RequestId,
+	}, &connId)
+	if sendErr != nil {
+		r.e.Logger.Error(sendErr)
+	}
+}
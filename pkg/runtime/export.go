@@ -0,0 +1,50 @@
+package runtime
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+
+	"github.com/labstack/echo/v4"
+	"github.com/yuyz0112/sunmao-ui-go-binding/pkg/sunmao"
+)
+
+// AppSchema is the compiled application together with its modules.
+type AppSchema struct {
+	Application sunmao.Application `json:"application"`
+	Modules     []sunmao.Module    `json:"modules"`
+}
+
+func (r *Runtime) schema() *AppSchema {
+	modules := make([]sunmao.Module, len(r.moduleBuilders), len(r.moduleBuilders)+len(r.remoteModules))
+	for i, b := range r.moduleBuilders {
+		modules[i] = b.ValueOf()
+	}
+	modules = append(modules, r.remoteModules...)
+
+	return &AppSchema{
+		Application: r.appBuilder.ValueOf(),
+		Modules:     modules,
+	}
+}
+
+// ExportApp writes the compiled application and modules schema to path
+// as indented JSON, the same document served at /app.json.
+func (r *Runtime) ExportApp(path string) error {
+	if r.appBuilder == nil {
+		return errAppNotLoaded
+	}
+
+	buf, err := json.MarshalIndent(r.schema(), "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, buf, 0644)
+}
+
+func (r *Runtime) registerExport() {
+	r.router.GET("/app.json", func(c echo.Context) error {
+		return c.JSONPretty(http.StatusOK, r.schema(), "\t")
+	})
+}
@@ -0,0 +1,256 @@
+package runtime
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxProxyBody caps the upstream responses a FetchProxy reads
+const maxProxyBody = 32 << 20
+
+// proxyFlushInterval throttles the state pushes of streamed responses
+const proxyFlushInterval = 100 * time.Millisecond
+
+// FetchRequest builds the upstream request of a fetch from the params the
+// client sent, nil when the app was served. Add credentials here, they
+// never reach the browser.
+type FetchRequest func(conn *Conn, params map[string]any) (*http.Request, error)
+
+// FetchState has the shape of the state of sunmao's core/v1/fetch trait,
+// so moving a fetch to the server only changes the expressions binding it.
+type FetchState struct {
+	Loading  bool   `json:"loading"`
+	Code     int    `json:"code,omitempty"`
+	CodeText string `json:"codeText,omitempty"`
+	Data     any    `json:"data"`
+	Error    string `json:"error,omitempty"`
+}
+
+// FetchProxy is the ServerState of a fetch made by the Go server, see
+// sunmao.ProxyData and sunmao.ProxyFetch. Every connection fetches on its
+// own, so the state is pushed per connection.
+type FetchProxy struct {
+	*ServerState
+	// Client, nil for http.DefaultClient, sends the upstream requests.
+	Client *http.Client
+	// Transform, if set, maps the decoded body of a successful response,
+	// or the list of items streamed so far, to Data. An error is shown
+	// next to the data it returned.
+	Transform func(data any) (any, error)
+	// TransformErrors applies Transform to the bodies of error responses
+	// too, for apis whose errors have the shape of their results.
+	TransformErrors bool
+
+	build FetchRequest
+	mu    sync.Mutex
+	conns map[int]*proxyConn
+}
+
+// proxyConn is the running fetch of a connection. Only the run of the
+// current generation may push, a cancelled fetch finishing late does not
+// overwrite the state of its successor.
+type proxyConn struct {
+	mu     sync.Mutex
+	gen    uint64
+	cancel context.CancelFunc
+}
+
+// NewFetchProxy serves the responses of the requests built by build under
+// id. Unless lazy, a connection fetches once its client served the app;
+// the handler id/fetch fetches again with its params. A fetch still running
+// when the next one starts is cancelled. JSON responses are decoded,
+// newline delimited JSON (application/x-ndjson) is streamed into Data as a
+// growing list, other bodies are kept as strings.
+func NewFetchProxy(r *Runtime, id string, build FetchRequest, lazy bool) (*FetchProxy, error) {
+	p := &FetchProxy{
+		ServerState: r.NewServerState(id, &FetchState{}),
+		build:       build,
+		conns:       map[int]*proxyConn{},
+	}
+
+	if !lazy {
+		r.OnAppServed(func(conn *Conn) {
+			p.start(conn, nil)
+		})
+	}
+	r.OnDisconnected(func(conn *Conn, _ CloseReason) {
+		p.stop(conn.Id)
+	})
+
+	return p, r.Handle(id+"/fetch", func(m *Message, connId int) error {
+		conn := r.conns.get(connId)
+		if conn == nil {
+			return nil
+		}
+		params, _ := m.Params.(map[string]any)
+		p.start(conn, params)
+		return nil
+	})
+}
+
+func (p *FetchProxy) start(conn *Conn, params map[string]any) {
+	ctx, cancel := context.WithCancel(context.Background())
+	pc, gen := p.next(conn.Id, cancel)
+
+	// the read loop goes on while the upstream request runs
+	go func() {
+		defer cancel()
+
+		connId := conn.Id
+		push := func(state *FetchState) {
+			pc.mu.Lock()
+			defer pc.mu.Unlock()
+
+			if pc.gen != gen {
+				return
+			}
+			if err := p.SetState(state, &connId); err != nil && err != errConnClosed {
+				p.r.e.Logger.Error(err)
+			}
+		}
+
+		push(&FetchState{Loading: true})
+		state, err := p.fetch(ctx, conn, params, push)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			p.r.e.Logger.Errorf("fetch proxy %v: %v", p.Id, err)
+			state.Error = err.Error()
+		}
+		state.Loading = false
+		push(state)
+	}()
+}
+
+// fetch sends the request and decodes its response, push shows the items
+// of a streamed response as they arrive.
+func (p *FetchProxy) fetch(ctx context.Context, conn *Conn, params map[string]any, push func(*FetchState)) (*FetchState, error) {
+	state := &FetchState{}
+	req, err := p.build(conn, params)
+	if err != nil {
+		return state, err
+	}
+	if req == nil {
+		return state, fmt.Errorf("fetch proxy %v: no request to send", p.Id)
+	}
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return state, err
+	}
+	defer res.Body.Close()
+
+	state.Code = res.StatusCode
+	state.CodeText = http.StatusText(res.StatusCode)
+	body := io.LimitReader(res.Body, maxProxyBody)
+	mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+
+	if mediaType == "application/x-ndjson" && res.StatusCode < 300 {
+		items := []any{}
+		last := time.Now()
+		scanner := bufio.NewScanner(body)
+		scanner.Buffer(make([]byte, 64<<10), maxProxyBody)
+		for scanner.Scan() {
+			if len(scanner.Bytes()) == 0 {
+				continue
+			}
+			var item any
+			if err := json.Unmarshal(scanner.Bytes(), &item); err != nil {
+				return state, fmt.Errorf("decode ndjson: %w", err)
+			}
+			items = append(items, item)
+			if time.Since(last) >= proxyFlushInterval {
+				last = time.Now()
+				data, _ := p.transform(append([]any{}, items...))
+				push(&FetchState{Loading: true, Code: state.Code, CodeText: state.CodeText, Data: data})
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			state.Data = items
+			return state, err
+		}
+		state.Data, err = p.transform(items)
+		return state, err
+	}
+
+	buf, err := io.ReadAll(body)
+	if err != nil {
+		return state, err
+	}
+	var data any
+	if json.Unmarshal(buf, &data) != nil {
+		data = string(buf)
+	}
+	state.Data = data
+	if res.StatusCode >= 300 {
+		err := fmt.Errorf("upstream responded %v", res.Status)
+		if p.Transform != nil && p.TransformErrors {
+			var transformErr error
+			if state.Data, transformErr = p.Transform(data); transformErr != nil {
+				err = transformErr
+			}
+		}
+		return state, err
+	}
+	state.Data, err = p.transform(data)
+	return state, err
+}
+
+func (p *FetchProxy) transform(data any) (any, error) {
+	if p.Transform == nil {
+		return data, nil
+	}
+	return p.Transform(data)
+}
+
+// next cancels the running fetch of connId and makes cancel the one of a
+// new generation.
+func (p *FetchProxy) next(connId int, cancel context.CancelFunc) (*proxyConn, uint64) {
+	p.mu.Lock()
+	pc, ok := p.conns[connId]
+	if !ok {
+		pc = &proxyConn{}
+		p.conns[connId] = pc
+	}
+	p.mu.Unlock()
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	if pc.cancel != nil {
+		pc.cancel()
+	}
+	pc.gen++
+	pc.cancel = cancel
+	return pc, pc.gen
+}
+
+// stop cancels the running fetch of connId once it disconnected.
+func (p *FetchProxy) stop(connId int) {
+	p.mu.Lock()
+	pc, ok := p.conns[connId]
+	delete(p.conns, connId)
+	p.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	if pc.cancel != nil {
+		pc.cancel()
+	}
+	pc.gen++
+}
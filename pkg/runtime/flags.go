@@ -0,0 +1,51 @@
+package runtime
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+// FlagProvider evaluates feature flags for a user, identity is nil for
+// anonymous clients.
+type FlagProvider interface {
+	Flags(identity *Identity) (map[string]any, error)
+}
+
+// FlagFunc adapts a function to FlagProvider.
+type FlagFunc func(identity *Identity) (map[string]any, error)
+
+func (f FlagFunc) Flags(identity *Identity) (map[string]any, error) {
+	return f(identity)
+}
+
+// WithFlags evaluates flags per page request and injects them into the
+// options payload, where client expressions read them through
+// sunmao.FlagExpr. Pages are no longer cached with a flag provider.
+func WithFlags(provider FlagProvider) Option {
+	return func(r *Runtime) {
+		r.flags = provider
+	}
+}
+
+// Flags evaluates the flags of the connection's user, empty without a
+// provider or when the provider failed.
+func (r *Runtime) Flags(conn *Conn) map[string]any {
+	if r.flags == nil || conn == nil {
+		return map[string]any{}
+	}
+	flags, err := r.flags.Flags(conn.Identity)
+	if err != nil {
+		r.e.Logger.Error(err)
+		return map[string]any{}
+	}
+	return flags
+}
+
+// Flag reports whether the boolean flag name is on for the connection.
+func (r *Runtime) Flag(conn *Conn, name string) bool {
+	on, _ := r.Flags(conn)[name].(bool)
+	return on
+}
+
+func (r *Runtime) requestFlags(c echo.Context) (map[string]any, error) {
+	return r.flags.Flags(r.Identity(c))
+}
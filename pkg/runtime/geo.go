@@ -0,0 +1,171 @@
+package runtime
+
+import (
+	"fmt"
+	"sync"
+)
+
+type MapMarker struct {
+	Id    string  `json:"id"`
+	Lat   float64 `json:"lat"`
+	Lng   float64 `json:"lng"`
+	Title string  `json:"title,omitempty"`
+	// Color is a css color, the theme color by default.
+	Color string `json:"color,omitempty"`
+	Data  any    `json:"data,omitempty"`
+}
+
+// MapLayer draws a GeoJSON object, a geometry, a Feature or a
+// FeatureCollection, over the tiles.
+type MapLayer struct {
+	Id      string `json:"id"`
+	GeoJSON any    `json:"geojson"`
+	Color   string `json:"color,omitempty"`
+}
+
+// MapView is the area a client shows, Bounds are south, west, north and
+// east in degrees.
+type MapView struct {
+	Lat    float64    `json:"lat"`
+	Lng    float64    `json:"lng"`
+	Zoom   int        `json:"zoom"`
+	Bounds [4]float64 `json:"bounds"`
+}
+
+// MapCallbacks receive what users do on a map, nil callbacks are skipped.
+type MapCallbacks struct {
+	OnMarkerClick func(conn *Conn, marker MapMarker) error
+	OnClick       func(conn *Conn, lat, lng float64) error
+	// OnMove runs once a pan or zoom settled, e.g. to load the markers of
+	// the shown area only.
+	OnMove func(conn *Conn, view MapView) error
+}
+
+type mapState struct {
+	Markers []MapMarker `json:"markers"`
+	Layers  []MapLayer  `json:"layers"`
+}
+
+// Map is the ServerState behind sunmao.NewMap.
+type Map struct {
+	*ServerState
+	mu        sync.Mutex
+	state     mapState
+	callbacks MapCallbacks
+}
+
+// NewMap serves markers and layers to the maps bound to id, they start
+// empty.
+func NewMap(r *Runtime, id string, callbacks MapCallbacks) (*Map, error) {
+	m := &Map{
+		ServerState: r.NewServerState(id, &mapState{Markers: []MapMarker{}, Layers: []MapLayer{}}),
+		state:       mapState{Markers: []MapMarker{}, Layers: []MapLayer{}},
+		callbacks:   callbacks,
+	}
+
+	if err := r.Handle(id+"/markerClick", m.handleMarkerClick); err != nil {
+		return nil, err
+	}
+	if err := r.Handle(id+"/click", func(msg *Message, connId int) error {
+		params, err := decodeParams[struct {
+			Lat float64 `json:"lat"`
+			Lng float64 `json:"lng"`
+		}](msg)
+		if err != nil || callbacks.OnClick == nil {
+			return err
+		}
+		return callbacks.OnClick(r.conns.get(connId), params.Lat, params.Lng)
+	}); err != nil {
+		return nil, err
+	}
+	return m, r.Handle(id+"/move", func(msg *Message, connId int) error {
+		view, err := decodeParams[MapView](msg)
+		if err != nil || callbacks.OnMove == nil {
+			return err
+		}
+		return callbacks.OnMove(r.conns.get(connId), view)
+	})
+}
+
+// SetMarkers replaces the markers of every client.
+func (m *Map) SetMarkers(markers []MapMarker) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.state.Markers = append([]MapMarker{}, markers...)
+	return m.push()
+}
+
+// PutMarker adds marker, or moves the marker with the same id, which is
+// how tracked assets are updated.
+func (m *Map) PutMarker(marker MapMarker) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	markers := make([]MapMarker, 0, len(m.state.Markers)+1)
+	replaced := false
+	for _, mk := range m.state.Markers {
+		if mk.Id == marker.Id {
+			mk, replaced = marker, true
+		}
+		markers = append(markers, mk)
+	}
+	if !replaced {
+		markers = append(markers, marker)
+	}
+	m.state.Markers = markers
+	return m.push()
+}
+
+// RemoveMarker deletes the marker with id.
+func (m *Map) RemoveMarker(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	markers := make([]MapMarker, 0, len(m.state.Markers))
+	for _, mk := range m.state.Markers {
+		if mk.Id != id {
+			markers = append(markers, mk)
+		}
+	}
+	m.state.Markers = markers
+	return m.push()
+}
+
+// SetLayers replaces the GeoJSON layers of every client.
+func (m *Map) SetLayers(layers []MapLayer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.state.Layers = append([]MapLayer{}, layers...)
+	return m.push()
+}
+
+// push broadcasts a copy, later changes must not race with the encoder.
+func (m *Map) push() error {
+	state := m.state
+	return m.SetState(&state, nil)
+}
+
+func (m *Map) handleMarkerClick(msg *Message, connId int) error {
+	params, _ := msg.Params.(map[string]any)
+	id, _ := params["marker"].(string)
+	if m.callbacks.OnMarkerClick == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	var marker *MapMarker
+	for i := range m.state.Markers {
+		if m.state.Markers[i].Id == id {
+			mk := m.state.Markers[i]
+			marker = &mk
+		}
+	}
+	m.mu.Unlock()
+
+	if marker == nil {
+		return fmt.Errorf("no marker %v", id)
+	}
+	return m.callbacks.OnMarkerClick(m.r.conns.get(connId), *marker)
+}
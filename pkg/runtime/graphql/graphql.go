@@ -0,0 +1,195 @@
+// Package graphql binds the results of GraphQL queries to the runtime.
+// Queries run on the Go server through a runtime.FetchProxy, credentials
+// stay there, or in the browser through sunmao's fetch trait.
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/yuyz0112/sunmao-ui-go-binding/pkg/runtime"
+	"github.com/yuyz0112/sunmao-ui-go-binding/pkg/sunmao"
+)
+
+// Request is the body of a GraphQL request.
+type Request struct {
+	Query         string         `json:"query"`
+	Variables     map[string]any `json:"variables,omitempty"`
+	OperationName string         `json:"operationName,omitempty"`
+}
+
+// Error is an entry of the errors of a GraphQL response.
+type Error struct {
+	Message string `json:"message"`
+	Path    []any  `json:"path,omitempty"`
+}
+
+// Errors are the errors of a response, its data may still be partial.
+type Errors []Error
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Message
+		if len(err.Path) > 0 {
+			messages[i] = fmt.Sprintf("%v: %v", pathString(err.Path), err.Message)
+		}
+	}
+	return strings.Join(messages, "; ")
+}
+
+func pathString(path []any) string {
+	parts := make([]string, len(path))
+	for i, p := range path {
+		parts[i] = fmt.Sprint(p)
+	}
+	return strings.Join(parts, ".")
+}
+
+type response struct {
+	Data   json.RawMessage `json:"data"`
+	Errors Errors          `json:"errors"`
+}
+
+// Client sends the requests of an endpoint.
+type Client struct {
+	Endpoint string
+	// HTTPClient defaults to http.DefaultClient.
+	HTTPClient *http.Client
+	// Authorize adds credentials to the requests made for conn, conn is nil
+	// for requests made with Do.
+	Authorize func(req *http.Request, conn *runtime.Conn) error
+}
+
+func (c *Client) newRequest(ctx context.Context, request Request, conn *runtime.Conn) (*http.Request, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if c.Authorize != nil {
+		if err := c.Authorize(req, conn); err != nil {
+			return nil, err
+		}
+	}
+	return req, nil
+}
+
+// Do runs request and decodes its data into out, e.g. from a handler of a
+// mutation. A response with errors returns them as Errors after decoding
+// the partial data.
+func (c *Client) Do(ctx context.Context, request Request, out any) error {
+	req, err := c.newRequest(ctx, request, nil)
+	if err != nil {
+		return err
+	}
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	var body response
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return fmt.Errorf("graphql %v: %v", c.Endpoint, res.Status)
+	}
+	if len(body.Data) > 0 && out != nil {
+		if err := json.Unmarshal(body.Data, out); err != nil {
+			return err
+		}
+	}
+	if len(body.Errors) > 0 {
+		return body.Errors
+	}
+	return nil
+}
+
+// Query serves the data of query under id, bind it with Data. Refetch
+// params replace the variables named in overridable, so a search box can
+// pass its value as a variable; the other variables, such as ones scoping
+// the query to a tenant, are always the given ones. Unless lazy, every
+// connection runs the query once its client served the app.
+func (c *Client) Query(r *runtime.Runtime, id, query string, variables map[string]any, lazy bool, overridable ...string) (*runtime.FetchProxy, error) {
+	p, err := runtime.NewFetchProxy(r, id, func(conn *runtime.Conn, params map[string]any) (*http.Request, error) {
+		merged := map[string]any{}
+		for k, v := range variables {
+			merged[k] = v
+		}
+		for _, k := range overridable {
+			if v, ok := params[k]; ok {
+				merged[k] = v
+			}
+		}
+		return c.newRequest(context.Background(), Request{Query: query, Variables: merged}, conn)
+	}, lazy)
+	if err != nil {
+		return nil, err
+	}
+	p.Client = c.HTTPClient
+	// servers answer failed queries with their errors and a 4xx or 5xx
+	p.TransformErrors = true
+	p.Transform = func(data any) (any, error) {
+		body, ok := data.(map[string]any)
+		if !ok {
+			return data, fmt.Errorf("graphql %v: unexpected response", c.Endpoint)
+		}
+		buf, err := json.Marshal(body["errors"])
+		if err != nil {
+			return body["data"], err
+		}
+		var errs Errors
+		if err := json.Unmarshal(buf, &errs); err == nil && len(errs) > 0 {
+			return body["data"], errs
+		}
+		return body["data"], nil
+	}
+	return p, nil
+}
+
+// Data binds the data of the query served under id, path selects a field
+// such as "orders.nodes" and may be empty.
+func Data(id, path string) string {
+	if path == "" {
+		return sunmao.ProxyData(id)
+	}
+	return fmt.Sprintf("{{ %v.state.data.%v }}", id, path)
+}
+
+// Refetch runs the query served under id again, variables may hold
+// expressions such as "{{ search.value }}".
+func Refetch(id string, variables map[string]interface{}) *sunmao.ServerHandler {
+	return sunmao.ProxyFetch(id, variables)
+}
+
+// Fetch is the fetch trait running query in the browser, for public
+// endpoints allowing the app's origin. Its data is at
+// "{{ component.fetch.data.data }}".
+func Fetch(endpoint, query string, variables map[string]interface{}) *sunmao.Fetch {
+	if variables == nil {
+		variables = map[string]interface{}{}
+	}
+	return &sunmao.Fetch{
+		URL:    endpoint,
+		Method: "post",
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+		Body: map[string]interface{}{
+			"query":     query,
+			"variables": variables,
+		},
+	}
+}
@@ -0,0 +1,209 @@
+// Package grpcui generates a console for calling the methods of a gRPC
+// server. The server is described and called through the Reflector and
+// Invoker interfaces, so the console works with whichever reflection
+// client the app uses, e.g. a few lines around grpcurl's descriptor source
+// and dynamic stubs. Requests and responses are the protojson mapping of
+// the messages.
+package grpcui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yuyz0112/sunmao-ui-go-binding/pkg/runtime"
+	"github.com/yuyz0112/sunmao-ui-go-binding/pkg/sunmao"
+)
+
+type FieldType string
+
+const (
+	String  FieldType = "string"
+	Int32   FieldType = "int32"
+	Int64   FieldType = "int64"
+	Float   FieldType = "float"
+	Bool    FieldType = "bool"
+	Bytes   FieldType = "bytes"
+	Enum    FieldType = "enum"
+	Message FieldType = "message"
+)
+
+// Field is a field of a request or response message, its json name as
+// used by protojson.
+type Field struct {
+	Name     string
+	Type     FieldType
+	Repeated bool
+	// Enum lists the value names of enum fields.
+	Enum []string
+	// Fields are the fields of message fields, recursive messages should be
+	// cut after a few levels.
+	Fields []Field
+}
+
+// Method is a method of a service, streaming methods are listed but can
+// not be called from the console.
+type Method struct {
+	// Service is the fully qualified service name, e.g. shop.v1.Orders.
+	Service         string
+	Name            string
+	Input           []Field
+	ClientStreaming bool
+	ServerStreaming bool
+}
+
+// FullName is the method path of the call, /shop.v1.Orders/Get.
+func (m Method) FullName() string {
+	return fmt.Sprintf("/%v/%v", m.Service, m.Name)
+}
+
+// Reflector lists the methods of the server, usually through its
+// reflection service.
+type Reflector interface {
+	Methods(ctx context.Context) ([]Method, error)
+}
+
+// Invoker calls a unary method with a protojson request and returns the
+// protojson response.
+type Invoker interface {
+	Invoke(ctx context.Context, method string, request json.RawMessage) (json.RawMessage, error)
+}
+
+// defaultTimeout bounds a call made from the console
+const defaultTimeout = 30 * time.Second
+
+// Console serves a request editor and a response viewer per method.
+type Console struct {
+	// Timeout bounds every call, 30s by default. Calls run on the read
+	// loop of the calling connection.
+	Timeout time.Duration
+	id      string
+	methods []Method
+	editors map[string]*methodEditors
+}
+
+type methodEditors struct {
+	request  *runtime.DataEditor
+	response *runtime.DataEditor
+}
+
+// NewConsole reflects the server once and registers the editors of its
+// methods below id. Requests are checked against a schema generated from
+// the method's input message on the client and the server. Requests,
+// responses and call errors are shown to the calling connection only.
+func NewConsole(ctx context.Context, r *runtime.Runtime, id string, reflector Reflector, invoker Invoker) (*Console, error) {
+	methods, err := reflector.Methods(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("reflect grpc server: %w", err)
+	}
+	sort.Slice(methods, func(i, j int) bool {
+		return methods[i].FullName() < methods[j].FullName()
+	})
+
+	c := &Console{id: id, methods: methods, editors: map[string]*methodEditors{}}
+	for _, m := range methods {
+		if m.ClientStreaming || m.ServerStreaming {
+			continue
+		}
+		m := m
+		editors := &methodEditors{}
+		editors.response, err = runtime.NewDataEditor(r, c.editorId(m, "response"), map[string]any{}, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		editors.request, err = runtime.NewDataEditor(r, c.editorId(m, "request"), Template(m.Input), Schema(m.Input), func(conn *runtime.Conn, value any) error {
+			request, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+			timeout := c.Timeout
+			if timeout <= 0 {
+				timeout = defaultTimeout
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			result := any(nil)
+			response, err := invoker.Invoke(ctx, m.FullName(), request)
+			if err == nil {
+				err = json.Unmarshal(response, &result)
+			}
+			if err != nil {
+				result = map[string]any{"error": err.Error()}
+			}
+			if conn == nil {
+				return nil
+			}
+			connId := conn.Id
+			return editors.response.Set(result, &connId)
+		})
+		if err != nil {
+			return nil, err
+		}
+		// requests may carry tokens or personal data
+		editors.request.PerConnection = true
+		c.editors[m.FullName()] = editors
+	}
+	return c, nil
+}
+
+// Methods returns the reflected methods in the order they are shown.
+func (c *Console) Methods() []Method {
+	return c.methods
+}
+
+func (c *Console) editorId(m Method, kind string) string {
+	name := strings.NewReplacer(".", "_", "/", "_").Replace(m.Service + "_" + m.Name)
+	return fmt.Sprintf("%v__%v__%v", c.id, name, kind)
+}
+
+// Components builds the console with b: the editor states, arco tabs with
+// a tab per service and a stack of the service's methods below them. Add
+// each returned builder to the app.
+func (c *Console) Components(b *sunmao.AppBuilder) []sunmao.BaseComponentBuilder {
+	components := []sunmao.BaseComponentBuilder{}
+	for _, m := range c.methods {
+		if e, ok := c.editors[m.FullName()]; ok {
+			components = append(components, e.request.AsComponent(), e.response.AsComponent())
+		}
+	}
+
+	services := []string{}
+	byService := map[string][]Method{}
+	for _, m := range c.methods {
+		if _, ok := byService[m.Service]; !ok {
+			services = append(services, m.Service)
+		}
+		byService[m.Service] = append(byService[m.Service], m)
+	}
+
+	tabs := (&sunmao.ArcoAppBuilder{AppBuilder: b}).NewTabs().Id(c.id)
+	components = append(components, tabs)
+	for i, service := range services {
+		tabs.Tab(&sunmao.ArcoTabsTab{Title: service})
+
+		children := []sunmao.BaseComponentBuilder{}
+		for _, m := range byService[service] {
+			children = append(children, b.NewText().Content(m.Name).Style("content", "font-weight: 600;"))
+			if m.ClientStreaming || m.ServerStreaming {
+				children = append(children, b.NewText().Content("Streaming methods can not be called from the console."))
+				continue
+			}
+			children = append(children,
+				b.NewDataEditor(c.editorId(m, "request")).Id(c.editorId(m, "request_view")),
+				b.NewDataEditor(c.editorId(m, "response")).Id(c.editorId(m, "response_view")).ReadOnly(),
+			)
+		}
+		stack := b.NewStack().Id(fmt.Sprintf("%v__service_%v", c.id, i)).Properties(map[string]interface{}{
+			"direction": "vertical",
+			"spacing":   16,
+		}).Children(map[string][]sunmao.BaseComponentBuilder{
+			"content": children,
+		}).Hidden(fmt.Sprintf("{{ %v.activeTab != %v }}", c.id, i))
+		components = append(components, stack)
+	}
+	return components
+}
@@ -0,0 +1,76 @@
+package grpcui
+
+// Schema is the JSON Schema of the protojson form of a message with
+// fields. 64 bit integers may be numbers or strings in protojson and are
+// not type checked.
+func Schema(fields []Field) map[string]interface{} {
+	properties := map[string]interface{}{}
+	for _, f := range fields {
+		properties[f.Name] = fieldSchema(f)
+	}
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+}
+
+func fieldSchema(f Field) map[string]interface{} {
+	var schema map[string]interface{}
+	switch f.Type {
+	case String, Bytes:
+		// bytes are base64 strings
+		schema = map[string]interface{}{"type": "string"}
+	case Int32:
+		schema = map[string]interface{}{"type": "integer"}
+	case Int64:
+		schema = map[string]interface{}{}
+	case Float:
+		schema = map[string]interface{}{"type": "number"}
+	case Bool:
+		schema = map[string]interface{}{"type": "boolean"}
+	case Enum:
+		enum := make([]interface{}, len(f.Enum))
+		for i, e := range f.Enum {
+			enum[i] = e
+		}
+		schema = map[string]interface{}{"type": "string", "enum": enum}
+	case Message:
+		schema = Schema(f.Fields)
+	default:
+		schema = map[string]interface{}{}
+	}
+	if f.Repeated {
+		return map[string]interface{}{"type": "array", "items": schema}
+	}
+	return schema
+}
+
+// Template is a request with the zero value of every field, a starting
+// point to edit.
+func Template(fields []Field) map[string]any {
+	v := map[string]any{}
+	for _, f := range fields {
+		v[f.Name] = zero(f)
+	}
+	return v
+}
+
+func zero(f Field) any {
+	if f.Repeated {
+		return []any{}
+	}
+	switch f.Type {
+	case Int32, Int64, Float:
+		return 0
+	case Bool:
+		return false
+	case Enum:
+		if len(f.Enum) > 0 {
+			return f.Enum[0]
+		}
+		return ""
+	case Message:
+		return Template(f.Fields)
+	}
+	return ""
+}
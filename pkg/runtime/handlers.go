@@ -0,0 +1,216 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yuyz0112/sunmao-ui-go-binding/pkg/sunmao"
+)
+
+type HandlerFunc = func(m *Message, connId int) error
+
+// HandlerOption declares extra behavior of a handler at registration.
+type HandlerOption func(h *handler)
+
+// handlerSpec is the part of a handler declaration the ui needs.
+type handlerSpec struct {
+	Store []string `json:"store,omitempty"`
+	// Params is the JSON Schema of the params, the ui checks calls too
+	Params  map[string]interface{} `json:"params,omitempty"`
+	Command *commandSpec           `json:"command,omitempty"`
+	Confirm string                 `json:"confirm,omitempty"`
+}
+
+type handler struct {
+	fn         HandlerFunc
+	spec       handlerSpec
+	maxStore   int
+	policies   []Policy
+	rateLimit  int
+	rateWindow time.Duration
+	debounce   time.Duration
+	maxParams  int
+}
+
+// StoreKeys makes the client send the state of the listed component ids
+// along with every call, available as Message.Store.
+func StoreKeys(keys ...string) HandlerOption {
+	return StorePaths(keys...)
+}
+
+// StorePaths is StoreKeys for dotted paths such as "my_input.value", the
+// client only serializes the listed fields and nests them by path.
+func StorePaths(paths ...string) HandlerOption {
+	return func(h *handler) {
+		h.spec.Store = append(h.spec.Store, paths...)
+	}
+}
+
+// MaxStoreSize rejects calls whose store is larger than n bytes,
+// overriding WithMaxStoreSize.
+func MaxStoreSize(n int) HandlerOption {
+	return func(h *handler) {
+		h.maxStore = n
+	}
+}
+
+// WithMaxStoreSize rejects calls whose store is larger than n bytes for
+// every handler without its own MaxStoreSize.
+func WithMaxStoreSize(n int) Option {
+	return func(r *Runtime) {
+		r.maxStoreSize = n
+	}
+}
+
+// ParamsSchema rejects calls whose params do not match the JSON Schema
+// with an ActionError coded "invalid_params". The schema is sent to the
+// ui, which checks calls before sending them as well.
+func ParamsSchema(schema map[string]interface{}) HandlerOption {
+	return func(h *handler) {
+		h.spec.Params = schema
+	}
+}
+
+// ParamsOf is ParamsSchema with the schema of T, see sunmao.SchemaOf.
+func ParamsOf[T any]() HandlerOption {
+	return ParamsSchema(sunmao.SchemaOf[T]())
+}
+
+// Confirm makes the ui ask the user to confirm message in a dialog before
+// it sends a call, {field} in message is replaced by that field of the
+// params, e.g. "Stop {container}?". It guards against slips only, calls
+// made without the ui are not confirmed.
+func Confirm(message string) HandlerOption {
+	return func(h *handler) {
+		h.spec.Confirm = message
+	}
+}
+
+func (h *handler) validateParams(msg *Message) error {
+	if h.spec.Params == nil {
+		return nil
+	}
+	if err := sunmao.ValidateSchema(h.spec.Params, msg.Params); err != nil {
+		return &ActionError{Code: "invalid_params", Message: err.Error()}
+	}
+	return nil
+}
+
+func (h *handler) maxStoreSize(fallback int) int {
+	if h.maxStore > 0 {
+		return h.maxStore
+	}
+	return fallback
+}
+
+type handlerRegistry struct {
+	mu       sync.RWMutex
+	handlers map[string]*handler
+}
+
+func newHandlerRegistry() *handlerRegistry {
+	return &handlerRegistry{handlers: map[string]*handler{}}
+}
+
+func (h *handlerRegistry) get(name string) (*handler, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	handler, ok := h.handlers[name]
+	return handler, ok
+}
+
+func newHandler(fn HandlerFunc, opts []HandlerOption) *handler {
+	h := &handler{fn: fn}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Handle registers fn as the server handler name, which the ui calls as
+// the binding/v1/<name> util method. Registering a name twice is an
+// error, use Override to replace a handler on purpose. Handlers added
+// after Run are pushed to connected clients.
+func (r *Runtime) Handle(name string, fn HandlerFunc, opts ...HandlerOption) error {
+	r.handlers.mu.Lock()
+	if _, ok := r.handlers.handlers[name]; ok {
+		r.handlers.mu.Unlock()
+		return fmt.Errorf("%w: %v", errDuplicateHandler, name)
+	}
+	r.handlers.handlers[name] = newHandler(fn, opts)
+	r.handlers.mu.Unlock()
+
+	return r.handlersChanged()
+}
+
+// Override registers fn as the handler name, replacing any existing one.
+func (r *Runtime) Override(name string, fn HandlerFunc, opts ...HandlerOption) error {
+	r.handlers.mu.Lock()
+	r.handlers.handlers[name] = newHandler(fn, opts)
+	r.handlers.mu.Unlock()
+
+	return r.handlersChanged()
+}
+
+// Unhandle removes a handler, clients calling it afterwards are ignored.
+func (r *Runtime) Unhandle(name string) error {
+	r.handlers.mu.Lock()
+	delete(r.handlers.handlers, name)
+	r.handlers.mu.Unlock()
+
+	return r.handlersChanged()
+}
+
+// Handlers lists the registered handler names in order.
+func (r *Runtime) Handlers() []string {
+	r.handlers.mu.RLock()
+	defer r.handlers.mu.RUnlock()
+
+	names := make([]string, 0, len(r.handlers.handlers))
+	for name := range r.handlers.handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (r *Runtime) handlerSpecs() map[string]handlerSpec {
+	r.handlers.mu.RLock()
+	defer r.handlers.mu.RUnlock()
+
+	specs := map[string]handlerSpec{}
+	for name, h := range r.handlers.handlers {
+		specs[name] = h.spec
+	}
+	return specs
+}
+
+func (r *Runtime) handlersChanged() error {
+	r.invalidatePages()
+
+	return r.send(map[string]interface{}{
+		"type":         "Handlers",
+		"handlers":     r.Handlers(),
+		"handlerSpecs": r.handlerSpecs(),
+	}, nil)
+}
+
+// decodeParams converts the params of m into T.
+func decodeParams[T any](m *Message) (T, error) {
+	return decodeValue[T](m.Params)
+}
+
+// decodeValue converts a decoded json value such as params into T.
+func decodeValue[T any](value any) (T, error) {
+	var v T
+	buf, err := json.Marshal(value)
+	if err != nil {
+		return v, err
+	}
+	err = json.Unmarshal(buf, &v)
+	return v, err
+}
@@ -0,0 +1,222 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// handoffTTL bounds how long a resume token stays valid, the client
+	// reconnects right after the old process closed its socket.
+	handoffTTL = time.Minute
+	// handoffTimeout bounds the wait for the tokens to reach the clients
+	handoffTimeout = time.Second
+)
+
+var errUnknownResumeToken = errors.New("unknown resume token")
+
+// HandoffState is what a connection carries over to the next process.
+// Identities are not part of it, they come back through the session.
+type HandoffState struct {
+	Saved       time.Time       `json:"saved"`
+	TabId       string          `json:"tabId"`
+	Preferences map[string]any  `json:"preferences"`
+	Data        json.RawMessage `json:"data,omitempty"`
+}
+
+// HandoffStore is shared by the old and the new process of a deploy.
+type HandoffStore interface {
+	Save(token string, state *HandoffState) error
+	// Load returns the state and forgets it, tokens are single use.
+	Load(token string) (*HandoffState, error)
+}
+
+// WithHandoff lets Handoff export connections to store and a new process
+// resume them, instead of every client reloading on each restart.
+func WithHandoff(store HandoffStore) Option {
+	return func(r *Runtime) {
+		r.handoffStore = store
+	}
+}
+
+// OnHandoff exports app state of a connection, e.g. a draft, the value
+// is handed to the OnResume hooks of the next process. Register it once,
+// the last hook's value wins.
+func (r *Runtime) OnHandoff(fn func(conn *Conn) (any, error)) {
+	r.hooks.handoff = append(r.hooks.handoff, fn)
+}
+
+// OnResume runs for connections resumed from a handoff, before the
+// connected hooks.
+func (r *Runtime) OnResume(fn func(conn *Conn, data json.RawMessage)) {
+	r.hooks.resume = append(r.hooks.resume, fn)
+}
+
+// Handoff saves every connection to the handoff store, hands the client a
+// resume token and closes the socket with code 1012, service restart.
+// Connections are handed off at once within a second, a connection whose
+// hook or save fails is skipped and its error returned along with the
+// others. Call it on shutdown right before the process exits.
+func (r *Runtime) Handoff() error {
+	if r.handoffStore == nil {
+		return errors.New("no handoff store configured")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), handoffTimeout)
+	defer cancel()
+
+	mu := sync.Mutex{}
+	errs := handoffErrors{}
+	wg := sync.WaitGroup{}
+	for _, conn := range r.conns.list() {
+		conn := conn
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := r.handoff(ctx, conn); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (r *Runtime) handoff(ctx context.Context, conn *Conn) error {
+	state := &HandoffState{
+		Saved:       time.Now(),
+		TabId:       conn.TabId,
+		Preferences: conn.preferences(),
+	}
+	for _, fn := range r.hooks.handoff {
+		data, err := fn(conn)
+		if err != nil {
+			return err
+		}
+		if state.Data, err = json.Marshal(data); err != nil {
+			return err
+		}
+	}
+
+	token := newRequestId()
+	if err := r.handoffStore.Save(token, state); err != nil {
+		return err
+	}
+
+	buf, err := json.Marshal(map[string]interface{}{
+		"type":  "Handoff",
+		"token": token,
+	})
+	if err != nil {
+		return err
+	}
+	written := make(chan struct{})
+	if err := conn.enqueueFrame(buf, func() { close(written) }); err != nil {
+		return nil
+	}
+	select {
+	case <-written:
+	case <-conn.done:
+	case <-ctx.Done():
+	}
+	conn.ws.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseServiceRestart, "handoff"),
+		time.Now().Add(time.Second))
+	return nil
+}
+
+// handoffErrors are the errors of the connections Handoff skipped.
+type handoffErrors []error
+
+func (e handoffErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return "handoff: " + strings.Join(msgs, "; ")
+}
+
+func (e handoffErrors) Unwrap() []error {
+	return e
+}
+
+func (r *Runtime) resume(conn *Conn, token string) {
+	if token == "" || r.handoffStore == nil {
+		return
+	}
+	state, err := r.handoffStore.Load(token)
+	if err != nil || time.Since(state.Saved) > handoffTTL {
+		return
+	}
+
+	if state.TabId != "" {
+		conn.TabId = state.TabId
+	}
+	conn.setPreferences(state.Preferences)
+	for _, fn := range r.hooks.resume {
+		fn(conn, state.Data)
+	}
+}
+
+var handoffToken = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// FileHandoffStore keeps handoff states as files in a directory, which
+// suits deploys replacing a process on the same host.
+type FileHandoffStore struct {
+	Dir string
+}
+
+func (s *FileHandoffStore) path(token string) (string, error) {
+	if !handoffToken.MatchString(token) {
+		return "", errUnknownResumeToken
+	}
+	return filepath.Join(s.Dir, token+".json"), nil
+}
+
+func (s *FileHandoffStore) Save(token string, state *HandoffState) error {
+	path, err := s.path(token)
+	if err != nil {
+		return err
+	}
+	buf, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf, 0600)
+}
+
+func (s *FileHandoffStore) Load(token string) (*HandoffState, error) {
+	path, err := s.path(token)
+	if err != nil {
+		return nil, err
+	}
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errUnknownResumeToken
+	}
+	os.Remove(path)
+
+	state := &HandoffState{}
+	if err := json.Unmarshal(buf, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
@@ -0,0 +1,208 @@
+package runtime
+
+import (
+	"sync"
+
+	"github.com/yuyz0112/sunmao-ui-go-binding/pkg/sunmao"
+)
+
+// sharedHistory keys the steps of broadcast SetState calls
+const sharedHistory = -1
+
+type historyStack struct {
+	past    []any
+	present any
+	future  []any
+}
+
+type historyStatus struct {
+	CanUndo bool `json:"canUndo"`
+	CanRedo bool `json:"canRedo"`
+}
+
+// History records the values set on a ServerState so they can be stepped
+// back and forth. Broadcast values share one history, values set for a
+// connection start a history of that connection which goes away with it.
+type History struct {
+	*ServerState
+	depth  int
+	mu     sync.Mutex
+	stacks map[int]*historyStack
+}
+
+// WithHistory keeps the last depth values of s, set them through the
+// returned History from then on. Its component also holds the history key,
+// {canUndo, canRedo}, to enable undo and redo buttons.
+func (s *ServerState) WithHistory(depth int) *History {
+	h := &History{ServerState: s, depth: depth, stacks: map[int]*historyStack{}}
+	s.r.OnDisconnected(func(conn *Conn, _ CloseReason) {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		delete(h.stacks, conn.Id)
+	})
+	return h
+}
+
+func (h *History) AsComponent() sunmao.BaseComponentBuilder {
+	return h.component().Trait(
+		h.r.appBuilder.NewTrait().Type("core/v1/state").
+			Properties(map[string]interface{}{
+				"key":          "history",
+				"initialValue": historyStatus{},
+			}))
+}
+
+// SetState records the value newState replaces and drops the steps that
+// could have been redone.
+func (h *History) SetState(newState any, connId *int) error {
+	h.mu.Lock()
+	st := h.stack(connId)
+	st.past = append(st.past, st.present)
+	if len(st.past) > h.depth {
+		st.past = st.past[len(st.past)-h.depth:]
+	}
+	st.present = newState
+	st.future = nil
+	h.mu.Unlock()
+
+	if err := h.ServerState.SetState(newState, connId); err != nil {
+		return err
+	}
+	return h.pushStatus(connId)
+}
+
+// Confirm accepts an optimistic value as a new step of the shared
+// history.
+func (h *History) Confirm(value any) error {
+	return h.SetState(value, nil)
+}
+
+// Undo restores the value before the last step of connId's history, nil
+// for the shared one. It reports false when there is nothing to undo.
+func (h *History) Undo(connId *int) (bool, error) {
+	return h.step(connId, true)
+}
+
+// Redo sets the value again that the last Undo replaced.
+func (h *History) Redo(connId *int) (bool, error) {
+	return h.step(connId, false)
+}
+
+// Bind registers the <id>/undo and <id>/redo handlers, see
+// sunmao.UndoHandler. They step the history of the calling connection
+// when it has one and the shared history otherwise. With shortcuts
+// mod+z and mod+shift+z are bound to them for every client.
+func (h *History) Bind(shortcuts bool) error {
+	for _, undo := range []bool{true, false} {
+		undo := undo
+		name := h.Id + "/redo"
+		if undo {
+			name = h.Id + "/undo"
+		}
+		err := h.r.Handle(name, func(m *Message, connId int) error {
+			var target *int
+			h.mu.Lock()
+			if _, ok := h.stacks[connId]; ok {
+				target = &connId
+			}
+			h.mu.Unlock()
+			_, err := h.step(target, undo)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if !shortcuts {
+		return nil
+	}
+	if err := h.r.Shortcut(nil, "mod+z", h.Id+"/undo"); err != nil {
+		return err
+	}
+	return h.r.Shortcut(nil, "mod+shift+z", h.Id+"/redo")
+}
+
+func (h *History) step(connId *int, undo bool) (bool, error) {
+	h.mu.Lock()
+	st := h.stack(connId)
+	from, to := &st.past, &st.future
+	if !undo {
+		from, to = to, from
+	}
+	if len(*from) == 0 {
+		h.mu.Unlock()
+		return false, nil
+	}
+	value := (*from)[len(*from)-1]
+	*from = (*from)[:len(*from)-1]
+	*to = append(*to, st.present)
+	st.present = value
+	h.mu.Unlock()
+
+	if err := h.ServerState.SetState(value, connId); err != nil {
+		return true, err
+	}
+	return true, h.pushStatus(connId)
+}
+
+// stack returns the history of connId, a connection's own history starts
+// from the shared value.
+func (h *History) stack(connId *int) *historyStack {
+	key := sharedHistory
+	if connId != nil {
+		key = *connId
+	}
+	st, ok := h.stacks[key]
+	if !ok {
+		st = &historyStack{present: h.initState}
+		if shared, ok := h.stacks[sharedHistory]; ok && key != sharedHistory {
+			st.present = shared.present
+		}
+		h.stacks[key] = st
+	}
+	return st
+}
+
+// pushStatus tells the clients whether they can undo or redo, after a
+// shared step connections with their own history keep its status.
+func (h *History) pushStatus(connId *int) error {
+	h.mu.Lock()
+	status := map[int]historyStatus{}
+	for key, st := range h.stacks {
+		status[key] = historyStatus{CanUndo: len(st.past) > 0, CanRedo: len(st.future) > 0}
+	}
+	h.mu.Unlock()
+
+	send := func(s historyStatus, connId *int) error {
+		err := h.r.Execute(&ExecuteTarget{
+			Id:     h.Id,
+			Method: "setValue",
+			Parameters: map[string]interface{}{
+				"key":   "history",
+				"value": s,
+			},
+		}, connId)
+		if err == errConnClosed {
+			return nil
+		}
+		return err
+	}
+
+	if connId != nil {
+		return send(status[*connId], connId)
+	}
+	if err := send(status[sharedHistory], nil); err != nil {
+		return err
+	}
+	for key, s := range status {
+		if key == sharedHistory {
+			continue
+		}
+		connId := key
+		if err := send(s, &connId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
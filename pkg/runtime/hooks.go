@@ -0,0 +1,90 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/labstack/echo/v4"
+)
+
+type hooks struct {
+	connected      []func(conn *Conn)
+	disconnected   []func(conn *Conn, reason CloseReason)
+	beforeAction   []func(conn *Conn, msg *Message) error
+	appServed      []func(conn *Conn)
+	visibility     []func(conn *Conn, visible bool)
+	focus          []func(conn *Conn, focused bool)
+	unload         []func(conn *Conn)
+	resize         []func(conn *Conn, viewport Viewport)
+	hydrate        []func(c echo.Context, h *Hydration) error
+	index          []func(c echo.Context, d *IndexData) error
+	clientError    []func(conn *Conn, e *ClientError)
+	handoff        []func(conn *Conn) (any, error)
+	resume         []func(conn *Conn, data json.RawMessage)
+	httpError      []func(c echo.Context, err error)
+	invalidMessage []func(conn *Conn, m *InvalidMessage)
+}
+
+// VetoError is returned for actions rejected by an OnBeforeAction hook.
+type VetoError struct {
+	Err error
+}
+
+func (e *VetoError) Error() string {
+	return fmt.Sprintf("action vetoed: %v", e.Err)
+}
+
+func (e *VetoError) Unwrap() error {
+	return e.Err
+}
+
+// OnConnected runs after a client opened its websocket.
+func (r *Runtime) OnConnected(fn func(conn *Conn)) {
+	r.hooks.connected = append(r.hooks.connected, fn)
+}
+
+// OnDisconnected runs exactly once per connection, whatever closed it.
+func (r *Runtime) OnDisconnected(fn func(conn *Conn, reason CloseReason)) {
+	r.hooks.disconnected = append(r.hooks.disconnected, fn)
+}
+
+// OnBeforeAction runs before every handler, a non nil error rejects the
+// action. conn is nil for calls without a websocket such as the action api.
+func (r *Runtime) OnBeforeAction(fn func(conn *Conn, msg *Message) error) {
+	r.hooks.beforeAction = append(r.hooks.beforeAction, fn)
+}
+
+// OnAppServed runs once the client finished rendering the application on
+// a connection, which is the right moment to push initial states.
+func (r *Runtime) OnAppServed(fn func(conn *Conn)) {
+	r.hooks.appServed = append(r.hooks.appServed, fn)
+}
+
+// On registers a "connected" or "disconnected" hook.
+//
+// Deprecated: use the typed OnConnected and OnDisconnected instead.
+func (r *Runtime) On(hook string, fn func(connId int) error) {
+	switch hook {
+	case "connected":
+		r.OnConnected(func(conn *Conn) { fn(conn.Id) })
+	case "disconnected":
+		r.OnDisconnected(func(conn *Conn, reason CloseReason) { fn(conn.Id) })
+	}
+}
+
+func (r *Runtime) beforeAction(conn *Conn, msg *Message) error {
+	for _, fn := range r.hooks.beforeAction {
+		if err := fn(conn, msg); err != nil {
+			return &VetoError{Err: err}
+		}
+	}
+	return nil
+}
+
+func (r *Runtime) appServed(conn *Conn) {
+	r.userStates.hydrate(r, conn)
+	r.announceTo(conn)
+	for _, fn := range r.hooks.appServed {
+		fn(conn)
+	}
+}
@@ -0,0 +1,13 @@
+package runtime
+
+import "golang.org/x/net/http2"
+
+// WithH2C serves cleartext HTTP/2 for deployments where a proxy in front
+// terminates TLS, so the asset bundles load multiplexed. Websocket
+// upgrades keep using HTTP/1.1 on the same port. With TLS, HTTP/2 is
+// negotiated through ALPN without any option.
+func WithH2C() Option {
+	return func(r *Runtime) {
+		r.h2c = &http2.Server{}
+	}
+}
@@ -0,0 +1,105 @@
+package runtime
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+var errorPageTemplate = template.Must(template.New("error").Parse(`<!DOCTYPE html>
+<html lang="en">
+  <head>
+    <meta charset="UTF-8" />
+    <meta name="viewport" content="width=device-width, initial-scale=1.0" />
+    <title>{{.Code}} {{.Status}}</title>
+    <style>
+      body { margin: 0; font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, sans-serif; color: #1d2129; background: #f7f8fa; }
+      main { max-width: 480px; margin: 20vh auto 0; padding: 0 24px; text-align: center; }
+      h1 { margin: 0; font-size: 64px; color: #165dff; }
+      p { color: #4e5969; }
+      a { color: #165dff; text-decoration: none; }
+    </style>
+  </head>
+  <body>
+    <main>
+      <h1>{{.Code}}</h1>
+      <p>{{.Message}}</p>
+      <a href="{{.Home}}">{{if .Title}}Back to {{.Title}}{{else}}Back to home{{end}}</a>
+    </main>
+  </body>
+</html>
+`))
+
+// WithHTTPErrorHandler replaces the default error pages, OnHTTPError hooks
+// still run before h.
+func WithHTTPErrorHandler(h echo.HTTPErrorHandler) Option {
+	return func(r *Runtime) {
+		r.httpErrorHandler = h
+	}
+}
+
+// OnHTTPError runs for every failed request including recovered panics,
+// e.g. to report them. The response is written after the hooks ran.
+func (r *Runtime) OnHTTPError(fn func(c echo.Context, err error)) {
+	r.hooks.httpError = append(r.hooks.httpError, fn)
+}
+
+// handleHTTPError renders an error page for browsers, other clients get
+// echo's json errors. Messages of 5xx errors are never shown.
+func (r *Runtime) handleHTTPError(err error, c echo.Context) {
+	for _, fn := range r.hooks.httpError {
+		fn(c, err)
+	}
+	if r.httpErrorHandler != nil {
+		r.httpErrorHandler(err, c)
+		return
+	}
+	if c.Response().Committed {
+		return
+	}
+
+	req := c.Request()
+	if req.Method != http.MethodGet || !strings.Contains(req.Header.Get(echo.HeaderAccept), echo.MIMETextHTML) {
+		r.e.DefaultHTTPErrorHandler(err, c)
+		return
+	}
+
+	code := http.StatusInternalServerError
+	message := "Something went wrong, please try again later."
+	he := &echo.HTTPError{}
+	if errors.As(err, &he) {
+		code = he.Code
+	}
+	if code < http.StatusInternalServerError {
+		message = http.StatusText(code)
+		if m, ok := he.Message.(string); ok {
+			message = m
+		}
+	} else {
+		c.Logger().Error(err)
+	}
+
+	title := ""
+	if r.appBuilder != nil {
+		title = r.appBuilder.MetaOf().Title
+	}
+
+	buf := &bytes.Buffer{}
+	if err := errorPageTemplate.Execute(buf, map[string]any{
+		"Code":    code,
+		"Status":  http.StatusText(code),
+		"Message": message,
+		"Title":   title,
+		"Home":    r.basePath + "/",
+	}); err != nil {
+		c.Logger().Error(err)
+		return
+	}
+	if err := c.HTMLBlob(code, buf.Bytes()); err != nil {
+		c.Logger().Error(err)
+	}
+}
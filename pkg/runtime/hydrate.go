@@ -0,0 +1,70 @@
+package runtime
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Hydration collects the initial ServerState values of one page request.
+type Hydration struct {
+	states map[string]any
+}
+
+// Set renders state with value instead of its initial state.
+func (h *Hydration) Set(state *ServerState, value any) {
+	h.states[state.Id] = value
+}
+
+// OnHydrate runs while the index page is requested, states set on h are
+// part of the first render, so the page does not flash the initial state
+// until a connected hook pushed the real one. Pages are no longer cached
+// once a hydrate hook is registered.
+func (r *Runtime) OnHydrate(fn func(c echo.Context, h *Hydration) error) {
+	r.hooks.hydrate = append(r.hooks.hydrate, fn)
+	r.invalidatePages()
+}
+
+// serveHydrated renders the page per request, for hydration, flags and
+// index hooks.
+func (r *Runtime) serveHydrated(c echo.Context, name string) error {
+	h := &Hydration{states: map[string]any{}}
+	for _, fn := range r.hooks.hydrate {
+		if err := fn(c, h); err != nil {
+			return err
+		}
+	}
+
+	options, err := r.uiOptions()
+	if err != nil {
+		return err
+	}
+	options["hydration"] = h.states
+	if r.flags != nil {
+		flags, err := r.requestFlags(c)
+		if err != nil {
+			return err
+		}
+		options["flags"] = flags
+	}
+
+	buf := &bytes.Buffer{}
+	if err := r.renderPage(buf, name, options); err != nil {
+		return err
+	}
+	d, err := r.requestIndexData(c)
+	if err != nil {
+		return err
+	}
+	html, err := r.renderHead(buf.Bytes(), d)
+	if err != nil {
+		return err
+	}
+
+	if r.cspNonce {
+		return r.servePageWithNonce(c, &cachedPage{html: html})
+	}
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+	return c.HTMLBlob(http.StatusOK, html)
+}
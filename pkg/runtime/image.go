@@ -0,0 +1,90 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+
+	"github.com/yuyz0112/sunmao-ui-go-binding/pkg/sunmao"
+)
+
+// ImageSource holds the object url of the last image sent to it, bind an
+// image component's src to Src().
+type ImageSource struct {
+	r  *Runtime
+	Id string
+}
+
+func (r *Runtime) NewImageSource(id string) *ImageSource {
+	return &ImageSource{r: r, Id: id}
+}
+
+func (s *ImageSource) AsComponent() sunmao.BaseComponentBuilder {
+	return s.r.appBuilder.NewComponent().Type("core/v1/dummy").Id(s.Id).
+		Trait(
+			s.r.appBuilder.NewTrait().Type("core/v1/state").
+				Properties(map[string]interface{}{
+					"key":          "src",
+					"initialValue": "",
+				}))
+}
+
+// Src is the expression to use as the src property of an image.
+func (s *ImageSource) Src() string {
+	return fmt.Sprintf("{{ %v.src }}", s.Id)
+}
+
+// Send is a shortcut for SendImage on this source.
+func (s *ImageSource) Send(connId *int, img any, mime string) error {
+	return s.r.SendImage(connId, s.Id, img, mime)
+}
+
+// SendImage transfers an image as a binary frame and points the
+// ImageSource componentId at it. img is an image.Image, encoded as png,
+// or already encoded []byte of the given mime type. The client revokes
+// the previous object url of the source.
+func (r *Runtime) SendImage(connId *int, componentId string, img any, mime string) error {
+	var body []byte
+	switch v := img.(type) {
+	case []byte:
+		body = v
+	case image.Image:
+		buf := &bytes.Buffer{}
+		if err := png.Encode(buf, v); err != nil {
+			return err
+		}
+		body = buf.Bytes()
+		mime = "image/png"
+	default:
+		return fmt.Errorf("unsupported image type %T", img)
+	}
+	if mime == "" {
+		mime = "application/octet-stream"
+	}
+
+	header, err := json.Marshal(map[string]interface{}{
+		"type":        "Image",
+		"componentId": componentId,
+		"mime":        mime,
+	})
+	if err != nil {
+		return err
+	}
+
+	if connId != nil {
+		conn := r.conns.get(*connId)
+		if conn == nil {
+			return nil
+		}
+		return conn.enqueueBinary(header, body)
+	}
+
+	for _, conn := range r.conns.list() {
+		if err := conn.enqueueBinary(header, body); err != nil && err != errConnClosed {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package runtime
+
+import (
+	"encoding/json"
+
+	"github.com/yuyz0112/sunmao-ui-go-binding/pkg/sunmao"
+)
+
+// WithIncrementalReload makes ReloadApp push the diff against the
+// previously served application, so clients keep scroll positions and
+// inputs while iterating in development.
+func WithIncrementalReload() Option {
+	return func(r *Runtime) {
+		r.incrementalReload = true
+	}
+}
+
+// snapshotComponents deep copies the components, builders may keep being
+// mutated after they were loaded.
+func snapshotComponents(builder *sunmao.AppBuilder) []sunmao.ComponentSchema {
+	buf, err := json.Marshal(builder.ValueOf().Spec.Components)
+	if err != nil {
+		return nil
+	}
+	components := []sunmao.ComponentSchema{}
+	if err := json.Unmarshal(buf, &components); err != nil {
+		return nil
+	}
+	return components
+}
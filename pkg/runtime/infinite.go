@@ -0,0 +1,137 @@
+package runtime
+
+import "sync"
+
+// Page is one batch of an infinite list. Next is the cursor of the page
+// after it, empty once there is no more data.
+type Page struct {
+	Items []any
+	Next  string
+}
+
+// PageLoader loads the page at cursor, "" for the first page.
+type PageLoader func(conn *Conn, cursor string) (Page, error)
+
+type infiniteState struct {
+	Items  []any  `json:"items"`
+	Cursor string `json:"cursor"`
+	Done   bool   `json:"done"`
+	Error  string `json:"error,omitempty"`
+}
+
+// InfiniteList is the ServerState behind sunmao.NewLoadMore. Every
+// connection scrolls on its own, so the loaded items are kept and pushed
+// per connection.
+type InfiniteList struct {
+	*ServerState
+	load  PageLoader
+	mu    sync.Mutex
+	conns map[int]*infiniteState
+}
+
+// NewInfiniteList serves the pages of load under id. The first page is
+// pushed once a client served the app, the following ones are appended
+// whenever its binding/v1/loadMore component asks for them. Bind lists and
+// tables to sunmao.InfiniteItems(id).
+func NewInfiniteList(r *Runtime, id string, load PageLoader) (*InfiniteList, error) {
+	l := &InfiniteList{
+		ServerState: r.NewServerState(id, &infiniteState{Items: []any{}}),
+		load:        load,
+		conns:       map[int]*infiniteState{},
+	}
+
+	r.OnAppServed(func(conn *Conn) {
+		if err := l.reset(conn); err != nil {
+			r.e.Logger.Error(err)
+		}
+	})
+	r.OnDisconnected(func(conn *Conn, _ CloseReason) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		delete(l.conns, conn.Id)
+	})
+
+	return l, r.Handle(id+"/more", l.handleMore)
+}
+
+// Reset drops the loaded items and starts again from the first page, e.g.
+// after the filters behind load changed. A nil connId resets every
+// connection.
+func (l *InfiniteList) Reset(connId *int) error {
+	conns := l.r.conns.list()
+	if connId != nil {
+		conns = []*Conn{l.r.conns.get(*connId)}
+	}
+	for _, conn := range conns {
+		if conn == nil {
+			continue
+		}
+		if err := l.reset(conn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *InfiniteList) reset(conn *Conn) error {
+	l.mu.Lock()
+	l.conns[conn.Id] = &infiniteState{Items: []any{}}
+	l.mu.Unlock()
+
+	return l.next(conn, "")
+}
+
+func (l *InfiniteList) handleMore(m *Message, connId int) error {
+	conn := l.r.conns.get(connId)
+	if conn == nil {
+		return nil
+	}
+	params, err := decodeParams[struct {
+		Cursor string `json:"cursor"`
+	}](m)
+	if err != nil {
+		return err
+	}
+	return l.next(conn, params.Cursor)
+}
+
+// next appends the page at cursor. Requests for another cursor than the
+// one the connection is at are repeated scroll events and dropped, so no
+// page is ever appended twice.
+func (l *InfiniteList) next(conn *Conn, cursor string) error {
+	l.mu.Lock()
+	state, ok := l.conns[conn.Id]
+	if !ok || state.Done || state.Cursor != cursor {
+		l.mu.Unlock()
+		return nil
+	}
+	l.mu.Unlock()
+
+	page, err := l.load(conn, cursor)
+
+	l.mu.Lock()
+	if l.conns[conn.Id] != state || state.Done || state.Cursor != cursor {
+		// reset or loaded by a concurrent request meanwhile
+		l.mu.Unlock()
+		return nil
+	}
+	if err != nil {
+		// the client offers a retry at the same cursor
+		state.Error = err.Error()
+	} else {
+		state.Items = append(state.Items, page.Items...)
+		state.Cursor = page.Next
+		state.Done = page.Next == ""
+		state.Error = ""
+	}
+	next := *state
+	next.Items = append([]any{}, state.Items...)
+	l.mu.Unlock()
+
+	connId := conn.Id
+	if serr := l.SetState(&next, &connId); serr != nil && serr != errConnClosed {
+		return serr
+	}
+	return err
+}
@@ -0,0 +1,200 @@
+package runtime
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	inspectorHistory = 500
+	// inspectorQueueSize holds the history and what piles up behind a slow
+	// inspector tab, frames beyond it are dropped for that tab
+	inspectorQueueSize = 2 * inspectorHistory
+)
+
+type inspector struct {
+	mu      sync.Mutex
+	history []*Frame
+	subs    map[*inspectorSub]bool
+}
+
+// inspectorSub is an inspector tab, its frames are written by a goroutine
+// of its own so a slow tab never stalls the app's sends.
+type inspectorSub struct {
+	ws     *websocket.Conn
+	frames chan *Frame
+}
+
+// WithInspector serves a live log of websocket frames at
+// /sunmao-binding-inspector. It is meant for development only.
+func WithInspector() Option {
+	return func(r *Runtime) {
+		r.inspector = &inspector{
+			history: []*Frame{},
+			subs:    map[*inspectorSub]bool{},
+		}
+	}
+}
+
+func (i *inspector) publish(frame *Frame) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.history = append(i.history, frame)
+	if len(i.history) > inspectorHistory {
+		i.history = i.history[len(i.history)-inspectorHistory:]
+	}
+
+	for sub := range i.subs {
+		select {
+		case sub.frames <- frame:
+		default:
+		}
+	}
+}
+
+func (i *inspector) subscribe(ws *websocket.Conn) *inspectorSub {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	sub := &inspectorSub{ws: ws, frames: make(chan *Frame, inspectorQueueSize)}
+	for _, frame := range i.history {
+		sub.frames <- frame
+	}
+	i.subs[sub] = true
+	return sub
+}
+
+func (i *inspector) unsubscribe(sub *inspectorSub) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.subs[sub] {
+		delete(i.subs, sub)
+		close(sub.frames)
+	}
+}
+
+// write sends the frames of sub until it is unsubscribed or a write fails.
+func (sub *inspectorSub) write() {
+	for frame := range sub.frames {
+		if err := sub.ws.WriteJSON(frame); err != nil {
+			// the read loop notices the closed socket and unsubscribes
+			sub.ws.Close()
+			return
+		}
+	}
+}
+
+func (r *Runtime) registerInspector() {
+	r.router.GET("/sunmao-binding-inspector", func(c echo.Context) error {
+		return c.HTML(http.StatusOK, inspectorHTML)
+	})
+
+	r.router.GET("/sunmao-binding-inspector/ws", func(c echo.Context) error {
+		ws, err := upgrader.Upgrade(c.Response(), c.Request(), nil)
+		if err != nil {
+			return err
+		}
+		sub := r.inspector.subscribe(ws)
+		defer func() {
+			r.inspector.unsubscribe(sub)
+			ws.Close()
+		}()
+		go sub.write()
+
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return nil
+			}
+		}
+	})
+}
+
+const inspectorHTML = `
+<!DOCTYPE html>
+<html>
+    <head>
+        <title>Sunmao Binding Inspector</title>
+        <style>
+            body { font-family: monospace; margin: 0; }
+            header { position: sticky; top: 0; background: #f1f3f5; padding: 8px; display: flex; gap: 8px; }
+            .frame { border-bottom: 1px solid #e9ecef; padding: 4px 8px; cursor: pointer; }
+            .frame pre { display: none; margin: 4px 0; white-space: pre-wrap; }
+            .frame.open pre { display: block; }
+            .in { color: #1971c2; }
+            .out { color: #2f9e44; }
+        </style>
+    </head>
+    <body>
+        <header>
+            <select id="kind">
+                <option value="">all</option>
+                <option value="Action">Action</option>
+                <option value="UiMethod">Execute</option>
+                <option value="setValue">state set</option>
+            </select>
+            <input id="filter" placeholder="filter by text" />
+            <button id="clear">clear</button>
+        </header>
+        <div id="frames"></div>
+        <script>
+            var frames = document.getElementById('frames');
+            var kind = document.getElementById('kind');
+            var filter = document.getElementById('filter');
+
+            function kindOf(data) {
+                if (data.type === 'UiMethod' && data.name === 'setValue') {
+                    return 'setValue';
+                }
+                return data.type;
+            }
+
+            function matches(el) {
+                var k = kind.value;
+                var f = filter.value;
+                return (!k || el.dataset.kind === k || (k === 'UiMethod' && el.dataset.kind === 'setValue')) &&
+                    (!f || el.textContent.indexOf(f) !== -1);
+            }
+
+            function apply() {
+                Array.prototype.forEach.call(frames.children, function (el) {
+                    el.style.display = matches(el) ? '' : 'none';
+                });
+            }
+
+            kind.onchange = apply;
+            filter.oninput = apply;
+            document.getElementById('clear').onclick = function () {
+                frames.innerHTML = '';
+            };
+
+            var ws = new WebSocket((location.protocol === 'https:' ? 'wss://' : 'ws://') + location.host + location.pathname + '/ws');
+            ws.onmessage = function (evt) {
+                var frame = JSON.parse(evt.data);
+                var el = document.createElement('div');
+                el.className = 'frame';
+                el.dataset.kind = kindOf(frame.data);
+                el.onclick = function () { el.classList.toggle('open'); };
+
+                var summary = document.createElement('span');
+                summary.className = frame.direction;
+                summary.textContent = frame.time + ' #' + frame.connId + ' ' +
+                    (frame.direction === 'in' ? '>> ' : '<< ') + el.dataset.kind + ' ' +
+                    (frame.data.handler || frame.data.componentId || '');
+
+                var payload = document.createElement('pre');
+                payload.textContent = JSON.stringify(frame.data, null, 2);
+
+                el.appendChild(summary);
+                el.appendChild(payload);
+                el.style.display = matches(el) ? '' : 'none';
+                frames.appendChild(el);
+            };
+        </script>
+    </body>
+</html>
+`
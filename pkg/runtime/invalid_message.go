@@ -0,0 +1,75 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// invalid message reasons
+const (
+	InvalidMalformed      = "malformed"
+	InvalidUnknownType    = "unknown_type"
+	InvalidUnknownHandler = "unknown_handler"
+	InvalidTooLarge       = "too_large"
+)
+
+const invalidMessageExcerpt = 1024
+
+// InvalidMessage describes an inbound websocket message the runtime did not
+// act on.
+type InvalidMessage struct {
+	Reason string
+	Err    error
+	// Data is the start of the message, nil for messages over the read
+	// limit since they are not read to the end.
+	Data []byte
+	Size int
+}
+
+// WithStrictMessages rejects inbound messages with fields the runtime does
+// not know as InvalidMalformed, instead of ignoring the fields. It does
+// not apply once SetJSONCodec replaced the decoder.
+func WithStrictMessages() Option {
+	return func(r *Runtime) {
+		r.strictMessages = true
+	}
+}
+
+// OnInvalidMessage runs for every message which is malformed, of an
+// unknown type, calls an unknown handler or exceeds WithMaxMessageSize,
+// so misbehaving clients can be detected. Messages over the size limit
+// close the connection right after.
+func (r *Runtime) OnInvalidMessage(fn func(conn *Conn, m *InvalidMessage)) {
+	r.hooks.invalidMessage = append(r.hooks.invalidMessage, fn)
+}
+
+func (r *Runtime) decodeMessage(msgBytes []byte, v any) error {
+	if !r.strictMessages || codec.custom {
+		return codec.unmarshal(msgBytes, v)
+	}
+	dec := json.NewDecoder(bytes.NewReader(msgBytes))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+func (r *Runtime) invalidMessage(connId int, reason string, err error, msgBytes []byte) {
+	if len(r.hooks.invalidMessage) == 0 {
+		return
+	}
+	conn := r.conns.get(connId)
+	if conn == nil {
+		return
+	}
+
+	m := &InvalidMessage{Reason: reason, Err: err, Size: len(msgBytes)}
+	if len(msgBytes) > invalidMessageExcerpt {
+		msgBytes = msgBytes[:invalidMessageExcerpt]
+	}
+	m.Data = append([]byte(nil), msgBytes...)
+	if len(m.Data) == 0 {
+		m.Data = nil
+	}
+	for _, fn := range r.hooks.invalidMessage {
+		fn(conn, m)
+	}
+}
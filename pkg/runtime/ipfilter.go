@@ -0,0 +1,51 @@
+package runtime
+
+import (
+	"net/netip"
+
+	"github.com/labstack/echo/v4"
+)
+
+// CIDR is an address range such as netip.MustParsePrefix("10.0.0.0/8").
+type CIDR = netip.Prefix
+
+type ipFilter struct {
+	allow []CIDR
+	deny  []CIDR
+}
+
+// WithIPFilter rejects requests, including the websocket upgrade, from
+// addresses in deny, or outside of allow when allow is not empty.
+func WithIPFilter(allow, deny []CIDR) Option {
+	return func(r *Runtime) {
+		r.ipFilter = &ipFilter{allow: allow, deny: deny}
+	}
+}
+
+func containsIP(ranges []CIDR, ip netip.Addr) bool {
+	for _, p := range ranges {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *ipFilter) allowed(ip netip.Addr) bool {
+	if containsIP(f.deny, ip) {
+		return false
+	}
+	return len(f.allow) == 0 || containsIP(f.allow, ip)
+}
+
+func (r *Runtime) ipFilterMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ip, err := netip.ParseAddr(c.RealIP())
+			if err != nil || !r.ipFilter.allowed(ip.Unmap()) {
+				return echo.ErrForbidden
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package runtime
+
+import (
+	"fmt"
+	"sync"
+)
+
+type KanbanCard struct {
+	Id          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+}
+
+type KanbanColumn struct {
+	Id    string       `json:"id"`
+	Title string       `json:"title"`
+	Cards []KanbanCard `json:"cards"`
+}
+
+type KanbanBoard struct {
+	Columns []KanbanColumn `json:"columns"`
+}
+
+// KanbanCallbacks persist the changes users make on a board, a nil
+// callback disables the change. An error rolls the change back on the
+// client that made it.
+type KanbanCallbacks struct {
+	OnMove func(conn *Conn, cardId, column string, index int) error
+	// OnCreate returns the stored card, with the id it was given.
+	OnCreate func(conn *Conn, column, title string) (*KanbanCard, error)
+	OnDelete func(conn *Conn, cardId string) error
+}
+
+// Kanban is the ServerState behind sunmao.NewKanban. Moves and deletes are
+// shown by the client right away and confirmed or corrected once the
+// callback returned.
+type Kanban struct {
+	*ServerState
+	mu        sync.Mutex
+	board     KanbanBoard
+	callbacks KanbanCallbacks
+}
+
+type kanbanMove struct {
+	Card   string `json:"card"`
+	Column string `json:"column"`
+	Index  int    `json:"index"`
+}
+
+type kanbanCreate struct {
+	Column string `json:"column"`
+	Title  string `json:"title"`
+}
+
+type kanbanDelete struct {
+	Card string `json:"card"`
+}
+
+// NewKanban serves board to the kanbans bound to id, pages served after a
+// change show the changed board.
+func NewKanban(r *Runtime, id string, board KanbanBoard, callbacks KanbanCallbacks) (*Kanban, error) {
+	k := &Kanban{
+		ServerState: r.NewServerState(id, board),
+		board:       board,
+		callbacks:   callbacks,
+	}
+	k.ServeLatest()
+
+	if err := r.Handle(id+"/move", k.handleMove, ParamsOf[kanbanMove]()); err != nil {
+		return nil, err
+	}
+	if err := r.Handle(id+"/create", k.handleCreate, ParamsOf[kanbanCreate]()); err != nil {
+		return nil, err
+	}
+	return k, r.Handle(id+"/delete", k.handleDelete, ParamsOf[kanbanDelete]())
+}
+
+// Board returns the current board.
+func (k *Kanban) Board() KanbanBoard {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return k.board
+}
+
+// SetBoard replaces the board of every client, e.g. after a change made
+// outside of the ui.
+func (k *Kanban) SetBoard(board KanbanBoard) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.board = board
+	return k.Confirm(board)
+}
+
+// handleMove, like handleDelete, holds the lock through the callback so
+// changes apply in the order the board versions were handed out.
+func (k *Kanban) handleMove(m *Message, connId int) error {
+	move, err := decodeParams[kanbanMove](m)
+	if err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.callbacks.OnMove == nil {
+		return k.reject(m, connId, &ActionError{Code: ForbiddenCode, Message: "cards can not be moved"})
+	}
+	next, ok := moveCard(k.board, move.Card, move.Column, move.Index)
+	if !ok {
+		return k.reject(m, connId, fmt.Errorf("no card %v or column %v", move.Card, move.Column))
+	}
+	if err := k.callbacks.OnMove(k.r.conns.get(connId), move.Card, move.Column, move.Index); err != nil {
+		return k.reject(m, connId, err)
+	}
+	k.board = next
+	return k.Confirm(next)
+}
+
+func (k *Kanban) handleCreate(m *Message, connId int) error {
+	create, err := decodeParams[kanbanCreate](m)
+	if err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.callbacks.OnCreate == nil {
+		return &ActionError{Code: ForbiddenCode, Message: "cards can not be created"}
+	}
+	column := columnIndex(k.board, create.Column)
+	if column < 0 {
+		return fmt.Errorf("no column %v", create.Column)
+	}
+	card, err := k.callbacks.OnCreate(k.r.conns.get(connId), create.Column, create.Title)
+	if err != nil {
+		return err
+	}
+
+	next := cloneBoard(k.board)
+	next.Columns[column].Cards = append(next.Columns[column].Cards, *card)
+	k.board = next
+	return k.Confirm(next)
+}
+
+func (k *Kanban) handleDelete(m *Message, connId int) error {
+	del, err := decodeParams[kanbanDelete](m)
+	if err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.callbacks.OnDelete == nil {
+		return k.reject(m, connId, &ActionError{Code: ForbiddenCode, Message: "cards can not be deleted"})
+	}
+	next, ok := deleteCard(k.board, del.Card)
+	if !ok {
+		return k.reject(m, connId, fmt.Errorf("no card %v", del.Card))
+	}
+	if err := k.callbacks.OnDelete(k.r.conns.get(connId), del.Card); err != nil {
+		return k.reject(m, connId, err)
+	}
+	k.board = next
+	return k.Confirm(next)
+}
+
+// reject rolls an optimistic change of the client back to the board.
+func (k *Kanban) reject(m *Message, connId int, err error) error {
+	if _, ok := k.Optimistic(m); ok {
+		if cerr := k.Correct(connId, k.board); cerr != nil && cerr != errConnClosed {
+			k.r.e.Logger.Error(cerr)
+		}
+	}
+	return err
+}
+
+func moveCard(board KanbanBoard, cardId, column string, index int) (KanbanBoard, bool) {
+	to := columnIndex(board, column)
+	if to < 0 {
+		return board, false
+	}
+	var card KanbanCard
+	found := false
+	for _, c := range board.Columns {
+		for _, kc := range c.Cards {
+			if kc.Id == cardId {
+				card, found = kc, true
+			}
+		}
+	}
+	if !found {
+		return board, false
+	}
+
+	next, _ := deleteCard(board, cardId)
+	cards := next.Columns[to].Cards
+	if index < 0 || index > len(cards) {
+		index = len(cards)
+	}
+	cards = append(cards[:index], append([]KanbanCard{card}, cards[index:]...)...)
+	next.Columns[to].Cards = cards
+	return next, true
+}
+
+func deleteCard(board KanbanBoard, cardId string) (KanbanBoard, bool) {
+	next := cloneBoard(board)
+	for i, c := range next.Columns {
+		for j, kc := range c.Cards {
+			if kc.Id == cardId {
+				next.Columns[i].Cards = append(c.Cards[:j], c.Cards[j+1:]...)
+				return next, true
+			}
+		}
+	}
+	return board, false
+}
+
+func columnIndex(board KanbanBoard, column string) int {
+	for i, c := range board.Columns {
+		if c.Id == column {
+			return i
+		}
+	}
+	return -1
+}
+
+// cloneBoard copies the card slices, states already sent must not change
+// under the encoder.
+func cloneBoard(board KanbanBoard) KanbanBoard {
+	next := KanbanBoard{Columns: make([]KanbanColumn, len(board.Columns))}
+	for i, c := range board.Columns {
+		c.Cards = append([]KanbanCard{}, c.Cards...)
+		next.Columns[i] = c
+	}
+	return next
+}
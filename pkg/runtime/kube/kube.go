@@ -0,0 +1,184 @@
+// Package kube binds Kubernetes clusters to the runtime: informer fed
+// tables of pods, deployments and events, followed container logs in log
+// views and exec sessions in terminals. It does not depend on client-go,
+// the few calls it needs are passed in as functions.
+package kube
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/yuyz0112/sunmao-ui-go-binding/pkg/runtime"
+	"github.com/yuyz0112/sunmao-ui-go-binding/pkg/sunmao"
+)
+
+// Target is a container, an empty Container picks the pod's default one.
+type Target struct {
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	Container string `json:"container,omitempty"`
+}
+
+// Size is a terminal size.
+type Size struct {
+	Cols int
+	Rows int
+}
+
+// LogsFunc opens the followed log of target. With client-go:
+//
+//	clientset.CoreV1().Pods(t.Namespace).GetLogs(t.Pod, &corev1.PodLogOptions{
+//		Container: t.Container, Follow: true, TailLines: &tail,
+//	}).Stream(ctx)
+type LogsFunc func(ctx context.Context, target Target) (io.ReadCloser, error)
+
+// ExecFunc runs a shell in target with a tty until ctx is done or the
+// shell exits, sizes carries the terminal size on every resize. With
+// client-go it is remotecommand.NewSPDYExecutor for the pod's exec
+// subresource and StreamWithContext, its TerminalSizeQueue reading sizes.
+type ExecFunc func(ctx context.Context, target Target, stdin io.Reader, stdout io.Writer, sizes <-chan Size) error
+
+// Cluster is what the log and exec helpers call.
+type Cluster struct {
+	Logs LogsFunc
+	Exec ExecFunc
+	// Authorize decides whether conn may run action, "logs" or "exec", on
+	// target and is required. Targets come from the client and the calls
+	// run with the server's credentials, so scope them to what the user
+	// may see here.
+	Authorize func(conn *runtime.Conn, action string, target Target) error
+}
+
+func (c *Cluster) authorize(conn *runtime.Conn, action string, target Target) error {
+	if target.Namespace == "" || target.Pod == "" {
+		return &runtime.ActionError{Code: "invalid_params", Message: "namespace and pod are required"}
+	}
+	if err := c.Authorize(conn, action, target); err != nil {
+		return &runtime.ActionError{Code: "forbidden", Message: err.Error()}
+	}
+	return nil
+}
+
+// FollowLogs serves followed container logs under id, start one with
+// Follow and show it with sunmao.NewLogView(id). At most limit logs are
+// followed at once, 0 for no limit.
+func (c *Cluster) FollowLogs(r *runtime.Runtime, id string, limit int) (*runtime.CommandRunner, error) {
+	if c.Logs == nil {
+		return nil, errors.New("kube: Cluster.Logs is required")
+	}
+	if c.Authorize == nil {
+		return nil, errors.New("kube: Cluster.Authorize is required")
+	}
+	return runtime.NewLogStream(r, id, func(ctx context.Context, conn *runtime.Conn, params map[string]any) (io.ReadCloser, error) {
+		target := targetOf(params)
+		if err := c.authorize(conn, "logs", target); err != nil {
+			return nil, err
+		}
+		return c.Logs(ctx, target)
+	}, limit)
+}
+
+// Follow starts following the log of target in the log view of id, its
+// fields may be expressions such as "{{ pods.selectedItem.name }}".
+func Follow(id string, target Target) *sunmao.ServerHandler {
+	return sunmao.StartCommand(id, target.params())
+}
+
+// ExecTerminal serves exec sessions under id, show them with
+// sunmao.NewTerminal(id).Params(ExecParams(target)).
+func (c *Cluster) ExecTerminal(r *runtime.Runtime, id string) (*runtime.Terminal, error) {
+	if c.Exec == nil {
+		return nil, errors.New("kube: Cluster.Exec is required")
+	}
+	if c.Authorize == nil {
+		return nil, errors.New("kube: Cluster.Authorize is required")
+	}
+	return runtime.NewTerminal(r, id, func(conn *runtime.Conn, params map[string]any, cols, rows int) (runtime.TerminalSession, error) {
+		target := targetOf(params)
+		if err := c.authorize(conn, "exec", target); err != nil {
+			return nil, err
+		}
+		return startExec(c.Exec, target, Size{Cols: cols, Rows: rows}), nil
+	})
+}
+
+// ExecParams are the terminal params of an exec session into target.
+func ExecParams(target Target) map[string]interface{} {
+	return target.params()
+}
+
+func (t Target) params() map[string]interface{} {
+	return map[string]interface{}{
+		"namespace": t.Namespace,
+		"pod":       t.Pod,
+		"container": t.Container,
+	}
+}
+
+func targetOf(params map[string]any) Target {
+	s := func(k string) string {
+		v, _ := params[k].(string)
+		return v
+	}
+	return Target{Namespace: s("namespace"), Pod: s("pod"), Container: s("container")}
+}
+
+// execSession is the terminal side of a running ExecFunc.
+type execSession struct {
+	cancel context.CancelFunc
+	stdin  *io.PipeWriter
+	stdout *io.PipeReader
+	sizes  chan Size
+	once   sync.Once
+}
+
+func startExec(exec ExecFunc, target Target, size Size) *execSession {
+	ctx, cancel := context.WithCancel(context.Background())
+	stdinR, stdinW := io.Pipe()
+	stdoutR, stdoutW := io.Pipe()
+	s := &execSession{cancel: cancel, stdin: stdinW, stdout: stdoutR, sizes: make(chan Size, 1)}
+	s.sizes <- size
+
+	go func() {
+		err := exec(ctx, target, stdinR, stdoutW, s.sizes)
+		if ctx.Err() != nil {
+			err = nil
+		}
+		// the terminal shows the error once the output ends
+		stdoutW.CloseWithError(err)
+		stdinR.Close()
+	}()
+	return s
+}
+
+func (s *execSession) Read(p []byte) (int, error) {
+	return s.stdout.Read(p)
+}
+
+func (s *execSession) Write(p []byte) (int, error) {
+	return s.stdin.Write(p)
+}
+
+// Resize replaces a size the exec did not pick up yet.
+func (s *execSession) Resize(cols, rows int) error {
+	select {
+	case <-s.sizes:
+	default:
+	}
+	select {
+	case s.sizes <- Size{Cols: cols, Rows: rows}:
+	default:
+	}
+	return nil
+}
+
+func (s *execSession) Close() error {
+	s.once.Do(func() {
+		s.cancel()
+		s.stdin.Close()
+		s.stdout.Close()
+	})
+	return nil
+}
@@ -0,0 +1,253 @@
+package kube
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yuyz0112/sunmao-ui-go-binding/pkg/runtime"
+	"github.com/yuyz0112/sunmao-ui-go-binding/pkg/sunmao"
+)
+
+// tablePushDelay coalesces the bursts of events an informer delivers,
+// e.g. its initial list
+const tablePushDelay = 200 * time.Millisecond
+
+// RowFunc maps an object, in its json shape as the api server returns
+// it, to a table row.
+type RowFunc func(obj map[string]any) map[string]any
+
+// Table mirrors the objects of an informer into a state as rows ordered
+// by namespace/name. Register its methods as the informer's handler:
+//
+//	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+//		AddFunc:    table.Add,
+//		UpdateFunc: table.Update,
+//		DeleteFunc: table.Delete,
+//	})
+type Table struct {
+	state *runtime.ServerState
+	row   RowFunc
+	mu    sync.Mutex
+	rows  map[string]map[string]any
+	timer *time.Timer
+}
+
+// NewTable fills state with the rows of row, e.g. PodRow, bind a table to
+// it with "{{ state.state }}" and the matching columns such as PodColumns.
+// Clients served later get the current rows.
+func NewTable(state *runtime.ServerState, row RowFunc) *Table {
+	state.ServeLatest()
+	return &Table{state: state, row: row, rows: map[string]map[string]any{}}
+}
+
+func (t *Table) Add(obj any) {
+	t.set(obj)
+}
+
+func (t *Table) Update(_, obj any) {
+	t.set(obj)
+}
+
+func (t *Table) Delete(obj any) {
+	m, err := object(obj)
+	if err != nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.rows, key(m))
+	t.schedule()
+}
+
+func (t *Table) set(obj any) {
+	m, err := object(obj)
+	if err != nil {
+		return
+	}
+	row := t.row(m)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.rows[key(m)] = row
+	t.schedule()
+}
+
+// schedule pushes the rows after tablePushDelay, t.mu is held.
+func (t *Table) schedule() {
+	if t.timer != nil {
+		return
+	}
+	t.timer = time.AfterFunc(tablePushDelay, t.push)
+}
+
+func (t *Table) push() {
+	t.mu.Lock()
+	t.timer = nil
+	keys := make([]string, 0, len(t.rows))
+	for k := range t.rows {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	rows := make([]map[string]any, len(keys))
+	for i, k := range keys {
+		rows[i] = t.rows[k]
+	}
+	t.mu.Unlock()
+
+	// a failed push is retried by the next event
+	_ = t.state.SetState(rows, nil)
+}
+
+// object turns a typed or unstructured object into its json shape. The
+// tombstones informers hand to Delete for missed deletions hold the object
+// in Obj.
+func object(obj any) (map[string]any, error) {
+	buf, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	m := map[string]any{}
+	if err := json.Unmarshal(buf, &m); err != nil {
+		return nil, err
+	}
+	if inner, ok := m["Obj"].(map[string]any); ok {
+		if _, ok := m["Key"]; ok {
+			return inner, nil
+		}
+	}
+	// unstructured.Unstructured marshals as the object itself, its
+	// wrapping Object field only shows in other encodings
+	if inner, ok := m["Object"].(map[string]any); ok && len(m) == 1 {
+		return inner, nil
+	}
+	return m, nil
+}
+
+func key(obj map[string]any) string {
+	ns := str(obj, "metadata", "namespace")
+	if ns == "" {
+		return str(obj, "metadata", "name")
+	}
+	return ns + "/" + str(obj, "metadata", "name")
+}
+
+func field(obj map[string]any, path ...string) any {
+	var v any = obj
+	for _, p := range path {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[p]
+	}
+	return v
+}
+
+func str(obj map[string]any, path ...string) string {
+	s, _ := field(obj, path...).(string)
+	return s
+}
+
+func num(obj map[string]any, path ...string) int {
+	n, _ := field(obj, path...).(float64)
+	return int(n)
+}
+
+// PodRow has the columns of kubectl get pods.
+func PodRow(obj map[string]any) map[string]any {
+	statuses, _ := field(obj, "status", "containerStatuses").([]any)
+	containers, _ := field(obj, "spec", "containers").([]any)
+	ready, restarts := 0, 0
+	names := []string{}
+	for _, c := range containers {
+		if c, ok := c.(map[string]any); ok {
+			names = append(names, str(c, "name"))
+		}
+	}
+	for _, s := range statuses {
+		s, _ := s.(map[string]any)
+		if r, _ := s["ready"].(bool); r {
+			ready++
+		}
+		restarts += num(s, "restartCount")
+	}
+	status := str(obj, "status", "phase")
+	if str(obj, "metadata", "deletionTimestamp") != "" {
+		status = "Terminating"
+	}
+	return map[string]any{
+		"key":        key(obj),
+		"namespace":  str(obj, "metadata", "namespace"),
+		"name":       str(obj, "metadata", "name"),
+		"ready":      fmt.Sprintf("%v/%v", ready, len(containers)),
+		"status":     status,
+		"restarts":   restarts,
+		"node":       str(obj, "spec", "nodeName"),
+		"created":    str(obj, "metadata", "creationTimestamp"),
+		"containers": names,
+	}
+}
+
+// DeploymentRow has the columns of kubectl get deployments.
+func DeploymentRow(obj map[string]any) map[string]any {
+	return map[string]any{
+		"key":       key(obj),
+		"namespace": str(obj, "metadata", "namespace"),
+		"name":      str(obj, "metadata", "name"),
+		"ready":     fmt.Sprintf("%v/%v", num(obj, "status", "readyReplicas"), num(obj, "spec", "replicas")),
+		"updated":   num(obj, "status", "updatedReplicas"),
+		"available": num(obj, "status", "availableReplicas"),
+		"created":   str(obj, "metadata", "creationTimestamp"),
+	}
+}
+
+// EventRow has the columns of kubectl get events.
+func EventRow(obj map[string]any) map[string]any {
+	last := str(obj, "lastTimestamp")
+	if last == "" {
+		last = str(obj, "eventTime")
+	}
+	count := num(obj, "count")
+	if count == 0 {
+		count = 1
+	}
+	return map[string]any{
+		"key":       key(obj),
+		"namespace": str(obj, "metadata", "namespace"),
+		"type":      str(obj, "type"),
+		"reason":    str(obj, "reason"),
+		"object":    fmt.Sprintf("%v/%v", str(obj, "involvedObject", "kind"), str(obj, "involvedObject", "name")),
+		"message":   str(obj, "message"),
+		"count":     count,
+		"last":      last,
+	}
+}
+
+func columns(names ...string) []*sunmao.ArcoTableColumn {
+	cols := make([]*sunmao.ArcoTableColumn, len(names))
+	for i, name := range names {
+		cols[i] = &sunmao.ArcoTableColumn{Title: name, DataIndex: name, Sorter: true, Filter: name == "namespace" || name == "status" || name == "type"}
+	}
+	return cols
+}
+
+// PodColumns are the columns of PodRow, rows are keyed by "key".
+func PodColumns() []*sunmao.ArcoTableColumn {
+	return columns("namespace", "name", "ready", "status", "restarts", "node", "created")
+}
+
+// DeploymentColumns are the columns of DeploymentRow.
+func DeploymentColumns() []*sunmao.ArcoTableColumn {
+	return columns("namespace", "name", "ready", "updated", "available", "created")
+}
+
+// EventColumns are the columns of EventRow.
+func EventColumns() []*sunmao.ArcoTableColumn {
+	return columns("namespace", "last", "type", "reason", "object", "message", "count")
+}
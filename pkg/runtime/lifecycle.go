@@ -0,0 +1,79 @@
+package runtime
+
+// Viewport is the inner size of the client window in css pixels.
+type Viewport struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// clientEvent is the params of a ClientEvent message.
+type clientEvent struct {
+	Event    string   `json:"event"`
+	Visible  bool     `json:"visible"`
+	Focused  bool     `json:"focused"`
+	Viewport Viewport `json:"viewport"`
+}
+
+// WithClientEvents makes the client report page visibility, window focus,
+// unload and viewport resizes, see OnVisibilityChange and its siblings.
+func WithClientEvents() Option {
+	return func(r *Runtime) {
+		r.clientEvents = true
+	}
+}
+
+// OnVisibilityChange runs when the page of a connection is hidden or shown
+// again, e.g. to pause streaming while nobody is watching.
+func (r *Runtime) OnVisibilityChange(fn func(conn *Conn, visible bool)) {
+	r.hooks.visibility = append(r.hooks.visibility, fn)
+}
+
+// OnFocusChange runs when the client window gains or loses focus.
+func (r *Runtime) OnFocusChange(fn func(conn *Conn, focused bool)) {
+	r.hooks.focus = append(r.hooks.focus, fn)
+}
+
+// OnUnload runs when the client is leaving the page, it is best effort and
+// is followed by the disconnected hook.
+func (r *Runtime) OnUnload(fn func(conn *Conn)) {
+	r.hooks.unload = append(r.hooks.unload, fn)
+}
+
+// OnResize runs with the new viewport after the client window was resized,
+// the client debounces resizes.
+func (r *Runtime) OnResize(fn func(conn *Conn, viewport Viewport)) {
+	r.hooks.resize = append(r.hooks.resize, fn)
+}
+
+// Hidden reports whether the page of the connection was last reported
+// hidden. It is always false without WithClientEvents.
+func (c *Conn) Hidden() bool {
+	return c.hidden.Load()
+}
+
+func (r *Runtime) clientEvent(conn *Conn, params any) {
+	evt, err := decodeValue[clientEvent](params)
+	if err != nil {
+		return
+	}
+
+	switch evt.Event {
+	case "visibility":
+		conn.hidden.Store(!evt.Visible)
+		for _, fn := range r.hooks.visibility {
+			fn(conn, evt.Visible)
+		}
+	case "focus":
+		for _, fn := range r.hooks.focus {
+			fn(conn, evt.Focused)
+		}
+	case "unload":
+		for _, fn := range r.hooks.unload {
+			fn(conn)
+		}
+	case "resize":
+		for _, fn := range r.hooks.resize {
+			fn(conn, evt.Viewport)
+		}
+	}
+}
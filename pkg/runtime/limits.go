@@ -0,0 +1,154 @@
+package runtime
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RateLimit rejects more than n calls of the handler per connection within
+// every window with an ActionError coded "rate_limited". Calls through the
+// action api are not limited.
+func RateLimit(n int, window time.Duration) HandlerOption {
+	return func(h *handler) {
+		h.rateLimit = n
+		h.rateWindow = window
+	}
+}
+
+// Debounce runs the handler once calls of a connection paused for window,
+// with the last call's message. It runs in order with the other calls of
+// the connection, superseded calls get an ActionError coded "superseded".
+func Debounce(window time.Duration) HandlerOption {
+	return func(h *handler) {
+		h.debounce = window
+	}
+}
+
+// MaxParamsSize rejects calls whose params are larger than n bytes with
+// an ActionError coded "params_too_large".
+func MaxParamsSize(n int) HandlerOption {
+	return func(h *handler) {
+		h.maxParams = n
+	}
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+type debounced struct {
+	timer *time.Timer
+	msg   *Message
+}
+
+// connLimits holds the per handler rate windows and debounce timers of a
+// connection, they go away with it.
+type connLimits struct {
+	mu      sync.Mutex
+	windows map[string]*rateWindow
+	pending map[string]*debounced
+	closed  bool
+}
+
+func (l *connLimits) allow(name string, n int, window time.Duration) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.windows == nil {
+		l.windows = map[string]*rateWindow{}
+	}
+	now := time.Now()
+	w, ok := l.windows[name]
+	if !ok || now.Sub(w.start) >= window {
+		w = &rateWindow{start: now}
+		l.windows[name] = w
+	}
+	w.count++
+	return w.count <= n
+}
+
+// debounce runs fn after window unless msg is superseded first. It
+// returns the pending message msg supersedes, if any.
+func (l *connLimits) debounce(msg *Message, window time.Duration, fn func()) *Message {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return nil
+	}
+	if l.pending == nil {
+		l.pending = map[string]*debounced{}
+	}
+	var superseded *Message
+	if p, ok := l.pending[msg.Handler]; ok && p.timer.Stop() {
+		superseded = p.msg
+	}
+	p := &debounced{msg: msg}
+	p.timer = time.AfterFunc(window, func() {
+		l.mu.Lock()
+		if l.pending[msg.Handler] == p {
+			delete(l.pending, msg.Handler)
+		}
+		l.mu.Unlock()
+		fn()
+	})
+	l.pending[msg.Handler] = p
+	return superseded
+}
+
+// stop drops pending debounced calls once the connection closed.
+func (l *connLimits) stop() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.closed = true
+	for _, p := range l.pending {
+		p.timer.Stop()
+	}
+}
+
+// debounceCall runs msg once the calls of conn paused. The call is handed
+// back to the read loop of conn, so it does not run alongside the calls
+// that follow it, and it is audited once it finished.
+func (r *Runtime) debounceCall(handler *handler, conn *Conn, msg *Message) {
+	superseded := conn.limits.debounce(msg, handler.debounce, func() {
+		conn.deferCall(func() {
+			start := time.Now()
+			err := r.invokeHandler(handler, conn, msg, conn.Id)
+			if r.audit != nil {
+				r.auditAction(msg, conn.Id, time.Since(start), err)
+			}
+			r.actionDone(conn.Id, msg, err)
+		})
+	})
+	if superseded == nil {
+		return
+	}
+
+	err := &ActionError{
+		Code:    "superseded",
+		Message: fmt.Sprintf("%v was called again within %v", superseded.Handler, handler.debounce),
+	}
+	if r.audit != nil {
+		r.auditAction(superseded, conn.Id, 0, err)
+	}
+	r.actionDone(conn.Id, superseded, err)
+}
+
+func (h *handler) checkLimits(conn *Conn, msg *Message) error {
+	if h.maxParams > 0 && msg.paramsSize > h.maxParams {
+		return &ActionError{
+			Code:    "params_too_large",
+			Message: fmt.Sprintf("params of %v bytes exceed the limit of %v bytes for %v", msg.paramsSize, h.maxParams, msg.Handler),
+		}
+	}
+	if h.rateLimit > 0 && conn != nil && !conn.limits.allow(msg.Handler, h.rateLimit, h.rateWindow) {
+		return &ActionError{
+			Code:    "rate_limited",
+			Message: fmt.Sprintf("%v allows %v calls per %v", msg.Handler, h.rateLimit, h.rateWindow),
+		}
+	}
+	return nil
+}
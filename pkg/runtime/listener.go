@@ -0,0 +1,103 @@
+package runtime
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+)
+
+// systemd passes activated sockets starting at fd 3
+const listenFdsStart = 3
+
+// WithListener serves on l instead of binding Addr, e.g. a unix socket
+// behind a local proxy.
+func WithListener(l net.Listener) Option {
+	return func(r *Runtime) {
+		r.listener = l
+	}
+}
+
+// WithUnixSocket serves on a unix domain socket at path, replacing a stale
+// socket file left by a previous run. The socket is bound once Run
+// starts, failing to bind it fails Run like any other listen error.
+func WithUnixSocket(path string) Option {
+	return func(r *Runtime) {
+		r.unixSocket = path
+	}
+}
+
+// listenUnix binds the socket of WithUnixSocket, only a socket file is
+// removed so a mistyped path can not delete a regular file.
+func listenUnix(path string) (net.Listener, error) {
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	return net.Listen("unix", path)
+}
+
+// WithSystemdActivation serves on the socket systemd passed through
+// LISTEN_FDS. Without socket activation it keeps binding Addr, so the
+// same binary runs outside of systemd too.
+func WithSystemdActivation() Option {
+	return func(r *Runtime) {
+		l, err := systemdListener()
+		if err != nil {
+			r.e.Logger.Warn(err)
+			return
+		}
+		r.listener = l
+	}
+}
+
+func systemdListener() (net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, errors.New("not socket activated")
+	}
+	fds, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || fds < 1 {
+		return nil, errors.New("no sockets passed by systemd")
+	}
+	// children must not inherit the activation
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	f := os.NewFile(uintptr(listenFdsStart), "LISTEN_FD_3")
+	defer f.Close()
+	return net.FileListener(f)
+}
+
+// useListener hands a configured listener to echo, which then skips
+// binding Addr.
+func (r *Runtime) useListener() error {
+	if r.listener == nil && r.unixSocket != "" {
+		l, err := listenUnix(r.unixSocket)
+		if err != nil {
+			return err
+		}
+		r.listener = l
+	}
+	if r.listener == nil {
+		return nil
+	}
+	if r.tls == nil {
+		r.e.Listener = r.listener
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.tls.CertFile, r.tls.KeyFile)
+	if err != nil {
+		return err
+	}
+	r.e.TLSListener = tls.NewListener(r.listener, &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		// keep HTTP/2 negotiable like StartTLS does
+		NextProtos: []string{"h2", "http/1.1"},
+	})
+	return nil
+}
@@ -0,0 +1,19 @@
+package runtime
+
+import "github.com/yuyz0112/sunmao-ui-go-binding/pkg/sunmao"
+
+// Loading wraps content in skeleton placeholders shown while the state is
+// pending, between SetLoading and SetReady.
+func (s *ServerState) Loading(content ...sunmao.BaseComponentBuilder) sunmao.BaseComponentBuilder {
+	return s.r.appBuilder.Loading(s.Id, content...)
+}
+
+// SetLoading switches the state to the pending sentinel.
+func (s *ServerState) SetLoading(connId *int) error {
+	return s.SetState(map[string]interface{}{sunmao.PendingKey: true}, connId)
+}
+
+// SetReady replaces the pending sentinel with the loaded value.
+func (s *ServerState) SetReady(value any, connId *int) error {
+	return s.SetState(value, connId)
+}
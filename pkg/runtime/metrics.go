@@ -0,0 +1,31 @@
+package runtime
+
+import "time"
+
+// MetricsSink receives runtime events for custom telemetry such as statsd
+// or Datadog. Callbacks run on the goroutines serving connections and
+// must not block. Embed NopMetrics to implement only some of them.
+type MetricsSink interface {
+	ConnOpened(conn *Conn)
+	ConnClosed(conn *Conn, reason CloseReason)
+	MessageReceived(connId int, messageType string, size int)
+	MessageSent(connId int, size int)
+	HandlerDone(handler string, duration time.Duration, err error)
+	Error(err error)
+}
+
+// NopMetrics ignores every event.
+type NopMetrics struct{}
+
+func (NopMetrics) ConnOpened(conn *Conn)                                         {}
+func (NopMetrics) ConnClosed(conn *Conn, reason CloseReason)                     {}
+func (NopMetrics) MessageReceived(connId int, messageType string, size int)      {}
+func (NopMetrics) MessageSent(connId int, size int)                              {}
+func (NopMetrics) HandlerDone(handler string, duration time.Duration, err error) {}
+func (NopMetrics) Error(err error)                                               {}
+
+func WithMetrics(sink MetricsSink) Option {
+	return func(r *Runtime) {
+		r.metrics = sink
+	}
+}
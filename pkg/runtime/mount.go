@@ -0,0 +1,52 @@
+package runtime
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/yuyz0112/sunmao-ui-go-binding/pkg/sunmao"
+)
+
+// LoadAppAt serves another application under prefix from the same process.
+// The returned Runtime has its own handlers, hooks, modules, patches and
+// websocket channel, register them on it instead of the parent. Only the
+// parent Runtime should be Run.
+func (r *Runtime) LoadAppAt(prefix string, builder *sunmao.AppBuilder) *Runtime {
+	prefix = "/" + strings.Trim(prefix, "/")
+
+	// copy the parent to inherit its options, then reset the per app state
+	m := *r
+	m.basePath = r.basePath + prefix
+	m.conns = newConnSet()
+	m.handlers = newHandlerRegistry()
+	m.hooks = &hooks{}
+	m.moduleBuilders = nil
+	m.patchDir = filepath.Join(r.patchDir, filepath.FromSlash(strings.TrimPrefix(prefix, "/")))
+	m.pageCache = &pageCache{pages: map[string]*cachedPage{}}
+	m.shortcuts = newShortcutRegistry()
+	m.preferences = newPreferenceRegistry()
+	m.reports = map[string]ReportFunc{}
+	m.uploads = map[string]*upload{}
+	m.userStates = newUserStates()
+	m.customComponents = nil
+	m.remoteModules = nil
+	m.announcement = &announcement{}
+	m.mounts = nil
+	m.router = r.e.Group(m.basePath)
+	m.LoadApp(builder)
+
+	r.mounts = append(r.mounts, &m)
+	return &m
+}
+
+// mountOf returns the mounted Runtime serving path, or r itself. Mounts
+// may be nested in each other's prefix, the longest base path wins.
+func (r *Runtime) mountOf(path string) *Runtime {
+	best := r
+	for _, m := range r.mounts {
+		if len(m.basePath) > len(best.basePath) && (path == m.basePath || strings.HasPrefix(path, m.basePath+"/")) {
+			best = m
+		}
+	}
+	return best
+}
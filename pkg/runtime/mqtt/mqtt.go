@@ -0,0 +1,138 @@
+// Package mqtt connects MQTT topics to the runtime, device readings flow
+// into ServerStates and UI controls publish back. It works with any client
+// behind the small Client interface, e.g. a few lines around paho.
+package mqtt
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/yuyz0112/sunmao-ui-go-binding/pkg/runtime"
+	"github.com/yuyz0112/sunmao-ui-go-binding/pkg/sunmao"
+)
+
+// Client is the part of an MQTT client the bridge uses. With paho,
+// Subscribe wraps client.Subscribe(topic, qos, handler).Wait() and Publish
+// wraps client.Publish(topic, qos, retained, payload).Wait().
+type Client interface {
+	Subscribe(topic string, handler func(topic string, payload []byte)) error
+	Publish(topic string, payload []byte, retained bool) error
+}
+
+// Bridge maps topics of client to states and handlers of r.
+type Bridge struct {
+	r      *runtime.Runtime
+	client Client
+
+	mu     sync.Mutex
+	states map[*runtime.ServerState]string
+}
+
+func NewBridge(r *runtime.Runtime, client Client) *Bridge {
+	return &Bridge{r: r, client: client, states: map[*runtime.ServerState]string{}}
+}
+
+// State keeps state at the last payload of topic, clients served later get
+// it too. Payloads are decoded as json, other payloads such as "on" are
+// kept as strings. A topic with the wildcards + or # keeps an object of the
+// last payload per matching topic, e.g. {"sensors/kitchen/temp": 21.5} for
+// sensors/+/temp. A state follows one topic, use a wildcard for several.
+func (b *Bridge) State(topic string, state *runtime.ServerState) error {
+	b.mu.Lock()
+	if bound, ok := b.states[state]; ok {
+		b.mu.Unlock()
+		return fmt.Errorf("mqtt: state %v already follows %v", state.Id, bound)
+	}
+	b.states[state] = topic
+	b.mu.Unlock()
+
+	wildcard := strings.ContainsAny(topic, "+#")
+	mu := sync.Mutex{}
+	values := map[string]any{}
+	// holds the latest value only, a slow broadcast skips the payloads
+	// that arrived meanwhile instead of blocking the mqtt client
+	ch := make(chan any, 1)
+	runtime.SyncChan(b.r, state, ch)
+
+	err := b.client.Subscribe(topic, func(t string, payload []byte) {
+		// clients may call handlers concurrently, the last payload wins
+		mu.Lock()
+		defer mu.Unlock()
+
+		value := decode(payload)
+		if wildcard {
+			values[t] = value
+			snapshot := make(map[string]any, len(values))
+			for k, v := range values {
+				snapshot[k] = v
+			}
+			value = snapshot
+		}
+		select {
+		case ch <- value:
+		default:
+			// replace the value not taken yet, mu keeps other payloads
+			// from refilling ch in between
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- value
+		}
+	})
+	if err != nil {
+		close(ch)
+		b.mu.Lock()
+		delete(b.states, state)
+		b.mu.Unlock()
+	}
+	return err
+}
+
+// Control registers the handler id publishing the value param to topic,
+// call it from a control with Publish. Strings are published as they are,
+// other values as json. Retained publishes are kept by the broker for
+// devices connecting later.
+func (b *Bridge) Control(id, topic string, retained bool) error {
+	return b.r.Handle(id, func(m *runtime.Message, connId int) error {
+		params, _ := m.Params.(map[string]any)
+		value, ok := params["value"]
+		if !ok {
+			return &runtime.ActionError{Code: "invalid_params", Message: "value is missing"}
+		}
+		payload, err := encode(value)
+		if err != nil {
+			return err
+		}
+		if err := b.client.Publish(topic, payload, retained); err != nil {
+			return fmt.Errorf("mqtt publish %v: %w", topic, err)
+		}
+		return nil
+	})
+}
+
+// Publish calls the Control handler id with value, usually an expression
+// such as "{{ lamp.value }}" of a switch.
+func Publish(id string, value any) *sunmao.ServerHandler {
+	return &sunmao.ServerHandler{
+		Name:       id,
+		Parameters: map[string]interface{}{"value": value},
+	}
+}
+
+func decode(payload []byte) any {
+	var v any
+	if err := json.Unmarshal(payload, &v); err != nil {
+		return string(payload)
+	}
+	return v
+}
+
+func encode(value any) ([]byte, error) {
+	if s, ok := value.(string); ok {
+		return []byte(s), nil
+	}
+	return json.Marshal(value)
+}
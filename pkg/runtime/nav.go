@@ -0,0 +1,116 @@
+package runtime
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"github.com/yuyz0112/sunmao-ui-go-binding/pkg/sunmao"
+)
+
+// Navigation is the ServerState behind sunmao.NewNavShell, every client
+// gets the menu items its identity may see.
+type Navigation struct {
+	*ServerState
+	mu    sync.RWMutex
+	items []sunmao.MenuItem
+}
+
+// NewNavigation serves items to the nav shells bound to id. onNavigate,
+// if set, runs when a client opens an item, calls for items the client
+// may not see are rejected.
+func NewNavigation(r *Runtime, id string, items []sunmao.MenuItem, onNavigate func(conn *Conn, key string) error) (*Navigation, error) {
+	n := &Navigation{
+		// the page holds no items until they are filtered for the request
+		ServerState: r.NewServerState(id, []sunmao.MenuItem{}),
+		items:       items,
+	}
+
+	r.OnHydrate(func(c echo.Context, h *Hydration) error {
+		h.Set(n.ServerState, n.visible(r.Identity(c)))
+		return nil
+	})
+	// roles may have changed since the page was rendered
+	r.OnAppServed(func(conn *Conn) {
+		n.push(conn)
+	})
+
+	return n, r.Handle(id+"/navigate", func(m *Message, connId int) error {
+		params, _ := m.Params.(map[string]any)
+		key, _ := params["key"].(string)
+		conn := r.conns.get(connId)
+		if conn == nil {
+			return nil
+		}
+		if !containsKey(n.visible(conn.Identity), key) {
+			return &ActionError{Code: ForbiddenCode, Message: fmt.Sprintf("no menu item %v", key)}
+		}
+		if onNavigate == nil {
+			return nil
+		}
+		return onNavigate(conn, key)
+	})
+}
+
+// SetItems replaces the menu of every connected client.
+func (n *Navigation) SetItems(items []sunmao.MenuItem) {
+	n.mu.Lock()
+	n.items = items
+	n.mu.Unlock()
+
+	for _, conn := range n.r.conns.list() {
+		n.push(conn)
+	}
+}
+
+func (n *Navigation) push(conn *Conn) {
+	connId := conn.Id
+	if err := n.SetState(n.visible(conn.Identity), &connId); err != nil && err != errConnClosed {
+		n.r.e.Logger.Error(err)
+	}
+}
+
+func (n *Navigation) visible(identity *Identity) []sunmao.MenuItem {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	return filterMenu(n.items, identity)
+}
+
+func filterMenu(items []sunmao.MenuItem, identity *Identity) []sunmao.MenuItem {
+	visible := []sunmao.MenuItem{}
+	for _, item := range items {
+		if !allowedItem(item, identity) {
+			continue
+		}
+		hadChildren := len(item.Children) > 0
+		item.Children = filterMenu(item.Children, identity)
+		// a group whose children are all hidden has nothing to open
+		if hadChildren && len(item.Children) == 0 {
+			continue
+		}
+		visible = append(visible, item)
+	}
+	return visible
+}
+
+func allowedItem(item sunmao.MenuItem, identity *Identity) bool {
+	if len(item.Roles) == 0 {
+		return true
+	}
+	for _, role := range item.Roles {
+		if identity.HasRole(role) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsKey(items []sunmao.MenuItem, key string) bool {
+	for _, item := range items {
+		if item.Key == key || containsKey(item.Children, key) {
+			return true
+		}
+	}
+	return false
+}
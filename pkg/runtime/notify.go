@@ -0,0 +1,28 @@
+package runtime
+
+// utilsId targets the util methods of the client instead of a component.
+const utilsId = "$utils"
+
+// Beep plays a short tone on the client, e.g. when a long running job
+// finished.
+func (r *Runtime) Beep(connId *int) error {
+	return r.Execute(&ExecuteTarget{
+		Id:         utilsId,
+		Method:     "binding/v1/beep",
+		Parameters: map[string]interface{}{},
+	}, connId)
+}
+
+// BrowserNotification shows a desktop notification, which reaches
+// operators who tabbed away. The client asks for the permission on first
+// use and falls back to a beep when it is denied.
+func (r *Runtime) BrowserNotification(connId *int, title, body string) error {
+	return r.Execute(&ExecuteTarget{
+		Id:     utilsId,
+		Method: "binding/v1/notify",
+		Parameters: map[string]interface{}{
+			"title": title,
+			"body":  body,
+		},
+	}, connId)
+}
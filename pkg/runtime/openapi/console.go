@@ -0,0 +1,396 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yuyz0112/sunmao-ui-go-binding/pkg/runtime"
+	"github.com/yuyz0112/sunmao-ui-go-binding/pkg/sunmao"
+)
+
+const (
+	// maxResponseSize caps the bodies shown by the console
+	maxResponseSize = 8 << 20
+	// defaultTimeout bounds the requests of the default client
+	defaultTimeout = 30 * time.Second
+)
+
+type Config struct {
+	// BaseURL is prefixed to the operation paths, the document's first
+	// server by default.
+	BaseURL string
+	// Client defaults to a client with a 30s timeout. Requests run on the
+	// read loop of the calling connection, so keep one.
+	Client *http.Client
+	// Authorize adds the credentials of conn to req, e.g. a token looked up
+	// by its identity. A returned error fails the request.
+	Authorize func(req *http.Request, conn *runtime.Conn) error
+}
+
+// Console serves a request editor, a response viewer and, for operations
+// returning a list of objects, a table per operation.
+type Console struct {
+	id     string
+	doc    *Document
+	config Config
+	ops    map[string]*operationState
+}
+
+type operationState struct {
+	request  *runtime.DataEditor
+	response *runtime.DataEditor
+	// rows is nil unless the response is a list of objects
+	rows    *runtime.ServerState
+	columns []string
+}
+
+// NewConsole registers the states of the document's operations below id.
+// The request editor of an operation holds an object of its path and query
+// parameters and its body; saving it sends the request from the server.
+// Requests and responses are shown to the saving connection only.
+func NewConsole(r *runtime.Runtime, id string, doc *Document, config Config) (*Console, error) {
+	if config.BaseURL == "" && len(doc.Servers) > 0 {
+		config.BaseURL = doc.Servers[0]
+	}
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: defaultTimeout}
+	}
+	c := &Console{id: id, doc: doc, config: config, ops: map[string]*operationState{}}
+
+	for _, op := range doc.Operations {
+		op := op
+		state := &operationState{columns: columns(op.Response)}
+		var err error
+		if state.columns != nil {
+			state.rows = r.NewServerState(c.stateId(op, "rows"), []any{})
+		}
+		state.response, err = runtime.NewDataEditor(r, c.stateId(op, "response"), map[string]any{}, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		schema := requestSchema(op)
+		state.request, err = runtime.NewDataEditor(r, c.stateId(op, "request"), template(schema, 0), schema, func(conn *runtime.Conn, value any) error {
+			if conn == nil {
+				return nil
+			}
+			result, err := c.send(op, conn, value)
+			if err != nil {
+				result = map[string]any{"error": err.Error()}
+			}
+			connId := conn.Id
+			if state.rows != nil {
+				rows, ok := result.([]any)
+				if !ok {
+					rows = []any{}
+				}
+				if err := state.rows.SetState(rows, &connId); err != nil {
+					return err
+				}
+			}
+			return state.response.Set(result, &connId)
+		})
+		if err != nil {
+			return nil, err
+		}
+		// requests may carry tokens or personal data
+		state.request.PerConnection = true
+		c.ops[op.Id] = state
+	}
+	return c, nil
+}
+
+// send makes the request of op with the saved editor value and decodes the
+// json response. Other errors than failing to reach the server are shown
+// with the response.
+func (c *Console) send(op Operation, conn *runtime.Conn, value any) (any, error) {
+	params, _ := value.(map[string]any)
+	pathParams, _ := params["path"].(map[string]any)
+	queryParams, _ := params["query"].(map[string]any)
+
+	path := op.Path
+	for name, v := range pathParams {
+		segment := fmt.Sprint(v)
+		// PathEscape keeps dots, the request would leave the operation
+		if segment == "." || segment == ".." {
+			return nil, fmt.Errorf("invalid path parameter %v: %q", name, segment)
+		}
+		path = strings.ReplaceAll(path, "{"+name+"}", url.PathEscape(segment))
+	}
+	query := url.Values{}
+	for name, v := range queryParams {
+		if list, ok := v.([]any); ok {
+			for _, item := range list {
+				query.Add(name, fmt.Sprint(item))
+			}
+			continue
+		}
+		query.Set(name, fmt.Sprint(v))
+	}
+	target := strings.TrimSuffix(c.config.BaseURL, "/") + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var body io.Reader
+	if b, ok := params["body"]; ok && op.Body != nil {
+		buf, err := json.Marshal(b)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(buf)
+	}
+	req, err := http.NewRequest(op.Method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.config.Authorize != nil {
+		if err := c.config.Authorize(req, conn); err != nil {
+			return nil, err
+		}
+	}
+
+	res, err := c.config.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	buf, err := io.ReadAll(io.LimitReader(res.Body, maxResponseSize))
+	if err != nil {
+		return nil, err
+	}
+	var result any
+	if len(buf) > 0 && json.Unmarshal(buf, &result) != nil {
+		result = string(buf)
+	}
+	if res.StatusCode >= 300 {
+		return map[string]any{"error": res.Status, "body": result}, nil
+	}
+	return result, nil
+}
+
+func (c *Console) stateId(op Operation, kind string) string {
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, op.Id)
+	return fmt.Sprintf("%v__%v__%v", c.id, name, kind)
+}
+
+// Resources returns the resource names in the order of their tabs.
+func (c *Console) Resources() []string {
+	resources := []string{}
+	seen := map[string]bool{}
+	for _, op := range c.doc.Operations {
+		if !seen[op.Resource] {
+			seen[op.Resource] = true
+			resources = append(resources, op.Resource)
+		}
+	}
+	return resources
+}
+
+// Components builds the console with b: the operation states, arco tabs
+// with a tab per resource and a stack of the resource's operations below
+// them. Add each returned builder to the app.
+func (c *Console) Components(b *sunmao.AppBuilder) []sunmao.BaseComponentBuilder {
+	arco := &sunmao.ArcoAppBuilder{AppBuilder: b}
+	components := []sunmao.BaseComponentBuilder{}
+	for _, op := range c.doc.Operations {
+		state := c.ops[op.Id]
+		components = append(components, state.request.AsComponent(), state.response.AsComponent())
+		if state.rows != nil {
+			components = append(components, state.rows.AsComponent())
+		}
+	}
+
+	tabs := arco.NewTabs().Id(c.id)
+	components = append(components, tabs)
+	for i, resource := range c.Resources() {
+		tabs.Tab(&sunmao.ArcoTabsTab{Title: resource})
+
+		children := []sunmao.BaseComponentBuilder{}
+		for _, op := range c.doc.Operations {
+			if op.Resource != resource {
+				continue
+			}
+			state := c.ops[op.Id]
+			title := fmt.Sprintf("%v %v", op.Method, op.Path)
+			if op.Summary != "" {
+				title += " · " + op.Summary
+			}
+			children = append(children,
+				b.NewText().Content(title).Style("content", "font-weight: 600;"),
+				b.NewDataEditor(c.stateId(op, "request")).Id(c.stateId(op, "request_view")),
+			)
+			if state.rows != nil {
+				table := arco.NewTable().Id(c.stateId(op, "table")).Data(fmt.Sprintf("{{ %v.state }}", c.stateId(op, "rows")))
+				for _, column := range state.columns {
+					table.Column(&sunmao.ArcoTableColumn{Title: column, DataIndex: column, Sorter: true})
+				}
+				if len(state.columns) > 0 {
+					table.Properties(map[string]interface{}{"rowKey": rowKey(state.columns)})
+				}
+				children = append(children, table)
+			}
+			children = append(children, b.NewDataEditor(c.stateId(op, "response")).Id(c.stateId(op, "response_view")).ReadOnly())
+		}
+		stack := b.NewStack().Id(fmt.Sprintf("%v__resource_%v", c.id, i)).Properties(map[string]interface{}{
+			"direction": "vertical",
+			"spacing":   16,
+		}).Children(map[string][]sunmao.BaseComponentBuilder{
+			"content": children,
+		}).Hidden(fmt.Sprintf("{{ %v.activeTab != %v }}", c.id, i))
+		components = append(components, stack)
+	}
+	return components
+}
+
+// columns are the scalar properties of the items of a list response, nil
+// if the response is not a list of objects.
+func columns(schema map[string]any) []string {
+	if schema["type"] != "array" {
+		return nil
+	}
+	items, _ := schema["items"].(map[string]any)
+	properties, ok := items["properties"].(map[string]any)
+	if !ok {
+		return nil
+	}
+	names := []string{}
+	for name, p := range properties {
+		p, _ := p.(map[string]any)
+		if t := p["type"]; t != "object" && t != "array" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func rowKey(columns []string) string {
+	for _, name := range []string{"id", "uuid", "key", "name"} {
+		for _, column := range columns {
+			if column == name {
+				return name
+			}
+		}
+	}
+	return columns[0]
+}
+
+// requestSchema is the schema of the request editor value. Path parameters
+// are always required, query parameters when the document says so.
+func requestSchema(op Operation) map[string]any {
+	properties := map[string]any{}
+	required := []any{}
+	for _, in := range []string{"path", "query"} {
+		params := map[string]any{}
+		names := []any{}
+		for _, p := range op.Parameters {
+			if p.In != in {
+				continue
+			}
+			schema := p.Schema
+			if schema == nil {
+				schema = map[string]any{}
+			}
+			params[p.Name] = editable(schema)
+			if p.Required || in == "path" {
+				names = append(names, p.Name)
+			}
+		}
+		if len(params) == 0 {
+			continue
+		}
+		properties[in] = map[string]any{"type": "object", "properties": params, "required": names}
+		if len(names) > 0 {
+			required = append(required, in)
+		}
+	}
+	if op.Body != nil {
+		properties["body"] = editable(op.Body)
+		required = append(required, "body")
+	}
+	return map[string]any{"type": "object", "properties": properties, "required": required}
+}
+
+// editable drops the type of OpenAPI 3.0 nullable schemas, the editor's
+// schemas know a single type only.
+func editable(schema map[string]any) map[string]any {
+	out := make(map[string]any, len(schema))
+	for k, v := range schema {
+		switch v := v.(type) {
+		case map[string]any:
+			out[k] = editable(v)
+		case []any:
+			// allOf and friends hold schemas, enum and required do not
+			list := make([]any, len(v))
+			for i, item := range v {
+				if item, ok := item.(map[string]any); ok {
+					list[i] = editable(item)
+					continue
+				}
+				list[i] = item
+			}
+			out[k] = list
+		default:
+			out[k] = v
+		}
+	}
+	if nullable, _ := schema["nullable"].(bool); nullable {
+		delete(out, "type")
+	}
+	return out
+}
+
+// template is the initial editor value of schema: its example or default,
+// else the required properties of objects and zero values.
+func template(schema map[string]any, depth int) any {
+	if v, ok := schema["example"]; ok {
+		return v
+	}
+	if v, ok := schema["default"]; ok {
+		return v
+	}
+	if enum, ok := schema["enum"].([]any); ok && len(enum) > 0 {
+		return enum[0]
+	}
+	switch schema["type"] {
+	case "object":
+		value := map[string]any{}
+		if depth >= maxRefDepth {
+			return value
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		required, _ := schema["required"].([]any)
+		for _, name := range required {
+			name, _ := name.(string)
+			p, _ := properties[name].(map[string]any)
+			value[name] = template(p, depth+1)
+		}
+		return value
+	case "array":
+		return []any{}
+	case "string":
+		return ""
+	case "integer", "number":
+		return 0
+	case "boolean":
+		return false
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+// Package openapi generates an admin console from an OpenAPI 3 document:
+// a tab per resource, a request editor per operation checked against the
+// operation's schemas and a table or viewer for the response. Requests are
+// made by the Go server, so credentials stay on it and CORS does not apply.
+package openapi
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxRefDepth cuts recursive schemas
+const maxRefDepth = 8
+
+var methods = []string{"get", "post", "put", "patch", "delete"}
+
+// Parameter is a path or query parameter of an operation.
+type Parameter struct {
+	Name     string
+	In       string
+	Required bool
+	Schema   map[string]any
+}
+
+// Operation is a method on a path, its schemas with every $ref resolved.
+type Operation struct {
+	Method string
+	Path   string
+	Id     string
+	// Resource groups the operations on a tab, the first tag or the first
+	// path segment.
+	Resource   string
+	Summary    string
+	Parameters []Parameter
+	// Body is the json request body schema, nil without a body.
+	Body map[string]any
+	// Response is the schema of the json 2xx response, nil if unknown.
+	Response map[string]any
+}
+
+// Document is what the console needs of an OpenAPI document.
+type Document struct {
+	Title string
+	// Servers are the server urls, the first is used by default.
+	Servers    []string
+	Operations []Operation
+}
+
+// Parse reads an OpenAPI 3 document in JSON or YAML.
+func Parse(doc []byte) (*Document, error) {
+	var v any
+	if err := yaml.Unmarshal(doc, &v); err != nil {
+		return nil, fmt.Errorf("parse openapi document: %w", err)
+	}
+	raw, ok := normalize(v).(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("parse openapi document: not an object")
+	}
+	if v, _ := raw["openapi"].(string); !strings.HasPrefix(v, "3.") {
+		return nil, fmt.Errorf("unsupported openapi version %q", raw["openapi"])
+	}
+
+	d := &Document{}
+	if info, ok := raw["info"].(map[string]any); ok {
+		d.Title, _ = info["title"].(string)
+	}
+	servers, _ := raw["servers"].([]any)
+	for _, s := range servers {
+		if s, ok := s.(map[string]any); ok {
+			if u, ok := s["url"].(string); ok {
+				d.Servers = append(d.Servers, u)
+			}
+		}
+	}
+
+	resolver := &resolver{root: raw}
+	paths, _ := raw["paths"].(map[string]any)
+	keys := make([]string, 0, len(paths))
+	for path := range paths {
+		keys = append(keys, path)
+	}
+	sort.Strings(keys)
+	for _, path := range keys {
+		item, _ := resolver.resolve(paths[path], 0).(map[string]any)
+		shared := parameters(resolver, item["parameters"])
+		for _, method := range methods {
+			op, ok := resolver.resolve(item[method], 0).(map[string]any)
+			if !ok {
+				continue
+			}
+			d.Operations = append(d.Operations, operation(resolver, method, path, op, shared))
+		}
+	}
+	return d, nil
+}
+
+func operation(resolver *resolver, method, path string, op map[string]any, shared []Parameter) Operation {
+	o := Operation{Method: strings.ToUpper(method), Path: path}
+	o.Id, _ = op["operationId"].(string)
+	if o.Id == "" {
+		o.Id = method + strings.NewReplacer("/", "_", "{", "", "}", "").Replace(path)
+	}
+	o.Summary, _ = op["summary"].(string)
+	if tags, _ := op["tags"].([]any); len(tags) > 0 {
+		o.Resource, _ = tags[0].(string)
+	}
+	if o.Resource == "" {
+		o.Resource = strings.Split(strings.Trim(path, "/"), "/")[0]
+	}
+
+	// operation parameters override the path item's ones of the same name
+	own := parameters(resolver, op["parameters"])
+	seen := map[string]bool{}
+	for _, p := range own {
+		seen[p.In+":"+p.Name] = true
+	}
+	for _, p := range shared {
+		if !seen[p.In+":"+p.Name] {
+			own = append(own, p)
+		}
+	}
+	o.Parameters = own
+
+	if body, ok := resolver.resolve(op["requestBody"], 0).(map[string]any); ok {
+		o.Body = jsonSchema(resolver, body["content"])
+	}
+	responses, _ := op["responses"].(map[string]any)
+	for _, code := range []string{"200", "201", "2XX", "default"} {
+		if res, ok := resolver.resolve(responses[code], 0).(map[string]any); ok {
+			if o.Response = jsonSchema(resolver, res["content"]); o.Response != nil {
+				break
+			}
+		}
+	}
+	return o
+}
+
+func parameters(resolver *resolver, v any) []Parameter {
+	list, _ := v.([]any)
+	params := []Parameter{}
+	for _, item := range list {
+		p, ok := resolver.resolve(item, 0).(map[string]any)
+		if !ok {
+			continue
+		}
+		in, _ := p["in"].(string)
+		if in != "path" && in != "query" {
+			// headers and cookies are the server's business
+			continue
+		}
+		param := Parameter{In: in}
+		param.Name, _ = p["name"].(string)
+		param.Required, _ = p["required"].(bool)
+		param.Schema, _ = resolver.resolve(p["schema"], 0).(map[string]any)
+		params = append(params, param)
+	}
+	return params
+}
+
+// jsonSchema picks the json media type of a content map.
+func jsonSchema(resolver *resolver, content any) map[string]any {
+	types, _ := content.(map[string]any)
+	for mime, media := range types {
+		if !strings.Contains(mime, "json") {
+			continue
+		}
+		if media, ok := media.(map[string]any); ok {
+			schema, _ := resolver.resolve(media["schema"], 0).(map[string]any)
+			return schema
+		}
+	}
+	return nil
+}
+
+type resolver struct {
+	root map[string]any
+}
+
+// resolve replaces local $refs in v, deeper than maxRefDepth they become
+// empty schemas.
+func (r *resolver) resolve(v any, depth int) any {
+	switch v := v.(type) {
+	case map[string]any:
+		if ref, ok := v["$ref"].(string); ok {
+			if depth >= maxRefDepth {
+				return map[string]any{}
+			}
+			return r.resolve(r.lookup(ref), depth+1)
+		}
+		out := make(map[string]any, len(v))
+		for k, item := range v {
+			out[k] = r.resolve(item, depth)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = r.resolve(item, depth)
+		}
+		return out
+	}
+	return v
+}
+
+func (r *resolver) lookup(ref string) any {
+	if !strings.HasPrefix(ref, "#/") {
+		return map[string]any{}
+	}
+	var node any = r.root
+	for _, key := range strings.Split(ref[2:], "/") {
+		key = strings.NewReplacer("~1", "/", "~0", "~").Replace(key)
+		m, ok := node.(map[string]any)
+		if !ok {
+			return map[string]any{}
+		}
+		node = m[key]
+	}
+	return node
+}
+
+// normalize gives every yaml mapping string keys, unquoted status codes
+// such as 200 decode as ints.
+func normalize(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, item := range v {
+			v[k] = normalize(item)
+		}
+		return v
+	case map[any]any:
+		out := make(map[string]any, len(v))
+		for k, item := range v {
+			out[fmt.Sprint(k)] = normalize(item)
+		}
+		return out
+	case []any:
+		for i, item := range v {
+			v[i] = normalize(item)
+		}
+		return v
+	}
+	return v
+}
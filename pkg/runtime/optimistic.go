@@ -0,0 +1,52 @@
+package runtime
+
+import "encoding/json"
+
+// Optimistic is a change the client already applied to a ServerState
+// while its action is on the way.
+type Optimistic struct {
+	State string `json:"state"`
+	// Version is the ServerState version the change is based on.
+	Version uint64 `json:"version"`
+	Value   any    `json:"value"`
+	// connId is the connection that sent the change
+	connId int
+}
+
+// Optimistic returns the optimistic change m carries for s, a handler
+// answers it with Confirm or Correct.
+func (s *ServerState) Optimistic(m *Message) (*Optimistic, bool) {
+	if m.Optimistic == nil || m.Optimistic.State != s.Id {
+		return nil, false
+	}
+	return m.Optimistic, true
+}
+
+// DecodeOptimistic converts the optimistic value into T.
+func DecodeOptimistic[T any](o *Optimistic) (T, error) {
+	var v T
+	buf, err := json.Marshal(o.Value)
+	if err != nil {
+		return v, err
+	}
+	err = json.Unmarshal(buf, &v)
+	return v, err
+}
+
+// Stale reports whether another SetState reaching the client happened
+// after it made the change.
+func (s *ServerState) Stale(o *Optimistic) bool {
+	return o.Version != s.versionOf(o.connId)
+}
+
+// Confirm accepts the state, usually the optimistic value, and
+// broadcasts it with a new version.
+func (s *ServerState) Confirm(value any) error {
+	return s.SetState(value, nil)
+}
+
+// Correct rolls the optimistic change of connId back to value, the
+// authoritative state, without bumping the version.
+func (s *ServerState) Correct(connId int, value any) error {
+	return s.setState(value, s.versionOf(connId), &connId)
+}
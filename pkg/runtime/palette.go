@@ -0,0 +1,27 @@
+package runtime
+
+type commandSpec struct {
+	Title    string   `json:"title"`
+	Keywords []string `json:"keywords,omitempty"`
+}
+
+// Command lists the handler in the command palette as title, keywords
+// match the search as well. The handler receives {"command": title} as
+// params when it is picked.
+func Command(title string, keywords ...string) HandlerOption {
+	return func(h *handler) {
+		h.spec.Command = &commandSpec{Title: title, Keywords: keywords}
+	}
+}
+
+// WithCommandPalette opens a palette of the Command handlers on keys such
+// as "mod+k", the default. Commands of handlers added later show up once
+// they are pushed to the client.
+func WithCommandPalette(keys string) Option {
+	return func(r *Runtime) {
+		if keys == "" {
+			keys = "mod+k"
+		}
+		r.commandPalette = normalizeKeys(keys)
+	}
+}
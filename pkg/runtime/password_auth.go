@@ -0,0 +1,193 @@
+package runtime
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	passwordLoginPath  = "/login"
+	passwordLogoutPath = "/logout"
+	csrfFormField      = "_csrf"
+	// failed attempts are answered late to slow down guessing
+	loginFailureDelay = 500 * time.Millisecond
+)
+
+// ErrInvalidCredentials is what a PasswordCheck returns for a wrong user
+// or password, the login page shows it as such. Other errors are logged.
+var ErrInvalidCredentials = errors.New("invalid user or password")
+
+type PasswordCheck func(user string, password string) (*Identity, error)
+
+// LoginPage brands the page of WithPasswordAuth, the title defaults to
+// the AppBuilder meta.
+type LoginPage struct {
+	Title string
+	// Logo is the url of an image shown above the form.
+	Logo   string
+	Footer string
+}
+
+type passwordAuth struct {
+	check PasswordCheck
+	page  LoginPage
+}
+
+var loginPageTemplate = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html lang="en">
+  <head>
+    <meta charset="UTF-8" />
+    <meta name="viewport" content="width=device-width, initial-scale=1.0" />
+    <title>Sign in{{if .Title}} - {{.Title}}{{end}}</title>
+    <style>
+      body { margin: 0; font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, sans-serif; color: #1d2129; background: #f7f8fa; }
+      main { max-width: 360px; margin: 15vh auto 0; padding: 32px; background: #fff; border-radius: 8px; box-shadow: 0 4px 16px rgba(0, 0, 0, 0.08); }
+      img { display: block; max-height: 48px; margin: 0 auto 16px; }
+      h1 { margin: 0 0 24px; font-size: 20px; text-align: center; }
+      label { display: block; margin-bottom: 16px; font-size: 14px; color: #4e5969; }
+      input { display: block; width: 100%; box-sizing: border-box; margin-top: 4px; padding: 8px 10px; border: 1px solid #e5e6eb; border-radius: 4px; font-size: 14px; }
+      button { width: 100%; padding: 10px; border: none; border-radius: 4px; background: #165dff; color: #fff; font-size: 14px; cursor: pointer; }
+      .error { margin-bottom: 16px; color: #f53f3f; font-size: 14px; }
+      footer { margin-top: 24px; text-align: center; font-size: 12px; color: #86909c; }
+    </style>
+  </head>
+  <body>
+    <main>
+      {{if .Logo}}<img src="{{.Logo}}" alt="" />{{end}}
+      <h1>{{if .Title}}{{.Title}}{{else}}Sign in{{end}}</h1>
+      {{if .Error}}<div class="error" role="alert">{{.Error}}</div>{{end}}
+      <form method="post" action="{{.Action}}">
+        <input type="hidden" name="return_to" value="{{.ReturnTo}}" />
+        {{if .CSRF}}<input type="hidden" name="` + csrfFormField + `" value="{{.CSRF}}" />{{end}}
+        <label>User<input name="user" autocomplete="username" value="{{.User}}" required autofocus /></label>
+        <label>Password<input name="password" type="password" autocomplete="current-password" required /></label>
+        <button type="submit">Sign in</button>
+      </form>
+      {{if .Footer}}<footer>{{.Footer}}</footer>{{end}}
+    </main>
+  </body>
+</html>
+`))
+
+// WithPasswordAuth gates the app behind a login page checking user and
+// password with check. It enables in memory sessions unless WithSessions
+// is given, and RequireAuth with the login page unless another gate is
+// configured. POST /logout, or the binding/v1/logout util method, ends the
+// session.
+func WithPasswordAuth(check PasswordCheck) Option {
+	return func(r *Runtime) {
+		if r.passwordAuth == nil {
+			r.passwordAuth = &passwordAuth{}
+		}
+		r.passwordAuth.check = check
+		if r.sessions == nil {
+			WithSessions(NewMemorySessionStore(12 * time.Hour))(r)
+		}
+		if r.authGate == nil {
+			RequireAuth(passwordLoginPath)(r)
+		}
+	}
+}
+
+// WithLoginPage brands the page of WithPasswordAuth.
+func WithLoginPage(page LoginPage) Option {
+	return func(r *Runtime) {
+		if r.passwordAuth == nil {
+			r.passwordAuth = &passwordAuth{}
+		}
+		r.passwordAuth.page = page
+	}
+}
+
+// localPath only allows redirects back to this server.
+func localPath(path string, fallback string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.HasPrefix(path, "/\\") {
+		return fallback
+	}
+	return path
+}
+
+func (r *Runtime) renderLogin(c echo.Context, code int, user string, loginErr string) error {
+	page := r.passwordAuth.page
+	if page.Title == "" && r.appBuilder != nil {
+		page.Title = r.appBuilder.MetaOf().Title
+	}
+	csrf, _ := c.Get("csrf").(string)
+
+	buf := &bytes.Buffer{}
+	err := loginPageTemplate.Execute(buf, map[string]any{
+		"Title":    page.Title,
+		"Logo":     page.Logo,
+		"Footer":   page.Footer,
+		"Action":   r.basePath + passwordLoginPath,
+		"ReturnTo": localPath(c.FormValue("return_to"), r.basePath+"/"),
+		"CSRF":     csrf,
+		"User":     user,
+		"Error":    loginErr,
+	})
+	if err != nil {
+		return err
+	}
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+	return c.HTMLBlob(code, buf.Bytes())
+}
+
+func (r *Runtime) registerPasswordAuth() {
+	if r.passwordAuth == nil || r.passwordAuth.check == nil {
+		return
+	}
+
+	r.router.GET(passwordLoginPath, func(c echo.Context) error {
+		// QueryParam is what RequireAuth redirects with
+		if r.Identity(c) != nil {
+			return c.Redirect(http.StatusFound, localPath(c.QueryParam("return_to"), r.basePath+"/"))
+		}
+		return r.renderLogin(c, http.StatusOK, "", "")
+	})
+
+	r.router.POST(passwordLoginPath, func(c echo.Context) error {
+		user := c.FormValue("user")
+		identity, err := r.passwordAuth.check(user, c.FormValue("password"))
+		if err == nil && identity == nil {
+			err = ErrInvalidCredentials
+		}
+		if err != nil {
+			if !errors.Is(err, ErrInvalidCredentials) {
+				c.Logger().Error(err)
+			}
+			time.Sleep(loginFailureDelay)
+			return r.renderLogin(c, http.StatusUnauthorized, user, ErrInvalidCredentials.Error())
+		}
+
+		if err := r.Login(c, identity); err != nil {
+			return err
+		}
+		return c.Redirect(http.StatusSeeOther, localPath(c.FormValue("return_to"), r.basePath+"/"))
+	})
+
+	r.router.POST(passwordLogoutPath, func(c echo.Context) error {
+		if err := r.Logout(c); err != nil {
+			return err
+		}
+		if strings.Contains(c.Request().Header.Get(echo.HeaderAccept), echo.MIMETextHTML) {
+			return c.Redirect(http.StatusSeeOther, r.basePath+passwordLoginPath)
+		}
+		return c.NoContent(http.StatusNoContent)
+	})
+}
+
+// SignOut ends the session of the client on connId, e.g. from a logout
+// handler, and sends it to the login page.
+func (r *Runtime) SignOut(connId int) error {
+	return r.Execute(&ExecuteTarget{
+		Id:         utilsId,
+		Method:     "binding/v1/logout",
+		Parameters: map[string]interface{}{},
+	}, &connId)
+}
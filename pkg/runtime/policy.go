@@ -0,0 +1,58 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Policy authorizes a call of a handler. conn is nil for calls through the
+// action api, a policy decides whether to trust token callers.
+type Policy func(conn *Conn) error
+
+// ForbiddenCode is the ActionError code of calls denied by a Policy.
+const ForbiddenCode = "forbidden"
+
+// Authorize runs policy before every call of the handler, a denied call
+// never reaches it and the client gets an ActionError with ForbiddenCode,
+// or the ActionError returned by policy. Policies run after
+// OnBeforeAction hooks.
+func Authorize(policy Policy) HandlerOption {
+	return func(h *handler) {
+		h.policies = append(h.policies, policy)
+	}
+}
+
+// RequireRole is a Policy granting identities which have any of roles.
+func RequireRole(roles ...string) Policy {
+	return func(conn *Conn) error {
+		if conn != nil {
+			for _, role := range roles {
+				if conn.Identity.HasRole(role) {
+					return nil
+				}
+			}
+		}
+		return fmt.Errorf("requires role %v", strings.Join(roles, " or "))
+	}
+}
+
+// HandleAuth is Handle with an authorization policy.
+func (r *Runtime) HandleAuth(name string, policy Policy, fn HandlerFunc, opts ...HandlerOption) error {
+	return r.Handle(name, fn, append(opts, Authorize(policy))...)
+}
+
+func (h *handler) authorize(conn *Conn) error {
+	for _, policy := range h.policies {
+		err := policy(conn)
+		if err == nil {
+			continue
+		}
+		actionErr := &ActionError{}
+		if errors.As(err, &actionErr) {
+			return actionErr
+		}
+		return &ActionError{Code: ForbiddenCode, Message: err.Error()}
+	}
+	return nil
+}
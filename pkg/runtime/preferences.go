@@ -0,0 +1,95 @@
+package runtime
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+type preferenceRegistry struct {
+	mu       sync.RWMutex
+	defaults map[string]any
+}
+
+func newPreferenceRegistry() *preferenceRegistry {
+	return &preferenceRegistry{defaults: map[string]any{}}
+}
+
+func (p *preferenceRegistry) all() map[string]any {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	defaults := make(map[string]any, len(p.defaults))
+	for k, v := range p.defaults {
+		defaults[k] = v
+	}
+	return defaults
+}
+
+// Preference declares a client preference such as a column width or a
+// collapsed panel. The client keeps it in localStorage, reports it on
+// every connect and whenever the binding/v1/setPreference util method
+// changes it. def is used until the client stored a value.
+func (r *Runtime) Preference(key string, def any) {
+	r.preferences.mu.Lock()
+	r.preferences.defaults[key] = def
+	r.preferences.mu.Unlock()
+	r.invalidatePages()
+}
+
+// SetPreference stores a preference on the client, a nil connId writes it
+// for every connected client.
+func (r *Runtime) SetPreference(connId *int, key string, value any) error {
+	return r.send(map[string]interface{}{
+		"type":  "Preference",
+		"key":   key,
+		"value": value,
+	}, connId)
+}
+
+// Preference returns the value the client reported for key, nil if the
+// preference was not declared.
+func (c *Conn) Preference(key string) any {
+	c.prefMu.RLock()
+	defer c.prefMu.RUnlock()
+
+	return c.prefs[key]
+}
+
+// DecodePreference converts a reported preference into T.
+func DecodePreference[T any](c *Conn, key string) (T, error) {
+	var v T
+	buf, err := json.Marshal(c.Preference(key))
+	if err != nil {
+		return v, err
+	}
+	err = json.Unmarshal(buf, &v)
+	return v, err
+}
+
+func (c *Conn) setPreferences(params any) {
+	values, ok := params.(map[string]any)
+	if !ok || len(values) == 0 {
+		return
+	}
+
+	c.prefMu.Lock()
+	defer c.prefMu.Unlock()
+
+	if c.prefs == nil {
+		c.prefs = map[string]any{}
+	}
+	for k, v := range values {
+		c.prefs[k] = v
+	}
+}
+
+func (c *Conn) preferences() map[string]any {
+	c.prefMu.RLock()
+	defer c.prefMu.RUnlock()
+
+	prefs := make(map[string]any, len(c.prefs))
+	for k, v := range c.prefs {
+		prefs[k] = v
+	}
+	return prefs
+}
@@ -0,0 +1,80 @@
+package runtime
+
+import (
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const headerXForwardedHost = "X-Forwarded-Host"
+
+// headers echo reads the scheme from, see echo.Context.Scheme
+var forwardedSchemeHeaders = []string{
+	echo.HeaderXForwardedProto,
+	echo.HeaderXForwardedProtocol,
+	echo.HeaderXForwardedSsl,
+	echo.HeaderXUrlScheme,
+}
+
+// WithTrustedProxies resolves the client ip, scheme and host from the
+// X-Forwarded-* headers set by proxies in the given ranges, which the ip
+// filter, logs, secure cookies and the websocket origin check rely on.
+// The headers of any other peer are ignored, so clients can not spoof
+// them.
+func WithTrustedProxies(proxies ...CIDR) Option {
+	return func(r *Runtime) {
+		r.trustedProxies = proxies
+		r.e.IPExtractor = r.extractIP
+	}
+}
+
+func (r *Runtime) trustedPeer(req *http.Request) bool {
+	ip, err := netip.ParseAddr(echo.ExtractIPDirect()(req))
+	return err == nil && containsIP(r.trustedProxies, ip.Unmap())
+}
+
+// extractIP walks X-Forwarded-For from the right and returns the first
+// hop which is not a trusted proxy.
+func (r *Runtime) extractIP(req *http.Request) string {
+	client := echo.ExtractIPDirect()(req)
+	if !r.trustedPeer(req) {
+		return client
+	}
+
+	hops := strings.Split(strings.Join(req.Header.Values(echo.HeaderXForwardedFor), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		ip, err := netip.ParseAddr(hop)
+		if err != nil {
+			break
+		}
+		client = hop
+		if !containsIP(r.trustedProxies, ip.Unmap()) {
+			break
+		}
+	}
+	return client
+}
+
+// forwardedMiddleware runs before routing, so tenants resolve from the
+// forwarded host too.
+func (r *Runtime) forwardedMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if !r.trustedPeer(req) {
+				for _, h := range forwardedSchemeHeaders {
+					req.Header.Del(h)
+				}
+				return next(c)
+			}
+
+			if host := strings.TrimSpace(strings.Split(req.Header.Get(headerXForwardedHost), ",")[0]); host != "" {
+				req.Host = host
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	manifestPath      = "/manifest.webmanifest"
+	serviceWorkerPath = "/sunmao-binding-sw.js"
+)
+
+// PWA configures the web app manifest served by WithPWA, the name and
+// description come from the AppBuilder meta.
+type PWA struct {
+	ShortName       string
+	ThemeColor      string
+	BackgroundColor string
+	// Display defaults to standalone.
+	Display string
+	Icons   []PWAIcon
+	// ServiceWorker registers a worker which keeps the hashed assets in
+	// the browser cache. Pages and the websocket always hit the network.
+	ServiceWorker bool
+}
+
+type PWAIcon struct {
+	Src   string `json:"src"`
+	Sizes string `json:"sizes"`
+	Type  string `json:"type,omitempty"`
+}
+
+type webManifest struct {
+	Name            string    `json:"name"`
+	ShortName       string    `json:"short_name,omitempty"`
+	Description     string    `json:"description,omitempty"`
+	StartUrl        string    `json:"start_url"`
+	Scope           string    `json:"scope"`
+	Display         string    `json:"display"`
+	ThemeColor      string    `json:"theme_color,omitempty"`
+	BackgroundColor string    `json:"background_color,omitempty"`
+	Icons           []PWAIcon `json:"icons,omitempty"`
+}
+
+// vite emits hashed asset names, so a cached asset never goes stale
+const serviceWorkerScript = `const CACHE = "sunmao-binding-assets";
+self.addEventListener("install", () => self.skipWaiting());
+self.addEventListener("activate", (evt) => evt.waitUntil(self.clients.claim()));
+self.addEventListener("fetch", (evt) => {
+  const url = new URL(evt.request.url);
+  if (evt.request.method !== "GET" || url.origin !== location.origin || !url.pathname.includes("/assets/")) {
+    return;
+  }
+  evt.respondWith(
+    caches.open(CACHE).then((cache) =>
+      cache.match(evt.request).then(
+        (hit) =>
+          hit ||
+          fetch(evt.request).then((res) => {
+            if (res.ok) {
+              cache.put(evt.request, res.clone());
+            }
+            return res;
+          })
+      )
+    )
+  );
+});
+`
+
+// WithPWA serves a web app manifest, and optionally a service worker, so
+// the application can be installed from the browser.
+func WithPWA(p PWA) Option {
+	return func(r *Runtime) {
+		r.pwa = &p
+	}
+}
+
+func (r *Runtime) manifest() ([]byte, error) {
+	meta := r.appBuilder.MetaOf()
+	name := meta.Title
+	if name == "" {
+		name = r.appBuilder.ValueOf().Metadata.Name
+	}
+	display := r.pwa.Display
+	if display == "" {
+		display = "standalone"
+	}
+	return json.Marshal(webManifest{
+		Name:            name,
+		ShortName:       r.pwa.ShortName,
+		Description:     meta.Description,
+		StartUrl:        r.basePath + "/",
+		Scope:           r.basePath + "/",
+		Display:         display,
+		ThemeColor:      r.pwa.ThemeColor,
+		BackgroundColor: r.pwa.BackgroundColor,
+		Icons:           r.pwa.Icons,
+	})
+}
+
+func (r *Runtime) registerPWA() {
+	if r.pwa == nil {
+		return
+	}
+	// browsers fetch the manifest without credentials
+	r.authExempt = append(r.authExempt, manifestPath, serviceWorkerPath)
+
+	r.router.GET(manifestPath, func(c echo.Context) error {
+		buf, err := r.manifest()
+		if err != nil {
+			return err
+		}
+		return c.Blob(http.StatusOK, "application/manifest+json", buf)
+	})
+
+	if r.pwa.ServiceWorker {
+		r.router.GET(serviceWorkerPath, func(c echo.Context) error {
+			c.Response().Header().Set(echo.HeaderCacheControl, "no-cache")
+			return c.Blob(http.StatusOK, "text/javascript; charset=utf-8", []byte(serviceWorkerScript))
+		})
+	}
+}
+
+// pwaHead links the manifest and registers the service worker.
+func (r *Runtime) pwaHead(d *IndexData) {
+	if r.pwa == nil {
+		return
+	}
+	if r.pwa.ThemeColor != "" {
+		d.Meta["theme-color"] = r.pwa.ThemeColor
+	}
+	d.Head = append(d.Head, template.HTML(fmt.Sprintf(
+		`<link rel="manifest" href="%v">`,
+		template.HTMLEscapeString(r.basePath+manifestPath),
+	)))
+	if r.pwa.ServiceWorker {
+		d.Head = append(d.Head, template.HTML(fmt.Sprintf(
+			`<script>if ("serviceWorker" in navigator) navigator.serviceWorker.register("%v");</script>`,
+			template.JSEscapeString(r.basePath+serviceWorkerPath),
+		)))
+	}
+}
@@ -0,0 +1,56 @@
+package runtime
+
+import "time"
+
+type ReconnectBehavior string
+
+const (
+	// ReconnectReload reloads the page once the websocket is back.
+	ReconnectReload ReconnectBehavior = "reload"
+	// ReconnectResume keeps the page and its client state, the server sees
+	// a new connection.
+	ReconnectResume ReconnectBehavior = "resume"
+)
+
+// ReconnectPolicy configures how the client retries a lost websocket.
+type ReconnectPolicy struct {
+	// MaxRetries of zero retries forever.
+	MaxRetries   int
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	OnReconnect  ReconnectBehavior
+}
+
+func (p *ReconnectPolicy) options() map[string]interface{} {
+	return map[string]interface{}{
+		"maxRetries":   p.MaxRetries,
+		"initialDelay": p.InitialDelay.Milliseconds(),
+		"maxDelay":     p.MaxDelay.Milliseconds(),
+		"onReconnect":  p.OnReconnect,
+	}
+}
+
+// WithReloadWhenWsDisconnected controls whether the client reloads the page
+// after losing the websocket, or after running out of reconnect retries.
+func WithReloadWhenWsDisconnected(reload bool) Option {
+	return func(r *Runtime) {
+		r.reloadWhenWsDisconnected = reload
+	}
+}
+
+// WithReconnectPolicy lets the client retry the websocket with exponential
+// backoff before falling back to WithReloadWhenWsDisconnected.
+func WithReconnectPolicy(policy ReconnectPolicy) Option {
+	return func(r *Runtime) {
+		if policy.InitialDelay <= 0 {
+			policy.InitialDelay = 500 * time.Millisecond
+		}
+		if policy.MaxDelay < policy.InitialDelay {
+			policy.MaxDelay = 30 * time.Second
+		}
+		if policy.OnReconnect == "" {
+			policy.OnReconnect = ReconnectResume
+		}
+		r.reconnectPolicy = &policy
+	}
+}
@@ -0,0 +1,141 @@
+package runtime
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	directionIn  = "in"
+	directionOut = "out"
+)
+
+// Frame is a single recorded websocket message.
+type Frame struct {
+	Time      time.Time       `json:"time"`
+	ConnId    int             `json:"connId"`
+	Direction string          `json:"direction"`
+	Data      json.RawMessage `json:"data"`
+}
+
+type recorder struct {
+	path string
+	mu   sync.Mutex
+	// f is opened by Run and closed by Shutdown, frames before and after
+	// are not recorded
+	f   *os.File
+	enc *json.Encoder
+}
+
+// WithRecorder records every inbound and outbound frame to path,
+// one JSON encoded Frame per line. The file is opened once Run starts,
+// failing to open it fails Run.
+func WithRecorder(path string) Option {
+	return func(r *Runtime) {
+		r.recorder = &recorder{path: path}
+	}
+}
+
+func (rec *recorder) open() error {
+	f, err := os.OpenFile(rec.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	rec.f = f
+	rec.enc = json.NewEncoder(f)
+	return nil
+}
+
+func (rec *recorder) close() error {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	if rec.f == nil {
+		return nil
+	}
+	err := rec.f.Close()
+	rec.f = nil
+	rec.enc = nil
+	return err
+}
+
+func (r *Runtime) trace(direction string, connId int, data []byte) {
+	if r.recorder == nil && r.inspector == nil {
+		return
+	}
+	if !json.Valid(data) {
+		return
+	}
+
+	frame := &Frame{
+		Time:      time.Now(),
+		ConnId:    connId,
+		Direction: direction,
+		Data:      data,
+	}
+	if r.recorder != nil {
+		r.record(frame)
+	}
+	if r.inspector != nil {
+		r.inspector.publish(frame)
+	}
+}
+
+func (r *Runtime) record(frame *Frame) {
+	r.recorder.mu.Lock()
+	defer r.recorder.mu.Unlock()
+
+	if r.recorder.enc == nil {
+		return
+	}
+	err := r.recorder.enc.Encode(frame)
+	if err != nil {
+		r.e.Logger.Error(err)
+	}
+}
+
+// ReadRecording loads all frames from a file written by WithRecorder.
+func ReadRecording(path string) ([]Frame, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	frames := []Frame{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		frame := Frame{}
+		if err := json.Unmarshal(scanner.Bytes(), &frame); err != nil {
+			return nil, err
+		}
+		frames = append(frames, frame)
+	}
+
+	return frames, scanner.Err()
+}
+
+// Replay feeds the inbound frames of a recording through the registered
+// handlers in their original order, using the recorded connIds.
+func (r *Runtime) Replay(path string) error {
+	frames, err := ReadRecording(path)
+	if err != nil {
+		return err
+	}
+
+	for _, frame := range frames {
+		if frame.Direction != directionIn {
+			continue
+		}
+		r.dispatch(frame.Data, frame.ConnId)
+	}
+
+	return nil
+}
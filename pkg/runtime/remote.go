@@ -0,0 +1,146 @@
+package runtime
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/yuyz0112/sunmao-ui-go-binding/pkg/sunmao"
+)
+
+var remoteClient = &http.Client{Timeout: 10 * time.Second}
+
+// LoadRemoteModules fetches module schemas published by other teams or
+// binaries, each url serves one module or an array of modules. Fetched
+// schemas are cached under the patch dir, so a startup with an
+// unreachable url falls back to the last good copy. They are served next
+// to the modules of LoadModule.
+func (r *Runtime) LoadRemoteModules(urls ...string) error {
+	modules := []sunmao.Module{}
+	fetched := []*remoteModules{}
+	for _, u := range urls {
+		remote, err := r.fetchRemoteModules(u)
+		if err != nil {
+			return fmt.Errorf("remote module %v: %w", u, err)
+		}
+		fetched = append(fetched, remote)
+		modules = append(modules, remote.modules...)
+	}
+
+	local := make([]sunmao.Module, len(r.moduleBuilders))
+	for i, b := range r.moduleBuilders {
+		local[i] = b.ValueOf()
+	}
+
+	seen := map[string]bool{}
+	for _, m := range append(local, modules...) {
+		if err := validModule(m); err != nil {
+			return err
+		}
+		key := m.Version + "/" + m.Metadata.Name
+		if seen[key] {
+			return fmt.Errorf("module %v is declared twice", key)
+		}
+		seen[key] = true
+	}
+
+	// only a copy which passed the checks replaces the last good one
+	for _, remote := range fetched {
+		remote.cache()
+	}
+
+	r.remoteModules = modules
+	r.invalidatePages()
+	return nil
+}
+
+func validModule(m sunmao.Module) error {
+	if m.Kind != "Module" || m.VersionMetadata == nil || m.Metadata.Name == "" {
+		return fmt.Errorf("invalid module schema %+v", m.VersionMetadata)
+	}
+	return nil
+}
+
+// remoteModules are the modules of one url, fresh ones are cached once
+// all modules were checked.
+type remoteModules struct {
+	modules   []sunmao.Module
+	buf       []byte
+	cachePath string
+	fresh     bool
+}
+
+func (m *remoteModules) cache() {
+	if !m.fresh {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(m.cachePath), os.ModePerm); err == nil {
+		os.WriteFile(m.cachePath, m.buf, 0644)
+	}
+}
+
+// fetchRemoteModules falls back to the cached copy when the url is
+// unreachable or serves something which is not a list of modules.
+func (r *Runtime) fetchRemoteModules(u string) (*remoteModules, error) {
+	sum := sha1.Sum([]byte(u))
+	cachePath := filepath.Join(r.patchDir, "remote-modules", hex.EncodeToString(sum[:])+".json")
+
+	buf, err := fetchRemote(u)
+	if err == nil {
+		modules, parseErr := parseModules(buf)
+		if parseErr == nil {
+			for _, m := range modules {
+				if parseErr = validModule(m); parseErr != nil {
+					break
+				}
+			}
+		}
+		if parseErr == nil {
+			return &remoteModules{modules: modules, buf: buf, cachePath: cachePath, fresh: true}, nil
+		}
+		err = parseErr
+	}
+
+	cached, cacheErr := os.ReadFile(cachePath)
+	if cacheErr != nil {
+		return nil, err
+	}
+	r.e.Logger.Warnf("using cached copy of %v: %v", u, err)
+	modules, err := parseModules(cached)
+	if err != nil {
+		return nil, err
+	}
+	return &remoteModules{modules: modules, cachePath: cachePath}, nil
+}
+
+func fetchRemote(u string) ([]byte, error) {
+	resp, err := remoteClient.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %v", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func parseModules(buf []byte) ([]sunmao.Module, error) {
+	modules := []sunmao.Module{}
+	if err := json.Unmarshal(buf, &modules); err == nil {
+		return modules, nil
+	}
+
+	module := sunmao.Module{}
+	if err := json.Unmarshal(buf, &module); err != nil {
+		return nil, err
+	}
+	return []sunmao.Module{module}, nil
+}
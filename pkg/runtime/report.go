@@ -0,0 +1,78 @@
+package runtime
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/labstack/echo/v4"
+	"github.com/yuyz0112/sunmao-ui-go-binding/pkg/sunmao"
+)
+
+// ReportFunc builds the application printed as a report, query holds the
+// parameters given to PrintReport.
+type ReportFunc func(query url.Values) (*sunmao.AppBuilder, error)
+
+// Report registers a printable report at /sunmao-binding-report/<name>.
+// The page renders the built app without a websocket and opens the
+// browser print dialog, where it can be saved as PDF. Call it before Run.
+func (r *Runtime) Report(name string, fn ReportFunc) {
+	r.reports[name] = fn
+}
+
+// PrintReport renders the report name in a hidden frame of the client
+// and prints it, a nil connId prints it on every client.
+func (r *Runtime) PrintReport(connId *int, name string, query url.Values) error {
+	if _, ok := r.reports[name]; !ok {
+		return fmt.Errorf("unknown report %v", name)
+	}
+
+	u := fmt.Sprintf("%v/sunmao-binding-report/%v", r.basePath, url.PathEscape(name))
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	return r.send(map[string]interface{}{
+		"type": "PrintReport",
+		"url":  u,
+	}, connId)
+}
+
+// Print opens the browser print dialog for the component marked with
+// Printable, laid out alone on the page, a nil connId prints it on every
+// client.
+func (r *Runtime) Print(connId *int, componentId string) error {
+	return r.send(map[string]interface{}{
+		"type":        "Print",
+		"componentId": componentId,
+	}, connId)
+}
+
+func (r *Runtime) registerReports() {
+	r.router.GET("/sunmao-binding-report/:name", func(c echo.Context) error {
+		fn, ok := r.reports[c.Param("name")]
+		if !ok {
+			return echo.ErrNotFound
+		}
+
+		app, err := fn(c.QueryParams())
+		if err != nil {
+			return err
+		}
+
+		options, err := r.uiOptions()
+		if err != nil {
+			return err
+		}
+		options["application"] = app.ValueOf()
+		options["applicationPatch"] = map[string]interface{}{}
+		options["wsUrl"] = ""
+		options["reloadWhenWsDisconnected"] = false
+		options["handlers"] = []string{}
+		options["print"] = true
+
+		c.Response().Header().Set(echo.HeaderContentType, echo.MIMETextHTMLCharsetUTF8)
+		c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+		c.Response().WriteHeader(http.StatusOK)
+		return r.renderPage(c.Response(), "index.html", options)
+	})
+}
@@ -0,0 +1,11 @@
+package runtime
+
+import gonanoid "github.com/matoous/go-nanoid/v2"
+
+func newRequestId() string {
+	id, err := gonanoid.New()
+	if err != nil {
+		return ""
+	}
+	return id
+}
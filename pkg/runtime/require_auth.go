@@ -0,0 +1,58 @@
+package runtime
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+type authGate struct {
+	loginPath string
+	public    []string
+}
+
+// RequireAuth rejects requests without an identity, which needs sessions
+// to be enabled. Pages redirect to loginPath, everything else including
+// the websocket upgrade gets a 401. Paths under loginPath and public are
+// reachable anonymously, all paths are relative to the base path.
+func RequireAuth(loginPath string, public ...string) Option {
+	return func(r *Runtime) {
+		r.authGate = &authGate{
+			loginPath: loginPath,
+			public:    append(public, loginPath, "/assets/"),
+		}
+	}
+}
+
+// matchPath matches path exactly, or by prefix for patterns ending in /.
+func matchPath(patterns []string, path string) bool {
+	for _, p := range patterns {
+		if path == p || (strings.HasSuffix(p, "/") && strings.HasPrefix(path, p)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Runtime) authMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			// paths are relative to the app serving the request, mounts
+			// have a login page of their own
+			m := r.mountOf(c.Request().URL.Path)
+			path := strings.TrimPrefix(c.Request().URL.Path, m.basePath)
+			// authExempt routes such as the action api and webhooks
+			// authenticate callers on their own
+			if r.Identity(c) != nil || matchPath(r.authGate.public, path) || matchPath(m.authExempt, path) {
+				return next(c)
+			}
+
+			if c.Request().Method == http.MethodGet && (path == "" || path == "/" || path == "/editor") {
+				return c.Redirect(http.StatusFound, m.basePath+r.authGate.loginPath+"?return_to="+url.QueryEscape(c.Request().URL.RequestURI()))
+			}
+			return echo.ErrUnauthorized
+		}
+	}
+}
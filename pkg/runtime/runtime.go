@@ -1,49 +1,136 @@
 package runtime
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html/template"
+	"io"
 	"log"
+	"net"
 	"net/http"
 	"os"
-	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/yuyz0112/sunmao-ui-go-binding/pkg/sunmao"
+	"golang.org/x/net/http2"
 )
 
 type Runtime struct {
 	e                        *echo.Echo
-	conns                    map[int]*websocket.Conn
+	router                   *echo.Group
+	basePath                 string
+	conns                    *connSet
 	appBuilder               *sunmao.AppBuilder
 	moduleBuilders           []*sunmao.ModuleBuilder
 	reloadWhenWsDisconnected bool
-	handlers                 map[string]func(m *Message, connId int) error
-	hooks                    map[string]func(connId int) error
+	handlers                 *handlerRegistry
+	hooks                    *hooks
 	uiDir                    string
 	patchDir                 string
+	pageCache                *pageCache
+	streamPages              bool
+	cspNonce                 bool
+	recorder                 *recorder
+	inspector                *inspector
+	mounts                   []*Runtime
+	connSeq                  *int64
+	tenants                  *tenants
+	sessions                 SessionStore
+	authGate                 *authGate
+	apiTokens                []string
+	authExempt               []string
+	csrf                     bool
+	ipFilter                 *ipFilter
+	addr                     string
+	tls                      *TLSConfig
+	maxMessageSize           int64
+	reconnectPolicy          *ReconnectPolicy
+	maxStoreSize             int
+	shortcuts                *shortcutRegistry
+	clientEvents             bool
+	preferences              *preferenceRegistry
+	reports                  map[string]ReportFunc
+	uploads                  map[string]*upload
+	uploadTokens             *uploadTokens
+	userStates               *userStates
+	customComponents         []sunmao.CustomComponent
+	remoteModules            []sunmao.Module
+	incrementalReload        bool
+	servedComponents         []sunmao.ComponentSchema
+	chunkSize                int
+	chunkProgress            ChunkProgress
+	compression              *compression
+	backpressure             *backpressure
+	metrics                  MetricsSink
+	audit                    *audit
+	flags                    FlagProvider
+	announcement             *announcement
+	handoffStore             HandoffStore
+	listener                 net.Listener
+	unixSocket               string
+	h2c                      *http2.Server
+	headTemplate             *template.Template
+	pwa                      *PWA
+	httpErrorHandler         echo.HTTPErrorHandler
+	accessLog                *accessLogger
+	trustedProxies           []CIDR
+	strictMessages           bool
+	commandPalette           string
+	passwordAuth             *passwordAuth
 }
 
-func New(uiDir string, patchDir string) *Runtime {
+type Option func(r *Runtime)
+
+func New(uiDir string, patchDir string, opts ...Option) *Runtime {
 	e := echo.New()
+	// do not trust X-Forwarded-For unless proxies are configured
+	e.IPExtractor = echo.ExtractIPDirect()
 
 	r := &Runtime{
 		e:                        e,
-		conns:                    map[int]*websocket.Conn{},
+		conns:                    newConnSet(),
 		reloadWhenWsDisconnected: true,
-		handlers:                 map[string]func(m *Message, connId int) error{},
-		hooks:                    map[string]func(connId int) error{},
+		handlers:                 newHandlerRegistry(),
+		hooks:                    &hooks{},
 		uiDir:                    uiDir,
 		patchDir:                 patchDir,
+		pageCache:                &pageCache{pages: map[string]*cachedPage{}},
+		connSeq:                  new(int64),
+		addr:                     ":8999",
+		shortcuts:                newShortcutRegistry(),
+		preferences:              newPreferenceRegistry(),
+		reports:                  map[string]ReportFunc{},
+		uploads:                  map[string]*upload{},
+		uploadTokens:             newUploadTokens(),
+		userStates:               newUserStates(),
+		metrics:                  NopMetrics{},
+		announcement:             &announcement{},
+	}
+
+	for _, opt := range opts {
+		opt(r)
 	}
+	e.HTTPErrorHandler = r.handleHTTPError
+	r.router = e.Group(r.basePath)
 
 	return r
 }
 
 var (
 	upgrader = websocket.Upgrader{}
+
+	errAppNotLoaded     = errors.New("please load app before run")
+	errUnknownHandler   = errors.New("unknown handler")
+	errDuplicateHandler = errors.New("handler is already registered")
+	errDebounced        = errors.New("call is debounced")
 )
 
 type Message struct {
@@ -51,22 +138,28 @@ type Message struct {
 	Handler string         `json:"handler"`
 	Params  any            `json:"params"`
 	Store   map[string]any `json:"store"`
+	// Optimistic is set when the client applied the action's effect on a
+	// ServerState before sending it
+	Optimistic *Optimistic `json:"optimistic,omitempty"`
+	// RequestId identifies an Action across logs, errors and the ui
+	// methods executed while handling it. The client may send one as a
+	// correlation id, otherwise the runtime generates it.
+	RequestId string `json:"requestId,omitempty"`
+	// storeSize and paramsSize are the encoded sizes as received
+	storeSize  int
+	paramsSize int
+	// files are the contents of an upload, see HandleUpload
+	files []UploadedFile
 }
 
 type DeltaBody struct {
 	Delta map[string]interface{} `json:"delta"`
 }
 
-func (r *Runtime) formatUiOptions() (*string, error) {
-	handlers := []string{}
-	for k := range r.handlers {
-		handlers = append(handlers, k)
-	}
+func (r *Runtime) uiOptions() (map[string]interface{}, error) {
+	handlers := r.Handlers()
 
-	modules := make([]any, len(r.moduleBuilders))
-	for i, b := range r.moduleBuilders {
-		modules[i] = b.ValueOf()
-	}
+	schema := r.schema()
 
 	appPatch := map[string]interface{}{}
 	appPatchBuf, err := os.ReadFile(fmt.Sprintf("%v/app.patch.json", r.patchDir))
@@ -86,68 +179,188 @@ func (r *Runtime) formatUiOptions() (*string, error) {
 		}
 	}
 
-	optionsBuf, err := json.Marshal(map[string]interface{}{
-		"application":              r.appBuilder.ValueOf(),
-		"modules":                  modules,
+	options := map[string]interface{}{
+		"application":              schema.Application,
+		"modules":                  schema.Modules,
 		"applicationPatch":         appPatch,
 		"modulesPatch":             modulesPatch,
 		"reloadWhenWsDisconnected": r.reloadWhenWsDisconnected,
 		"handlers":                 handlers,
-	})
-	if err != nil {
-		return nil, err
+		"handlerSpecs":             r.handlerSpecs(),
+		"shortcuts":                r.shortcuts.all(),
+		"preferences":              r.preferences.all(),
+		"customComponents":         r.customComponents,
+		"basePath":                 r.basePath,
+	}
+
+	if r.reconnectPolicy != nil {
+		options["reconnect"] = r.reconnectPolicy.options()
+	}
+
+	if r.clientEvents {
+		options["clientEvents"] = true
 	}
 
-	s := string(optionsBuf)
-	return &s, nil
+	if r.commandPalette != "" {
+		options["commandPalette"] = map[string]interface{}{"keys": r.commandPalette}
+	}
+
+	if r.csrf {
+		options["csrf"] = map[string]interface{}{
+			"cookie": csrfCookie,
+			"header": echo.HeaderXCSRFToken,
+		}
+	}
+
+	return options, nil
 }
 
-func (r *Runtime) Run() {
-	if r.appBuilder == nil {
-		log.Fatalln("please load app before run")
+const applicationPlaceholder = "/* APPLICATION */"
+
+// renderPage injects options into one of the html entries of the ui dist.
+// The options are encoded straight into w so large applications are never
+// held in memory as a whole string.
+func (r *Runtime) renderPage(w io.Writer, page string, options map[string]interface{}) error {
+	buf, err := os.ReadFile(fmt.Sprintf("%v/dist/%v", r.uiDir, page))
+	if err != nil {
+		return err
 	}
 
-	os.MkdirAll(r.patchDir, os.ModePerm)
+	if r.basePath != "" {
+		buf = rewriteAssetUrls(buf, r.basePath)
+	}
+
+	before, after, found := bytes.Cut(buf, []byte(applicationPlaceholder))
+	if !found {
+		_, err = w.Write(buf)
+		return err
+	}
 
-	r.e.Use(middleware.Gzip())
+	if _, err := w.Write(before); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, "options = Object.assign(options, "); err != nil {
+		return err
+	}
+	// <, > and & as well as U+2028 and U+2029 are escaped, so a property
+	// containing </script> can not break out of the script tag
+	if err := encodeOptions(w, options); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, ")"); err != nil {
+		return err
+	}
+	_, err = w.Write(after)
+	return err
+}
 
-	r.e.Static("/assets", fmt.Sprintf("%v/dist/assets", r.uiDir))
+func (r *Runtime) Run() {
+	if r.trustedProxies != nil {
+		r.e.Pre(r.forwardedMiddleware())
+	}
+	if r.tenants != nil {
+		r.e.Pre(r.tenantMiddleware())
+	}
+	r.e.Use(r.middlewares()...)
 
-	r.e.GET("/", func(c echo.Context) error {
-		buf, err := os.ReadFile(fmt.Sprintf("%v/dist/index.html", r.uiDir))
-		if err != nil {
-			return err
+	for _, m := range append([]*Runtime{r}, r.mounts...) {
+		if m.appBuilder == nil {
+			log.Fatalln(errAppNotLoaded)
 		}
+		m.registerRoutes()
+	}
 
-		options, err := r.formatUiOptions()
-		if err != nil {
-			return err
+	if r.recorder != nil {
+		if err := r.recorder.open(); err != nil {
+			r.e.Logger.Fatal(err)
 		}
+	}
+	if err := r.useListener(); err != nil {
+		r.e.Logger.Fatal(err)
+	}
+	var err error
+	switch {
+	case r.tls != nil:
+		err = r.e.StartTLS(r.addr, r.tls.CertFile, r.tls.KeyFile)
+	case r.h2c != nil:
+		err = r.e.StartH2CServer(r.addr, r.h2c)
+	default:
+		err = r.e.Start(r.addr)
+	}
+	if !errors.Is(err, http.ErrServerClosed) {
+		r.e.Logger.Fatal(err)
+	}
+}
 
-		html := strings.Replace(string(buf),
-			"/* APPLICATION */",
-			fmt.Sprintf("options = Object.assign(options, %v)", *options), 1)
-		return c.HTML(http.StatusOK, html)
-	})
-
-	r.e.GET("/editor", func(c echo.Context) error {
-		buf, err := os.ReadFile(fmt.Sprintf("%v/dist/editor.html", r.uiDir))
-		if err != nil {
-			return err
+// Shutdown stops serving, waits for pending requests until ctx is done and
+// closes the access log file opened from the config and the recording.
+// Run returns once it is called, call Handoff first to keep websocket
+// clients.
+func (r *Runtime) Shutdown(ctx context.Context) error {
+	err := r.e.Shutdown(ctx)
+	if r.accessLog != nil {
+		if closeErr := r.accessLog.close(); err == nil {
+			err = closeErr
 		}
+	}
+	if r.recorder != nil {
+		if closeErr := r.recorder.close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
 
-		options, err := r.formatUiOptions()
-		if err != nil {
-			return err
+// middlewares are shared by the root echo instance and tenant instances.
+func (r *Runtime) middlewares() []echo.MiddlewareFunc {
+	// panics end up in the error handler instead of dropping the connection
+	m := []echo.MiddlewareFunc{}
+	if r.accessLog != nil {
+		m = append(m, r.accessLogMiddleware())
+	}
+	m = append(m, middleware.Recover())
+	if r.ipFilter != nil {
+		m = append(m, r.ipFilterMiddleware())
+	}
+	m = append(m, r.gzipMiddleware())
+	if r.sessions != nil {
+		m = append(m, r.sessionMiddleware())
+	}
+	if r.authGate != nil {
+		m = append(m, r.authMiddleware())
+	}
+	if r.csrf {
+		m = append(m, r.csrfMiddleware())
+	}
+	return m
+}
+
+func (r *Runtime) registerRoutes() {
+	os.MkdirAll(r.patchDir, os.ModePerm)
+
+	if !r.streamPages {
+		if _, err := r.page("index.html"); err != nil {
+			r.e.Logger.Error(err)
 		}
+	}
 
-		html := strings.Replace(string(buf),
-			"/* APPLICATION */",
-			fmt.Sprintf("options = Object.assign(options, %v)", *options), 1)
-		return c.HTML(http.StatusOK, html)
+	r.router.GET("/assets/*", r.serveAsset)
+
+	if r.basePath != "" {
+		r.e.GET(r.basePath, func(c echo.Context) error {
+			return c.Redirect(http.StatusMovedPermanently, r.basePath+"/")
+		})
+	}
+
+	r.router.GET("/", func(c echo.Context) error {
+		return r.servePage(c, "index.html")
+	})
+
+	r.router.GET("/editor", func(c echo.Context) error {
+		return r.servePage(c, "editor.html")
 	})
 
-	r.e.PUT("/sunmao-binding-patch/app", func(c echo.Context) error {
+	r.router.PUT("/sunmao-binding-patch/app", func(c echo.Context) error {
 		b := &DeltaBody{}
 		if err := c.Bind(b); err != nil {
 			return err
@@ -162,11 +375,12 @@ func (r *Runtime) Run() {
 		if err != nil {
 			return err
 		}
+		r.invalidatePages()
 
 		return c.String(http.StatusOK, "ok")
 	})
 
-	r.e.PUT("/sunmao-binding-patch/modules", func(c echo.Context) error {
+	r.router.PUT("/sunmao-binding-patch/modules", func(c echo.Context) error {
 		b := &DeltaBody{}
 		if err := c.Bind(b); err != nil {
 			return err
@@ -181,20 +395,30 @@ func (r *Runtime) Run() {
 		if err != nil {
 			return err
 		}
+		r.invalidatePages()
 
 		return c.String(http.StatusOK, "ok")
 	})
 
-	r.e.GET("/sunmao-binding-patch/app/visualize", func(c echo.Context) error {
+	r.router.GET("/sunmao-binding-patch/app/visualize", func(c echo.Context) error {
 		appBuf, err := json.Marshal(r.appBuilder.ValueOf())
 		if err != nil {
 			return err
 		}
 
-		appPatchStr := "{}"
+		appPatch := map[string]interface{}{}
 		appPatchBuf, err := os.ReadFile(fmt.Sprintf("%v/app.patch.json", r.patchDir))
 		if err == nil {
-			appPatchStr = string(appPatchBuf)
+			err = json.Unmarshal(appPatchBuf, &appPatch)
+			if err != nil {
+				return err
+			}
+		}
+		// re-encode instead of embedding the file as is, json.Marshal
+		// escapes html sensitive characters
+		appPatchEscaped, err := json.Marshal(appPatch)
+		if err != nil {
+			return err
 		}
 
 		html := fmt.Sprintf(`
@@ -214,82 +438,203 @@ func (r *Runtime) Run() {
         </script>
     </body>
 </html>
-`, string(appBuf), appPatchStr)
+`, string(appBuf), string(appPatchEscaped))
 
 		return c.HTML(http.StatusOK, html)
 	})
 
-	connId := 0
+	r.registerExport()
+	r.registerReports()
+	r.registerUploads()
+	r.registerPWA()
+	r.registerPasswordAuth()
 
-	r.e.GET("/ws", func(c echo.Context) error {
-		ws, err := upgrader.Upgrade(c.Response(), c.Request(), nil)
-		if err != nil {
-			return err
+	if len(r.apiTokens) > 0 {
+		r.registerActionAPI()
+	}
+
+	if r.inspector != nil {
+		r.registerInspector()
+	}
+
+	r.router.GET("/ws", r.serveWs)
+}
+
+// Router exposes the route group of the app, for registering custom
+// routes such as login pages next to the runtime's own.
+func (r *Runtime) Router() *echo.Group {
+	return r.router
+}
+
+func (r *Runtime) dispatch(msgBytes []byte, connId int) {
+	raw := &struct {
+		Message
+		Params json.RawMessage `json:"params"`
+		Store  json.RawMessage `json:"store"`
+	}{}
+
+	if err := r.decodeMessage(msgBytes, raw); err != nil {
+		r.invalidMessage(connId, InvalidMalformed, err, msgBytes)
+		return
+	}
+
+	msg := &raw.Message
+	r.metrics.MessageReceived(connId, msg.Type, len(msgBytes))
+	msg.paramsSize = len(raw.Params)
+	if len(raw.Params) > 0 {
+		codec.unmarshal(raw.Params, &msg.Params)
+	}
+	msg.storeSize = len(raw.Store)
+	if len(raw.Store) > 0 {
+		codec.unmarshal(raw.Store, &msg.Store)
+	}
+
+	switch msg.Type {
+	case "Action":
+		err := r.callHandler(msg, connId)
+		if err == errUnknownHandler {
+			r.invalidMessage(connId, InvalidUnknownHandler, err, msgBytes)
+		}
+		r.actionDone(connId, msg, err)
+	case "AppServed":
+		if conn := r.conns.get(connId); conn != nil {
+			r.appServed(conn)
+		}
+	case "Preferences":
+		if conn := r.conns.get(connId); conn != nil {
+			conn.setPreferences(msg.Params)
+		}
+	case "ClientError":
+		if conn := r.conns.get(connId); conn != nil {
+			r.clientError(conn, msg.Params)
+		}
+	case "ClientEvent":
+		if conn := r.conns.get(connId); conn != nil {
+			r.clientEvent(conn, msg.Params)
 		}
-		connId++
-		r.conns[connId] = ws
-		defer func() {
-			delete(r.conns, connId)
-			ws.Close()
-		}()
-
-		connectedHook, ok := r.hooks["connected"]
-		if ok {
-			connectedHook(connId)
+	case "UploadToken":
+		if conn := r.conns.get(connId); conn != nil {
+			r.issueUploadToken(conn, msg.Params)
 		}
+	default:
+		r.invalidMessage(connId, InvalidUnknownType, fmt.Errorf("unknown message type %q", msg.Type), msgBytes)
+	}
+}
 
-		for {
-			_, msgBytes, err := ws.ReadMessage()
-			if err != nil {
-				if strings.Contains(err.Error(), "close 1001") {
-					disconnectedHook, ok := r.hooks["disconnected"]
-					if ok {
-						disconnectedHook(connId)
-					}
-
-					break
-				} else {
-					c.Logger().Error(err)
-				}
-			}
+// actionDone reports the outcome of an Action sent over a connection.
+func (r *Runtime) actionDone(connId int, msg *Message, err error) {
+	if actionErr := actionErrorOf(err); actionErr != nil {
+		r.sendActionError(connId, msg, actionErr)
+	} else if err != nil && err != errUnknownHandler {
+		r.e.Logger.Errorf("request %v: %v", msg.RequestId, err)
+		r.metrics.Error(err)
+	}
+}
 
-			msg := &Message{}
+func (r *Runtime) callHandler(msg *Message, connId int) error {
+	if msg.RequestId == "" {
+		msg.RequestId = newRequestId()
+	}
+	handler, ok := r.handlers.get(msg.Handler)
+	if !ok {
+		return errUnknownHandler
+	}
+	return r.callWith(handler, msg, connId)
+}
 
-			err = json.Unmarshal(msgBytes, msg)
-			if err != nil {
-				// ignore
-			}
+// callWith runs handler for msg, handlers which are not registered by name
+// such as uploads call it directly.
+func (r *Runtime) callWith(handler *handler, msg *Message, connId int) error {
+	start := time.Now()
+	if msg.Optimistic != nil {
+		msg.Optimistic.connId = connId
+	}
+	err := r.runHandler(handler, msg, connId)
+	if err == errDebounced {
+		// reported and audited once it ran or got superseded
+		return nil
+	}
+	if r.audit != nil {
+		r.auditAction(msg, connId, time.Since(start), err)
+	}
+	return err
+}
 
-			if msg.Type == "Action" {
-				handler, ok := r.handlers[msg.Handler]
-				if ok {
-					handler(msg, connId)
-				}
-			}
+func (r *Runtime) runHandler(handler *handler, msg *Message, connId int) error {
+	if r.inMaintenance() {
+		return &ActionError{Code: "maintenance", Message: maintenanceMessage}
+	}
+	if limit := handler.maxStoreSize(r.maxStoreSize); limit > 0 && msg.storeSize > limit {
+		return &ActionError{
+			Code:    "store_too_large",
+			Message: fmt.Sprintf("store of %v bytes exceeds the limit of %v bytes for %v", msg.storeSize, limit, msg.Handler),
 		}
+	}
+	conn := r.conns.get(connId)
+	if err := r.beforeAction(conn, msg); err != nil {
+		return err
+	}
+	if err := handler.authorize(conn); err != nil {
+		return err
+	}
+	if err := handler.checkLimits(conn, msg); err != nil {
+		return err
+	}
+	if err := handler.validateParams(msg); err != nil {
+		return err
+	}
 
-		return nil
-	})
+	if handler.debounce > 0 && conn != nil {
+		r.debounceCall(handler, conn, msg)
+		return errDebounced
+	}
+	return r.invokeHandler(handler, conn, msg, connId)
+}
 
-	r.e.Logger.Fatal(r.e.Start(":8999"))
+func (r *Runtime) invokeHandler(handler *handler, conn *Conn, msg *Message, connId int) error {
+	if conn != nil {
+		conn.requestId.Store(msg.RequestId)
+		defer conn.requestId.Store("")
+	}
+
+	start := time.Now()
+	err := handler.fn(msg, connId)
+	r.metrics.HandlerDone(msg.Handler, time.Since(start), err)
+	return err
 }
 
 func (r *Runtime) LoadApp(builder *sunmao.AppBuilder) error {
 	r.appBuilder = builder
+	if r.incrementalReload {
+		r.servedComponents = snapshotComponents(builder)
+	}
+	r.invalidatePages()
 	return nil
 }
 
-func (r *Runtime) LoadModule(builder ...*sunmao.ModuleBuilder) error {
-	r.moduleBuilders = builder
-	return nil
-}
+// ReloadApp swaps the served application and asks connected clients to
+// reload the page. With WithIncrementalReload clients patch the changed
+// components in place instead.
+func (r *Runtime) ReloadApp(builder *sunmao.AppBuilder) error {
+	served := r.servedComponents
+	r.LoadApp(builder)
+
+	if r.incrementalReload && served != nil {
+		return r.send(map[string]interface{}{
+			"type":    "SchemaPatch",
+			"changes": sunmao.DiffComponents(served, r.servedComponents),
+		}, nil)
+	}
 
-func (r *Runtime) Handle(handler string, fn func(m *Message, connId int) error) {
-	r.handlers[handler] = fn
+	return r.send(map[string]interface{}{
+		"type": "Reload",
+	}, nil)
 }
 
-func (r *Runtime) On(hook string, fn func(connId int) error) {
-	r.hooks[hook] = fn
+func (r *Runtime) LoadModule(builder ...*sunmao.ModuleBuilder) error {
+	r.moduleBuilders = builder
+	r.invalidatePages()
+	return nil
 }
 
 type ExecuteTarget struct {
@@ -300,33 +645,66 @@ type ExecuteTarget struct {
 
 // maybe this is a bad idea, but currently we let connId == nil to represent broadcasting
 func (r *Runtime) Execute(target *ExecuteTarget, connId *int) error {
-	for id, ws := range r.conns {
-		if connId != nil && id != *connId {
-			continue
-		}
+	return r.send(map[string]interface{}{
+		"type":        "UiMethod",
+		"componentId": target.Id,
+		"name":        target.Method,
+		"parameters":  target.Parameters,
+	}, connId)
+}
 
-		msg, err := json.Marshal(map[string]interface{}{
-			"type":        "UiMethod",
-			"componentId": target.Id,
-			"name":        target.Method,
-			"parameters":  target.Parameters,
-		})
-		if err != nil {
-			return err
+// send writes a message to connId, or to every connection when it is nil.
+func (r *Runtime) send(message map[string]interface{}, connId *int) error {
+	var conns []*Conn
+	if connId != nil {
+		conn := r.conns.get(*connId)
+		if conn == nil {
+			return nil
 		}
-
-		err = ws.WriteMessage(websocket.TextMessage, msg)
-		if err != nil {
-			return err
+		conns = []*Conn{conn}
+		// ties ui methods executed by a handler to its request
+		if id, _ := conn.requestId.Load().(string); id != "" && message["requestId"] == nil {
+			message["requestId"] = id
 		}
+	} else {
+		conns = r.conns.list()
 	}
-	return nil
+	if len(conns) == 0 {
+		return nil
+	}
+
+	// encode once, write many
+	return withEncoded(message, func(msg []byte) error {
+		if connId != nil {
+			return r.enqueueMessage(conns[0], msg)
+		}
+		for _, conn := range conns {
+			// a connection closing concurrently is not an error for broadcasts
+			if err := r.enqueueMessage(conn, msg); err != nil && err != errConnClosed {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 type ServerState struct {
 	r         *Runtime
 	initState any
 	Id        string
+	// seq hands out versions to SetState calls, version is the one of the
+	// last broadcast. Clients send theirs back with optimistic edits, those
+	// served a per connection SetState since hold a later one, see versionOf.
+	seq     uint64
+	version uint64
+	// value is the last broadcast state, observers run after it changed
+	valueMu   sync.RWMutex
+	value     any
+	observers []func(value any)
+	// latest counts the sources keeping clients served later up to date,
+	// see ServeLatest
+	latest     int32
+	latestOnce sync.Once
 }
 
 func (r *Runtime) NewServerState(id string, initState any) *ServerState {
@@ -334,22 +712,115 @@ func (r *Runtime) NewServerState(id string, initState any) *ServerState {
 		r:         r,
 		initState: initState,
 		Id:        id,
+		value:     initState,
 	}
 }
 
 func (s *ServerState) AsComponent() sunmao.BaseComponentBuilder {
+	return s.component()
+}
+
+// component is the dummy holding the state and version keys, wrappers
+// add keys of their own.
+func (s *ServerState) component() *sunmao.ComponentBuilder {
 	t := s.r.appBuilder.NewComponent().Type("core/v1/dummy").Id(s.Id).
 		Trait(
 			s.r.appBuilder.NewTrait().Type("core/v1/state").
 				Properties(map[string]interface{}{
 					"key":          "state",
 					"initialValue": s.initState,
+				})).
+		Trait(
+			s.r.appBuilder.NewTrait().Type("core/v1/state").
+				Properties(map[string]interface{}{
+					"key":          "version",
+					"initialValue": 0,
 				}))
 	return t
 }
 
 func (s *ServerState) SetState(newState any, connId *int) error {
-	return s.r.Execute(&ExecuteTarget{
+	version := s.nextVersion()
+	if connId == nil {
+		s.broadcastVersion(version)
+	} else {
+		s.connVersion(*connId, version)
+	}
+	err := s.setState(newState, version, connId)
+	if connId == nil {
+		s.changed(newState)
+	}
+	return err
+}
+
+func (s *ServerState) nextVersion() uint64 {
+	return atomic.AddUint64(&s.seq, 1)
+}
+
+// broadcastVersion records version as the last broadcast unless a later
+// broadcast got there first.
+func (s *ServerState) broadcastVersion(version uint64) {
+	for {
+		last := atomic.LoadUint64(&s.version)
+		if version <= last || atomic.CompareAndSwapUint64(&s.version, last, version) {
+			return
+		}
+	}
+}
+
+// connVersion records version as pushed to connId alone, it lives as long
+// as the connection.
+func (s *ServerState) connVersion(connId int, version uint64) {
+	conn := s.r.conns.get(connId)
+	if conn == nil {
+		return
+	}
+	conn.versionMu.Lock()
+	defer conn.versionMu.Unlock()
+
+	if conn.versions == nil {
+		conn.versions = map[string]uint64{}
+	}
+	conn.versions[s.Id] = version
+}
+
+// Version is the version of the last broadcast SetState.
+func (s *ServerState) Version() uint64 {
+	return atomic.LoadUint64(&s.version)
+}
+
+// versionOf is the version connId holds, the one of a per connection
+// SetState made after the last broadcast or else the broadcast one.
+func (s *ServerState) versionOf(connId int) uint64 {
+	version := s.Version()
+	conn := s.r.conns.get(connId)
+	if conn == nil {
+		return version
+	}
+	conn.versionMu.Lock()
+	defer conn.versionMu.Unlock()
+
+	if v := conn.versions[s.Id]; v > version {
+		return v
+	}
+	return version
+}
+
+// serveBroadcast pushes the last broadcast value to connId, replacing what
+// per connection SetState calls showed it.
+func (s *ServerState) serveBroadcast(value any, connId int) error {
+	if conn := s.r.conns.get(connId); conn != nil {
+		conn.versionMu.Lock()
+		delete(conn.versions, s.Id)
+		conn.versionMu.Unlock()
+	}
+	return s.setState(value, s.Version(), &connId)
+}
+
+// setState sends the state before the version, so a client never sees a
+// version whose state has not arrived yet.
+func (s *ServerState) setState(newState any, version uint64, connId *int) error {
+	err := s.r.Execute(&ExecuteTarget{
 		Id:     s.Id,
 		Method: "setValue",
 		Parameters: map[string]interface{}{
@@ -357,4 +828,15 @@ func (s *ServerState) SetState(newState any, connId *int) error {
 			"value": newState,
 		},
 	}, connId)
+	if err != nil {
+		return err
+	}
+	return s.r.Execute(&ExecuteTarget{
+		Id:     s.Id,
+		Method: "setValue",
+		Parameters: map[string]interface{}{
+			"key":   "version",
+			"value": version,
+		},
+	}, connId)
 }
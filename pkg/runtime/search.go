@@ -0,0 +1,123 @@
+package runtime
+
+import (
+	"context"
+	"sync"
+)
+
+// SearchResult is one hit shown by the binding/v1/search component.
+type SearchResult struct {
+	Id          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	// Group heads the results of a kind, such as "Users" or "Orders".
+	Group string `json:"group,omitempty"`
+	Data  any    `json:"data,omitempty"`
+}
+
+// Searcher is one source of search results. ctx is cancelled once the
+// client typed on, so slow sources can stop early.
+type Searcher interface {
+	Search(ctx context.Context, query string) ([]SearchResult, error)
+}
+
+type SearchFunc func(ctx context.Context, query string) ([]SearchResult, error)
+
+func (f SearchFunc) Search(ctx context.Context, query string) ([]SearchResult, error) {
+	return f(ctx, query)
+}
+
+type search struct {
+	r       *Runtime
+	id      string
+	sources []Searcher
+	mu      sync.Mutex
+	// cancel stops the running query of a connection
+	cancel map[int]context.CancelFunc
+}
+
+// Search serves the binding/v1/search components with the given id, see
+// sunmao.NewSearchBox. Every source is queried concurrently and streams
+// its results to the client as soon as it is done, results of a query the
+// client moved on from are dropped. onSelect runs for the picked result.
+func (r *Runtime) Search(id string, onSelect func(conn *Conn, result SearchResult) error, sources ...Searcher) error {
+	s := &search{r: r, id: id, sources: sources, cancel: map[int]context.CancelFunc{}}
+
+	if err := r.Handle(id+"/query", s.handleQuery); err != nil {
+		return err
+	}
+	return r.Handle(id+"/select", func(m *Message, connId int) error {
+		conn := r.conns.get(connId)
+		if conn == nil {
+			return nil
+		}
+		params, err := decodeParams[struct {
+			Result SearchResult `json:"result"`
+		}](m)
+		if err != nil {
+			return err
+		}
+		return onSelect(conn, params.Result)
+	})
+}
+
+func (s *search) handleQuery(m *Message, connId int) error {
+	params, _ := m.Params.(map[string]any)
+	query, _ := params["query"].(string)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.mu.Lock()
+	if prev, ok := s.cancel[connId]; ok {
+		prev()
+	}
+	s.cancel[connId] = cancel
+	s.mu.Unlock()
+
+	// the read loop goes on while the sources run
+	go s.run(ctx, cancel, connId, query)
+	return nil
+}
+
+func (s *search) run(ctx context.Context, cancel context.CancelFunc, connId int, query string) {
+	wg := sync.WaitGroup{}
+	for _, source := range s.sources {
+		wg.Add(1)
+		go func(source Searcher) {
+			defer wg.Done()
+			results, err := source.Search(ctx, query)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				s.r.e.Logger.Errorf("search %v: %v", s.id, err)
+				return
+			}
+			s.send(connId, query, results, false)
+		}(source)
+	}
+	wg.Wait()
+
+	if ctx.Err() == nil {
+		s.send(connId, query, []SearchResult{}, true)
+	}
+
+	s.mu.Lock()
+	if ctx.Err() == nil {
+		delete(s.cancel, connId)
+	}
+	s.mu.Unlock()
+	cancel()
+}
+
+func (s *search) send(connId int, query string, results []SearchResult, done bool) {
+	err := s.r.send(map[string]interface{}{
+		"type":    "SearchResults",
+		"search":  s.id,
+		"query":   query,
+		"results": results,
+		"done":    done,
+	}, &connId)
+	if err != nil && err != errConnClosed {
+		s.r.e.Logger.Error(err)
+	}
+}
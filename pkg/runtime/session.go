@@ -0,0 +1,320 @@
+package runtime
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	sessionCookie      = "sunmao_session"
+	identityContextKey = "sunmao.identity"
+)
+
+var errInvalidSession = errors.New("invalid session")
+
+// Identity is the authenticated user behind a request or connection.
+type Identity struct {
+	Id     string         `json:"id"`
+	Name   string         `json:"name,omitempty"`
+	Roles  []string       `json:"roles,omitempty"`
+	Claims map[string]any `json:"claims,omitempty"`
+}
+
+// HasRole reports whether the identity was granted role.
+func (i *Identity) HasRole(role string) bool {
+	if i == nil {
+		return false
+	}
+	for _, r := range i.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// SessionStore persists the identity of a browser between requests.
+type SessionStore interface {
+	Load(c echo.Context) (*Identity, error)
+	Save(c echo.Context, identity *Identity) error
+	Clear(c echo.Context) error
+}
+
+// WithSessions resolves the identity of every request, including the
+// websocket upgrade, from store.
+func WithSessions(store SessionStore) Option {
+	return func(r *Runtime) {
+		r.sessions = store
+	}
+}
+
+func (r *Runtime) sessionMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			identity, err := r.sessions.Load(c)
+			if err == nil && identity != nil {
+				c.Set(identityContextKey, identity)
+			}
+			return next(c)
+		}
+	}
+}
+
+// Identity returns the identity of the request, or nil if anonymous.
+func (r *Runtime) Identity(c echo.Context) *Identity {
+	identity, _ := c.Get(identityContextKey).(*Identity)
+	return identity
+}
+
+// Login stores identity in the session of the request.
+func (r *Runtime) Login(c echo.Context, identity *Identity) error {
+	if r.sessions == nil {
+		return errors.New("sessions are not enabled")
+	}
+	c.Set(identityContextKey, identity)
+	return r.sessions.Save(c, identity)
+}
+
+// Logout clears the session of the request and closes the websocket
+// connections opened with it.
+func (r *Runtime) Logout(c echo.Context) error {
+	if r.sessions == nil {
+		return nil
+	}
+	session := sessionOf(c)
+	c.Set(identityContextKey, nil)
+	if err := r.sessions.Clear(c); err != nil {
+		return err
+	}
+	if session != "" {
+		r.closeSession(session)
+	}
+	return nil
+}
+
+// sessionOf is the session cookie of the request, empty without one.
+func sessionOf(c echo.Context) string {
+	cookie, err := c.Cookie(sessionCookie)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+// closeSession closes the connections of session on r and its mounts, the
+// read loops notice and clean up.
+func (r *Runtime) closeSession(session string) {
+	for _, m := range append([]*Runtime{r}, r.mounts...) {
+		for _, conn := range m.conns.list() {
+			if conn.session != session {
+				continue
+			}
+			conn.ws.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, "logged out"),
+				time.Now().Add(time.Second))
+			conn.ws.Close()
+		}
+	}
+}
+
+type cookieOptions struct {
+	path   string
+	maxAge time.Duration
+}
+
+func (o *cookieOptions) cookie(c echo.Context, value string) *http.Cookie {
+	path := o.path
+	if path == "" {
+		path = "/"
+	}
+	return &http.Cookie{
+		Name:     sessionCookie,
+		Value:    value,
+		Path:     path,
+		MaxAge:   int(o.maxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   c.Scheme() == "https",
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+func (o *cookieOptions) clear(c echo.Context) {
+	cookie := o.cookie(c, "")
+	cookie.MaxAge = -1
+	c.SetCookie(cookie)
+}
+
+type cookiePayload struct {
+	Identity *Identity `json:"identity"`
+	Expires  int64     `json:"expires"`
+}
+
+// CookieSessionStore keeps the identity in a HMAC signed cookie, so no
+// server side state is needed.
+type CookieSessionStore struct {
+	cookieOptions
+	secret []byte
+}
+
+// minCookieSecret is the shortest secret accepted for signing cookies, a
+// short one makes them forgeable.
+const minCookieSecret = 32
+
+// NewCookieSessionStore signs cookies with secret, which must be at least
+// 32 random bytes.
+func NewCookieSessionStore(secret []byte, maxAge time.Duration) (*CookieSessionStore, error) {
+	if len(secret) < minCookieSecret {
+		return nil, fmt.Errorf("cookie session secret must be at least %v bytes, got %v", minCookieSecret, len(secret))
+	}
+	return &CookieSessionStore{
+		cookieOptions: cookieOptions{maxAge: maxAge},
+		secret:        secret,
+	}, nil
+}
+
+func (s *CookieSessionStore) sign(payload string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *CookieSessionStore) Load(c echo.Context) (*Identity, error) {
+	cookie, err := c.Cookie(sessionCookie)
+	if err != nil {
+		return nil, nil
+	}
+
+	payload, sig, ok := strings.Cut(cookie.Value, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(s.sign(payload))) {
+		return nil, errInvalidSession
+	}
+
+	buf, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &cookiePayload{}
+	if err := json.Unmarshal(buf, p); err != nil {
+		return nil, err
+	}
+	if time.Now().Unix() > p.Expires {
+		return nil, errInvalidSession
+	}
+	return p.Identity, nil
+}
+
+func (s *CookieSessionStore) Save(c echo.Context, identity *Identity) error {
+	buf, err := json.Marshal(&cookiePayload{
+		Identity: identity,
+		Expires:  time.Now().Add(s.maxAge).Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(buf)
+	c.SetCookie(s.cookie(c, payload+"."+s.sign(payload)))
+	return nil
+}
+
+func (s *CookieSessionStore) Clear(c echo.Context) error {
+	s.clear(c)
+	return nil
+}
+
+type memorySession struct {
+	identity *Identity
+	expires  time.Time
+}
+
+// MemorySessionStore keeps identities in process memory and only a random
+// session id in the cookie, sessions are lost on restart.
+type MemorySessionStore struct {
+	cookieOptions
+	mu       sync.Mutex
+	sessions map[string]*memorySession
+	// swept is when expired sessions were last purged
+	swept time.Time
+}
+
+// memorySweepInterval bounds how often Save purges expired sessions, which
+// are otherwise only dropped when their cookie comes back.
+const memorySweepInterval = time.Minute
+
+func NewMemorySessionStore(maxAge time.Duration) *MemorySessionStore {
+	return &MemorySessionStore{
+		cookieOptions: cookieOptions{maxAge: maxAge},
+		sessions:      map[string]*memorySession{},
+	}
+}
+
+func (s *MemorySessionStore) Load(c echo.Context) (*Identity, error) {
+	cookie, err := c.Cookie(sessionCookie)
+	if err != nil {
+		return nil, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[cookie.Value]
+	if !ok {
+		return nil, errInvalidSession
+	}
+	if time.Now().After(session.expires) {
+		delete(s.sessions, cookie.Value)
+		return nil, errInvalidSession
+	}
+	return session.identity, nil
+}
+
+func (s *MemorySessionStore) Save(c echo.Context, identity *Identity) error {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return err
+	}
+	id := hex.EncodeToString(buf)
+
+	now := time.Now()
+	s.mu.Lock()
+	if now.Sub(s.swept) > memorySweepInterval {
+		for id, session := range s.sessions {
+			if now.After(session.expires) {
+				delete(s.sessions, id)
+			}
+		}
+		s.swept = now
+	}
+	s.sessions[id] = &memorySession{
+		identity: identity,
+		expires:  now.Add(s.maxAge),
+	}
+	s.mu.Unlock()
+
+	c.SetCookie(s.cookie(c, id))
+	return nil
+}
+
+func (s *MemorySessionStore) Clear(c echo.Context) error {
+	if cookie, err := c.Cookie(sessionCookie); err == nil {
+		s.mu.Lock()
+		delete(s.sessions, cookie.Value)
+		s.mu.Unlock()
+	}
+	s.clear(c)
+	return nil
+}
@@ -0,0 +1,168 @@
+package runtime
+
+import (
+	"errors"
+
+	"github.com/labstack/echo/v4"
+	"github.com/yuyz0112/sunmao-ui-go-binding/pkg/sunmao"
+)
+
+// UserProfile is the part of a user the settings page edits.
+type UserProfile struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// UserStore loads and saves the users behind a settings page, userId is
+// the Identity.Id of the connection.
+type UserStore interface {
+	Profile(userId string) (*UserProfile, error)
+	UpdateProfile(userId string, profile *UserProfile) error
+	// ChangePassword returns ErrInvalidCredentials when current is wrong.
+	ChangePassword(userId, current, next string) error
+}
+
+type settingsState struct {
+	Profile     *UserProfile   `json:"profile"`
+	Preferences map[string]any `json:"preferences"`
+	Message     string         `json:"message"`
+}
+
+type passwordChange struct {
+	Current string `json:"current"`
+	Next    string `json:"next"`
+	Confirm string `json:"confirm"`
+}
+
+// Settings is the ServerState behind sunmao.NewSettingsPage, each client
+// sees the profile of its identity and its own preferences.
+type Settings struct {
+	*ServerState
+	store UserStore
+	prefs []sunmao.SettingsPreference
+}
+
+// NewSettings serves the settings page of id from store and declares the
+// preferences it edits. Anonymous clients are rejected with ForbiddenCode.
+func NewSettings(r *Runtime, id string, store UserStore, prefs ...sunmao.SettingsPreference) (*Settings, error) {
+	s := &Settings{
+		ServerState: r.NewServerState(id, &settingsState{Profile: &UserProfile{}, Preferences: map[string]any{}}),
+		store:       store,
+		prefs:       prefs,
+	}
+	for _, p := range prefs {
+		r.Preference(p.Key, p.Default)
+	}
+
+	r.OnHydrate(func(c echo.Context, h *Hydration) error {
+		identity := r.Identity(c)
+		if identity == nil {
+			return nil
+		}
+		profile, err := store.Profile(identity.Id)
+		if err != nil {
+			return err
+		}
+		// preferences live in the browser, they arrive with the connection
+		h.Set(s.ServerState, &settingsState{Profile: profile, Preferences: map[string]any{}})
+		return nil
+	})
+	r.OnAppServed(func(conn *Conn) {
+		if err := s.push(conn, ""); err != nil && err != errConnClosed {
+			r.e.Logger.Error(err)
+		}
+	})
+
+	if err := r.Handle(id+"/profile", s.handleProfile, ParamsOf[UserProfile]()); err != nil {
+		return nil, err
+	}
+	return s, r.Handle(id+"/password", s.handlePassword, ParamsOf[passwordChange]())
+}
+
+// Module is the settings page module, load it next to the application's
+// own modules.
+func (s *Settings) Module() *sunmao.ModuleBuilder {
+	return sunmao.NewSettingsModule(s.Id, s.prefs...)
+}
+
+func (s *Settings) handleProfile(m *Message, connId int) error {
+	conn, err := s.identified(connId)
+	if err != nil || conn == nil {
+		return err
+	}
+	profile, err := decodeParams[UserProfile](m)
+	if err != nil {
+		return err
+	}
+	if err := s.store.UpdateProfile(conn.Identity.Id, &profile); err != nil {
+		return err
+	}
+
+	// the other tabs of the user show the new profile as well
+	for _, c := range s.r.UserConns(conn.Identity.Id) {
+		message := ""
+		if c.Id == connId {
+			message = "Profile saved"
+		}
+		if err := s.push(c, message); err != nil && err != errConnClosed {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Settings) handlePassword(m *Message, connId int) error {
+	conn, err := s.identified(connId)
+	if err != nil || conn == nil {
+		return err
+	}
+	change, err := decodeParams[passwordChange](m)
+	if err != nil {
+		return err
+	}
+	if change.Next == "" || change.Next != change.Confirm {
+		return &ActionError{Code: "password_mismatch", Message: "the new passwords do not match"}
+	}
+	err = s.store.ChangePassword(conn.Identity.Id, change.Current, change.Next)
+	if errors.Is(err, ErrInvalidCredentials) {
+		return &ActionError{Code: "invalid_credentials", Message: "the current password is wrong"}
+	}
+	if err != nil {
+		return err
+	}
+	return s.push(conn, "Password changed")
+}
+
+// identified returns the open connection of connId, settings belong to an
+// identity so anonymous calls are forbidden.
+func (s *Settings) identified(connId int) (*Conn, error) {
+	conn := s.r.conns.get(connId)
+	if conn == nil {
+		return nil, nil
+	}
+	if conn.Identity == nil {
+		return nil, &ActionError{Code: ForbiddenCode, Message: "sign in to change settings"}
+	}
+	return conn, nil
+}
+
+func (s *Settings) push(conn *Conn, message string) error {
+	if conn.Identity == nil {
+		return nil
+	}
+	profile, err := s.store.Profile(conn.Identity.Id)
+	if err != nil {
+		return err
+	}
+	prefs := map[string]any{}
+	for _, p := range s.prefs {
+		if v := conn.Preference(p.Key); v != nil {
+			prefs[p.Key] = v
+		} else {
+			prefs[p.Key] = p.Default
+		}
+	}
+
+	connId := conn.Id
+	return s.SetState(&settingsState{Profile: profile, Preferences: prefs, Message: message}, &connId)
+}
@@ -0,0 +1,101 @@
+package runtime
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// Edit is a change a client made to a SharedState.
+type Edit[E any] struct {
+	ConnId int
+	// BaseVersion is the version the client saw when it made the edit,
+	// lower than the current version when another edit won the race.
+	BaseVersion uint64
+	Value       E
+}
+
+// Reducer applies an edit to the current state. Edits reach it one at a
+// time in arrival order, so the merged state is the same for everyone. A
+// non nil error rejects the edit and resyncs the editing client.
+type Reducer[S, E any] func(state S, edit Edit[E]) (S, error)
+
+// SharedState is a ServerState every client may edit. Edits are applied
+// through a server side reducer and the merged state is broadcast with
+// an increasing version, instead of the last writer clobbering the rest.
+type SharedState[S, E any] struct {
+	*ServerState
+	mu     sync.Mutex
+	state  S
+	reduce Reducer[S, E]
+}
+
+// NewSharedState registers the edit handler of the state, clients send
+// {"version": n, "edit": E} to EditHandler().
+func NewSharedState[S, E any](r *Runtime, id string, initState S, reduce Reducer[S, E]) (*SharedState[S, E], error) {
+	s := &SharedState[S, E]{
+		ServerState: r.NewServerState(id, initState),
+		state:       initState,
+		reduce:      reduce,
+	}
+	if err := r.Handle(s.EditHandler(), s.handleEdit); err != nil {
+		return nil, err
+	}
+	// the page carries the initial state, catch late comers up
+	r.OnAppServed(func(conn *Conn) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.serveBroadcast(s.state, conn.Id)
+	})
+	return s, nil
+}
+
+// EditHandler is the handler name to bind edit events to.
+func (s *SharedState[S, E]) EditHandler() string {
+	return s.Id + "/edit"
+}
+
+// Get returns the merged state and its version.
+func (s *SharedState[S, E]) Get() (S, uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.state, s.Version()
+}
+
+// Update applies fn as a server side edit and broadcasts the result.
+func (s *SharedState[S, E]) Update(fn func(state S) S) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state = fn(s.state)
+	return s.SetState(s.state, nil)
+}
+
+func (s *SharedState[S, E]) handleEdit(m *Message, connId int) error {
+	params, err := decodeParams[struct {
+		Version uint64          `json:"version"`
+		Edit    json.RawMessage `json:"edit"`
+	}](m)
+	if err != nil {
+		return err
+	}
+	edit := Edit[E]{ConnId: connId, BaseVersion: params.Version}
+	if len(params.Edit) > 0 {
+		if err := json.Unmarshal(params.Edit, &edit.Value); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next, err := s.reduce(s.state, edit)
+	if err != nil {
+		// the client may already show its edit, put it back in line
+		s.serveBroadcast(s.state, connId)
+		return &ActionError{Code: "edit_rejected", Message: err.Error()}
+	}
+	s.state = next
+	return s.SetState(s.state, nil)
+}
@@ -0,0 +1,57 @@
+package runtime
+
+import (
+	"strings"
+	"sync"
+)
+
+type shortcutRegistry struct {
+	mu   sync.RWMutex
+	keys map[string]string
+}
+
+func newShortcutRegistry() *shortcutRegistry {
+	return &shortcutRegistry{keys: map[string]string{}}
+}
+
+func (s *shortcutRegistry) all() map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make(map[string]string, len(s.keys))
+	for k, v := range s.keys {
+		keys[k] = v
+	}
+	return keys
+}
+
+// normalizeKeys lower cases a combination such as "Ctrl+K" and drops
+// whitespace, "mod" stands for cmd on macOS and ctrl elsewhere.
+func normalizeKeys(keys string) string {
+	return strings.ToLower(strings.ReplaceAll(keys, " ", ""))
+}
+
+// Shortcut binds a key combination such as "ctrl+k" or "mod+shift+p" to
+// a handler, which receives {"shortcut": keys} as params. A nil connId
+// binds it for every current and future client, otherwise only for that
+// connection. An empty handler removes the binding.
+func (r *Runtime) Shortcut(connId *int, keys string, handler string) error {
+	keys = normalizeKeys(keys)
+
+	if connId == nil {
+		r.shortcuts.mu.Lock()
+		if handler == "" {
+			delete(r.shortcuts.keys, keys)
+		} else {
+			r.shortcuts.keys[keys] = handler
+		}
+		r.shortcuts.mu.Unlock()
+		r.invalidatePages()
+	}
+
+	return r.send(map[string]interface{}{
+		"type":    "Shortcut",
+		"keys":    keys,
+		"handler": handler,
+	}, connId)
+}
@@ -0,0 +1,77 @@
+package sshterm
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// recorder writes a session as an asciicast v2 file, one json array per
+// event after the header line. Play it with asciinema play.
+type recorder struct {
+	mu    sync.Mutex
+	w     io.WriteCloser
+	buf   *bufio.Writer
+	start time.Time
+	err   error
+}
+
+func newRecorder(w io.WriteCloser, cols, rows int, term string) (*recorder, error) {
+	r := &recorder{w: w, buf: bufio.NewWriter(w), start: time.Now()}
+	header, err := json.Marshal(map[string]interface{}{
+		"version":   2,
+		"width":     cols,
+		"height":    rows,
+		"timestamp": r.start.Unix(),
+		"env":       map[string]string{"TERM": term},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := r.line(header); err != nil {
+		w.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// event records data of kind "o" for output, "i" for input or "r" for a
+// resize. A failed write stops the recording, the session goes on.
+func (r *recorder) event(kind string, data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return
+	}
+	elapsed := time.Since(r.start).Seconds()
+	buf, err := json.Marshal([]interface{}{elapsed, kind, string(data)})
+	if err == nil {
+		err = r.line(buf)
+	}
+	r.err = err
+}
+
+func (r *recorder) line(buf []byte) error {
+	if _, err := r.buf.Write(buf); err != nil {
+		return err
+	}
+	return r.buf.WriteByte('\n')
+}
+
+// Close flushes the recording and closes its writer.
+func (r *recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.buf.Flush()
+	if closeErr := r.w.Close(); err == nil {
+		err = closeErr
+	}
+	if r.err != nil {
+		return r.err
+	}
+	return err
+}
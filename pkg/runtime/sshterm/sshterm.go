@@ -0,0 +1,195 @@
+// Package sshterm bridges SSH sessions to the binding/v1/terminal
+// component, through any number of jump hosts, for bastion style tools.
+// Credentials are looked up per connection and sessions can be recorded
+// as asciicast files.
+package sshterm
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/yuyz0112/sunmao-ui-go-binding/pkg/runtime"
+	"golang.org/x/crypto/ssh"
+)
+
+// Credentials returns the user and auth methods conn logs in to addr
+// with, e.g. a key picked by the connection's identity. An error denies
+// the session.
+type Credentials func(conn *runtime.Conn, addr string) (user string, auth []ssh.AuthMethod, err error)
+
+type Config struct {
+	// Route returns the host:port addresses to go through for conn, jump
+	// hosts first and the target last.
+	Route           func(conn *runtime.Conn) ([]string, error)
+	Credentials     Credentials
+	HostKeyCallback ssh.HostKeyCallback
+	// Term is the terminal type of the pty, "xterm" by default.
+	Term string
+	// Timeout bounds dialing each hop, 10 seconds by default.
+	Timeout time.Duration
+	// Record, if set, opens the recording of a session of conn. The output
+	// is written in the asciicast v2 format, input only with RecordInput as
+	// it holds the passwords typed into the session.
+	Record      func(conn *runtime.Conn) (io.WriteCloser, error)
+	RecordInput bool
+}
+
+// NewTerminal serves the ssh sessions of config under id, show them with
+// sunmao.NewTerminal(id).
+func NewTerminal(r *runtime.Runtime, id string, config Config) (*runtime.Terminal, error) {
+	if config.Route == nil || config.Credentials == nil {
+		return nil, errors.New("sshterm: Route and Credentials are required")
+	}
+	if config.HostKeyCallback == nil {
+		return nil, errors.New("sshterm: HostKeyCallback is required")
+	}
+	if config.Term == "" {
+		config.Term = "xterm"
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 10 * time.Second
+	}
+	return runtime.NewTerminal(r, id, func(conn *runtime.Conn, _ map[string]any, cols, rows int) (runtime.TerminalSession, error) {
+		return open(&config, conn, cols, rows)
+	})
+}
+
+type session struct {
+	// clients are the hops in dial order, closed in reverse
+	clients  []*ssh.Client
+	session  *ssh.Session
+	stdin    io.WriteCloser
+	stdout   io.Reader
+	recorder *recorder
+	input    bool
+}
+
+func open(config *Config, conn *runtime.Conn, cols, rows int) (s *session, err error) {
+	route, err := config.Route(conn)
+	if err != nil {
+		return nil, err
+	}
+	if len(route) == 0 {
+		return nil, errors.New("sshterm: empty route")
+	}
+
+	s = &session{input: config.RecordInput}
+	defer func() {
+		if err != nil {
+			s.Close()
+		}
+	}()
+
+	for _, addr := range route {
+		user, auth, err := config.Credentials(conn, addr)
+		if err != nil {
+			return nil, err
+		}
+		client, err := dial(s.last(), addr, &ssh.ClientConfig{
+			User:            user,
+			Auth:            auth,
+			HostKeyCallback: config.HostKeyCallback,
+			Timeout:         config.Timeout,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("ssh %v: %w", addr, err)
+		}
+		s.clients = append(s.clients, client)
+	}
+
+	if s.session, err = s.last().NewSession(); err != nil {
+		return nil, err
+	}
+	if s.stdin, err = s.session.StdinPipe(); err != nil {
+		return nil, err
+	}
+	if s.stdout, err = s.session.StdoutPipe(); err != nil {
+		return nil, err
+	}
+	// with a pty the remote side merges stderr into stdout already
+	s.session.Stderr = io.Discard
+	modes := ssh.TerminalModes{ssh.ECHO: 1}
+	if err := s.session.RequestPty(config.Term, rows, cols, modes); err != nil {
+		return nil, err
+	}
+	if err := s.session.Shell(); err != nil {
+		return nil, err
+	}
+
+	if config.Record != nil {
+		w, err := config.Record(conn)
+		if err != nil {
+			return nil, err
+		}
+		if s.recorder, err = newRecorder(w, cols, rows, config.Term); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// dial connects to addr directly or, through via, from the previous hop.
+func dial(via *ssh.Client, addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	if via == nil {
+		return ssh.Dial("tcp", addr, config)
+	}
+	nc, err := via.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	// Timeout only covers direct dials, the handshake gets a deadline too
+	_ = nc.SetDeadline(time.Now().Add(config.Timeout))
+	c, chans, reqs, err := ssh.NewClientConn(nc, addr, config)
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	_ = nc.SetDeadline(time.Time{})
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+func (s *session) last() *ssh.Client {
+	if len(s.clients) == 0 {
+		return nil
+	}
+	return s.clients[len(s.clients)-1]
+}
+
+func (s *session) Read(p []byte) (int, error) {
+	n, err := s.stdout.Read(p)
+	if n > 0 && s.recorder != nil {
+		s.recorder.event("o", p[:n])
+	}
+	return n, err
+}
+
+func (s *session) Write(p []byte) (int, error) {
+	if s.input && s.recorder != nil {
+		s.recorder.event("i", p)
+	}
+	return s.stdin.Write(p)
+}
+
+func (s *session) Resize(cols, rows int) error {
+	if s.recorder != nil {
+		s.recorder.event("r", []byte(fmt.Sprintf("%vx%v", cols, rows)))
+	}
+	return s.session.WindowChange(rows, cols)
+}
+
+// Close ends the session and the connections to every hop, errors of a
+// session already ended by the remote side are not reported.
+func (s *session) Close() error {
+	if s.session != nil {
+		s.session.Close()
+	}
+	for i := len(s.clients) - 1; i >= 0; i-- {
+		s.clients[i].Close()
+	}
+	if s.recorder != nil {
+		return s.recorder.Close()
+	}
+	return nil
+}
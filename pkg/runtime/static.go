@@ -0,0 +1,99 @@
+package runtime
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ExportStatic renders index.html with the options payload baked in and
+// copies the ui assets into outDir. The result does not open a websocket,
+// so it only suits apps without server handlers or server states.
+func (r *Runtime) ExportStatic(outDir string) error {
+	if r.appBuilder == nil {
+		return errAppNotLoaded
+	}
+
+	options, err := r.uiOptions()
+	if err != nil {
+		return err
+	}
+	options["wsUrl"] = ""
+	options["reloadWhenWsDisconnected"] = false
+	options["handlers"] = []string{}
+
+	if err := os.MkdirAll(outDir, os.ModePerm); err != nil {
+		return err
+	}
+
+	buf := &bytes.Buffer{}
+	if err := r.renderPage(buf, "index.html", options); err != nil {
+		return err
+	}
+	html, err := r.renderHead(buf.Bytes(), r.indexData())
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(outDir, "index.html"), html, 0644); err != nil {
+		return err
+	}
+
+	if r.pwa != nil {
+		manifest, err := r.manifest()
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(outDir, manifestPath), manifest, 0644); err != nil {
+			return err
+		}
+		if r.pwa.ServiceWorker {
+			if err := os.WriteFile(filepath.Join(outDir, serviceWorkerPath), []byte(serviceWorkerScript), 0644); err != nil {
+				return err
+			}
+		}
+	}
+
+	return copyDir(fmt.Sprintf("%v/dist/assets", r.uiDir), filepath.Join(outDir, "assets"))
+}
+
+func copyDir(src string, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		if d.IsDir() {
+			return os.MkdirAll(target, os.ModePerm)
+		}
+		return copyFile(path, target)
+	})
+}
+
+func copyFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	// a failed close may lose buffered writes, report it
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
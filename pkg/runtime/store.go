@@ -0,0 +1,18 @@
+package runtime
+
+import "encoding/json"
+
+// DecodeStore converts the client store sent with a message into T, whose
+// fields are usually the component ids declared with StoreKeys.
+func DecodeStore[T any](m *Message) (*T, error) {
+	buf, err := json.Marshal(m.Store)
+	if err != nil {
+		return nil, err
+	}
+
+	v := new(T)
+	if err := json.Unmarshal(buf, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
@@ -0,0 +1,224 @@
+// Package stream maps messages consumed from Kafka topics or NATS subjects
+// to the runtime. It does not depend on a broker client: the consumer the
+// app already runs hands its messages to Bridge.Consume.
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yuyz0112/sunmao-ui-go-binding/pkg/runtime"
+)
+
+// Message is a record read from a Kafka topic or a NATS subject.
+type Message struct {
+	// Subject is the NATS subject or the Kafka topic.
+	Subject string
+	// Key is the Kafka record key, nil for NATS.
+	Key     []byte
+	Value   []byte
+	Headers map[string]string
+	Time    time.Time
+}
+
+// Update is one effect of a message, set any of its fields.
+type Update struct {
+	// State is broadcast Value.
+	State *runtime.ServerState
+	// Execute calls a component method on every client.
+	Execute *runtime.ExecuteTarget
+	// Topic delivers Value to the connections subscribed to it whose filter
+	// accepts it.
+	Topic string
+	Value any
+}
+
+// Transform turns a message into its updates, none to skip it.
+type Transform func(m Message) ([]Update, error)
+
+type route struct {
+	pattern   string
+	transform Transform
+}
+
+type subscription struct {
+	connId  int
+	topic   string
+	filter  func(value any) bool
+	deliver func(value any) error
+}
+
+// Bridge applies the updates of the messages it consumes.
+type Bridge struct {
+	r      *runtime.Runtime
+	mu     sync.RWMutex
+	routes []route
+	subs   map[*subscription]struct{}
+	// served are the states updates went to, clients served later get
+	// their last value
+	served map[*runtime.ServerState]struct{}
+}
+
+func NewBridge(r *runtime.Runtime) *Bridge {
+	b := &Bridge{r: r, subs: map[*subscription]struct{}{}, served: map[*runtime.ServerState]struct{}{}}
+	r.OnDisconnected(func(conn *runtime.Conn, _ runtime.CloseReason) {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		for s := range b.subs {
+			if s.connId == conn.Id {
+				delete(b.subs, s)
+			}
+		}
+	})
+	return b
+}
+
+// Route transforms the messages whose subject matches pattern, in which
+// "*" matches one dot separated token and a trailing ">" the rest, as in
+// NATS. Every matching route runs.
+func (b *Bridge) Route(pattern string, transform Transform) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.routes = append(b.routes, route{pattern: pattern, transform: transform})
+}
+
+// Subscribe delivers the values of topic accepted by filter to conn until
+// it disconnects or unsubscribe is called. filter may be nil, deliver
+// usually sets a state for the connection or executes a method on it.
+func (b *Bridge) Subscribe(conn *runtime.Conn, topic string, filter func(value any) bool, deliver func(value any) error) (unsubscribe func()) {
+	s := &subscription{connId: conn.Id, topic: topic, filter: filter, deliver: deliver}
+
+	b.mu.Lock()
+	b.subs[s] = struct{}{}
+	b.mu.Unlock()
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		delete(b.subs, s)
+	}
+}
+
+// Consume applies the messages of ch until it is closed or ctx is done.
+// Failed messages go to onError, which may be nil, and do not stop it.
+func (b *Bridge) Consume(ctx context.Context, ch <-chan Message, onError func(m Message, err error)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case m, ok := <-ch:
+			if !ok {
+				return
+			}
+			if err := b.Apply(m); err != nil && onError != nil {
+				onError(m, err)
+			}
+		}
+	}
+}
+
+// Apply runs the routes matching the subject of m and applies their
+// updates.
+func (b *Bridge) Apply(m Message) error {
+	b.mu.RLock()
+	routes := append([]route{}, b.routes...)
+	b.mu.RUnlock()
+
+	for _, route := range routes {
+		if !MatchSubject(route.pattern, m.Subject) {
+			continue
+		}
+		updates, err := route.transform(m)
+		if err != nil {
+			return fmt.Errorf("stream %v: %w", m.Subject, err)
+		}
+		for _, u := range updates {
+			if err := b.update(u); err != nil {
+				return fmt.Errorf("stream %v: %w", m.Subject, err)
+			}
+		}
+	}
+	return nil
+}
+
+// serve keeps clients served later up to date with state, once per state.
+func (b *Bridge) serve(state *runtime.ServerState) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.served[state]; ok {
+		return
+	}
+	b.served[state] = struct{}{}
+	state.ServeLatest()
+}
+
+func (b *Bridge) update(u Update) error {
+	if u.State != nil {
+		b.serve(u.State)
+		if err := u.State.SetState(u.Value, nil); err != nil {
+			return err
+		}
+	}
+	if u.Execute != nil {
+		if err := b.r.Execute(u.Execute, nil); err != nil {
+			return err
+		}
+	}
+	if u.Topic == "" {
+		return nil
+	}
+
+	b.mu.RLock()
+	subs := []*subscription{}
+	for s := range b.subs {
+		if s.topic == u.Topic && (s.filter == nil || s.filter(u.Value)) {
+			subs = append(subs, s)
+		}
+	}
+	b.mu.RUnlock()
+
+	// a failed delivery does not hold back the others, the first error is
+	// returned once all were tried
+	var first error
+	for _, s := range subs {
+		if err := s.deliver(u.Value); err != nil && first == nil {
+			first = fmt.Errorf("topic %v to conn %v: %w", u.Topic, s.connId, err)
+		}
+	}
+	return first
+}
+
+// MatchSubject reports whether subject matches a NATS style pattern.
+func MatchSubject(pattern, subject string) bool {
+	p := strings.Split(pattern, ".")
+	s := strings.Split(subject, ".")
+	for i, token := range p {
+		if token == ">" && i == len(p)-1 {
+			return len(s) > i
+		}
+		if i >= len(s) || (token != "*" && token != s[i]) {
+			return false
+		}
+	}
+	return len(p) == len(s)
+}
+
+// JSONState is the Transform broadcasting every message value, decoded as
+// json, to state.
+func JSONState(state *runtime.ServerState) Transform {
+	return func(m Message) ([]Update, error) {
+		var v any
+		if err := json.Unmarshal(m.Value, &v); err != nil {
+			return nil, err
+		}
+		return []Update{{State: state, Value: v}}, nil
+	}
+}
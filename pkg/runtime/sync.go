@@ -0,0 +1,68 @@
+package runtime
+
+import (
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Sync polls source every interval and broadcasts its value to state
+// whenever it differs from the last one, clients served later get the
+// latest value. Failed polls are logged and keep the last value. Call stop
+// to end polling.
+func (r *Runtime) Sync(state *ServerState, source func() (any, error), interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	release := state.ServeLatest()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			value, err := source()
+			if err != nil {
+				r.e.Logger.Errorf("sync %v: %v", state.Id, err)
+			} else {
+				r.syncValue(state, value)
+			}
+
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	once := sync.Once{}
+	return func() {
+		once.Do(func() {
+			close(done)
+			release()
+		})
+	}
+}
+
+// SyncChan broadcasts every value received from ch to state unless it
+// equals the last one, until ch is closed. Clients served later get the
+// latest value until then. Methods can not have type
+// parameters, so unlike Sync it takes the runtime as an argument.
+func SyncChan[T any](r *Runtime, state *ServerState, ch <-chan T) {
+	release := state.ServeLatest()
+
+	go func() {
+		defer release()
+		for value := range ch {
+			r.syncValue(state, value)
+		}
+	}()
+}
+
+func (r *Runtime) syncValue(state *ServerState, value any) {
+	if reflect.DeepEqual(value, state.Value()) {
+		return
+	}
+	if err := state.SetState(value, nil); err != nil && err != errConnClosed {
+		r.e.Logger.Errorf("sync %v: %v", state.Id, err)
+	}
+}
@@ -0,0 +1,44 @@
+package runtime
+
+// TabConn returns the connection of the tab, nil if the tab is closed or
+// currently reconnecting.
+func (r *Runtime) TabConn(tabId string) *Conn {
+	for _, conn := range r.conns.list() {
+		if conn.TabId == tabId {
+			return conn
+		}
+	}
+	return nil
+}
+
+// ExecuteTab runs a ui method in exactly the given tab.
+func (r *Runtime) ExecuteTab(tabId string, target *ExecuteTarget) error {
+	conn := r.TabConn(tabId)
+	if conn == nil {
+		return nil
+	}
+	connId := conn.Id
+	return r.Execute(target, &connId)
+}
+
+// ExecuteUser runs a ui method in every tab of userId, e.g. to log the
+// user out everywhere.
+func (r *Runtime) ExecuteUser(userId string, target *ExecuteTarget) error {
+	for _, conn := range r.UserConns(userId) {
+		connId := conn.Id
+		if err := r.Execute(target, &connId); err != nil && err != errConnClosed {
+			return err
+		}
+	}
+	return nil
+}
+
+// FocusTab asks the browser to bring the tab to the front. Browsers only
+// honor it in some situations, such as right after a notification click.
+func (r *Runtime) FocusTab(tabId string) error {
+	return r.ExecuteTab(tabId, &ExecuteTarget{
+		Id:         utilsId,
+		Method:     "binding/v1/focus",
+		Parameters: map[string]interface{}{},
+	})
+}
@@ -0,0 +1,108 @@
+package runtime
+
+import (
+	"bytes"
+	"html/template"
+	"regexp"
+
+	"github.com/labstack/echo/v4"
+)
+
+// IndexData is rendered into the head of the index page through the head
+// template, see WithHeadTemplate.
+type IndexData struct {
+	// Title replaces the title of the ui dist when set.
+	Title   string
+	Favicon string
+	// Meta is rendered as <meta name="key" content="value"> tags.
+	Meta map[string]string
+	// Head holds trusted snippets such as analytics scripts, they are
+	// rendered unescaped.
+	Head []template.HTML
+	// Values are free for custom head templates.
+	Values map[string]any
+}
+
+var defaultHeadTemplate = template.Must(template.New("head").Parse(
+	`{{if .Title}}<title>{{.Title}}</title>{{end}}` +
+		`{{if .Favicon}}<link rel="icon" href="{{.Favicon}}">{{end}}` +
+		`{{range $name, $content := .Meta}}<meta name="{{$name}}" content="{{$content}}">{{end}}` +
+		`{{range .Head}}{{.}}{{end}}`,
+))
+
+var titlePattern = regexp.MustCompile(`(?s)<title>.*?</title>`)
+
+// WithHeadTemplate replaces the default head template, tmpl is executed
+// with *IndexData and its output is inserted right before </head>. The
+// title of the ui dist is dropped once IndexData.Title is set, so tmpl is
+// expected to render it.
+func WithHeadTemplate(tmpl *template.Template) Option {
+	return func(r *Runtime) {
+		r.headTemplate = tmpl
+	}
+}
+
+// OnIndex runs while the index page is requested, e.g. to set a per tenant
+// title or inject snippets. Pages are no longer cached once an index hook
+// is registered.
+func (r *Runtime) OnIndex(fn func(c echo.Context, d *IndexData) error) {
+	r.hooks.index = append(r.hooks.index, fn)
+	r.invalidatePages()
+}
+
+// indexData starts from the AppBuilder meta, index hooks may override it.
+func (r *Runtime) indexData() *IndexData {
+	d := &IndexData{
+		Meta:   map[string]string{},
+		Values: map[string]any{},
+	}
+	if r.appBuilder != nil {
+		meta := r.appBuilder.MetaOf()
+		d.Title = meta.Title
+		d.Favicon = meta.Favicon
+		if meta.Description != "" {
+			d.Meta["description"] = meta.Description
+		}
+	}
+	r.pwaHead(d)
+	return d
+}
+
+func (r *Runtime) requestIndexData(c echo.Context) (*IndexData, error) {
+	d := r.indexData()
+	for _, fn := range r.hooks.index {
+		if err := fn(c, d); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// renderHead executes the head template with d into a rendered page.
+func (r *Runtime) renderHead(page []byte, d *IndexData) ([]byte, error) {
+	tmpl := r.headTemplate
+	if tmpl == nil {
+		tmpl = defaultHeadTemplate
+	}
+
+	head := &bytes.Buffer{}
+	if err := tmpl.Execute(head, d); err != nil {
+		return nil, err
+	}
+	if head.Len() == 0 {
+		return page, nil
+	}
+
+	if d.Title != "" {
+		page = titlePattern.ReplaceAll(page, nil)
+	}
+	before, after, found := bytes.Cut(page, []byte("</head>"))
+	if !found {
+		return page, nil
+	}
+	out := make([]byte, 0, len(page)+head.Len())
+	out = append(out, before...)
+	out = append(out, head.Bytes()...)
+	out = append(out, "</head>"...)
+	return append(out, after...), nil
+}
@@ -0,0 +1,228 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"github.com/yuyz0112/sunmao-ui-go-binding/pkg/sunmao"
+)
+
+// TenantResolver returns the application served for a hostname. Returning
+// a nil builder falls back to the app loaded on the Runtime itself.
+type TenantResolver func(host string) (*sunmao.AppBuilder, error)
+
+// defaultMaxTenants bounds the tenants kept in memory, see WithMaxTenants
+const defaultMaxTenants = 1000
+
+var errTooManyTenants = errors.New("too many tenants")
+
+type tenants struct {
+	mu       sync.Mutex
+	resolve  TenantResolver
+	runtimes map[string]*Runtime
+	max      int
+	// used orders the tenants by their last request for eviction
+	used  map[string]uint64
+	clock uint64
+}
+
+// WithTenantResolver serves a different application per hostname. Every
+// tenant gets its own connections, patches and pages, while handlers and
+// hooks registered on the Runtime are shared by all tenants. Use Tenant to
+// push states to the connections of a single hostname.
+func WithTenantResolver(resolve TenantResolver) Option {
+	return func(r *Runtime) {
+		max := defaultMaxTenants
+		if r.tenants != nil {
+			max = r.tenants.max
+		}
+		r.tenants = &tenants{
+			resolve:  resolve,
+			runtimes: map[string]*Runtime{},
+			max:      max,
+			used:     map[string]uint64{},
+		}
+	}
+}
+
+// WithMaxTenants bounds the tenants kept in memory. Once reached, the least
+// recently requested tenant without connections is dropped and resolved
+// again on its next request. Pass it after WithTenantResolver.
+func WithMaxTenants(n int) Option {
+	return func(r *Runtime) {
+		if r.tenants != nil {
+			r.tenants.max = n
+		}
+	}
+}
+
+// hostname strips the port of host and lowercases it. Hosts which are not
+// a valid hostname or ip are rejected, the result names the patch
+// directory of the tenant.
+func hostname(host string) (string, bool) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+	if host == "" || len(host) > 253 || host[0] == '.' || strings.Contains(host, "..") {
+		return "", false
+	}
+	for _, c := range host {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '.' || c == ':') {
+			return "", false
+		}
+	}
+	return host, true
+}
+
+// Tenant returns the runtime serving host, or nil if the host has not been
+// resolved yet.
+func (r *Runtime) Tenant(host string) *Runtime {
+	if r.tenants == nil {
+		return nil
+	}
+
+	host, ok := hostname(host)
+	if !ok {
+		return nil
+	}
+
+	r.tenants.mu.Lock()
+	defer r.tenants.mu.Unlock()
+
+	return r.tenants.runtimes[host]
+}
+
+// TenantOf returns the hostname a connection belongs to.
+func (r *Runtime) TenantOf(connId int) (string, bool) {
+	if r.tenants == nil {
+		return "", false
+	}
+
+	r.tenants.mu.Lock()
+	defer r.tenants.mu.Unlock()
+
+	for host, t := range r.tenants.runtimes {
+		if t.conns.get(connId) != nil {
+			return host, true
+		}
+	}
+	return "", false
+}
+
+// cachedTenant returns the tenant of host if it was built, marking it used.
+func (r *Runtime) cachedTenant(host string) *Runtime {
+	r.tenants.mu.Lock()
+	defer r.tenants.mu.Unlock()
+
+	t, ok := r.tenants.runtimes[host]
+	if ok {
+		r.tenants.clock++
+		r.tenants.used[host] = r.tenants.clock
+	}
+	return t
+}
+
+func (r *Runtime) tenant(rawHost string) (*Runtime, error) {
+	host, ok := hostname(rawHost)
+	if !ok {
+		return nil, fmt.Errorf("invalid host %q", rawHost)
+	}
+
+	if t := r.cachedTenant(host); t != nil {
+		return t, nil
+	}
+
+	// resolve is user code such as a database lookup, requests of other
+	// tenants must not wait for it
+	builder, err := r.tenants.resolve(host)
+	if err != nil {
+		return nil, err
+	}
+	if builder == nil {
+		return nil, nil
+	}
+
+	r.tenants.mu.Lock()
+	defer r.tenants.mu.Unlock()
+
+	// a concurrent request for host may have resolved it meanwhile
+	r.tenants.clock++
+	if t, ok := r.tenants.runtimes[host]; ok {
+		r.tenants.used[host] = r.tenants.clock
+		return t, nil
+	}
+	if r.tenants.max > 0 && len(r.tenants.runtimes) >= r.tenants.max && !r.tenants.evict() {
+		return nil, errTooManyTenants
+	}
+
+	// copy the parent to inherit its options, connection ids stay unique
+	// across tenants since connSeq is shared
+	t := *r
+	t.e = echo.New()
+	t.e.HideBanner = true
+	t.e.HidePort = true
+	t.e.IPExtractor = r.e.IPExtractor
+	t.e.HTTPErrorHandler = t.handleHTTPError
+	t.router = t.e.Group(t.basePath)
+	t.conns = newTenantConnSet(r.conns)
+	// ':' of ipv6 hosts is not allowed in windows paths
+	t.patchDir = filepath.Join(r.patchDir, strings.ReplaceAll(host, ":", "_"))
+	t.pageCache = &pageCache{pages: map[string]*cachedPage{}}
+	t.mounts = nil
+	t.tenants = nil
+	t.LoadApp(builder)
+
+	t.e.Use(t.middlewares()...)
+	t.registerRoutes()
+
+	r.tenants.runtimes[host] = &t
+	r.tenants.used[host] = r.tenants.clock
+	return &t, nil
+}
+
+// evict drops the least recently used tenant without connections, it
+// reports false if every tenant is connected. t.mu is held.
+func (t *tenants) evict() bool {
+	oldest := ""
+	for host, used := range t.used {
+		if len(t.runtimes[host].conns.list()) > 0 {
+			continue
+		}
+		if oldest == "" || used < t.used[oldest] {
+			oldest = host
+		}
+	}
+	if oldest == "" {
+		return false
+	}
+	delete(t.runtimes, oldest)
+	delete(t.used, oldest)
+	return true
+}
+
+func (r *Runtime) tenantMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			t, err := r.tenant(c.Request().Host)
+			if err == errTooManyTenants {
+				return echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+			}
+			if err != nil {
+				return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("unknown tenant: %v", err))
+			}
+			if t == nil {
+				return next(c)
+			}
+
+			t.e.ServeHTTP(c.Response(), c.Request())
+			return nil
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package runtime
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"sync"
+)
+
+// terminalReadSize is the most output sent in one message
+const terminalReadSize = 32 << 10
+
+// TerminalSession is what a terminal of a client talks to, e.g. a pty or
+// an ssh session. Output is read from it and keystrokes are written to it.
+type TerminalSession interface {
+	io.ReadWriter
+	Resize(cols, rows int) error
+	Close() error
+}
+
+// TerminalOpen starts the session of conn with the size of its terminal,
+// params are those of the terminal component, e.g. the pod to exec into.
+type TerminalOpen func(conn *Conn, params map[string]any, cols, rows int) (TerminalSession, error)
+
+// TerminalState is the state of a connection's terminal.
+type TerminalState struct {
+	Open  bool   `json:"open"`
+	Error string `json:"error,omitempty"`
+}
+
+// Terminal is the ServerState behind sunmao.NewTerminal, every connection
+// has a session of its own.
+type Terminal struct {
+	*ServerState
+	open     TerminalOpen
+	mu       sync.Mutex
+	sessions map[int]TerminalSession
+}
+
+// NewTerminal registers the handlers id/open, id/input, id/resize and
+// id/close. The terminal component opens the session itself once it is
+// shown and again when its params change. The session is closed when its
+// connection closes, the client closes it or its output ends.
+func NewTerminal(r *Runtime, id string, open TerminalOpen) (*Terminal, error) {
+	t := &Terminal{
+		ServerState: r.NewServerState(id, &TerminalState{}),
+		open:        open,
+		sessions:    map[int]TerminalSession{},
+	}
+
+	r.OnDisconnected(func(conn *Conn, _ CloseReason) {
+		t.close(conn.Id)
+	})
+
+	type size struct {
+		Cols int `json:"cols"`
+		Rows int `json:"rows"`
+	}
+	handlers := map[string]HandlerFunc{
+		"open": func(m *Message, connId int) error {
+			conn := r.conns.get(connId)
+			if conn == nil {
+				return nil
+			}
+			params, err := decodeParams[struct {
+				size
+				Params map[string]any `json:"params"`
+			}](m)
+			if err != nil {
+				return err
+			}
+			return t.start(conn, params.Params, params.Cols, params.Rows)
+		},
+		"input": func(m *Message, connId int) error {
+			params, err := decodeParams[struct {
+				Data string `json:"data"`
+			}](m)
+			if err != nil {
+				return err
+			}
+			if s := t.session(connId); s != nil {
+				_, err = io.WriteString(s, params.Data)
+			}
+			return err
+		},
+		"resize": func(m *Message, connId int) error {
+			params, err := decodeParams[size](m)
+			if err != nil {
+				return err
+			}
+			if s := t.session(connId); s != nil && params.Cols > 0 && params.Rows > 0 {
+				return s.Resize(params.Cols, params.Rows)
+			}
+			return nil
+		},
+		"close": func(m *Message, connId int) error {
+			t.close(connId)
+			return t.SetState(&TerminalState{}, &connId)
+		},
+	}
+	for _, name := range []string{"open", "input", "resize", "close"} {
+		if err := r.Handle(id+"/"+name, handlers[name]); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+func (t *Terminal) start(conn *Conn, params map[string]any, cols, rows int) error {
+	if cols <= 0 || rows <= 0 {
+		cols, rows = 80, 24
+	}
+	// a reopening client starts afresh
+	t.close(conn.Id)
+
+	connId := conn.Id
+	s, err := t.open(conn, params, cols, rows)
+	if err != nil {
+		return t.SetState(&TerminalState{Error: err.Error()}, &connId)
+	}
+	t.mu.Lock()
+	t.sessions[connId] = s
+	t.mu.Unlock()
+	if err := t.SetState(&TerminalState{Open: true}, &connId); err != nil {
+		t.close(connId)
+		return err
+	}
+
+	go func() {
+		buf := make([]byte, terminalReadSize)
+		state := &TerminalState{}
+		for {
+			n, err := s.Read(buf)
+			if n > 0 {
+				sendErr := t.r.send(map[string]interface{}{
+					"type":     "TerminalOutput",
+					"terminal": t.Id,
+					// base64 keeps utf-8 sequences split across reads intact
+					"data": base64.StdEncoding.EncodeToString(buf[:n]),
+				}, &connId)
+				if sendErr != nil {
+					break
+				}
+			}
+			if err != nil {
+				if !errors.Is(err, io.EOF) && t.current(connId, s) {
+					state.Error = err.Error()
+				}
+				break
+			}
+		}
+		// a session replaced by a reopen leaves the state to the new one
+		if t.current(connId, s) {
+			t.close(connId)
+			if err := t.SetState(state, &connId); err != nil && err != errConnClosed {
+				t.r.e.Logger.Error(err)
+			}
+		}
+	}()
+	return nil
+}
+
+func (t *Terminal) session(connId int) TerminalSession {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.sessions[connId]
+}
+
+func (t *Terminal) current(connId int, s TerminalSession) bool {
+	return t.session(connId) == s
+}
+
+func (t *Terminal) close(connId int) {
+	t.mu.Lock()
+	s, ok := t.sessions[connId]
+	delete(t.sessions, connId)
+	t.mu.Unlock()
+
+	if ok {
+		if err := s.Close(); err != nil {
+			t.r.e.Logger.Errorf("terminal %v: %v", t.Id, err)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package runtime
+
+import "fmt"
+
+// TreeNode is a node of the binding/v1/tree component.
+type TreeNode struct {
+	Id    string `json:"id"`
+	Title string `json:"title"`
+	// Icon is an emoji or the url of an image.
+	Icon string `json:"icon,omitempty"`
+	// Leaf nodes have no children and no expand toggle.
+	Leaf bool `json:"leaf,omitempty"`
+	Data any  `json:"data,omitempty"`
+}
+
+// TreeChildren loads the children of nodeId, "" for the roots.
+type TreeChildren func(nodeId string) ([]TreeNode, error)
+
+// Tree serves the binding/v1/tree components with its id, see
+// sunmao.NewTree.
+type Tree struct {
+	r        *Runtime
+	id       string
+	children TreeChildren
+}
+
+// NewTree serves a tree whose nodes are loaded from children when the
+// client expands them. onSelect, if set, runs for the node a user picked.
+// The expanded nodes are kept as the client preference "tree:<id>" and
+// loaded again on the next visit.
+func NewTree(r *Runtime, id string, children TreeChildren, onSelect func(conn *Conn, node TreeNode) error) (*Tree, error) {
+	t := &Tree{r: r, id: id, children: children}
+	r.Preference("tree:"+id, []string{})
+
+	if err := r.Handle(id+"/children", t.handleChildren); err != nil {
+		return nil, err
+	}
+	return t, r.Handle(id+"/select", func(m *Message, connId int) error {
+		conn := r.conns.get(connId)
+		if conn == nil || onSelect == nil {
+			return nil
+		}
+		params, err := decodeParams[struct {
+			Node TreeNode `json:"node"`
+		}](m)
+		if err != nil {
+			return err
+		}
+		return onSelect(conn, params.Node)
+	})
+}
+
+// Refresh loads the children of nodeId again and replaces them on every
+// client that expanded it, "" refreshes the roots.
+func (t *Tree) Refresh(nodeId string) error {
+	nodes, err := t.children(nodeId)
+	if err != nil {
+		return err
+	}
+	return t.send(nodeId, nodes, "", nil)
+}
+
+func (t *Tree) handleChildren(m *Message, connId int) error {
+	params, _ := m.Params.(map[string]any)
+	nodeId, _ := params["node"].(string)
+
+	// a failed node shows its error and may be expanded again
+	nodes, err := t.children(nodeId)
+	message := ""
+	if err != nil {
+		message = err.Error()
+		nodes = []TreeNode{}
+	}
+	if serr := t.send(nodeId, nodes, message, &connId); serr != nil && serr != errConnClosed {
+		return serr
+	}
+	if err != nil {
+		return fmt.Errorf("tree %v node %q: %w", t.id, nodeId, err)
+	}
+	return nil
+}
+
+func (t *Tree) send(nodeId string, nodes []TreeNode, message string, connId *int) error {
+	if nodes == nil {
+		nodes = []TreeNode{}
+	}
+	return t.r.send(map[string]interface{}{
+		"type":  "TreeChildren",
+		"tree":  t.id,
+		"node":  nodeId,
+		"nodes": nodes,
+		"error": message,
+	}, connId)
+}
@@ -0,0 +1,123 @@
+package runtime
+
+import (
+	"context"
+	"sync"
+)
+
+// SuggestOption is a suggestion of the binding/v1/typeahead component,
+// Value is what the input holds once it was picked.
+type SuggestOption struct {
+	Value       string `json:"value"`
+	Label       string `json:"label,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Suggest returns the options for what a user typed so far. ctx is
+// cancelled once the user typed on, so slow lookups can stop early.
+type Suggest func(ctx context.Context, conn *Conn, prefix string) ([]SuggestOption, error)
+
+// Typeahead serves the binding/v1/typeahead components with its id, see
+// sunmao.NewTypeahead.
+type Typeahead struct {
+	r       *Runtime
+	id      string
+	suggest Suggest
+	mu      sync.Mutex
+	// cancel stops the running lookup of a connection
+	cancel map[int]context.CancelFunc
+}
+
+// NewTypeahead answers the debounced input of the typeaheads bound to id
+// with suggest, a lookup still running when the next keystroke arrives is
+// cancelled and its options are never sent. onSelect, if set, runs for the
+// option a user picked.
+func NewTypeahead(r *Runtime, id string, suggest Suggest, onSelect func(conn *Conn, option SuggestOption) error) (*Typeahead, error) {
+	t := &Typeahead{r: r, id: id, suggest: suggest, cancel: map[int]context.CancelFunc{}}
+
+	r.OnDisconnected(func(conn *Conn, _ CloseReason) {
+		t.stop(conn.Id, nil)
+	})
+
+	if err := r.Handle(id+"/suggest", t.handleSuggest); err != nil {
+		return nil, err
+	}
+	return t, r.Handle(id+"/select", func(m *Message, connId int) error {
+		conn := r.conns.get(connId)
+		if conn == nil || onSelect == nil {
+			return nil
+		}
+		params, err := decodeParams[struct {
+			Option SuggestOption `json:"option"`
+		}](m)
+		if err != nil {
+			return err
+		}
+		return onSelect(conn, params.Option)
+	})
+}
+
+func (t *Typeahead) handleSuggest(m *Message, connId int) error {
+	conn := t.r.conns.get(connId)
+	if conn == nil {
+		return nil
+	}
+	params, err := decodeParams[struct {
+		Prefix string `json:"prefix"`
+		// Seq numbers the queries of a client, which drops replies to
+		// older ones
+		Seq int `json:"seq"`
+	}](m)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.stop(connId, cancel)
+
+	// the read loop goes on while suggest runs
+	go func() {
+		// the entry is replaced by the next query or dropped on disconnect
+		defer cancel()
+
+		options, err := t.suggest(ctx, conn, params.Prefix)
+		if ctx.Err() != nil {
+			return
+		}
+		message := ""
+		if err != nil {
+			t.r.e.Logger.Errorf("typeahead %v: %v", t.id, err)
+			message = err.Error()
+		}
+		if options == nil {
+			options = []SuggestOption{}
+		}
+		err = t.r.send(map[string]interface{}{
+			"type":      "Suggestions",
+			"typeahead": t.id,
+			"seq":       params.Seq,
+			"options":   options,
+			"error":     message,
+		}, &connId)
+		if err != nil && err != errConnClosed {
+			t.r.e.Logger.Error(err)
+		}
+	}()
+	return nil
+}
+
+// stop cancels the running lookup of connId and makes next the running
+// one, nil removes it.
+func (t *Typeahead) stop(connId int, next context.CancelFunc) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if prev, ok := t.cancel[connId]; ok {
+		prev()
+	}
+	if next == nil {
+		delete(t.cancel, connId)
+		return
+	}
+	t.cancel[connId] = next
+}
@@ -0,0 +1,220 @@
+package runtime
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultMaxUploadSize = 32 << 20
+	// uploadTokenTTL is how long a client may take from asking for a token
+	// to posting its files
+	uploadTokenTTL = time.Minute
+	// maxUploadTokens bounds the unused tokens of one connection
+	maxUploadTokens = 16
+)
+
+// UploadedFile is a file a client dropped or pasted onto a drop zone.
+type UploadedFile struct {
+	Name string
+	// Type is the mime type the browser reported, pasted images are
+	// usually image/png.
+	Type string
+	Data []byte
+}
+
+// UploadHandler receives the files of one drop or paste on conn,
+// componentId is the drop zone they landed on.
+type UploadHandler func(conn *Conn, componentId string, files []UploadedFile) error
+
+type upload struct {
+	maxSize int64
+	fn      UploadHandler
+	handler *handler
+}
+
+// HandleUpload receives the files of the binding/v1/dropZone components
+// uploading to id, see sunmao.NewDropZone. Files are posted over http
+// rather than the websocket and a request larger than maxSize bytes is
+// rejected, zero allows 32MB. Uploads go through maintenance mode, the
+// before action hooks, opts such as Authorize and the audit like the call
+// of a handler named id/upload. Call it before Run.
+func (r *Runtime) HandleUpload(id string, maxSize int64, fn UploadHandler, opts ...HandlerOption) {
+	if maxSize <= 0 {
+		maxSize = defaultMaxUploadSize
+	}
+	u := &upload{maxSize: maxSize, fn: fn}
+	u.handler = newHandler(func(m *Message, connId int) error {
+		component, _ := m.Params.(map[string]any)["component"].(string)
+		return u.fn(r.conns.get(connId), component, m.files)
+	}, opts)
+	r.uploads[id] = u
+}
+
+type uploadToken struct {
+	connId    int
+	upload    string
+	component string
+	expires   time.Time
+}
+
+// uploadTokens are handed to a connection over its websocket before each
+// upload. A token is used once, so a post can not be replayed or made on
+// behalf of a connection by anyone who does not hold its socket.
+type uploadTokens struct {
+	mu     sync.Mutex
+	tokens map[string]*uploadToken
+}
+
+func newUploadTokens() *uploadTokens {
+	return &uploadTokens{tokens: map[string]*uploadToken{}}
+}
+
+func (t *uploadTokens) issue(connId int, upload, component string) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	count := 0
+	for k, v := range t.tokens {
+		if now.After(v.expires) {
+			delete(t.tokens, k)
+		} else if v.connId == connId {
+			count++
+		}
+	}
+	if count >= maxUploadTokens {
+		return "", &ActionError{Code: "too_many_uploads", Message: "too many pending uploads"}
+	}
+	t.tokens[token] = &uploadToken{connId: connId, upload: upload, component: component, expires: now.Add(uploadTokenTTL)}
+	return token, nil
+}
+
+func (t *uploadTokens) use(token string) *uploadToken {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	v, ok := t.tokens[token]
+	if !ok {
+		return nil
+	}
+	delete(t.tokens, token)
+	if time.Now().After(v.expires) {
+		return nil
+	}
+	return v
+}
+
+// issueUploadToken answers an UploadToken message of a drop zone with the
+// token of its next post.
+func (r *Runtime) issueUploadToken(conn *Conn, params any) {
+	m, _ := params.(map[string]any)
+	id, _ := m["upload"].(string)
+	component, _ := m["component"].(string)
+	if _, ok := r.uploads[id]; !ok {
+		return
+	}
+
+	message := map[string]interface{}{
+		"type":      "UploadToken",
+		"upload":    id,
+		"component": component,
+	}
+	token, err := r.uploadTokens.issue(conn.Id, id, component)
+	if err != nil {
+		message["error"] = err.Error()
+	} else {
+		message["token"] = token
+	}
+	if err := r.send(message, &conn.Id); err != nil {
+		r.e.Logger.Error(err)
+	}
+}
+
+func (r *Runtime) registerUploads() {
+	r.router.POST("/sunmao-binding-upload/:id", func(c echo.Context) error {
+		id := c.Param("id")
+		u, ok := r.uploads[id]
+		if !ok {
+			return echo.ErrNotFound
+		}
+
+		token := r.uploadTokens.use(c.QueryParam("token"))
+		if token == nil || token.upload != id {
+			return echo.NewHTTPError(http.StatusForbidden, "invalid or expired upload token")
+		}
+		conn := r.conns.get(token.connId)
+		if conn == nil {
+			return echo.NewHTTPError(http.StatusConflict, "the connection of this upload is closed")
+		}
+		// a leaked token is still of no use to another user
+		if conn.Identity != nil {
+			identity := r.Identity(c)
+			if identity == nil || identity.Id != conn.Identity.Id {
+				return echo.ErrForbidden
+			}
+		}
+
+		c.Request().Body = http.MaxBytesReader(c.Response(), c.Request().Body, u.maxSize)
+		form, err := c.MultipartForm()
+		if err != nil {
+			maxBytesErr := &http.MaxBytesError{}
+			if errors.As(err, &maxBytesErr) {
+				return echo.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("uploads are limited to %v bytes", u.maxSize))
+			}
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		defer form.RemoveAll()
+
+		files := []UploadedFile{}
+		// hooks and the audit see the names and sizes, not the contents
+		described := []any{}
+		for _, header := range form.File["files"] {
+			f, err := header.Open()
+			if err != nil {
+				return err
+			}
+			data, err := io.ReadAll(f)
+			f.Close()
+			if err != nil {
+				return err
+			}
+			file := UploadedFile{
+				Name: header.Filename,
+				Type: header.Header.Get(echo.HeaderContentType),
+				Data: data,
+			}
+			files = append(files, file)
+			described = append(described, map[string]any{"name": file.Name, "type": file.Type, "size": len(data)})
+		}
+
+		msg := &Message{
+			Type:      "Action",
+			Handler:   id + "/upload",
+			Params:    map[string]any{"component": token.component, "files": described},
+			RequestId: newRequestId(),
+			files:     files,
+		}
+		if err := r.callWith(u.handler, msg, conn.Id); err != nil {
+			if actionErr := actionErrorOf(err); actionErr != nil {
+				return c.JSON(http.StatusUnprocessableEntity, actionErr)
+			}
+			return err
+		}
+		return c.NoContent(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,157 @@
+package runtime
+
+import (
+	"sync"
+	"time"
+)
+
+// userStateGrace is how long the states of a user without connections are
+// kept for a reconnect.
+const userStateGrace = 10 * time.Minute
+
+type userStates struct {
+	mu    sync.Mutex
+	users map[string]*UserState
+	// idle are the eviction timers of users whose last connection closed
+	idle map[string]*time.Timer
+}
+
+func newUserStates() *userStates {
+	return &userStates{users: map[string]*UserState{}, idle: map[string]*time.Timer{}}
+}
+
+// UserState holds ServerState values of one user. Unlike per connection
+// SetState calls they are shared by all tabs of the user, survive
+// reconnects and are pushed again whenever one of its connections served
+// the app. They are dropped once the user had no connection for ten
+// minutes.
+type UserState struct {
+	r      *Runtime
+	userId string
+	mu     sync.RWMutex
+	values map[string]*userValue
+}
+
+type userValue struct {
+	state *ServerState
+	value any
+}
+
+// UserState returns the state of userId, matched against Identity.Id.
+func (r *Runtime) UserState(userId string) *UserState {
+	r.userStates.mu.Lock()
+	defer r.userStates.mu.Unlock()
+
+	u, ok := r.userStates.users[userId]
+	if !ok {
+		u = &UserState{r: r, userId: userId, values: map[string]*userValue{}}
+		r.userStates.users[userId] = u
+	}
+	return u
+}
+
+// UserConns returns the open connections of userId.
+func (r *Runtime) UserConns(userId string) []*Conn {
+	conns := []*Conn{}
+	for _, conn := range r.conns.list() {
+		if conn.Identity != nil && conn.Identity.Id == userId {
+			conns = append(conns, conn)
+		}
+	}
+	return conns
+}
+
+// Get returns the value of state for the user.
+func (u *UserState) Get(state *ServerState) (any, bool) {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	v, ok := u.values[state.Id]
+	if !ok {
+		return nil, false
+	}
+	return v.value, true
+}
+
+// Set stores the value of state for the user and sends it to each of the
+// user's connections.
+func (u *UserState) Set(state *ServerState, value any) error {
+	u.mu.Lock()
+	u.values[state.Id] = &userValue{state: state, value: value}
+	u.mu.Unlock()
+
+	version := state.nextVersion()
+	for _, conn := range u.r.UserConns(u.userId) {
+		connId := conn.Id
+		state.connVersion(connId, version)
+		if err := state.setState(value, version, &connId); err != nil && err != errConnClosed {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete drops the value of state, connections keep what they show.
+func (u *UserState) Delete(state *ServerState) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	delete(u.values, state.Id)
+}
+
+// hydrate pushes the stored values of the connection's user.
+func (s *userStates) hydrate(r *Runtime, conn *Conn) {
+	if conn.Identity == nil {
+		return
+	}
+	s.mu.Lock()
+	u, ok := s.users[conn.Identity.Id]
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	connId := conn.Id
+	for _, v := range u.values {
+		version := v.state.nextVersion()
+		v.state.connVersion(connId, version)
+		if err := v.state.setState(v.value, version, &connId); err != nil && err != errConnClosed {
+			r.e.Logger.Error(err)
+		}
+	}
+}
+
+// disconnected evicts the user of conn once it stayed without connections
+// for userStateGrace.
+func (s *userStates) disconnected(r *Runtime, conn *Conn) {
+	if conn.Identity == nil {
+		return
+	}
+	userId := conn.Identity.Id
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if t, ok := s.idle[userId]; ok {
+		t.Stop()
+	}
+	var t *time.Timer
+	t = time.AfterFunc(userStateGrace, func() {
+		if len(r.UserConns(userId)) > 0 {
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		// a later disconnect restarted the grace
+		if s.idle[userId] != t {
+			return
+		}
+		delete(s.users, userId)
+		delete(s.idle, userId)
+	})
+	s.idle[userId] = t
+}
@@ -0,0 +1,106 @@
+package runtime
+
+import "fmt"
+
+// maxVirtualRange caps the items of one range request, a client asks for
+// what fits its viewport.
+const maxVirtualRange = 500
+
+// VirtualItem is a row of the binding/v1/virtualList component.
+type VirtualItem struct {
+	Key         string `json:"key"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Data        any    `json:"data,omitempty"`
+}
+
+// VirtualSource supplies a virtual list on demand, only the visible range
+// of items is ever loaded.
+type VirtualSource interface {
+	Count(conn *Conn) (int, error)
+	Items(conn *Conn, offset, limit int) ([]VirtualItem, error)
+}
+
+// VirtualList serves the binding/v1/virtualList components with its id,
+// see sunmao.NewVirtualList.
+type VirtualList struct {
+	r      *Runtime
+	id     string
+	source VirtualSource
+}
+
+// NewVirtualList serves source to the virtual lists bound to id. onSelect,
+// if set, runs for the item a user clicked.
+func NewVirtualList(r *Runtime, id string, source VirtualSource, onSelect func(conn *Conn, item VirtualItem) error) (*VirtualList, error) {
+	l := &VirtualList{r: r, id: id, source: source}
+
+	if err := r.Handle(id+"/range", l.handleRange); err != nil {
+		return nil, err
+	}
+	return l, r.Handle(id+"/select", func(m *Message, connId int) error {
+		conn := r.conns.get(connId)
+		if conn == nil || onSelect == nil {
+			return nil
+		}
+		params, err := decodeParams[struct {
+			Item VirtualItem `json:"item"`
+		}](m)
+		if err != nil {
+			return err
+		}
+		return onSelect(conn, params.Item)
+	})
+}
+
+// Invalidate makes every client drop the items it loaded and request its
+// visible range again, call it once the source changed.
+func (l *VirtualList) Invalidate() error {
+	return l.r.send(map[string]interface{}{
+		"type": "VirtualInvalidate",
+		"list": l.id,
+	}, nil)
+}
+
+func (l *VirtualList) handleRange(m *Message, connId int) error {
+	conn := l.r.conns.get(connId)
+	if conn == nil {
+		return nil
+	}
+	params, err := decodeParams[struct {
+		Offset int `json:"offset"`
+		Limit  int `json:"limit"`
+	}](m)
+	if err != nil {
+		return err
+	}
+	if params.Offset < 0 || params.Limit <= 0 || params.Limit > maxVirtualRange {
+		return &ActionError{Code: "invalid_params", Message: fmt.Sprintf("range %v+%v is out of bounds", params.Offset, params.Limit)}
+	}
+
+	total, err := l.source.Count(conn)
+	if err != nil {
+		return err
+	}
+	items := []VirtualItem{}
+	if params.Offset < total {
+		limit := params.Limit
+		if params.Offset+limit > total {
+			limit = total - params.Offset
+		}
+		if items, err = l.source.Items(conn, params.Offset, limit); err != nil {
+			return err
+		}
+	}
+
+	err = l.r.send(map[string]interface{}{
+		"type":   "VirtualItems",
+		"list":   l.id,
+		"offset": params.Offset,
+		"items":  items,
+		"total":  total,
+	}, &connId)
+	if err == errConnClosed {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,138 @@
+package runtime
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const maxWebhookPayload = 10 << 20
+
+var (
+	errInvalidSignature   = errors.New("invalid webhook signature")
+	errEmptyWebhookSecret = errors.New("webhook secret is empty, use InsecureWebhook to skip verification")
+)
+
+func hmacSHA256(secret []byte, payload []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func equalHex(expected []byte, signature string) bool {
+	buf, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(expected, buf)
+}
+
+// VerifyGitHubSignature checks a X-Hub-Signature-256 header.
+func VerifyGitHubSignature(secret []byte, payload []byte, header string) error {
+	if !strings.HasPrefix(header, "sha256=") ||
+		!equalHex(hmacSHA256(secret, payload), strings.TrimPrefix(header, "sha256=")) {
+		return errInvalidSignature
+	}
+	return nil
+}
+
+// VerifyStripeSignature checks a Stripe-Signature header and rejects
+// events signed longer than tolerance ago.
+func VerifyStripeSignature(secret []byte, payload []byte, header string, tolerance time.Duration) error {
+	var timestamp string
+	signatures := []string{}
+	for _, part := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(part, "=")
+		switch k {
+		case "t":
+			timestamp = v
+		case "v1":
+			signatures = append(signatures, v)
+		}
+	}
+
+	t, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errInvalidSignature
+	}
+	if time.Since(time.Unix(t, 0)) > tolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", errInvalidSignature)
+	}
+
+	expected := hmacSHA256(secret, []byte(timestamp+"."+string(payload)))
+	for _, signature := range signatures {
+		if equalHex(expected, signature) {
+			return nil
+		}
+	}
+	return errInvalidSignature
+}
+
+// VerifySignature checks a plain hex encoded HMAC-SHA256 of the payload.
+func VerifySignature(secret []byte, payload []byte, header string) error {
+	if !equalHex(hmacSHA256(secret, payload), header) {
+		return errInvalidSignature
+	}
+	return nil
+}
+
+func verifyWebhook(secret []byte, payload []byte, header http.Header) error {
+	if h := header.Get("X-Hub-Signature-256"); h != "" {
+		return VerifyGitHubSignature(secret, payload, h)
+	}
+	if h := header.Get("Stripe-Signature"); h != "" {
+		return VerifyStripeSignature(secret, payload, h, 5*time.Minute)
+	}
+	return VerifySignature(secret, payload, header.Get("X-Signature"))
+}
+
+// Webhook receives POST requests at path and passes the verified payload
+// to fn, which usually pushes it into server states or calls Execute.
+// GitHub and Stripe signature headers are detected, other senders must
+// put a hex HMAC-SHA256 of the body in X-Signature. The secret must not be
+// empty.
+func (r *Runtime) Webhook(path string, secret string, fn func(payload []byte) error) error {
+	if secret == "" {
+		return fmt.Errorf("%w: %v", errEmptyWebhookSecret, path)
+	}
+	r.webhook(path, secret, fn)
+	return nil
+}
+
+// InsecureWebhook is a Webhook without signature verification, for senders
+// that can not sign. Anyone reaching path can call fn, since webhooks are
+// exempt from RequireAuth.
+func (r *Runtime) InsecureWebhook(path string, fn func(payload []byte) error) {
+	r.webhook(path, "", fn)
+}
+
+func (r *Runtime) webhook(path string, secret string, fn func(payload []byte) error) {
+	r.authExempt = append(r.authExempt, path)
+
+	r.router.POST(path, func(c echo.Context) error {
+		payload, err := io.ReadAll(io.LimitReader(c.Request().Body, maxWebhookPayload))
+		if err != nil {
+			return err
+		}
+
+		if secret != "" {
+			if err := verifyWebhook([]byte(secret), payload, c.Request().Header); err != nil {
+				return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+			}
+		}
+
+		if err := fn(payload); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		return c.NoContent(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,38 @@
+package sunmao
+
+import "fmt"
+
+type ImageAnnotatorComponentBuilder struct {
+	*InnerComponentBuilder[*ImageAnnotatorComponentBuilder]
+}
+
+// NewImageAnnotator renders the image and boxes served by
+// runtime.NewImageAnnotator under annotatorId. Users draw a box by
+// dragging over the image, its state holds the "hovered" box.
+func (b *AppBuilder) NewImageAnnotator(annotatorId string) *ImageAnnotatorComponentBuilder {
+	t := &ImageAnnotatorComponentBuilder{
+		InnerComponentBuilder: newInnerComponent[*ImageAnnotatorComponentBuilder](b),
+	}
+	t.inner = t
+	return t.Type("binding/v1/imageAnnotator").Properties(map[string]interface{}{
+		"annotator":   annotatorId,
+		"src":         fmt.Sprintf("{{ %v.state.src }}", annotatorId),
+		"annotations": fmt.Sprintf("{{ %v.state.annotations }}", annotatorId),
+		"drawable":    true,
+	})
+}
+
+// Src binds the image to another expression, such as the Src of a
+// runtime.ImageSource for images sent as binary frames.
+func (b *ImageAnnotatorComponentBuilder) Src(src string) *ImageAnnotatorComponentBuilder {
+	return b.Properties(map[string]interface{}{
+		"src": src,
+	})
+}
+
+// ReadOnly turns drawing off, clicks are still reported.
+func (b *ImageAnnotatorComponentBuilder) ReadOnly() *ImageAnnotatorComponentBuilder {
+	return b.Properties(map[string]interface{}{
+		"drawable": false,
+	})
+}
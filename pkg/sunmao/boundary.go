@@ -0,0 +1,21 @@
+package sunmao
+
+type ErrorBoundaryComponentBuilder struct {
+	*InnerComponentBuilder[*ErrorBoundaryComponentBuilder]
+}
+
+// NewErrorBoundary catches render errors of the components in its content
+// slot, shows the fallback text instead and reports them to the server.
+func (b *AppBuilder) NewErrorBoundary() *ErrorBoundaryComponentBuilder {
+	t := &ErrorBoundaryComponentBuilder{
+		InnerComponentBuilder: newInnerComponent[*ErrorBoundaryComponentBuilder](b),
+	}
+	t.inner = t
+	return t.Type("binding/v1/errorBoundary").Fallback("Something went wrong.")
+}
+
+func (b *ErrorBoundaryComponentBuilder) Fallback(text string) *ErrorBoundaryComponentBuilder {
+	return b.Properties(map[string]interface{}{
+		"fallback": text,
+	})
+}
@@ -0,0 +1,38 @@
+package sunmao
+
+import "fmt"
+
+type CalendarComponentBuilder struct {
+	*InnerComponentBuilder[*CalendarComponentBuilder]
+}
+
+// NewCalendar renders the events served by runtime.NewCalendar under
+// calendarId in a month view, the user switches between month and week.
+// Its state holds the shown "view" and the "start" of the shown range.
+func (b *AppBuilder) NewCalendar(calendarId string) *CalendarComponentBuilder {
+	t := &CalendarComponentBuilder{
+		InnerComponentBuilder: newInnerComponent[*CalendarComponentBuilder](b),
+	}
+	t.inner = t
+	return t.Type("binding/v1/calendar").Properties(map[string]interface{}{
+		"calendar":     calendarId,
+		"events":       fmt.Sprintf("{{ %v.state }}", calendarId),
+		"view":         "month",
+		"weekStartsOn": 1,
+	})
+}
+
+// View picks the view shown first, "month" or "week".
+func (b *CalendarComponentBuilder) View(view string) *CalendarComponentBuilder {
+	return b.Properties(map[string]interface{}{
+		"view": view,
+	})
+}
+
+// WeekStartsOn sets the first day of a week, 0 for sunday and 1 for
+// monday, the default.
+func (b *CalendarComponentBuilder) WeekStartsOn(day int) *CalendarComponentBuilder {
+	return b.Properties(map[string]interface{}{
+		"weekStartsOn": day,
+	})
+}
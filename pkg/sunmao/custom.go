@@ -0,0 +1,51 @@
+package sunmao
+
+import "fmt"
+
+// CustomComponent declares a component implemented on the TS side, where
+// an implementation of the same Name, "version/name", must be registered
+// in ui/src/custom.
+type CustomComponent struct {
+	Name        string                 `json:"name"`
+	PropsSchema map[string]interface{} `json:"propsSchema"`
+	EventNames  []string               `json:"eventNames"`
+}
+
+type CustomComponentBuilder struct {
+	*InnerComponentBuilder[*CustomComponentBuilder]
+	declared CustomComponent
+}
+
+func (b *AppBuilder) NewCustomComponent(c CustomComponent) *CustomComponentBuilder {
+	t := &CustomComponentBuilder{
+		InnerComponentBuilder: newInnerComponent[*CustomComponentBuilder](b),
+		declared:              c,
+	}
+	t.inner = t
+	return t.Type(c.Name)
+}
+
+// On binds one of the declared events to a server handler.
+func (b *CustomComponentBuilder) On(event string, serverHandler *ServerHandler) *CustomComponentBuilder {
+	known := false
+	for _, name := range b.declared.EventNames {
+		known = known || name == event
+	}
+	if !known {
+		panic(fmt.Sprintf("%v declares no event %v", b.declared.Name, event))
+	}
+
+	b._Trait(b.appBuilder.NewTrait().Type("core/v1/event").Properties(map[string]interface{}{
+		"handlers": []map[string]interface{}{
+			{
+				"type":        event,
+				"componentId": "$utils",
+				"method": map[string]interface{}{
+					"name":       fmt.Sprintf("binding/v1/%v", serverHandler.Name),
+					"parameters": serverHandler.Parameters,
+				},
+			},
+		},
+	}))
+	return b
+}
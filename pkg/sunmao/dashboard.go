@@ -0,0 +1,38 @@
+package sunmao
+
+import "fmt"
+
+type DashboardComponentBuilder struct {
+	*InnerComponentBuilder[*DashboardComponentBuilder]
+}
+
+// NewDashboard renders the widgets of runtime.NewDashboard under
+// dashboardId on a grid. Users drag widgets by their title to reorder them
+// and resize them by the corner, the layout is kept as the client
+// preference "dashboard:<dashboardId>".
+func (b *AppBuilder) NewDashboard(dashboardId string) *DashboardComponentBuilder {
+	t := &DashboardComponentBuilder{
+		InnerComponentBuilder: newInnerComponent[*DashboardComponentBuilder](b),
+	}
+	t.inner = t
+	return t.Type("binding/v1/dashboard").Properties(map[string]interface{}{
+		"dashboard": dashboardId,
+		"columns":   12,
+		"rowHeight": 80,
+		"board":     fmt.Sprintf("{{ %v.state }}", dashboardId),
+	})
+}
+
+// Columns sets the number of grid columns, 12 by default.
+func (b *DashboardComponentBuilder) Columns(n int) *DashboardComponentBuilder {
+	return b.Properties(map[string]interface{}{
+		"columns": n,
+	})
+}
+
+// RowHeight sets the height of a grid row in pixels, 80 by default.
+func (b *DashboardComponentBuilder) RowHeight(px int) *DashboardComponentBuilder {
+	return b.Properties(map[string]interface{}{
+		"rowHeight": px,
+	})
+}
@@ -0,0 +1,37 @@
+package sunmao
+
+import "fmt"
+
+type DataEditorComponentBuilder struct {
+	*InnerComponentBuilder[*DataEditorComponentBuilder]
+}
+
+// NewDataEditor renders the value served by runtime.NewDataEditor under
+// editorId as a collapsible tree, users switch to a text editor to change
+// it. Its state holds the "dirty" flag and the last "error".
+func (b *AppBuilder) NewDataEditor(editorId string) *DataEditorComponentBuilder {
+	t := &DataEditorComponentBuilder{
+		InnerComponentBuilder: newInnerComponent[*DataEditorComponentBuilder](b),
+	}
+	t.inner = t
+	return t.Type("binding/v1/dataEditor").Properties(map[string]interface{}{
+		"editor":   editorId,
+		"data":     fmt.Sprintf("{{ %v.state }}", editorId),
+		"format":   "json",
+		"readOnly": false,
+	})
+}
+
+// Format picks the text format of the editor, "json" or "yaml".
+func (b *DataEditorComponentBuilder) Format(format string) *DataEditorComponentBuilder {
+	return b.Properties(map[string]interface{}{
+		"format": format,
+	})
+}
+
+// ReadOnly shows the tree only.
+func (b *DataEditorComponentBuilder) ReadOnly() *DataEditorComponentBuilder {
+	return b.Properties(map[string]interface{}{
+		"readOnly": true,
+	})
+}
@@ -0,0 +1,133 @@
+package sunmao
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+type ChangeKind string
+
+const (
+	ComponentAdded   ChangeKind = "added"
+	ComponentRemoved ChangeKind = "removed"
+	ComponentChanged ChangeKind = "changed"
+)
+
+// Change is one difference between two application schemas. Path is
+// empty for added and removed components, otherwise "type",
+// "properties.<key>" or "traits[<index>]".
+type Change struct {
+	Kind        ChangeKind  `json:"kind"`
+	ComponentId string      `json:"componentId"`
+	Path        string      `json:"path,omitempty"`
+	Old         interface{} `json:"old,omitempty"`
+	New         interface{} `json:"new,omitempty"`
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case ComponentAdded:
+		return fmt.Sprintf("+ %v", c.ComponentId)
+	case ComponentRemoved:
+		return fmt.Sprintf("- %v", c.ComponentId)
+	}
+	return fmt.Sprintf("~ %v %v: %v -> %v", c.ComponentId, c.Path, describe(c.Old), describe(c.New))
+}
+
+func describe(v interface{}) string {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(buf)
+}
+
+// Diff lists the component changes from a to b in component order.
+func Diff(a, b *AppBuilder) []Change {
+	return DiffComponents(a.ValueOf().Spec.Components, b.ValueOf().Spec.Components)
+}
+
+// DiffComponents compares two component lists by component id.
+func DiffComponents(a, b []ComponentSchema) []Change {
+	before := map[string]ComponentSchema{}
+	for _, c := range a {
+		before[c.Id] = normalize(c)
+	}
+
+	changes := []Change{}
+	after := map[string]bool{}
+	for _, c := range b {
+		after[c.Id] = true
+		old, ok := before[c.Id]
+		if !ok {
+			changes = append(changes, Change{Kind: ComponentAdded, ComponentId: c.Id, New: c})
+			continue
+		}
+		changes = append(changes, diffComponent(old, normalize(c))...)
+	}
+	for _, c := range a {
+		if !after[c.Id] {
+			changes = append(changes, Change{Kind: ComponentRemoved, ComponentId: c.Id, Old: c})
+		}
+	}
+	return changes
+}
+
+func diffComponent(a, b ComponentSchema) []Change {
+	changes := []Change{}
+	changed := func(path string, old, new interface{}) {
+		changes = append(changes, Change{Kind: ComponentChanged, ComponentId: a.Id, Path: path, Old: old, New: new})
+	}
+
+	if a.Type != b.Type {
+		changed("type", a.Type, b.Type)
+	}
+
+	keys := map[string]bool{}
+	for k := range a.Properties {
+		keys[k] = true
+	}
+	for k := range b.Properties {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+	for _, k := range sorted {
+		if !reflect.DeepEqual(a.Properties[k], b.Properties[k]) {
+			changed("properties."+k, a.Properties[k], b.Properties[k])
+		}
+	}
+
+	for i := 0; i < len(a.Traits) || i < len(b.Traits); i++ {
+		var old, new interface{}
+		if i < len(a.Traits) {
+			old = a.Traits[i]
+		}
+		if i < len(b.Traits) {
+			new = b.Traits[i]
+		}
+		if !reflect.DeepEqual(old, new) {
+			changed(fmt.Sprintf("traits[%v]", i), old, new)
+		}
+	}
+	return changes
+}
+
+// normalize round trips a component through json, so typed and untyped
+// values such as []string and []interface{} compare equal.
+func normalize(c ComponentSchema) ComponentSchema {
+	buf, err := json.Marshal(c)
+	if err != nil {
+		return c
+	}
+	n := ComponentSchema{}
+	if err := json.Unmarshal(buf, &n); err != nil {
+		return c
+	}
+	return n
+}
@@ -0,0 +1,37 @@
+package sunmao
+
+import "fmt"
+
+type DiffViewerComponentBuilder struct {
+	*InnerComponentBuilder[*DiffViewerComponentBuilder]
+}
+
+// NewDiffViewer renders the diffs served by runtime.NewDiffView under
+// diffId side by side, with syntax highlighting picked from the file
+// names.
+func (b *AppBuilder) NewDiffViewer(diffId string) *DiffViewerComponentBuilder {
+	t := &DiffViewerComponentBuilder{
+		InnerComponentBuilder: newInnerComponent[*DiffViewerComponentBuilder](b),
+	}
+	t.inner = t
+	return t.Type("binding/v1/diff").Properties(map[string]interface{}{
+		"files":    fmt.Sprintf("{{ %v.state }}", diffId),
+		"mode":     "split",
+		"language": "",
+	})
+}
+
+// Inline shows removed and added lines in one column.
+func (b *DiffViewerComponentBuilder) Inline() *DiffViewerComponentBuilder {
+	return b.Properties(map[string]interface{}{
+		"mode": "inline",
+	})
+}
+
+// Language highlights files of unknown type as language, such as "yaml"
+// or "go".
+func (b *DiffViewerComponentBuilder) Language(language string) *DiffViewerComponentBuilder {
+	return b.Properties(map[string]interface{}{
+		"language": language,
+	})
+}
@@ -0,0 +1,20 @@
+package sunmao
+
+// Draggable lets the component be dragged onto Droppable components,
+// payload travels with the drop event to the server.
+func (b *InnerComponentBuilder[K]) Draggable(payload any) K {
+	b._Trait(b.appBuilder.NewTrait().Type("binding/v1/draggable").Properties(map[string]interface{}{
+		"payload": payload,
+	}))
+	return b.inner
+}
+
+// Droppable calls serverHandler whenever a Draggable component is dropped
+// on this one, decode the params with runtime.DecodeDrop.
+func (b *InnerComponentBuilder[K]) Droppable(serverHandler *ServerHandler) K {
+	b._Trait(b.appBuilder.NewTrait().Type("binding/v1/droppable").Properties(map[string]interface{}{
+		"handler":    serverHandler.Name,
+		"parameters": serverHandler.Parameters,
+	}))
+	return b.inner
+}
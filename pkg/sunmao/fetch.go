@@ -0,0 +1,104 @@
+package sunmao
+
+import "fmt"
+
+// Fetch sets up sunmao's core/v1/fetch trait, the browser sends the
+// request. For APIs without CORS or needing secrets use
+// runtime.NewFetchProxy instead, which keeps the same state shape.
+type Fetch struct {
+	URL string
+	// Method defaults to "get".
+	Method  string
+	Headers map[string]string
+	Body    map[string]interface{}
+	// BodyType is "json", "formData" or "raw", "json" by default.
+	BodyType string
+	// Lazy waits for the triggerFetch method instead of fetching on mount.
+	Lazy     bool
+	Disabled bool
+	// OnComplete and OnError run after the response arrived.
+	OnComplete []*ServerHandler
+	OnError    []*ServerHandler
+}
+
+// Fetch loads the response of fetch into the component's fetch state, bind
+// it with FetchData.
+func (b *InnerComponentBuilder[K]) Fetch(fetch *Fetch) K {
+	method := fetch.Method
+	if method == "" {
+		method = "get"
+	}
+	bodyType := fetch.BodyType
+	if bodyType == "" {
+		bodyType = "json"
+	}
+	headers := fetch.Headers
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	body := fetch.Body
+	if body == nil {
+		body = map[string]interface{}{}
+	}
+	b._Trait(b.appBuilder.NewTrait().Type("core/v1/fetch").Properties(map[string]interface{}{
+		"url":        fetch.URL,
+		"method":     method,
+		"headers":    headers,
+		"body":       body,
+		"bodyType":   bodyType,
+		"lazy":       fetch.Lazy,
+		"disabled":   fetch.Disabled,
+		"onComplete": fetchHandlers(fetch.OnComplete),
+		"onError":    fetchHandlers(fetch.OnError),
+	}))
+	return b.inner
+}
+
+func fetchHandlers(serverHandlers []*ServerHandler) []map[string]interface{} {
+	handlers := []map[string]interface{}{}
+	for _, h := range serverHandlers {
+		handlers = append(handlers, map[string]interface{}{
+			"componentId": "$utils",
+			"method": map[string]interface{}{
+				"name":       fmt.Sprintf("binding/v1/%v", h.Name),
+				"parameters": h.Parameters,
+			},
+			"disabled": false,
+			"wait": map[string]interface{}{
+				"type": "delay",
+				"time": 0,
+			},
+		})
+	}
+	return handlers
+}
+
+// FetchData binds the response of the Fetch trait of componentId.
+func FetchData(componentId string) string {
+	return fmt.Sprintf("{{ %v.fetch.data }}", componentId)
+}
+
+// FetchLoading is true while the Fetch trait of componentId is loading.
+func FetchLoading(componentId string) string {
+	return fmt.Sprintf("{{ %v.fetch.loading }}", componentId)
+}
+
+// ProxyData binds the response of runtime.NewFetchProxy under proxyId.
+func ProxyData(proxyId string) string {
+	return fmt.Sprintf("{{ %v.state.data }}", proxyId)
+}
+
+// ProxyLoading is true while the fetch proxy proxyId is loading.
+func ProxyLoading(proxyId string) string {
+	return fmt.Sprintf("{{ %v.state.loading }}", proxyId)
+}
+
+// ProxyFetch makes the fetch proxy proxyId fetch again, params reach its
+// runtime.FetchRequest and may hold expressions such as
+// "{{ search.value }}".
+func ProxyFetch(proxyId string, params map[string]interface{}) *ServerHandler {
+	return &ServerHandler{
+		Name:       proxyId + "/fetch",
+		Parameters: params,
+	}
+}
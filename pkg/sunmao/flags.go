@@ -0,0 +1,18 @@
+package sunmao
+
+import "fmt"
+
+// FlagsId is the component the client creates to expose feature flags.
+const FlagsId = "bindingFlags"
+
+// FlagExpr is the expression of a feature flag, for properties or state
+// initialization that branch on it.
+func FlagExpr(name string) string {
+	return fmt.Sprintf("{{ %v.values[%q] }}", FlagsId, name)
+}
+
+// NotFlagExpr is true while the flag is off, use it as
+// Hidden(NotFlagExpr("beta")) to put a component behind a flag.
+func NotFlagExpr(name string) string {
+	return fmt.Sprintf("{{ !%v.values[%q] }}", FlagsId, name)
+}
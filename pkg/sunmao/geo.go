@@ -0,0 +1,53 @@
+package sunmao
+
+import "fmt"
+
+// OpenStreetMapTiles is the default tile url template of NewMap.
+const OpenStreetMapTiles = "https://tile.openstreetmap.org/{z}/{x}/{y}.png"
+
+type MapComponentBuilder struct {
+	*InnerComponentBuilder[*MapComponentBuilder]
+}
+
+// NewMap renders the markers and GeoJSON layers served by runtime.NewMap
+// under mapId over web mercator tiles. Users pan by dragging and zoom with
+// the wheel, its state holds the shown "center", "zoom" and "bounds".
+func (b *AppBuilder) NewMap(mapId string) *MapComponentBuilder {
+	t := &MapComponentBuilder{
+		InnerComponentBuilder: newInnerComponent[*MapComponentBuilder](b),
+	}
+	t.inner = t
+	return t.Type("binding/v1/map").Properties(map[string]interface{}{
+		"map":         mapId,
+		"data":        fmt.Sprintf("{{ %v.state }}", mapId),
+		"center":      map[string]interface{}{"lat": 0, "lng": 0},
+		"zoom":        2,
+		"height":      400,
+		"tiles":       OpenStreetMapTiles,
+		"attribution": "© OpenStreetMap contributors",
+	})
+}
+
+// Center sets the area shown first.
+func (b *MapComponentBuilder) Center(lat, lng float64, zoom int) *MapComponentBuilder {
+	return b.Properties(map[string]interface{}{
+		"center": map[string]interface{}{"lat": lat, "lng": lng},
+		"zoom":   zoom,
+	})
+}
+
+// Height sets the height of the map in pixels, 400 by default.
+func (b *MapComponentBuilder) Height(px int) *MapComponentBuilder {
+	return b.Properties(map[string]interface{}{
+		"height": px,
+	})
+}
+
+// Tiles sets the url template of the tile server, {z}, {x} and {y} are
+// replaced per tile. Follow the usage policy of the server you pick.
+func (b *MapComponentBuilder) Tiles(url, attribution string) *MapComponentBuilder {
+	return b.Properties(map[string]interface{}{
+		"tiles":       url,
+		"attribution": attribution,
+	})
+}
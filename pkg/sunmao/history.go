@@ -0,0 +1,25 @@
+package sunmao
+
+import "fmt"
+
+// UndoHandler calls the undo handler of runtime.History.Bind for stateId,
+// e.g. as the OnClick of a button.
+func UndoHandler(stateId string) *ServerHandler {
+	return &ServerHandler{Name: stateId + "/undo"}
+}
+
+// RedoHandler calls the redo handler of runtime.History.Bind for stateId.
+func RedoHandler(stateId string) *ServerHandler {
+	return &ServerHandler{Name: stateId + "/redo"}
+}
+
+// CanUndo is the expression telling whether stateId has a step to undo,
+// for properties such as a button's visibility.
+func CanUndo(stateId string) string {
+	return fmt.Sprintf("{{ %v.history.canUndo }}", stateId)
+}
+
+// CanRedo is the expression telling whether stateId has a step to redo.
+func CanRedo(stateId string) string {
+	return fmt.Sprintf("{{ %v.history.canRedo }}", stateId)
+}
@@ -0,0 +1,45 @@
+package sunmao
+
+import "fmt"
+
+// InfiniteItems binds the data of a list or table to the items loaded so
+// far by runtime.NewInfiniteList under listId.
+func InfiniteItems(listId string) string {
+	return fmt.Sprintf("{{ %v.state.items }}", listId)
+}
+
+type LoadMoreComponentBuilder struct {
+	*InnerComponentBuilder[*LoadMoreComponentBuilder]
+}
+
+// NewLoadMore renders the footer of an infinite list served by
+// runtime.NewInfiniteList under listId. Placed below the list or table it
+// asks for the next page as soon as it scrolls into view, shows a retry
+// after a failed page and an end note once all data is loaded.
+func (b *AppBuilder) NewLoadMore(listId string) *LoadMoreComponentBuilder {
+	t := &LoadMoreComponentBuilder{
+		InnerComponentBuilder: newInnerComponent[*LoadMoreComponentBuilder](b),
+	}
+	t.inner = t
+	return t.Type("binding/v1/loadMore").Properties(map[string]interface{}{
+		"list":    listId,
+		"page":    fmt.Sprintf("{{ %v.state }}", listId),
+		"version": fmt.Sprintf("{{ %v.version }}", listId),
+		"auto":    true,
+		"endText": "No more data",
+	})
+}
+
+// Manual loads the next page only when the user clicks the footer.
+func (b *LoadMoreComponentBuilder) Manual() *LoadMoreComponentBuilder {
+	return b.Properties(map[string]interface{}{
+		"auto": false,
+	})
+}
+
+// EndText is shown once the last page was loaded.
+func (b *LoadMoreComponentBuilder) EndText(text string) *LoadMoreComponentBuilder {
+	return b.Properties(map[string]interface{}{
+		"endText": text,
+	})
+}
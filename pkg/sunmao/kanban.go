@@ -0,0 +1,32 @@
+package sunmao
+
+import "fmt"
+
+type KanbanComponentBuilder struct {
+	*InnerComponentBuilder[*KanbanComponentBuilder]
+}
+
+// NewKanban renders the board served by runtime.NewKanban under kanbanId.
+// Cards are dragged between columns, created at the bottom of a column
+// and deleted from their corner.
+func (b *AppBuilder) NewKanban(kanbanId string) *KanbanComponentBuilder {
+	t := &KanbanComponentBuilder{
+		InnerComponentBuilder: newInnerComponent[*KanbanComponentBuilder](b),
+	}
+	t.inner = t
+	return t.Type("binding/v1/kanban").Properties(map[string]interface{}{
+		"kanban":      kanbanId,
+		"board":       fmt.Sprintf("{{ %v.state }}", kanbanId),
+		"version":     fmt.Sprintf("{{ %v.version }}", kanbanId),
+		"allowCreate": true,
+		"allowDelete": true,
+	})
+}
+
+// ReadOnly hides the create and delete controls, cards still move.
+func (b *KanbanComponentBuilder) ReadOnly() *KanbanComponentBuilder {
+	return b.Properties(map[string]interface{}{
+		"allowCreate": false,
+		"allowDelete": false,
+	})
+}
@@ -0,0 +1,33 @@
+package sunmao
+
+import "fmt"
+
+// PendingKey marks a state value as still loading, see Loading.
+const PendingKey = "$pending"
+
+// Loading wraps content in a stack that shows a skeleton instead while
+// the state of the ServerState stateId holds the pending sentinel.
+func (b *AppBuilder) Loading(stateId string, content ...BaseComponentBuilder) *StackComponentBuilder {
+	pending := fmt.Sprintf("{{ !!(%v.state && %v.state[%q]) }}", stateId, stateId, PendingKey)
+	ready := fmt.Sprintf("{{ !(%v.state && %v.state[%q]) }}", stateId, stateId, PendingKey)
+
+	skeleton := b.NewComponent().Type("arco/v1/skeleton").Id(stateId + "Skeleton").
+		Properties(map[string]interface{}{
+			"animation": true,
+			"loading":   true,
+			"image":     false,
+			"text": map[string]interface{}{
+				"rows": 3,
+			},
+		}).
+		Hidden(ready)
+	for _, c := range content {
+		c._Trait(b.NewTrait().Type("core/v1/hidden").Properties(map[string]interface{}{
+			"hidden": pending,
+		}))
+	}
+
+	return b.NewStack().Id(stateId + "Loading").Children(map[string][]BaseComponentBuilder{
+		"content": append([]BaseComponentBuilder{skeleton}, content...),
+	})
+}
@@ -0,0 +1,60 @@
+package sunmao
+
+import "fmt"
+
+type LogViewComponentBuilder struct {
+	*InnerComponentBuilder[*LogViewComponentBuilder]
+}
+
+// NewLogView shows the output of the commands runtime.NewCommandRunner
+// runs under runner for this client, stderr in red, and their exit code
+// once they ended. It follows the output unless scrolled up.
+func (b *AppBuilder) NewLogView(runner string) *LogViewComponentBuilder {
+	t := &LogViewComponentBuilder{
+		InnerComponentBuilder: newInnerComponent[*LogViewComponentBuilder](b),
+	}
+	t.inner = t
+	return t.Type("binding/v1/logView").Properties(map[string]interface{}{
+		"log":      runner,
+		"run":      fmt.Sprintf("{{ %v.state }}", runner),
+		"maxLines": 5000,
+		"height":   400,
+	})
+}
+
+// MaxLines is the number of lines kept, older ones are dropped.
+func (b *LogViewComponentBuilder) MaxLines(n int) *LogViewComponentBuilder {
+	return b.Properties(map[string]interface{}{
+		"maxLines": n,
+	})
+}
+
+// Height is the height of the view in pixels.
+func (b *LogViewComponentBuilder) Height(px int) *LogViewComponentBuilder {
+	return b.Properties(map[string]interface{}{
+		"height": px,
+	})
+}
+
+// StartCommand starts a command of the runner, params reach its
+// runtime.CommandFunc and may hold expressions such as "{{ branch.value }}".
+func StartCommand(runner string, params map[string]interface{}) *ServerHandler {
+	return &ServerHandler{
+		Name:       runner + "/start",
+		Parameters: params,
+	}
+}
+
+// CancelCommand kills the running command of the runner.
+func CancelCommand(runner string) *ServerHandler {
+	return &ServerHandler{
+		Name:       runner + "/cancel",
+		Parameters: map[string]interface{}{},
+	}
+}
+
+// CommandRunning is true while a command of the runner runs for the client,
+// e.g. to disable the start button.
+func CommandRunning(runner string) string {
+	return fmt.Sprintf("{{ %v.state.running }}", runner)
+}
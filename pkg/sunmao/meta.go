@@ -0,0 +1,24 @@
+package sunmao
+
+// AppMeta describes the page an application is served in, it is not part
+// of the schema sent to the client.
+type AppMeta struct {
+	Title       string
+	Favicon     string
+	Description string
+}
+
+// Meta sets the title, favicon url and description of the page serving
+// the application, empty values keep the ui defaults.
+func (b *AppBuilder) Meta(title string, favicon string, description string) *AppBuilder {
+	b.meta = AppMeta{
+		Title:       title,
+		Favicon:     favicon,
+		Description: description,
+	}
+	return b
+}
+
+func (b *AppBuilder) MetaOf() AppMeta {
+	return b.meta
+}
@@ -0,0 +1,108 @@
+package sunmao
+
+import "strings"
+
+// Migration rewrites components in place, e.g. to bring a persisted schema
+// in line with the current component library.
+type Migration func(components []ComponentSchema) error
+
+// Migrate runs migrations over the components of app in order.
+func Migrate(app *Application, migrations ...Migration) error {
+	return migrate(app.Spec.Components, migrations)
+}
+
+// MigrateModule runs migrations over the implementation of m in order.
+func MigrateModule(m *Module, migrations ...Migration) error {
+	return migrate(m.Impl, migrations)
+}
+
+func migrate(components []ComponentSchema, migrations []Migration) error {
+	for _, m := range migrations {
+		if err := m(components); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RenameComponentType changes every component of type from to type to.
+func RenameComponentType(from, to string) Migration {
+	return func(components []ComponentSchema) error {
+		for i := range components {
+			if components[i].Type == from {
+				components[i].Type = to
+			}
+		}
+		return nil
+	}
+}
+
+// MoveProperty moves a property of components of componentType, from and
+// to are dotted paths such as "text.raw".
+func MoveProperty(componentType, from, to string) Migration {
+	return func(components []ComponentSchema) error {
+		for i := range components {
+			c := &components[i]
+			if c.Type != componentType || c.Properties == nil {
+				continue
+			}
+			if v, ok := takePath(c.Properties, from); ok {
+				putPath(c.Properties, to, v)
+			}
+		}
+		return nil
+	}
+}
+
+// UpgradeTrait replaces traits of type from with type to, upgrade maps the
+// old properties to the new ones and may be nil to keep them.
+func UpgradeTrait(from, to string, upgrade func(properties map[string]interface{}) (map[string]interface{}, error)) Migration {
+	return func(components []ComponentSchema) error {
+		for i := range components {
+			for j := range components[i].Traits {
+				t := &components[i].Traits[j]
+				if t.Type != from {
+					continue
+				}
+				t.Type = to
+				if upgrade == nil {
+					continue
+				}
+				properties, err := upgrade(t.Properties)
+				if err != nil {
+					return err
+				}
+				t.Properties = properties
+			}
+		}
+		return nil
+	}
+}
+
+func takePath(m map[string]interface{}, path string) (interface{}, bool) {
+	keys := strings.Split(path, ".")
+	for _, k := range keys[:len(keys)-1] {
+		next, ok := m[k].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		m = next
+	}
+	last := keys[len(keys)-1]
+	v, ok := m[last]
+	delete(m, last)
+	return v, ok
+}
+
+func putPath(m map[string]interface{}, path string, v interface{}) {
+	keys := strings.Split(path, ".")
+	for _, k := range keys[:len(keys)-1] {
+		next, ok := m[k].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			m[k] = next
+		}
+		m = next
+	}
+	m[keys[len(keys)-1]] = v
+}
@@ -0,0 +1,52 @@
+package sunmao
+
+import "fmt"
+
+// MenuItem is an entry of the navigation shell. Roles limits the item and
+// its children to identities with any of the roles, they are filtered on
+// the server and never reach other clients.
+type MenuItem struct {
+	Key   string `json:"key"`
+	Title string `json:"title"`
+	// Icon is an emoji or the url of an image.
+	Icon     string     `json:"icon,omitempty"`
+	Roles    []string   `json:"-"`
+	Children []MenuItem `json:"children,omitempty"`
+}
+
+type NavShellComponentBuilder struct {
+	*InnerComponentBuilder[*NavShellComponentBuilder]
+}
+
+// NewNavShell renders a collapsible sidebar with the menu served by
+// runtime.NewNavigation under navId, and breadcrumbs of the active item.
+// The active key follows the url hash, so pages can be linked to.
+func (b *AppBuilder) NewNavShell(navId string) *NavShellComponentBuilder {
+	t := &NavShellComponentBuilder{
+		InnerComponentBuilder: newInnerComponent[*NavShellComponentBuilder](b),
+	}
+	t.inner = t
+	return t.Type("binding/v1/navShell").Properties(map[string]interface{}{
+		"nav":   navId,
+		"title": "",
+		"menu":  fmt.Sprintf("{{ %v.state }}", navId),
+	})
+}
+
+func (b *NavShellComponentBuilder) Title(title string) *NavShellComponentBuilder {
+	return b.Properties(map[string]interface{}{
+		"title": title,
+	})
+}
+
+// Page shows content while the menu item key is active.
+func (b *NavShellComponentBuilder) Page(key string, content ...BaseComponentBuilder) *NavShellComponentBuilder {
+	for _, c := range content {
+		c._Trait(b.appBuilder.NewTrait().Type("core/v1/hidden").Properties(map[string]interface{}{
+			"hidden": fmt.Sprintf("{{ %v.active !== %q }}", b.component.Id, key),
+		}))
+	}
+	return b.Children(map[string][]BaseComponentBuilder{
+		"content": content,
+	})
+}
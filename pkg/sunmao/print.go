@@ -0,0 +1,24 @@
+package sunmao
+
+// PrintLayout sets up the paper of a printable component, empty fields
+// keep the browser defaults.
+type PrintLayout struct {
+	// Size is a css page size such as "A4", "A5 landscape" or "62mm 29mm"
+	// for label printers.
+	Size   string `json:"size,omitempty"`
+	Margin string `json:"margin,omitempty"`
+}
+
+// Printable lets runtime.Print print the component on its own, the rest of
+// the page is hidden while the browser print dialog is open. layout may be
+// nil.
+func (b *InnerComponentBuilder[K]) Printable(layout *PrintLayout) K {
+	if layout == nil {
+		layout = &PrintLayout{}
+	}
+	b._Trait(b.appBuilder.NewTrait().Type("binding/v1/printable").Properties(map[string]interface{}{
+		"size":   layout.Size,
+		"margin": layout.Margin,
+	}))
+	return b.inner
+}
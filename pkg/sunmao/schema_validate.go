@@ -0,0 +1,187 @@
+package sunmao
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"unicode/utf8"
+)
+
+// SchemaError tells where a value does not match its schema.
+type SchemaError struct {
+	// Path is a json pointer such as /items/0/name, empty for the root.
+	Path    string
+	Message string
+}
+
+func (e *SchemaError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%v: %v", e.Path, e.Message)
+}
+
+// ValidateSchema checks a decoded json value against the subset of JSON
+// Schema SchemaOf generates, plus enum, minimum, maximum, minLength,
+// maxLength and pattern. Other keywords are ignored.
+func ValidateSchema(schema map[string]interface{}, v any) error {
+	return validateSchema(schema, v, "")
+}
+
+func schemaNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func schemaKeyword(schema map[string]interface{}, key string) (float64, bool) {
+	v, ok := schema[key]
+	if !ok {
+		return 0, false
+	}
+	return schemaNumber(v)
+}
+
+func matchesType(t string, v any) bool {
+	switch t {
+	case "null":
+		return v == nil
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "number":
+		_, ok := schemaNumber(v)
+		return ok
+	case "integer":
+		n, ok := schemaNumber(v)
+		return ok && n == math.Trunc(n)
+	case "array":
+		_, ok := v.([]any)
+		return ok
+	case "object":
+		_, ok := v.(map[string]any)
+		return ok
+	}
+	return true
+}
+
+func validateSchema(schema map[string]interface{}, v any, path string) error {
+	fail := func(format string, args ...any) error {
+		return &SchemaError{Path: path, Message: fmt.Sprintf(format, args...)}
+	}
+
+	if t, ok := schema["type"].(string); ok && !matchesType(t, v) {
+		return fail("expected %v", t)
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if fmt.Sprint(e) == fmt.Sprint(v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fail("must be one of %v", enum)
+		}
+	}
+
+	if n, ok := schemaNumber(v); ok {
+		if min, ok := schemaKeyword(schema, "minimum"); ok && n < min {
+			return fail("must be at least %v", min)
+		}
+		if max, ok := schemaKeyword(schema, "maximum"); ok && n > max {
+			return fail("must be at most %v", max)
+		}
+	}
+
+	switch value := v.(type) {
+	case string:
+		length := float64(utf8.RuneCountInString(value))
+		if min, ok := schemaKeyword(schema, "minLength"); ok && length < min {
+			return fail("must be at least %v characters", min)
+		}
+		if max, ok := schemaKeyword(schema, "maxLength"); ok && length > max {
+			return fail("must be at most %v characters", max)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return fail("invalid pattern %v", pattern)
+			}
+			if !re.MatchString(value) {
+				return fail("must match %v", pattern)
+			}
+		}
+	case []any:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range value {
+				if err := validateSchema(items, item, fmt.Sprintf("%v/%v", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case map[string]any:
+		for _, name := range requiredOf(schema) {
+			if _, ok := value[name]; !ok {
+				return &SchemaError{Path: path + "/" + name, Message: "is required"}
+			}
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		additional, _ := schema["additionalProperties"].(map[string]interface{})
+		// sorted so the reported error does not depend on map order
+		names := make([]string, 0, len(value))
+		for name := range value {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			sub, ok := properties[name].(map[string]interface{})
+			if !ok {
+				sub = additional
+			}
+			if sub == nil {
+				continue
+			}
+			if err := validateSchema(sub, value[name], path+"/"+name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// requiredOf accepts both the []string of SchemaOf and the []interface{}
+// of decoded schemas.
+func requiredOf(schema map[string]interface{}) []string {
+	switch required := schema["required"].(type) {
+	case []string:
+		return required
+	case []interface{}:
+		names := make([]string, 0, len(required))
+		for _, r := range required {
+			if name, ok := r.(string); ok {
+				names = append(names, name)
+			}
+		}
+		return names
+	}
+	return nil
+}
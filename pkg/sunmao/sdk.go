@@ -38,6 +38,7 @@ func (b *BaseBuilder[T]) Annotation(key string, value string) T {
 type AppBuilder struct {
 	*BaseBuilder[*AppBuilder]
 	application Application
+	meta        AppMeta
 }
 
 func NewApp() *AppBuilder {
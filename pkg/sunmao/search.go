@@ -0,0 +1,33 @@
+package sunmao
+
+type SearchBoxComponentBuilder struct {
+	*InnerComponentBuilder[*SearchBoxComponentBuilder]
+}
+
+// NewSearchBox renders a search input backed by runtime.Search with the
+// same id. Input is debounced on the client, the picked result is kept as
+// the selected state and fires onSelect.
+func (b *AppBuilder) NewSearchBox(search string) *SearchBoxComponentBuilder {
+	t := &SearchBoxComponentBuilder{
+		InnerComponentBuilder: newInnerComponent[*SearchBoxComponentBuilder](b),
+	}
+	t.inner = t
+	return t.Type("binding/v1/search").Properties(map[string]interface{}{
+		"search":      search,
+		"placeholder": "Search",
+		"debounce":    250,
+	})
+}
+
+func (b *SearchBoxComponentBuilder) Placeholder(text string) *SearchBoxComponentBuilder {
+	return b.Properties(map[string]interface{}{
+		"placeholder": text,
+	})
+}
+
+// Debounce is the pause in milliseconds after which input is sent.
+func (b *SearchBoxComponentBuilder) Debounce(ms int) *SearchBoxComponentBuilder {
+	return b.Properties(map[string]interface{}{
+		"debounce": ms,
+	})
+}
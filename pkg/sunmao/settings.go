@@ -0,0 +1,149 @@
+package sunmao
+
+import "fmt"
+
+// SettingsPreference is an entry of the preferences section of a settings
+// page, bound to the client preference Key.
+type SettingsPreference struct {
+	Key   string
+	Title string
+	// Default is used until the client stored a value, a bool renders a
+	// switch and anything else a text input.
+	Default any
+}
+
+// SettingsModuleType is the module type of the settings page of
+// settingsId.
+func SettingsModuleType(settingsId string) string {
+	return fmt.Sprintf("binding/v1/%v_settings", settingsId)
+}
+
+// NewSettingsModule builds the settings page served by
+// runtime.NewSettings under settingsId: the profile form, a password
+// change and the given preferences.
+func NewSettingsModule(settingsId string, prefs ...SettingsPreference) *ModuleBuilder {
+	b := NewChakraUIApp()
+	// ids inside a module are scoped by the id of its container
+	id := func(name string) string {
+		return "{{ $moduleId }}__" + name
+	}
+	value := func(name string) string {
+		return fmt.Sprintf("{{ {{ $moduleId }}__%v.value }}", name)
+	}
+	label := func(name, text string) BaseComponentBuilder {
+		return b.NewText().Id(id(name)).Content(text)
+	}
+	input := func(name, defaultValue string) BaseComponentBuilder {
+		return b.NewInput().Id(id(name)).Properties(map[string]interface{}{
+			"defaultValue": defaultValue,
+		})
+	}
+	password := func(name, placeholder string) BaseComponentBuilder {
+		return b.NewComponent().Id(id(name)).Type("arco/v1/passwordInput").Properties(map[string]interface{}{
+			"placeholder":      placeholder,
+			"disabled":         false,
+			"size":             "default",
+			"visibilityToggle": true,
+			"error":            false,
+		})
+	}
+
+	content := []BaseComponentBuilder{
+		label("profile_title", "Profile"),
+		label("name_label", "Name"),
+		input("name", "{{ profile.name }}"),
+		label("email_label", "Email"),
+		input("email", "{{ profile.email }}"),
+		b.NewButton().Id(id("save_profile")).Content("Save profile").OnClick(&ServerHandler{
+			Name: settingsId + "/profile",
+			Parameters: map[string]interface{}{
+				"name":  value("name"),
+				"email": value("email"),
+			},
+		}),
+		label("password_title", "Password"),
+		password("current", "Current password"),
+		password("next", "New password"),
+		password("confirm", "Repeat new password"),
+		b.NewButton().Id(id("change_password")).Content("Change password").OnClick(&ServerHandler{
+			Name: settingsId + "/password",
+			Parameters: map[string]interface{}{
+				"current": value("current"),
+				"next":    value("next"),
+				"confirm": value("confirm"),
+			},
+		}),
+	}
+
+	if len(prefs) > 0 {
+		content = append(content, label("preferences_title", "Preferences"))
+	}
+	for i, p := range prefs {
+		name := fmt.Sprintf("pref_%v", i)
+		current := fmt.Sprintf("{{ preferences[%q] }}", p.Key)
+		var field *ComponentBuilder
+		event := "onBlur"
+		if _, ok := p.Default.(bool); ok {
+			field = b.NewComponent().Id(id(name)).Type("arco/v1/switch").Properties(map[string]interface{}{
+				"defaultChecked": current,
+				"disabled":       false,
+				"loading":        false,
+				"type":           "circle",
+				"size":           "default",
+			})
+			event = "onChange"
+		} else {
+			field = b.NewInput().Id(id(name)).Properties(map[string]interface{}{
+				"defaultValue": current,
+			})
+		}
+		field.Trait(b.NewTrait().Type("core/v1/event").Properties(map[string]interface{}{
+			"handlers": []map[string]interface{}{
+				{
+					"type":        event,
+					"componentId": "$utils",
+					"method": map[string]interface{}{
+						"name": "binding/v1/setPreference",
+						"parameters": map[string]interface{}{
+							"key":   p.Key,
+							"value": value(name),
+						},
+					},
+				},
+			},
+		}))
+		content = append(content, label(name+"_label", p.Title), field)
+	}
+	content = append(content, b.NewText().Id(id("message")).Content("{{ message }}").Hidden("{{ !message }}"))
+
+	b.Component(b.NewStack().Id(id("root")).Properties(map[string]interface{}{
+		"direction": "vertical",
+	}).Children(map[string][]BaseComponentBuilder{
+		"content": content,
+	}))
+
+	return NewModule().
+		Version("binding/v1").
+		Name(settingsId + "_settings").
+		Properties(map[string]interface{}{
+			"profile":     map[string]interface{}{"name": "", "email": ""},
+			"preferences": map[string]interface{}{},
+			"message":     "",
+		}).
+		Impl(b.ValueOf())
+}
+
+// NewSettingsPage places the settings page of settingsId, its module
+// must be loaded with runtime.Settings.Module.
+func (b *AppBuilder) NewSettingsPage(settingsId string) *ComponentBuilder {
+	return b.NewComponent().Id(settingsId + "_page").Type("core/v1/moduleContainer").Properties(map[string]interface{}{
+		"id":   settingsId + "_page",
+		"type": SettingsModuleType(settingsId),
+		"properties": map[string]interface{}{
+			"profile":     fmt.Sprintf("{{ %v.state.profile }}", settingsId),
+			"preferences": fmt.Sprintf("{{ %v.state.preferences }}", settingsId),
+			"message":     fmt.Sprintf("{{ %v.state.message }}", settingsId),
+		},
+		"handlers": []interface{}{},
+	})
+}
@@ -0,0 +1,40 @@
+package sunmao
+
+import "fmt"
+
+type TerminalComponentBuilder struct {
+	*InnerComponentBuilder[*TerminalComponentBuilder]
+}
+
+// NewTerminal renders a terminal for the sessions of runtime.NewTerminal
+// under terminal. It opens a session sized to fit once shown and sends the
+// keystrokes typed while focused. Escape sequences are stripped, so line
+// based shells work but full screen programs such as vim do not.
+func (b *AppBuilder) NewTerminal(terminal string) *TerminalComponentBuilder {
+	t := &TerminalComponentBuilder{
+		InnerComponentBuilder: newInnerComponent[*TerminalComponentBuilder](b),
+	}
+	t.inner = t
+	return t.Type("binding/v1/terminal").Properties(map[string]interface{}{
+		"terminal": terminal,
+		"session":  fmt.Sprintf("{{ %v.state }}", terminal),
+		"params":   map[string]interface{}{},
+		"height":   400,
+	})
+}
+
+// Height is the height of the terminal in pixels.
+func (b *TerminalComponentBuilder) Height(px int) *TerminalComponentBuilder {
+	return b.Properties(map[string]interface{}{
+		"height": px,
+	})
+}
+
+// Params reach the runtime.TerminalOpen of the session and may hold
+// expressions such as "{{ pods.selectedItem.name }}", the session is
+// reopened when they change.
+func (b *TerminalComponentBuilder) Params(params map[string]interface{}) *TerminalComponentBuilder {
+	return b.Properties(map[string]interface{}{
+		"params": params,
+	})
+}
@@ -0,0 +1,17 @@
+package sunmao
+
+type TreeComponentBuilder struct {
+	*InnerComponentBuilder[*TreeComponentBuilder]
+}
+
+// NewTree renders the tree served by runtime.NewTree under treeId, nodes
+// are loaded when they are expanded. Its state holds the "selected" node.
+func (b *AppBuilder) NewTree(treeId string) *TreeComponentBuilder {
+	t := &TreeComponentBuilder{
+		InnerComponentBuilder: newInnerComponent[*TreeComponentBuilder](b),
+	}
+	t.inner = t
+	return t.Type("binding/v1/tree").Properties(map[string]interface{}{
+		"tree": treeId,
+	})
+}
@@ -0,0 +1,43 @@
+package sunmao
+
+type TypeaheadComponentBuilder struct {
+	*InnerComponentBuilder[*TypeaheadComponentBuilder]
+}
+
+// NewTypeahead renders an input suggesting options from
+// runtime.NewTypeahead with the same id. Keystrokes are debounced on the
+// client, the input text and the picked option are kept as the text and
+// value states and the pick fires onSelect.
+func (b *AppBuilder) NewTypeahead(typeahead string) *TypeaheadComponentBuilder {
+	t := &TypeaheadComponentBuilder{
+		InnerComponentBuilder: newInnerComponent[*TypeaheadComponentBuilder](b),
+	}
+	t.inner = t
+	return t.Type("binding/v1/typeahead").Properties(map[string]interface{}{
+		"typeahead":   typeahead,
+		"placeholder": "",
+		"debounce":    200,
+		"minLength":   1,
+	})
+}
+
+func (b *TypeaheadComponentBuilder) Placeholder(text string) *TypeaheadComponentBuilder {
+	return b.Properties(map[string]interface{}{
+		"placeholder": text,
+	})
+}
+
+// Debounce is the pause in milliseconds after which input is sent.
+func (b *TypeaheadComponentBuilder) Debounce(ms int) *TypeaheadComponentBuilder {
+	return b.Properties(map[string]interface{}{
+		"debounce": ms,
+	})
+}
+
+// MinLength is the number of characters before options are suggested, 0
+// suggests on focus already.
+func (b *TypeaheadComponentBuilder) MinLength(n int) *TypeaheadComponentBuilder {
+	return b.Properties(map[string]interface{}{
+		"minLength": n,
+	})
+}
@@ -0,0 +1,44 @@
+package sunmao
+
+type DropZoneComponentBuilder struct {
+	*InnerComponentBuilder[*DropZoneComponentBuilder]
+}
+
+// NewDropZone renders an area files can be dropped or pasted onto, they
+// are uploaded to runtime.HandleUpload with the same id together with the
+// zone's component id. Components in its content slot are shown instead of
+// the hint text.
+func (b *AppBuilder) NewDropZone(upload string) *DropZoneComponentBuilder {
+	t := &DropZoneComponentBuilder{
+		InnerComponentBuilder: newInnerComponent[*DropZoneComponentBuilder](b),
+	}
+	t.inner = t
+	return t.Type("binding/v1/dropZone").Properties(map[string]interface{}{
+		"upload":        upload,
+		"text":          "Drop or paste files here",
+		"accept":        "",
+		"pasteAnywhere": false,
+	})
+}
+
+func (b *DropZoneComponentBuilder) Text(text string) *DropZoneComponentBuilder {
+	return b.Properties(map[string]interface{}{
+		"text": text,
+	})
+}
+
+// Accept limits the files to a comma separated list of mime types and
+// extensions such as "image/*,.pdf", like the accept attribute of inputs.
+func (b *DropZoneComponentBuilder) Accept(accept string) *DropZoneComponentBuilder {
+	return b.Properties(map[string]interface{}{
+		"accept": accept,
+	})
+}
+
+// PasteAnywhere takes files pasted anywhere on the page, not only while
+// the zone has focus.
+func (b *DropZoneComponentBuilder) PasteAnywhere() *DropZoneComponentBuilder {
+	return b.Properties(map[string]interface{}{
+		"pasteAnywhere": true,
+	})
+}
@@ -0,0 +1,147 @@
+package sunmao
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Rule is one check of the core/v1/validation trait, with the Go check
+// doing the same on the server. Define rules once and use them for both
+// Validate and Check.
+type Rule struct {
+	Type    string
+	Message string
+	Options map[string]interface{}
+	// Expression is the client side check of custom rules.
+	Expression string
+	check      func(v any) bool
+}
+
+func Required(message string) Rule {
+	return Rule{Type: "required", Message: message, check: func(v any) bool {
+		return v != nil && strings.TrimSpace(fmt.Sprint(v)) != ""
+	}}
+}
+
+func MinLength(n int, message string) Rule {
+	return Rule{Type: "minLength", Message: message, Options: map[string]interface{}{"minLength": n}, check: func(v any) bool {
+		return len([]rune(fmt.Sprint(v))) >= n
+	}}
+}
+
+func MaxLength(n int, message string) Rule {
+	return Rule{Type: "maxLength", Message: message, Options: map[string]interface{}{"maxLength": n}, check: func(v any) bool {
+		return len([]rune(fmt.Sprint(v))) <= n
+	}}
+}
+
+func Min(n float64, message string) Rule {
+	return Rule{Type: "min", Message: message, Options: map[string]interface{}{"min": n}, check: func(v any) bool {
+		f, ok := toFloat(v)
+		return ok && f >= n
+	}}
+}
+
+func Max(n float64, message string) Rule {
+	return Rule{Type: "max", Message: message, Options: map[string]interface{}{"max": n}, check: func(v any) bool {
+		f, ok := toFloat(v)
+		return ok && f <= n
+	}}
+}
+
+// Pattern accepts values matching re, written in the common subset of Go
+// and JavaScript regular expressions.
+func Pattern(re string, message string) Rule {
+	compiled := regexp.MustCompile(re)
+	return Rule{Type: "regex", Message: message, Options: map[string]interface{}{"regex": re}, check: func(v any) bool {
+		return compiled.MatchString(fmt.Sprint(v))
+	}}
+}
+
+// Custom runs expression on the client, where value is the validated
+// value, and check on the server.
+func Custom(expression string, check func(v any) bool, message string) Rule {
+	return Rule{Type: "custom", Message: message, Expression: expression, check: check}
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case string:
+		f := 0.0
+		_, err := fmt.Sscan(n, &f)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func (r Rule) schema() map[string]interface{} {
+	rule := map[string]interface{}{
+		"type": r.Type,
+		"error": map[string]interface{}{
+			"message": r.Message,
+		},
+	}
+	if r.Options != nil {
+		rule["customOptions"] = r.Options
+	}
+	if r.Expression != "" {
+		rule["validate"] = r.Expression
+	}
+	return rule
+}
+
+// Validate adds a validator named name over the value expression, its
+// result is exposed as {{ id.validatedResult[name] }}.
+func (b *InnerComponentBuilder[K]) Validate(name string, value string, rules ...Rule) K {
+	schemas := make([]map[string]interface{}, len(rules))
+	for i, r := range rules {
+		schemas[i] = r.schema()
+	}
+	b._Trait(b.appBuilder.NewTrait().Type("core/v1/validation").Properties(map[string]interface{}{
+		"validators": []map[string]interface{}{
+			{
+				"name":  name,
+				"value": value,
+				"rules": schemas,
+			},
+		},
+	}))
+	return b.inner
+}
+
+// ValidationError lists the messages of the rules a value broke.
+type ValidationError struct {
+	Messages []string
+}
+
+func (e *ValidationError) Error() string {
+	return strings.Join(e.Messages, "; ")
+}
+
+// Check revalidates a submitted value on the server with the same rules
+// the client used.
+func Check(v any, rules ...Rule) error {
+	messages := []string{}
+	for _, r := range rules {
+		if r.Type != "required" && (v == nil || fmt.Sprint(v) == "") {
+			// like on the client, only required rejects an empty value
+			continue
+		}
+		if r.check != nil && !r.check(v) {
+			messages = append(messages, r.Message)
+		}
+	}
+	if len(messages) > 0 {
+		return &ValidationError{Messages: messages}
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package sunmao
+
+type VirtualListComponentBuilder struct {
+	*InnerComponentBuilder[*VirtualListComponentBuilder]
+}
+
+// NewVirtualList renders the items served by runtime.NewVirtualList under
+// listId, only the rows in view are requested and kept in the page. Its
+// state holds the "total" and the "selected" item.
+func (b *AppBuilder) NewVirtualList(listId string) *VirtualListComponentBuilder {
+	t := &VirtualListComponentBuilder{
+		InnerComponentBuilder: newInnerComponent[*VirtualListComponentBuilder](b),
+	}
+	t.inner = t
+	return t.Type("binding/v1/virtualList").Properties(map[string]interface{}{
+		"list":       listId,
+		"itemHeight": 48,
+		"height":     480,
+	})
+}
+
+// ItemHeight sets the fixed height of a row in pixels, 48 by default.
+func (b *VirtualListComponentBuilder) ItemHeight(px int) *VirtualListComponentBuilder {
+	return b.Properties(map[string]interface{}{
+		"itemHeight": px,
+	})
+}
+
+// Height sets the height of the scrolled viewport in pixels, 480 by
+// default.
+func (b *VirtualListComponentBuilder) Height(px int) *VirtualListComponentBuilder {
+	return b.Properties(map[string]interface{}{
+		"height": px,
+	})
+}
@@ -3,39 +3,110 @@ import "@sunmao-ui/arco-lib/dist/index.css";
 import {
   getLibs,
   useApiService,
+  useHandlers,
   BaseProps,
   patchApp,
   patchModules,
+  StateSetter,
+  hydrateApp,
+  withFlags,
 } from "./shared";
 import { RuntimeModule } from "@sunmao-ui/core";
+import { useShortcuts } from "./shortcuts";
+import { useClientEvents } from "./lifecycle";
+import { usePreferences } from "./preferences";
+import { useImages } from "./images";
+import { usePrint, usePrintReport } from "./report";
+import { useSchemaPatch } from "./schema";
+import { useAnnouncements } from "./announce";
+import { CommandPalette } from "./palette";
+import { useMemo, useState } from "react";
 
 function App(props: BaseProps) {
   const {
     application,
     modules,
     handlers,
+    handlerSpecs,
     ws,
     utilMethods,
     applicationPatch,
     modulesPatch,
+    shortcuts,
+    clientEvents,
+    preferences,
+    hydration,
+    flags,
+    commandPalette,
   } = props;
-  const {
-    App: SunmaoApp,
-    apiService,
-    registry,
-  } = initSunmaoUI({
-    libs: getLibs({ ws, handlers, utilMethods }),
-  });
+  // initialized once, schema patches must not reset the runtime state
+  const { SunmaoApp, apiService, registry, getStore, setState } =
+    useMemo(() => {
+      // the libs are created before the state manager exists
+      let store: Record<string, any> = {};
+      const getStore = () => store;
+      let setState: StateSetter = () => {};
+      const {
+        App: SunmaoApp,
+        apiService,
+        registry,
+        stateManager,
+      } = initSunmaoUI({
+        libs: getLibs({
+          ws,
+          handlers,
+          handlerSpecs,
+          getStore,
+          setState: (componentId, value) => setState(componentId, value),
+          utilMethods,
+        }),
+      });
+      store = stateManager.store;
+      setState = (componentId, value) =>
+        apiService.send("uiMethod", {
+          componentId,
+          name: "setValue",
+          parameters: { key: "state", value },
+        });
 
-  if (modules) {
-    patchModules(modules, modulesPatch).forEach((moduleSchema) => {
-      registry.registerModule(moduleSchema as RuntimeModule);
-    });
-  }
+      if (modules) {
+        patchModules(modules, modulesPatch).forEach((moduleSchema) => {
+          registry.registerModule(moduleSchema as RuntimeModule);
+        });
+      }
+      return { SunmaoApp, apiService, registry, getStore, setState };
+    }, []);
+  const [app, setApp] = useState(application);
 
+  // preferences go out first, so OnAppServed hooks can read them
+  usePreferences({ ws, preferences });
   useApiService({ ws, apiService });
+  useImages({ ws, apiService });
+  usePrintReport({ ws });
+  usePrint({ ws });
+  useHandlers({ ws, handlers, handlerSpecs, registry, getStore, setState });
+  useShortcuts({ ws, shortcuts });
+  useClientEvents({ ws, enabled: clientEvents });
+  useSchemaPatch({ ws, setApp });
+  useAnnouncements({ ws });
 
-  return <SunmaoApp options={patchApp(application, applicationPatch)} />;
+  return (
+    <>
+      <SunmaoApp
+        options={withFlags(
+          hydrateApp(patchApp(app, applicationPatch), hydration),
+          flags
+        )}
+      />
+      {commandPalette && (
+        <CommandPalette
+          ws={ws}
+          options={commandPalette}
+          handlerSpecs={handlerSpecs}
+        />
+      )}
+    </>
+  );
 }
 
 export default App;
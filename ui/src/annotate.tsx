@@ -0,0 +1,217 @@
+import { implementRuntimeComponent } from "@sunmao-ui/runtime";
+import { PointerEvent, useRef, useState } from "react";
+import { Socket } from "./socket";
+
+type Annotation = {
+  id: string;
+  label?: string;
+  x: number;
+  y: number;
+  w: number;
+  h: number;
+  color?: string;
+};
+
+type Box = { x: number; y: number; w: number; h: number };
+
+// drags shorter than this, as a fraction of the image, are clicks
+const MIN_BOX = 0.01;
+
+const styles = {
+  root: {
+    position: "relative",
+    display: "inline-block",
+    lineHeight: 0,
+    userSelect: "none",
+    touchAction: "none",
+  },
+  image: { maxWidth: "100%", display: "block" },
+  overlay: {
+    position: "absolute",
+    inset: 0,
+    width: "100%",
+    height: "100%",
+  },
+  label: {
+    position: "absolute",
+    padding: "0 4px",
+    fontSize: 12,
+    lineHeight: "16px",
+    color: "#fff",
+    whiteSpace: "nowrap",
+    pointerEvents: "none",
+    transform: "translateY(-100%)",
+  },
+} as const;
+
+type Point = { x: number; y: number };
+
+function normalize(from: Point, to: Point): Box {
+  return {
+    x: Math.min(from.x, to.x),
+    y: Math.min(from.y, to.y),
+    w: Math.abs(to.x - from.x),
+    h: Math.abs(to.y - from.y),
+  };
+}
+
+// the client of runtime.NewImageAnnotator, coordinates are fractions of
+// the image so boxes follow it when it scales
+export function imageAnnotatorComponent(ws: Socket | null) {
+  return implementRuntimeComponent({
+    version: "binding/v1",
+    metadata: {
+      name: "imageAnnotator",
+      displayName: "Image Annotator",
+      exampleProperties: {
+        annotator: "",
+        src: "",
+        annotations: [],
+        drawable: true,
+      },
+      annotations: { category: "Display" },
+      isDraggable: true,
+      isResizable: true,
+    },
+    spec: {
+      properties: {} as any,
+      state: {} as any,
+      methods: {},
+      slots: {},
+      styleSlots: ["content"],
+      events: ["onClick", "onDraw"],
+    },
+  })(
+    ({
+      annotator,
+      src,
+      annotations,
+      drawable,
+      mergeState,
+      callbackMap,
+      elementRef,
+    }: any) => {
+      const root = useRef<HTMLDivElement | null>(null);
+      const start = useRef<Point>();
+      const [draft, setDraft] = useState<Box>();
+      const list: Annotation[] = annotations || [];
+
+      const send = (action: string, params: any) =>
+        ws?.send(
+          JSON.stringify({
+            type: "Action",
+            handler: `${annotator}/${action}`,
+            params,
+          })
+        );
+
+      const pointOf = (evt: PointerEvent) => {
+        const rect = root.current!.getBoundingClientRect();
+        return {
+          x: Math.max(0, Math.min(1, (evt.clientX - rect.left) / rect.width)),
+          y: Math.max(0, Math.min(1, (evt.clientY - rect.top) / rect.height)),
+        };
+      };
+
+      const onPointerDown = (evt: PointerEvent) => {
+        (evt.target as Element).setPointerCapture?.(evt.pointerId);
+        start.current = pointOf(evt);
+      };
+      const onPointerMove = (evt: PointerEvent) => {
+        if (start.current && drawable) {
+          setDraft(normalize(start.current, pointOf(evt)));
+        }
+      };
+      const onPointerUp = (evt: PointerEvent) => {
+        const from = start.current;
+        start.current = undefined;
+        setDraft(undefined);
+        if (!from) {
+          return;
+        }
+        const to = pointOf(evt);
+        const box = normalize(from, to);
+        if (drawable && (box.w > MIN_BOX || box.h > MIN_BOX)) {
+          send("draw", box);
+          mergeState({ drawn: box });
+          callbackMap?.onDraw?.();
+          return;
+        }
+        send("click", to);
+        mergeState({ clicked: to });
+        callbackMap?.onClick?.();
+      };
+
+      const percent = (n: number) => `${n * 100}%`;
+
+      return (
+        <div
+          ref={(el) => {
+            root.current = el;
+            if (elementRef) {
+              elementRef.current = el;
+            }
+          }}
+          style={{ ...styles.root, cursor: drawable ? "crosshair" : "pointer" }}
+          onPointerDown={onPointerDown}
+          onPointerMove={onPointerMove}
+          onPointerUp={onPointerUp}
+        >
+          {src && (
+            <img src={src} alt="" draggable={false} style={styles.image} />
+          )}
+          <svg
+            style={styles.overlay}
+            viewBox="0 0 1 1"
+            preserveAspectRatio="none"
+          >
+            {list.map((a) => (
+              <rect
+                key={a.id}
+                x={a.x}
+                y={a.y}
+                width={a.w}
+                height={a.h}
+                fill={a.color || "#165dff"}
+                fillOpacity={0.1}
+                stroke={a.color || "#165dff"}
+                strokeWidth={2}
+                vectorEffect="non-scaling-stroke"
+                onPointerEnter={() => mergeState({ hovered: a })}
+                onPointerLeave={() => mergeState({ hovered: undefined })}
+              />
+            ))}
+            {draft && (
+              <rect
+                x={draft.x}
+                y={draft.y}
+                width={draft.w}
+                height={draft.h}
+                fill="none"
+                stroke="#ff7d00"
+                strokeWidth={2}
+                strokeDasharray="4 2"
+                vectorEffect="non-scaling-stroke"
+              />
+            )}
+          </svg>
+          {list
+            .filter((a) => a.label)
+            .map((a) => (
+              <div
+                key={a.id}
+                style={{
+                  ...styles.label,
+                  left: percent(a.x),
+                  top: percent(a.y),
+                  background: a.color || "#165dff",
+                }}
+              >
+                {a.label}
+              </div>
+            ))}
+        </div>
+      );
+    }
+  );
+}
@@ -0,0 +1,47 @@
+import { useEffect } from "react";
+import { Socket } from "./socket";
+
+const COLORS: Record<string, string> = {
+  info: "#e7f5ff",
+  warning: "#fff9db",
+  error: "#fff5f5",
+};
+
+// shows the banner of r.Announce on top of the app
+export function useAnnouncements({ ws }: { ws: Socket | null }) {
+  useEffect(() => {
+    if (!ws) {
+      return;
+    }
+    const socket = ws;
+    const banner = document.createElement("div");
+    banner.setAttribute("role", "status");
+    Object.assign(banner.style, {
+      position: "fixed",
+      top: "0",
+      left: "0",
+      right: "0",
+      zIndex: "10000",
+      padding: "8px 16px",
+      textAlign: "center",
+      fontFamily: "sans-serif",
+      display: "none",
+    });
+    document.body.appendChild(banner);
+
+    const messageHandler = (evt: Event) => {
+      const message = JSON.parse((evt as MessageEvent).data);
+      if (message.type !== "Announce") {
+        return;
+      }
+      banner.textContent = message.message;
+      banner.style.background = COLORS[message.level] || COLORS.info;
+      banner.style.display = message.message ? "block" : "none";
+    };
+    socket.addEventListener("message", messageHandler);
+    return () => {
+      socket.removeEventListener("message", messageHandler);
+      banner.remove();
+    };
+  }, [ws]);
+}
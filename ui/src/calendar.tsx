@@ -0,0 +1,314 @@
+import { implementRuntimeComponent } from "@sunmao-ui/runtime";
+import { useEffect, useState } from "react";
+import { Socket } from "./socket";
+
+type CalendarEvent = {
+  id: string;
+  title: string;
+  start: string;
+  end: string;
+  color?: string;
+};
+
+const DAY = 24 * 60 * 60 * 1000;
+const HOUR_HEIGHT = 40;
+
+const styles = {
+  toolbar: {
+    display: "flex",
+    alignItems: "center",
+    gap: 8,
+    marginBottom: 8,
+  },
+  range: { flex: 1, fontWeight: 500 },
+  button: {
+    padding: "4px 10px",
+    border: "1px solid #e5e6eb",
+    borderRadius: 4,
+    background: "#fff",
+    cursor: "pointer",
+  },
+  weekdays: {
+    display: "grid",
+    gridTemplateColumns: "repeat(7, 1fr)",
+    fontSize: 12,
+    color: "#86909c",
+  },
+  month: {
+    display: "grid",
+    gridTemplateColumns: "repeat(7, 1fr)",
+    gridAutoRows: "minmax(96px, auto)",
+    border: "1px solid #e5e6eb",
+    borderWidth: "1px 0 0 1px",
+  },
+  day: {
+    padding: 4,
+    border: "1px solid #e5e6eb",
+    borderWidth: "0 1px 1px 0",
+    cursor: "pointer",
+    minWidth: 0,
+  },
+  date: { fontSize: 12, marginBottom: 2 },
+  event: {
+    marginBottom: 2,
+    padding: "1px 4px",
+    borderRadius: 2,
+    color: "#fff",
+    fontSize: 12,
+    whiteSpace: "nowrap",
+    overflow: "hidden",
+    textOverflow: "ellipsis",
+    cursor: "pointer",
+  },
+  week: { display: "flex", overflowY: "auto", maxHeight: 600 },
+  hours: { flex: "0 0 48px", fontSize: 11, color: "#86909c" },
+  column: {
+    position: "relative",
+    flex: 1,
+    minWidth: 0,
+    borderLeft: "1px solid #e5e6eb",
+  },
+  hour: {
+    height: HOUR_HEIGHT,
+    boxSizing: "border-box",
+    borderBottom: "1px solid #f2f3f5",
+    cursor: "pointer",
+  },
+} as const;
+
+function startOfDay(d: Date) {
+  return new Date(d.getFullYear(), d.getMonth(), d.getDate());
+}
+
+function startOfWeek(d: Date, weekStartsOn: number) {
+  const day = startOfDay(d);
+  const back = (day.getDay() - weekStartsOn + 7) % 7;
+  return new Date(day.getFullYear(), day.getMonth(), day.getDate() - back);
+}
+
+function addDays(d: Date, n: number) {
+  return new Date(d.getFullYear(), d.getMonth(), d.getDate() + n);
+}
+
+// events overlapping the day, multi day events show on each of their days
+function eventsOn(events: CalendarEvent[], day: Date) {
+  const from = day.getTime();
+  const to = addDays(day, 1).getTime();
+  return events.filter(
+    (e) => new Date(e.start).getTime() < to && new Date(e.end).getTime() > from
+  );
+}
+
+// the client of runtime.NewCalendar
+export function calendarComponent(ws: Socket | null) {
+  return implementRuntimeComponent({
+    version: "binding/v1",
+    metadata: {
+      name: "calendar",
+      displayName: "Calendar",
+      exampleProperties: {
+        calendar: "",
+        events: [],
+        view: "month",
+        weekStartsOn: 1,
+      },
+      annotations: { category: "Display" },
+      isDraggable: true,
+      isResizable: true,
+    },
+    spec: {
+      properties: {} as any,
+      state: {} as any,
+      methods: {},
+      slots: {},
+      styleSlots: ["content"],
+      events: ["onEventClick"],
+    },
+  })(
+    ({
+      calendar,
+      events,
+      view: initialView,
+      weekStartsOn,
+      mergeState,
+      callbackMap,
+      elementRef,
+    }: any) => {
+      const [view, setView] = useState<string>(initialView || "month");
+      const [cursor, setCursor] = useState(() => startOfDay(new Date()));
+      const list: CalendarEvent[] = events || [];
+      const first = weekStartsOn ?? 1;
+
+      const monthStart = new Date(cursor.getFullYear(), cursor.getMonth());
+      const start = startOfWeek(view === "week" ? cursor : monthStart, first);
+      const days = Array.from({ length: view === "week" ? 7 : 42 }, (_, i) =>
+        addDays(start, i)
+      );
+
+      useEffect(() => {
+        mergeState({ view, start: start.toISOString() });
+      }, [view, start.getTime()]);
+
+      const send = (action: string, params: any) =>
+        ws?.send(
+          JSON.stringify({
+            type: "Action",
+            handler: `${calendar}/${action}`,
+            params,
+          })
+        );
+      const click = (evt: { stopPropagation(): void }, e: CalendarEvent) => {
+        evt.stopPropagation();
+        mergeState({ selected: e });
+        send("click", { event: e.id });
+        callbackMap?.onEventClick?.();
+      };
+      const create = (from: Date, to: Date) =>
+        send("create", { start: from.toISOString(), end: to.toISOString() });
+
+      const step = (n: number) =>
+        setCursor(
+          view === "week"
+            ? addDays(cursor, 7 * n)
+            : new Date(cursor.getFullYear(), cursor.getMonth() + n, 1)
+        );
+
+      const title =
+        view === "week"
+          ? `${days[0].toLocaleDateString()} – ${days[6].toLocaleDateString()}`
+          : cursor.toLocaleDateString(undefined, {
+              year: "numeric",
+              month: "long",
+            });
+
+      const badge = (e: CalendarEvent) => (
+        <div
+          key={e.id}
+          title={e.title}
+          style={{ ...styles.event, background: e.color || "#165dff" }}
+          onClick={(evt) => click(evt, e)}
+        >
+          {e.title}
+        </div>
+      );
+
+      return (
+        <div ref={elementRef}>
+          <div style={styles.toolbar}>
+            <button style={styles.button} onClick={() => step(-1)}>
+              ‹
+            </button>
+            <button
+              style={styles.button}
+              onClick={() => setCursor(startOfDay(new Date()))}
+            >
+              Today
+            </button>
+            <button style={styles.button} onClick={() => step(1)}>
+              ›
+            </button>
+            <span style={styles.range}>{title}</span>
+            {["month", "week"].map((v) => (
+              <button
+                key={v}
+                style={{
+                  ...styles.button,
+                  background: v === view ? "#e8f3ff" : "#fff",
+                }}
+                onClick={() => setView(v)}
+              >
+                {v === "month" ? "Month" : "Week"}
+              </button>
+            ))}
+          </div>
+          <div style={styles.weekdays}>
+            {days.slice(0, 7).map((d) => (
+              <div key={d.getTime()} style={{ padding: 4 }}>
+                {d.toLocaleDateString(undefined, { weekday: "short" })}
+                {view === "week" && ` ${d.getDate()}`}
+              </div>
+            ))}
+          </div>
+          {view === "week" ? (
+            <div style={styles.week}>
+              <div style={styles.hours}>
+                {Array.from({ length: 24 }, (_, h) => (
+                  <div key={h} style={{ height: HOUR_HEIGHT }}>
+                    {`${h}:00`}
+                  </div>
+                ))}
+              </div>
+              {days.map((day) => (
+                <div key={day.getTime()} style={styles.column}>
+                  {Array.from({ length: 24 }, (_, h) => (
+                    <div
+                      key={h}
+                      style={styles.hour}
+                      onClick={() =>
+                        create(
+                          new Date(day.getTime() + h * 3600000),
+                          new Date(day.getTime() + (h + 1) * 3600000)
+                        )
+                      }
+                    />
+                  ))}
+                  {eventsOn(list, day).map((e) => {
+                    // clipped to the day for events crossing midnight
+                    const from = Math.max(
+                      new Date(e.start).getTime(),
+                      day.getTime()
+                    );
+                    const to = Math.min(
+                      new Date(e.end).getTime(),
+                      day.getTime() + DAY
+                    );
+                    return (
+                      <div
+                        key={e.id}
+                        title={e.title}
+                        style={{
+                          ...styles.event,
+                          position: "absolute",
+                          left: 2,
+                          right: 2,
+                          top: ((from - day.getTime()) / 3600000) * HOUR_HEIGHT,
+                          height: Math.max(
+                            ((to - from) / 3600000) * HOUR_HEIGHT - 2,
+                            16
+                          ),
+                          background: e.color || "#165dff",
+                        }}
+                        onClick={(evt) => click(evt, e)}
+                      >
+                        {e.title}
+                      </div>
+                    );
+                  })}
+                </div>
+              ))}
+            </div>
+          ) : (
+            <div style={styles.month}>
+              {days.map((day) => (
+                <div
+                  key={day.getTime()}
+                  style={{
+                    ...styles.day,
+                    color:
+                      day.getMonth() === cursor.getMonth()
+                        ? undefined
+                        : "#c9cdd4",
+                  }}
+                  onClick={() => create(day, addDays(day, 1))}
+                >
+                  <div style={styles.date}>{day.getDate()}</div>
+                  {eventsOn(list, day).map(badge)}
+                </div>
+              ))}
+            </div>
+          )}
+        </div>
+      );
+    }
+  );
+}
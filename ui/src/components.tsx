@@ -0,0 +1,73 @@
+import { implementRuntimeComponent } from "@sunmao-ui/runtime";
+import React from "react";
+import { Socket } from "./socket";
+
+class Boundary extends React.Component<
+  {
+    fallback: string;
+    onError: (error: Error, componentStack: string) => void;
+    children?: React.ReactNode;
+  },
+  { error?: Error }
+> {
+  state: { error?: Error } = {};
+
+  static getDerivedStateFromError(error: Error) {
+    return { error };
+  }
+
+  componentDidCatch(error: Error, info: React.ErrorInfo) {
+    this.props.onError(error, info.componentStack);
+  }
+
+  render() {
+    if (this.state.error) {
+      return <div role="alert">{this.props.fallback}</div>;
+    }
+    return this.props.children;
+  }
+}
+
+// keeps a broken subtree from blanking the app and reports what broke
+export function errorBoundaryComponent(ws: Socket | null) {
+  return implementRuntimeComponent({
+    version: "binding/v1",
+    metadata: {
+      name: "errorBoundary",
+      displayName: "Error Boundary",
+      exampleProperties: { fallback: "Something went wrong." },
+      annotations: { category: "Advance" },
+      isDraggable: true,
+      isResizable: true,
+    },
+    spec: {
+      properties: {} as any,
+      state: {} as any,
+      methods: {},
+      slots: { content: { slotProps: {} as any } },
+      styleSlots: ["content"],
+      events: [],
+    },
+  })(({ component, fallback, slotsElements, elementRef }: any) => (
+    <div ref={elementRef}>
+      <Boundary
+        fallback={fallback}
+        onError={(error, componentStack) =>
+          ws?.send(
+            JSON.stringify({
+              type: "ClientError",
+              params: {
+                componentId: component.id,
+                message: error.message,
+                stack: error.stack,
+                componentStack,
+              },
+            })
+          )
+        }
+      >
+        {slotsElements.content ? slotsElements.content({}) : null}
+      </Boundary>
+    </div>
+  ));
+}
@@ -0,0 +1,36 @@
+import { Socket } from "../socket";
+
+// add the implementations of components declared with DeclareComponents
+// here, they are matched by "version/name"
+export const customComponents: any[] = [];
+
+export type CustomComponentDeclaration = {
+  name: string;
+  propsSchema?: any;
+  eventNames?: string[];
+};
+
+// reports declared components without an implementation, which would
+// otherwise only fail once rendered
+export function verifyCustomComponents(
+  ws: Socket | null,
+  declared?: CustomComponentDeclaration[] | null
+) {
+  const implemented = new Set(
+    customComponents.map((c) => `${c.version}/${c.metadata.name}`)
+  );
+  const missing = (declared || [])
+    .map((c) => c.name)
+    .filter((name) => !implemented.has(name));
+  if (missing.length === 0) {
+    return;
+  }
+  const message = `missing custom components: ${missing.join(", ")}`;
+  console.error(message);
+  ws?.send(
+    JSON.stringify({
+      type: "ClientError",
+      params: { componentId: "", message },
+    })
+  );
+}
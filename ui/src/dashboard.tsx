@@ -0,0 +1,343 @@
+import { implementRuntimeComponent } from "@sunmao-ui/runtime";
+import { PointerEvent, useRef, useState } from "react";
+import { readPreference, writePreference } from "./preferences";
+import { Socket } from "./socket";
+
+type Widget = {
+  key: string;
+  title: string;
+  kind: string;
+  w: number;
+  h: number;
+};
+
+type WidgetLayout = { key: string; w: number; h: number };
+
+type Board = {
+  widgets?: Widget[];
+  data?: Record<string, any>;
+  errors?: Record<string, string>;
+};
+
+const GAP = 12;
+
+const styles = {
+  grid: { display: "grid", gap: GAP, gridAutoFlow: "row dense" },
+  widget: {
+    position: "relative",
+    display: "flex",
+    flexDirection: "column",
+    minWidth: 0,
+    background: "#fff",
+    border: "1px solid #e5e6eb",
+    borderRadius: 4,
+    overflow: "hidden",
+  },
+  title: {
+    padding: "8px 12px",
+    borderBottom: "1px solid #f2f3f5",
+    fontWeight: 500,
+    cursor: "move",
+    userSelect: "none",
+  },
+  body: { flex: 1, minHeight: 0, padding: 12, overflow: "auto" },
+  handle: {
+    position: "absolute",
+    right: 0,
+    bottom: 0,
+    width: 12,
+    height: 12,
+    cursor: "nwse-resize",
+    background:
+      "linear-gradient(135deg, transparent 50%, #c9cdd4 50%, #c9cdd4 60%, transparent 60%)",
+  },
+  stat: { fontSize: 32, fontWeight: 600 },
+  caption: { fontSize: 12, color: "#86909c" },
+  bars: { display: "flex", alignItems: "flex-end", gap: 4, height: "100%" },
+  error: { color: "#f53f3f" },
+  list: { margin: 0, padding: 0, listStyle: "none" },
+  row: { padding: "4px 0", borderBottom: "1px solid #f2f3f5" },
+  pre: { margin: 0, fontSize: 12 },
+} as const;
+
+// passes both arrays of numbers and of { label, value }
+function points(data: any): { label: string; value: number }[] {
+  return (Array.isArray(data) ? data : []).map((p, i) =>
+    typeof p === "number" ? { label: String(i), value: p } : p
+  );
+}
+
+function Bars({ data }: { data: any }) {
+  const series = points(data);
+  const top = Math.max(1, ...series.map((p) => p.value));
+  return (
+    <div style={styles.bars}>
+      {series.map((p) => (
+        <div
+          key={p.label}
+          title={`${p.label}: ${p.value}`}
+          style={{
+            flex: 1,
+            height: `${(p.value / top) * 100}%`,
+            background: "#165dff",
+            borderRadius: "2px 2px 0 0",
+          }}
+        />
+      ))}
+    </div>
+  );
+}
+
+function Line({ data }: { data: any }) {
+  const series = points(data);
+  if (series.length < 2) {
+    return <Bars data={data} />;
+  }
+  const top = Math.max(...series.map((p) => p.value));
+  const bottom = Math.min(...series.map((p) => p.value));
+  const span = top - bottom || 1;
+  const path = series
+    .map((p, i) => {
+      const x = (i / (series.length - 1)) * 100;
+      const y = 100 - ((p.value - bottom) / span) * 100;
+      return `${i === 0 ? "M" : "L"}${x},${y}`;
+    })
+    .join(" ");
+  return (
+    <svg
+      viewBox="0 0 100 100"
+      preserveAspectRatio="none"
+      style={{ width: "100%", height: "100%" }}
+    >
+      <path
+        d={path}
+        fill="none"
+        stroke="#165dff"
+        strokeWidth={2}
+        vectorEffect="non-scaling-stroke"
+      />
+    </svg>
+  );
+}
+
+function WidgetBody({
+  kind,
+  data,
+  error,
+}: {
+  kind: string;
+  data: any;
+  error?: string;
+}) {
+  if (error) {
+    return <div style={styles.error}>{error}</div>;
+  }
+  if (data === undefined) {
+    return <div style={styles.caption}>Loading…</div>;
+  }
+  switch (kind) {
+    case "stat":
+      return (
+        <>
+          <div style={styles.stat}>{data?.value ?? data}</div>
+          {data?.caption && <div style={styles.caption}>{data.caption}</div>}
+        </>
+      );
+    case "bars":
+      return <Bars data={data} />;
+    case "line":
+      return <Line data={data} />;
+    case "list":
+      return (
+        <ul style={styles.list}>
+          {(Array.isArray(data) ? data : []).map((row: any, i: number) => (
+            <li key={i} style={styles.row}>
+              {typeof row === "string" ? (
+                row
+              ) : (
+                <>
+                  <div>{row.title}</div>
+                  {row.description && (
+                    <div style={styles.caption}>{row.description}</div>
+                  )}
+                </>
+              )}
+            </li>
+          ))}
+        </ul>
+      );
+    default:
+      return <pre style={styles.pre}>{JSON.stringify(data, null, 2)}</pre>;
+  }
+}
+
+// saved places first, widgets the user never arranged keep their default
+// size at the end, places of removed widgets are dropped
+function arrange(widgets: Widget[], saved: WidgetLayout[]): WidgetLayout[] {
+  const known = new Set(widgets.map((w) => w.key));
+  const layout = saved.filter((l) => known.has(l.key));
+  const placed = new Set(layout.map((l) => l.key));
+  widgets.forEach((w) => {
+    if (!placed.has(w.key)) {
+      layout.push({ key: w.key, w: w.w || 4, h: w.h || 2 });
+    }
+  });
+  return layout;
+}
+
+// the client of runtime.NewDashboard, widgets reorder by dragging their
+// title and resize by their corner, the layout is a client preference
+export function dashboardComponent(ws: Socket | null) {
+  return implementRuntimeComponent({
+    version: "binding/v1",
+    metadata: {
+      name: "dashboard",
+      displayName: "Dashboard",
+      exampleProperties: {
+        dashboard: "",
+        columns: 12,
+        rowHeight: 80,
+        board: {},
+      },
+      annotations: { category: "Display" },
+      isDraggable: true,
+      isResizable: true,
+    },
+    spec: {
+      properties: {} as any,
+      state: {} as any,
+      methods: {},
+      slots: {},
+      styleSlots: ["content"],
+      events: ["onLayoutChange"],
+    },
+  })(
+    ({
+      dashboard,
+      columns,
+      rowHeight,
+      board,
+      mergeState,
+      callbackMap,
+      elementRef,
+    }: any) => {
+      const { widgets = [], data = {}, errors = {} } = (board || {}) as Board;
+      const prefKey = `dashboard:${dashboard}`;
+      const [saved, setSaved] = useState<WidgetLayout[]>(() =>
+        readPreference(prefKey, [])
+      );
+      const [dragging, setDragging] = useState<string>();
+      const grid = useRef<HTMLDivElement>(null);
+      const cols = columns || 12;
+      const row = rowHeight || 80;
+      const layout = arrange(widgets, saved);
+
+      const save = (next: WidgetLayout[]) => {
+        setSaved(next);
+        writePreference(ws, prefKey, next);
+        mergeState({ layout: next });
+        callbackMap?.onLayoutChange?.();
+      };
+
+      const move = (key: string, before: string) => {
+        if (key === before) {
+          return;
+        }
+        const next = layout.filter((l) => l.key !== key);
+        const index = next.findIndex((l) => l.key === before);
+        next.splice(index, 0, layout.find((l) => l.key === key)!);
+        save(next);
+      };
+
+      // snaps the pointer movement to whole cells while dragging the corner
+      const resize = (evt: PointerEvent, place: WidgetLayout) => {
+        evt.preventDefault();
+        const width = grid.current?.clientWidth || 0;
+        const cell = (width - GAP * (cols - 1)) / cols + GAP;
+        const startX = evt.clientX;
+        const startY = evt.clientY;
+        let next = layout;
+        const onMove = (e: globalThis.PointerEvent) => {
+          const w = Math.round(place.w + (e.clientX - startX) / cell);
+          const h = Math.round(place.h + (e.clientY - startY) / (row + GAP));
+          next = layout.map((l) =>
+            l.key === place.key
+              ? {
+                  ...l,
+                  w: Math.min(cols, Math.max(1, w)),
+                  h: Math.max(1, h),
+                }
+              : l
+          );
+          setSaved(next);
+        };
+        const onUp = () => {
+          window.removeEventListener("pointermove", onMove);
+          window.removeEventListener("pointerup", onUp);
+          save(next);
+        };
+        window.addEventListener("pointermove", onMove);
+        window.addEventListener("pointerup", onUp);
+      };
+
+      return (
+        <div ref={elementRef}>
+          <div
+            ref={grid}
+            style={{
+              ...styles.grid,
+              gridTemplateColumns: `repeat(${cols}, minmax(0, 1fr))`,
+              gridAutoRows: row,
+            }}
+          >
+            {layout.map((place) => {
+              const widget = widgets.find((w) => w.key === place.key)!;
+              return (
+                <div
+                  key={place.key}
+                  style={{
+                    ...styles.widget,
+                    gridColumn: `span ${Math.min(cols, place.w)}`,
+                    gridRow: `span ${place.h}`,
+                    opacity: dragging === place.key ? 0.5 : 1,
+                  }}
+                  onDragOver={(evt) => dragging && evt.preventDefault()}
+                  onDrop={(evt) => {
+                    evt.preventDefault();
+                    if (dragging) {
+                      move(dragging, place.key);
+                    }
+                    setDragging(undefined);
+                  }}
+                >
+                  <div
+                    style={styles.title}
+                    draggable
+                    onDragStart={(evt) => {
+                      evt.dataTransfer.effectAllowed = "move";
+                      setDragging(place.key);
+                    }}
+                    onDragEnd={() => setDragging(undefined)}
+                  >
+                    {widget.title}
+                  </div>
+                  <div style={styles.body}>
+                    <WidgetBody
+                      kind={widget.kind}
+                      data={data[place.key]}
+                      error={errors[place.key]}
+                    />
+                  </div>
+                  <div
+                    style={styles.handle}
+                    onPointerDown={(evt) => resize(evt, place)}
+                  />
+                </div>
+              );
+            })}
+          </div>
+        </div>
+      );
+    }
+  );
+}
@@ -0,0 +1,238 @@
+import { implementRuntimeComponent } from "@sunmao-ui/runtime";
+import { useEffect, useState } from "react";
+import { highlight } from "./highlight";
+import { Socket } from "./socket";
+import { validateParams } from "./validate";
+
+const styles = {
+  toolbar: { display: "flex", gap: 8, marginBottom: 8 },
+  button: {
+    padding: "4px 10px",
+    border: "1px solid #e5e6eb",
+    borderRadius: 4,
+    background: "#fff",
+    cursor: "pointer",
+  },
+  primary: {
+    padding: "4px 10px",
+    border: "1px solid #165dff",
+    borderRadius: 4,
+    background: "#165dff",
+    color: "#fff",
+    cursor: "pointer",
+  },
+  tree: {
+    fontFamily: "ui-monospace, SFMono-Regular, Menlo, monospace",
+    fontSize: 12,
+    lineHeight: "20px",
+  },
+  toggle: { cursor: "pointer", userSelect: "none", color: "#86909c" },
+  key: { color: "#165dff" },
+  summary: { color: "#86909c" },
+  text: {
+    width: "100%",
+    minHeight: 240,
+    boxSizing: "border-box",
+    padding: 8,
+    border: "1px solid #e5e6eb",
+    borderRadius: 4,
+    fontFamily: "ui-monospace, SFMono-Regular, Menlo, monospace",
+    fontSize: 12,
+  },
+  error: { marginTop: 4, fontSize: 12, color: "#f53f3f" },
+} as const;
+
+function Value({ value }: { value: any }) {
+  return highlight(JSON.stringify(value), "json");
+}
+
+function Node({
+  name,
+  value,
+  depth,
+}: {
+  name?: string;
+  value: any;
+  depth: number;
+}) {
+  // the first levels start open, deeper ones are opened on demand
+  const [open, setOpen] = useState(depth < 2);
+  const label = name !== undefined && <span style={styles.key}>{name}: </span>;
+  if (value === null || typeof value !== "object") {
+    return (
+      <div style={{ paddingLeft: depth * 16 }}>
+        {label}
+        <Value value={value} />
+      </div>
+    );
+  }
+  const entries = Array.isArray(value)
+    ? value.map((v, i) => [String(i), v] as const)
+    : Object.entries(value);
+  const summary = Array.isArray(value)
+    ? `[${entries.length}]`
+    : `{${entries.length}}`;
+  return (
+    <div>
+      <div style={{ paddingLeft: depth * 16 }}>
+        <span style={styles.toggle} onClick={() => setOpen(!open)}>
+          {open ? "▾ " : "▸ "}
+        </span>
+        {label}
+        <span style={styles.summary}>{summary}</span>
+      </div>
+      {open &&
+        entries.map(([k, v]) => (
+          <Node key={k} name={k} value={v} depth={depth + 1} />
+        ))}
+    </div>
+  );
+}
+
+// the client of runtime.NewDataEditor, json edits are checked against the
+// schema before they are sent, yaml is only parsed by the server
+export function dataEditorComponent(ws: Socket | null) {
+  return implementRuntimeComponent({
+    version: "binding/v1",
+    metadata: {
+      name: "dataEditor",
+      displayName: "Data Editor",
+      exampleProperties: {
+        editor: "",
+        data: { value: {}, yaml: "" },
+        format: "json",
+        readOnly: false,
+      },
+      annotations: { category: "Input" },
+      isDraggable: true,
+      isResizable: true,
+    },
+    spec: {
+      properties: {} as any,
+      state: {} as any,
+      methods: {},
+      slots: {},
+      styleSlots: ["content"],
+      events: ["onSave"],
+    },
+  })(
+    ({
+      editor,
+      data,
+      format,
+      readOnly,
+      mergeState,
+      callbackMap,
+      elementRef,
+    }: any) => {
+      const [editing, setEditing] = useState(false);
+      const [text, setText] = useState("");
+      const [error, setError] = useState<string>();
+      const value = data?.value;
+      const source =
+        format === "yaml" ? data?.yaml || "" : JSON.stringify(value, null, 2);
+
+      useEffect(() => {
+        mergeState({ dirty: editing && text !== source, error });
+      }, [editing, text, source, error]);
+
+      // a saved value comes back as the new state, which ends the edit
+      useEffect(() => {
+        setEditing(false);
+        setError(undefined);
+      }, [data]);
+
+      useEffect(() => {
+        if (!ws) {
+          return;
+        }
+        const socket = ws;
+        const messageHandler = (evt: Event) => {
+          const message = JSON.parse((evt as MessageEvent).data);
+          if (
+            message.type === "ActionError" &&
+            message.handler === `${editor}/save`
+          ) {
+            setError(message.message);
+          }
+        };
+        socket.addEventListener("message", messageHandler);
+        return () => socket.removeEventListener("message", messageHandler);
+      }, [editor]);
+
+      const save = () => {
+        if (format !== "yaml") {
+          let parsed: any;
+          try {
+            parsed = JSON.parse(text);
+          } catch (err) {
+            setError(`invalid json: ${(err as Error).message}`);
+            return;
+          }
+          const invalid = data?.schema && validateParams(data.schema, parsed);
+          if (invalid) {
+            setError(invalid);
+            return;
+          }
+        }
+        setError(undefined);
+        ws?.send(
+          JSON.stringify({
+            type: "Action",
+            handler: `${editor}/save`,
+            params: { text, format: format === "yaml" ? "yaml" : "json" },
+          })
+        );
+        callbackMap?.onSave?.();
+      };
+
+      return (
+        <div ref={elementRef}>
+          {!readOnly && (
+            <div style={styles.toolbar}>
+              {editing ? (
+                <>
+                  <button style={styles.primary} onClick={save}>
+                    Save
+                  </button>
+                  <button
+                    style={styles.button}
+                    onClick={() => {
+                      setEditing(false);
+                      setError(undefined);
+                    }}
+                  >
+                    Cancel
+                  </button>
+                </>
+              ) : (
+                <button
+                  style={styles.button}
+                  onClick={() => {
+                    setText(source);
+                    setEditing(true);
+                  }}
+                >
+                  Edit
+                </button>
+              )}
+            </div>
+          )}
+          {editing ? (
+            <textarea
+              style={styles.text}
+              spellCheck={false}
+              value={text}
+              onChange={(evt) => setText(evt.target.value)}
+            />
+          ) : (
+            <div style={styles.tree}>
+              <Node value={value} depth={0} />
+            </div>
+          )}
+          {error && <div style={styles.error}>{error}</div>}
+        </div>
+      );
+    }
+  );
+}
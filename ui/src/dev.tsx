@@ -2,7 +2,8 @@ import { implementUtilMethod } from "@sunmao-ui/runtime";
 import React from "react";
 import ReactDOM from "react-dom";
 import Editor from "./Editor";
-import { MainOptions } from "./shared";
+import { MainOptions, resolveWsUrl, setBasePath, setCsrf } from "./shared";
+import { Socket } from "./socket";
 
 export function renderApp(options: MainOptions) {
   const {
@@ -11,21 +12,20 @@ export function renderApp(options: MainOptions) {
     modules,
     reloadWhenWsDisconnected,
     handlers,
+    handlerSpecs,
     utilMethods,
     applicationPatch,
     modulesPatch,
+    basePath,
+    csrf,
+    reconnect,
   } = options;
-  const ws = new WebSocket(wsUrl);
-  ws.onopen = () => {
-    console.log("ws connected");
-  };
-  ws.onclose = () => {
-    if (reloadWhenWsDisconnected) {
-      setTimeout(() => {
-        window.location.reload();
-      }, 1500);
-    }
-  };
+  setBasePath(basePath || "");
+  setCsrf(csrf);
+  // an empty wsUrl means a static export without any server
+  const ws = wsUrl
+    ? new Socket(resolveWsUrl(wsUrl), reloadWhenWsDisconnected, reconnect)
+    : null;
 
   ReactDOM.render(
     <React.StrictMode>
@@ -34,6 +34,7 @@ export function renderApp(options: MainOptions) {
         modules={modules}
         ws={ws}
         handlers={handlers}
+        handlerSpecs={handlerSpecs}
         utilMethods={utilMethods?.map(
           (u) => () => implementUtilMethod(u.options)(u.impl)
         )}
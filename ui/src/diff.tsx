@@ -0,0 +1,151 @@
+import { implementRuntimeComponent } from "@sunmao-ui/runtime";
+import { highlight, languageOf } from "./highlight";
+
+type DiffLine = {
+  kind: " " | "+" | "-";
+  old?: number;
+  new?: number;
+  text: string;
+};
+
+type DiffFile = { name?: string; language?: string; lines: DiffLine[] };
+
+// a row of the split view, one side may be empty
+type Row = { left?: DiffLine; right?: DiffLine };
+
+const backgrounds = { "+": "#e8ffea", "-": "#ffece8", " ": undefined };
+
+const styles = {
+  file: {
+    marginBottom: 12,
+    border: "1px solid #e5e6eb",
+    borderRadius: 4,
+    overflow: "hidden",
+  },
+  name: {
+    padding: "6px 10px",
+    background: "#f7f8fa",
+    borderBottom: "1px solid #e5e6eb",
+    fontWeight: 500,
+  },
+  table: {
+    width: "100%",
+    borderCollapse: "collapse",
+    tableLayout: "fixed",
+    fontFamily: "ui-monospace, SFMono-Regular, Menlo, monospace",
+    fontSize: 12,
+  },
+  number: {
+    width: 44,
+    padding: "0 6px",
+    textAlign: "right",
+    color: "#86909c",
+    userSelect: "none",
+    verticalAlign: "top",
+  },
+  code: { padding: "0 6px", whiteSpace: "pre-wrap", wordBreak: "break-all" },
+  empty: { padding: 12, color: "#86909c" },
+} as const;
+
+// pairs removed lines with the added lines that follow them, so a changed
+// line sits next to its new version
+function rows(lines: DiffLine[]): Row[] {
+  const result: Row[] = [];
+  let i = 0;
+  while (i < lines.length) {
+    if (lines[i].kind === " ") {
+      result.push({ left: lines[i], right: lines[i] });
+      i++;
+      continue;
+    }
+    const removed: DiffLine[] = [];
+    const added: DiffLine[] = [];
+    while (i < lines.length && lines[i].kind === "-") {
+      removed.push(lines[i++]);
+    }
+    while (i < lines.length && lines[i].kind === "+") {
+      added.push(lines[i++]);
+    }
+    for (let j = 0; j < Math.max(removed.length, added.length); j++) {
+      result.push({ left: removed[j], right: added[j] });
+    }
+  }
+  return result;
+}
+
+function Cells({ line, language }: { line?: DiffLine; language: string }) {
+  const background = line ? backgrounds[line.kind] : "#f7f8fa";
+  return (
+    <>
+      <td style={{ ...styles.number, background }}>
+        {line && (line.kind === "+" ? line.new : line.old)}
+      </td>
+      <td style={{ ...styles.code, background }}>
+        {line && highlight(line.text, language)}
+      </td>
+    </>
+  );
+}
+
+// renders runtime.DiffView files side by side or inline
+export const diffComponent = implementRuntimeComponent({
+  version: "binding/v1",
+  metadata: {
+    name: "diff",
+    displayName: "Diff",
+    exampleProperties: { files: [], mode: "split", language: "" },
+    annotations: { category: "Display" },
+    isDraggable: true,
+    isResizable: true,
+  },
+  spec: {
+    properties: {} as any,
+    state: {} as any,
+    methods: {},
+    slots: {},
+    styleSlots: ["content"],
+    events: [],
+  },
+})(({ files, mode, language, elementRef }: any) => {
+  const list: DiffFile[] = files || [];
+  return (
+    <div ref={elementRef}>
+      {list.length === 0 && <div style={styles.empty}>No changes</div>}
+      {list.map((file, i) => {
+        const lang = file.language || languageOf(file.name, language);
+        return (
+          <div key={`${file.name}/${i}`} style={styles.file}>
+            {file.name && <div style={styles.name}>{file.name}</div>}
+            <table style={styles.table}>
+              <tbody>
+                {mode === "inline"
+                  ? file.lines.map((line, j) => (
+                      <tr key={j}>
+                        <td
+                          style={{
+                            ...styles.number,
+                            background: backgrounds[line.kind],
+                          }}
+                        >
+                          {line.old}
+                        </td>
+                        <Cells
+                          line={{ ...line, old: line.new }}
+                          language={lang}
+                        />
+                      </tr>
+                    ))
+                  : rows(file.lines).map((row, j) => (
+                      <tr key={j}>
+                        <Cells line={row.left} language={lang} />
+                        <Cells line={row.right} language={lang} />
+                      </tr>
+                    ))}
+              </tbody>
+            </table>
+          </div>
+        );
+      })}
+    </div>
+  );
+});
@@ -0,0 +1,206 @@
+import { implementRuntimeComponent } from "@sunmao-ui/runtime";
+import { ClipboardEvent, DragEvent, useEffect, useState } from "react";
+import { csrfHeaders, withBasePath } from "./shared";
+import { Socket } from "./socket";
+
+const styles = {
+  zone: {
+    padding: 24,
+    border: "1px dashed #c9cdd4",
+    borderRadius: 4,
+    textAlign: "center",
+    color: "#86909c",
+    outline: "none",
+  },
+  status: { marginTop: 8, fontSize: 12 },
+  error: { marginTop: 8, fontSize: 12, color: "#f53f3f" },
+} as const;
+
+// matches a file against an accept list such as "image/*,.pdf"
+function accepts(accept: string, file: File) {
+  const patterns = accept
+    .split(",")
+    .map((p) => p.trim().toLowerCase())
+    .filter(Boolean);
+  if (patterns.length === 0) {
+    return true;
+  }
+  const name = file.name.toLowerCase();
+  const type = file.type.toLowerCase();
+  return patterns.some((p) => {
+    if (p.startsWith(".")) {
+      return name.endsWith(p);
+    }
+    if (p.endsWith("/*")) {
+      return type.startsWith(p.slice(0, -1));
+    }
+    return type === p;
+  });
+}
+
+const TOKEN_TIMEOUT = 10000;
+
+// asks the server over the socket for the single use token of one post,
+// it ties the files to this connection
+function uploadToken(ws: Socket, upload: string, component: string) {
+  return new Promise<string>((resolve, reject) => {
+    const done = () => {
+      clearTimeout(timer);
+      ws.removeEventListener("message", listener);
+    };
+    const listener = (evt: Event) => {
+      const message = JSON.parse((evt as MessageEvent).data);
+      if (
+        message.type !== "UploadToken" ||
+        message.upload !== upload ||
+        message.component !== component
+      ) {
+        return;
+      }
+      done();
+      if (message.error) {
+        reject(new Error(message.error));
+      } else {
+        resolve(message.token);
+      }
+    };
+    const timer = setTimeout(() => {
+      done();
+      reject(new Error("no upload token from the server"));
+    }, TOKEN_TIMEOUT);
+    ws.addEventListener("message", listener);
+    ws.send(
+      JSON.stringify({ type: "UploadToken", params: { upload, component } })
+    );
+  });
+}
+
+// the client of runtime.HandleUpload, files go over http with a token
+// handed out over the socket
+export function dropZoneComponent(ws: Socket | null) {
+  return implementRuntimeComponent({
+    version: "binding/v1",
+    metadata: {
+      name: "dropZone",
+      displayName: "Drop Zone",
+      exampleProperties: {
+        upload: "",
+        text: "Drop or paste files here",
+        accept: "",
+        pasteAnywhere: false,
+      },
+      annotations: { category: "Input" },
+      isDraggable: true,
+      isResizable: true,
+    },
+    spec: {
+      properties: {} as any,
+      state: {} as any,
+      methods: {},
+      slots: { content: { slotProps: {} as any } },
+      styleSlots: ["content"],
+      events: ["onUpload", "onError"],
+    },
+  })(
+    ({
+      component,
+      upload,
+      text,
+      accept,
+      pasteAnywhere,
+      slotsElements,
+      mergeState,
+      callbackMap,
+      elementRef,
+    }: any) => {
+      const [over, setOver] = useState(false);
+      const [uploading, setUploading] = useState(false);
+      const [error, setError] = useState<string>();
+
+      const fail = (message: string) => {
+        setError(message);
+        mergeState({ uploading: false, error: message });
+        callbackMap?.onError?.();
+      };
+
+      const send = async (list: FileList | File[]) => {
+        const files = Array.from(list).filter((f) => accepts(accept || "", f));
+        if (files.length === 0 || !ws) {
+          return;
+        }
+        const body = new FormData();
+        files.forEach((f) => body.append("files", f, f.name || "pasted"));
+        setUploading(true);
+        setError(undefined);
+        mergeState({ uploading: true, error: undefined });
+        try {
+          const token = await uploadToken(ws, upload, component.id);
+          const query = new URLSearchParams({ token });
+          const res = await fetch(
+            withBasePath(`/sunmao-binding-upload/${upload}?${query}`),
+            { method: "post", headers: csrfHeaders(), body }
+          );
+          if (!res.ok) {
+            const reply = await res.json().catch(() => undefined);
+            fail(reply?.message || res.statusText);
+            return;
+          }
+          mergeState({
+            uploading: false,
+            files: files.map((f) => ({ name: f.name, type: f.type })),
+          });
+          callbackMap?.onUpload?.();
+        } catch (err) {
+          fail((err as Error).message);
+        } finally {
+          setUploading(false);
+        }
+      };
+
+      const onPaste = (evt: ClipboardEvent | globalThis.ClipboardEvent) => {
+        const files = evt.clipboardData?.files;
+        if (files && files.length > 0) {
+          evt.preventDefault();
+          send(files);
+        }
+      };
+
+      useEffect(() => {
+        if (!pasteAnywhere) {
+          return;
+        }
+        const listener = (evt: globalThis.ClipboardEvent) => onPaste(evt);
+        document.addEventListener("paste", listener);
+        return () => document.removeEventListener("paste", listener);
+      }, [pasteAnywhere, upload, accept]);
+
+      return (
+        <div
+          ref={elementRef}
+          tabIndex={0}
+          style={{
+            ...styles.zone,
+            borderColor: over ? "#165dff" : "#c9cdd4",
+            background: over ? "#e8f3ff" : undefined,
+          }}
+          onDragOver={(evt: DragEvent) => {
+            evt.preventDefault();
+            setOver(true);
+          }}
+          onDragLeave={() => setOver(false)}
+          onDrop={(evt: DragEvent) => {
+            evt.preventDefault();
+            setOver(false);
+            send(evt.dataTransfer.files);
+          }}
+          // a page wide listener already takes the paste
+          onPaste={pasteAnywhere ? undefined : onPaste}
+        >
+          {slotsElements.content ? slotsElements.content({}) : text}
+          {uploading && <div style={styles.status}>Uploading…</div>}
+          {error && <div style={styles.error}>{error}</div>}
+        </div>
+      );
+    }
+  );
+}
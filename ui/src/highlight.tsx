@@ -0,0 +1,127 @@
+// a small line based highlighter, enough to tell keys, strings and
+// comments apart in configs and code without shipping a grammar per
+// language
+
+type Rule = [RegExp, string];
+
+const colors: Record<string, string> = {
+  comment: "#86909c",
+  string: "#0e7a0d",
+  number: "#b5520b",
+  keyword: "#a626a4",
+  key: "#165dff",
+};
+
+const common: Rule[] = [
+  [/^"(?:[^"\\]|\\.)*"?/, "string"],
+  [/^'(?:[^'\\]|\\.)*'?/, "string"],
+  [/^-?\d+(?:\.\d+)?(?:[eE][+-]?\d+)?\b/, "number"],
+];
+
+const keywords = (words: string) =>
+  new RegExp(`^(?:${words.split(" ").join("|")})\\b`);
+
+const languages: Record<string, Rule[]> = {
+  yaml: [
+    [/^#.*/, "comment"],
+    [/^[\w.-]+(?=\s*:(?:\s|$))/, "key"],
+    ...common,
+    [keywords("true false null yes no"), "keyword"],
+  ],
+  json: [
+    [/^"(?:[^"\\]|\\.)*"(?=\s*:)/, "key"],
+    ...common,
+    [keywords("true false null"), "keyword"],
+  ],
+  go: [
+    [/^\/\/.*/, "comment"],
+    [/^`[^`]*`?/, "string"],
+    ...common,
+    [
+      keywords(
+        "break case chan const continue default defer else fallthrough for func go goto if import interface map package range return select struct switch type var nil true false"
+      ),
+      "keyword",
+    ],
+  ],
+  js: [
+    [/^\/\/.*/, "comment"],
+    [/^`(?:[^`\\]|\\.)*`?/, "string"],
+    ...common,
+    [
+      keywords(
+        "async await break case catch class const continue default delete do else export extends finally for function if import in instanceof let new null of return switch this throw try typeof undefined var void while yield true false"
+      ),
+      "keyword",
+    ],
+  ],
+  sh: [
+    [/^#.*/, "comment"],
+    ...common,
+    [
+      keywords("if then else elif fi for while do done case esac function in"),
+      "keyword",
+    ],
+  ],
+  toml: [
+    [/^#.*/, "comment"],
+    [/^[\w.-]+(?=\s*=)/, "key"],
+    ...common,
+    [keywords("true false"), "keyword"],
+  ],
+};
+
+const extensions: Record<string, string> = {
+  yml: "yaml",
+  yaml: "yaml",
+  json: "json",
+  go: "go",
+  js: "js",
+  jsx: "js",
+  ts: "js",
+  tsx: "js",
+  sh: "sh",
+  bash: "sh",
+  toml: "toml",
+};
+
+export function languageOf(name?: string, fallback?: string) {
+  const ext = name?.split(".").pop()?.toLowerCase();
+  return (ext && extensions[ext]) || fallback || "";
+}
+
+export function highlight(text: string, language: string) {
+  const rules = languages[language];
+  if (!rules) {
+    return <>{text}</>;
+  }
+  const parts: JSX.Element[] = [];
+  let plain = "";
+  let rest = text;
+  // words are skipped whole, so keywords never match inside identifiers
+  const word = /^[A-Za-z_$][\w$]*/;
+  while (rest) {
+    const rule = rules.find(([re]) => re.test(rest));
+    const match = rule && rest.match(rule[0])![0];
+    if (rule && match) {
+      if (plain) {
+        parts.push(<span key={parts.length}>{plain}</span>);
+        plain = "";
+      }
+      parts.push(
+        <span key={parts.length} style={{ color: colors[rule[1]] }}>
+          {match}
+        </span>
+      );
+      rest = rest.slice(match.length);
+      continue;
+    }
+    const skip = rest.match(word)?.[0] || rest[0];
+    plain += skip;
+    rest = rest.slice(skip.length);
+  }
+  if (plain) {
+    parts.push(<span key={parts.length}>{plain}</span>);
+  }
+  return <>{parts}</>;
+}
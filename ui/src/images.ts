@@ -0,0 +1,42 @@
+import { initSunmaoUI } from "@sunmao-ui/runtime";
+import { useEffect } from "react";
+import { Socket } from "./socket";
+
+// turns Image frames into object urls and stores them in the src state of
+// the target ImageSource
+export function useImages({
+  ws,
+  apiService,
+}: {
+  ws: Socket | null;
+  apiService: ReturnType<typeof initSunmaoUI>["apiService"];
+}) {
+  useEffect(() => {
+    if (!ws) {
+      return;
+    }
+    const socket = ws;
+    const urls: Record<string, string> = {};
+    const binaryHandler = (evt: Event) => {
+      const { header, body } = (evt as MessageEvent).data;
+      if (header.type !== "Image") {
+        return;
+      }
+      const url = URL.createObjectURL(new Blob([body], { type: header.mime }));
+      if (urls[header.componentId]) {
+        URL.revokeObjectURL(urls[header.componentId]);
+      }
+      urls[header.componentId] = url;
+      apiService.send("uiMethod", {
+        componentId: header.componentId,
+        name: "setValue",
+        parameters: { key: "src", value: url },
+      });
+    };
+    socket.addEventListener("binary", binaryHandler);
+    return () => {
+      socket.removeEventListener("binary", binaryHandler);
+      Object.values(urls).forEach((url) => URL.revokeObjectURL(url));
+    };
+  }, [apiService]);
+}
@@ -0,0 +1,273 @@
+import { implementRuntimeComponent } from "@sunmao-ui/runtime";
+import { DragEvent, useEffect, useState } from "react";
+import { Socket } from "./socket";
+
+type Card = { id: string; title: string; description?: string };
+
+type Column = { id: string; title: string; cards: Card[] };
+
+type Board = { columns: Column[] };
+
+type Target = { column: string; index: number };
+
+const styles = {
+  board: {
+    display: "flex",
+    gap: 12,
+    alignItems: "flex-start",
+    overflowX: "auto",
+  },
+  column: {
+    flex: "0 0 260px",
+    display: "flex",
+    flexDirection: "column",
+    gap: 8,
+    padding: 8,
+    background: "#f2f3f5",
+    borderRadius: 4,
+  },
+  header: {
+    display: "flex",
+    justifyContent: "space-between",
+    fontWeight: 500,
+    padding: "0 4px",
+  },
+  count: { color: "#86909c", fontWeight: 400 },
+  card: {
+    position: "relative",
+    padding: "8px 24px 8px 10px",
+    background: "#fff",
+    borderRadius: 4,
+    boxShadow: "0 1px 2px rgba(0, 0, 0, 0.1)",
+    cursor: "grab",
+  },
+  description: { fontSize: 12, color: "#86909c", marginTop: 4 },
+  remove: {
+    position: "absolute",
+    top: 4,
+    right: 4,
+    border: "none",
+    background: "none",
+    color: "#86909c",
+    cursor: "pointer",
+  },
+  marker: { height: 2, background: "#165dff", borderRadius: 1 },
+  input: {
+    width: "100%",
+    boxSizing: "border-box",
+    padding: "6px 8px",
+    border: "1px solid #e5e6eb",
+    borderRadius: 4,
+  },
+} as const;
+
+function move(board: Board, card: string, column: string, index: number) {
+  let moved: Card | undefined;
+  const columns = board.columns.map((c) => ({
+    ...c,
+    cards: c.cards.filter((kc) => {
+      if (kc.id === card) {
+        moved = kc;
+        return false;
+      }
+      return true;
+    }),
+  }));
+  if (!moved) {
+    return board;
+  }
+  const to = columns.find((c) => c.id === column)!;
+  to.cards.splice(index < 0 ? to.cards.length : index, 0, moved);
+  return { columns };
+}
+
+function remove(board: Board, card: string) {
+  return {
+    columns: board.columns.map((c) => ({
+      ...c,
+      cards: c.cards.filter((kc) => kc.id !== card),
+    })),
+  };
+}
+
+// the client of runtime.NewKanban, moves and deletes show right away as
+// optimistic changes of the ServerState until the server answers
+export function kanbanComponent(ws: Socket | null) {
+  return implementRuntimeComponent({
+    version: "binding/v1",
+    metadata: {
+      name: "kanban",
+      displayName: "Kanban",
+      exampleProperties: {
+        kanban: "",
+        board: { columns: [] },
+        version: 0,
+        allowCreate: true,
+        allowDelete: true,
+      },
+      annotations: { category: "Display" },
+      isDraggable: true,
+      isResizable: true,
+    },
+    spec: {
+      properties: {} as any,
+      state: {} as any,
+      methods: {},
+      slots: {},
+      styleSlots: ["content"],
+      events: [],
+    },
+  })(
+    ({
+      kanban,
+      board,
+      version,
+      allowCreate,
+      allowDelete,
+      elementRef,
+    }: any) => {
+      // the optimistic board, until the server sends a state
+      const [pending, setPending] = useState<Board>();
+      const [dragging, setDragging] = useState<string>();
+      const [target, setTarget] = useState<Target>();
+      const [drafts, setDrafts] = useState<Record<string, string>>({});
+      useEffect(() => setPending(undefined), [board, version]);
+
+      const shown: Board = pending || board || { columns: [] };
+
+      const send = (action: string, params: any, optimistic?: Board) => {
+        if (optimistic) {
+          setPending(optimistic);
+        }
+        ws?.send(
+          JSON.stringify({
+            type: "Action",
+            handler: `${kanban}/${action}`,
+            params,
+            optimistic: optimistic && {
+              state: kanban,
+              value: optimistic,
+              version: version || 0,
+            },
+          })
+        );
+      };
+
+      const over = (evt: DragEvent, column: string, index: number) => {
+        if (!dragging) {
+          return;
+        }
+        evt.preventDefault();
+        evt.stopPropagation();
+        if (target?.column !== column || target.index !== index) {
+          setTarget({ column, index });
+        }
+      };
+
+      const drop = (evt: DragEvent) => {
+        evt.preventDefault();
+        if (dragging && target) {
+          // indexes past the dragged card shift once it left its place
+          const from = shown.columns.find((c) =>
+            c.cards.some((kc) => kc.id === dragging)
+          )!;
+          let index = target.index;
+          const at = from.cards.findIndex((kc) => kc.id === dragging);
+          if (from.id === target.column && at < index) {
+            index--;
+          }
+          send(
+            "move",
+            { card: dragging, column: target.column, index },
+            move(shown, dragging, target.column, index)
+          );
+        }
+        setDragging(undefined);
+        setTarget(undefined);
+      };
+
+      return (
+        <div ref={elementRef} style={styles.board}>
+          {shown.columns.map((column) => (
+            <div
+              key={column.id}
+              style={styles.column}
+              onDragOver={(evt) => over(evt, column.id, column.cards.length)}
+              onDrop={drop}
+            >
+              <div style={styles.header}>
+                <span>{column.title}</span>
+                <span style={styles.count}>{column.cards.length}</span>
+              </div>
+              {column.cards.map((card, index) => (
+                <div key={card.id}>
+                  {target?.column === column.id && target.index === index && (
+                    <div style={styles.marker} />
+                  )}
+                  <div
+                    style={{
+                      ...styles.card,
+                      opacity: dragging === card.id ? 0.5 : 1,
+                    }}
+                    draggable
+                    onDragStart={(evt) => {
+                      evt.dataTransfer.effectAllowed = "move";
+                      setDragging(card.id);
+                    }}
+                    onDragEnd={() => {
+                      setDragging(undefined);
+                      setTarget(undefined);
+                    }}
+                    onDragOver={(evt) => over(evt, column.id, index)}
+                  >
+                    <div>{card.title}</div>
+                    {card.description && (
+                      <div style={styles.description}>{card.description}</div>
+                    )}
+                    {allowDelete && (
+                      <button
+                        style={styles.remove}
+                        title="Delete"
+                        onClick={() =>
+                          send(
+                            "delete",
+                            { card: card.id },
+                            remove(shown, card.id)
+                          )
+                        }
+                      >
+                        ×
+                      </button>
+                    )}
+                  </div>
+                </div>
+              ))}
+              {target?.column === column.id &&
+                target.index === column.cards.length && (
+                  <div style={styles.marker} />
+                )}
+              {allowCreate && (
+                <input
+                  style={styles.input}
+                  placeholder="+ Add a card"
+                  value={drafts[column.id] || ""}
+                  onChange={(evt) =>
+                    setDrafts({ ...drafts, [column.id]: evt.target.value })
+                  }
+                  onKeyDown={(evt) => {
+                    const title = (drafts[column.id] || "").trim();
+                    if (evt.key !== "Enter" || !title) {
+                      return;
+                    }
+                    send("create", { column: column.id, title });
+                    setDrafts({ ...drafts, [column.id]: "" });
+                  }}
+                />
+              )}
+            </div>
+          ))}
+        </div>
+      );
+    }
+  );
+}
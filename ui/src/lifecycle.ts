@@ -0,0 +1,62 @@
+import { useEffect } from "react";
+import { Socket } from "./socket";
+
+const RESIZE_DEBOUNCE = 250;
+
+// reports page visibility, window focus, unload and viewport resizes so
+// the server can pause work nobody is watching
+export function useClientEvents({
+  ws,
+  enabled,
+}: {
+  ws: Socket | null;
+  enabled?: boolean;
+}) {
+  useEffect(() => {
+    if (!ws || !enabled) {
+      return;
+    }
+    const socket = ws;
+    const report = (params: Record<string, any>) =>
+      socket.send(JSON.stringify({ type: "ClientEvent", params }));
+    const viewport = () => ({
+      width: window.innerWidth,
+      height: window.innerHeight,
+    });
+
+    const onVisibility = () =>
+      report({
+        event: "visibility",
+        visible: document.visibilityState === "visible",
+      });
+    const onFocus = () => report({ event: "focus", focused: true });
+    const onBlur = () => report({ event: "focus", focused: false });
+    const onUnload = () => report({ event: "unload" });
+    let timer: ReturnType<typeof setTimeout> | undefined;
+    const onResize = () => {
+      clearTimeout(timer);
+      timer = setTimeout(
+        () => report({ event: "resize", viewport: viewport() }),
+        RESIZE_DEBOUNCE
+      );
+    };
+
+    // the server starts from a visible page, only correct it when needed
+    if (document.visibilityState !== "visible") {
+      onVisibility();
+    }
+    document.addEventListener("visibilitychange", onVisibility);
+    window.addEventListener("focus", onFocus);
+    window.addEventListener("blur", onBlur);
+    window.addEventListener("pagehide", onUnload);
+    window.addEventListener("resize", onResize);
+    return () => {
+      clearTimeout(timer);
+      document.removeEventListener("visibilitychange", onVisibility);
+      window.removeEventListener("focus", onFocus);
+      window.removeEventListener("blur", onBlur);
+      window.removeEventListener("pagehide", onUnload);
+      window.removeEventListener("resize", onResize);
+    };
+  }, [ws, enabled]);
+}
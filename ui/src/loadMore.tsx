@@ -0,0 +1,155 @@
+import { implementRuntimeComponent } from "@sunmao-ui/runtime";
+import { useEffect, useRef, useState } from "react";
+import { Socket } from "./socket";
+
+const styles = {
+  footer: {
+    padding: 12,
+    textAlign: "center",
+    fontSize: 12,
+    color: "#86909c",
+  },
+  button: {
+    padding: "4px 10px",
+    border: "1px solid #e5e6eb",
+    borderRadius: 4,
+    background: "#fff",
+    cursor: "pointer",
+  },
+  error: { marginBottom: 8, color: "#f53f3f" },
+} as const;
+
+// the client of runtime.NewInfiniteList, it sits below the list and asks
+// for the page after the loaded ones
+export function loadMoreComponent(ws: Socket | null) {
+  return implementRuntimeComponent({
+    version: "binding/v1",
+    metadata: {
+      name: "loadMore",
+      displayName: "Load More",
+      exampleProperties: {
+        list: "",
+        page: { items: [], cursor: "", done: false },
+        version: 0,
+        auto: true,
+        endText: "No more data",
+      },
+      annotations: { category: "Display" },
+      isDraggable: true,
+      isResizable: false,
+    },
+    spec: {
+      properties: {} as any,
+      state: {} as any,
+      methods: {},
+      slots: {},
+      styleSlots: ["content"],
+      events: ["onLoadMore", "onEnd"],
+    },
+  })(
+    ({
+      list,
+      page,
+      version,
+      auto,
+      endText,
+      mergeState,
+      callbackMap,
+      elementRef,
+    }: any) => {
+      const footer = useRef<HTMLDivElement | null>(null);
+      // the cursor asked for, until the page of it arrived
+      const requested = useRef<string>();
+      const [visible, setVisible] = useState(false);
+      const cursor: string = page?.cursor || "";
+      const done = !!page?.done;
+      const error: string | undefined = page?.error;
+      // the server pushes the first page itself, later ones are asked for
+      const ready = version > 0;
+      const loading = ready && requested.current === cursor && !error;
+
+      useEffect(() => {
+        requested.current = undefined;
+        mergeState({
+          count: page?.items?.length || 0,
+          done,
+          error,
+        });
+        if (done) {
+          callbackMap?.onEnd?.();
+        }
+      }, [version]);
+
+      useEffect(() => {
+        const el = footer.current;
+        if (!el || !auto) {
+          return;
+        }
+        const observer = new IntersectionObserver((entries) =>
+          setVisible(entries.some((entry) => entry.isIntersecting))
+        );
+        observer.observe(el);
+        return () => observer.disconnect();
+      }, [auto]);
+
+      const more = () => {
+        if (!ready || done || requested.current === cursor) {
+          return;
+        }
+        requested.current = cursor;
+        ws?.send(
+          JSON.stringify({
+            type: "Action",
+            handler: `${list}/more`,
+            params: { cursor },
+          })
+        );
+        callbackMap?.onLoadMore?.();
+      };
+
+      // a short page leaves the footer in view, so the next one follows
+      // without another scroll; a failed page waits for the retry
+      useEffect(() => {
+        if (auto && visible && !error) {
+          more();
+        }
+      }, [auto, visible, version]);
+
+      let content;
+      if (done) {
+        content = endText;
+      } else if (error) {
+        content = (
+          <>
+            <div style={styles.error}>{error}</div>
+            <button style={styles.button} onClick={more}>
+              Retry
+            </button>
+          </>
+        );
+      } else if (loading || !ready || auto) {
+        content = "Loading…";
+      } else {
+        content = (
+          <button style={styles.button} onClick={more}>
+            Load more
+          </button>
+        );
+      }
+
+      return (
+        <div
+          ref={(el) => {
+            footer.current = el;
+            if (elementRef) {
+              elementRef.current = el;
+            }
+          }}
+          style={styles.footer}
+        >
+          {content}
+        </div>
+      );
+    }
+  );
+}
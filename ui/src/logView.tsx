@@ -0,0 +1,134 @@
+import { implementRuntimeComponent } from "@sunmao-ui/runtime";
+import { useEffect, useRef, useState } from "react";
+import { Socket } from "./socket";
+
+type LogLine = { stream: "stdout" | "stderr"; text: string };
+
+type CommandState = {
+  running?: boolean;
+  run?: number;
+  exitCode?: number;
+  error?: string;
+};
+
+const styles = {
+  viewport: {
+    overflowY: "auto",
+    margin: 0,
+    padding: "8px 12px",
+    background: "#1d2129",
+    color: "#e5e6eb",
+    fontFamily: "ui-monospace, SFMono-Regular, Menlo, monospace",
+    fontSize: 12,
+    lineHeight: 1.5,
+    whiteSpace: "pre-wrap",
+    wordBreak: "break-all",
+  },
+  stderr: { color: "#f76965" },
+  status: { marginTop: 8, color: "#86909c" },
+} as const;
+
+// follows the output while the viewport is scrolled to its bottom
+const STICK_DISTANCE = 16;
+
+// the client of runtime.NewCommandRunner, lines arrive in batches tagged
+// with the run they belong to
+export function logViewComponent(ws: Socket | null) {
+  return implementRuntimeComponent({
+    version: "binding/v1",
+    metadata: {
+      name: "logView",
+      displayName: "Log View",
+      exampleProperties: { log: "", run: {}, maxLines: 5000, height: 400 },
+      annotations: { category: "Display" },
+      isDraggable: true,
+      isResizable: true,
+    },
+    spec: {
+      properties: {} as any,
+      state: {} as any,
+      methods: {},
+      slots: {},
+      styleSlots: ["content"],
+      events: [],
+    },
+  })(({ log, run, maxLines, height, elementRef }: any) => {
+    const state: CommandState = run || {};
+    const [lines, setLines] = useState<LogLine[]>([]);
+    const current = useRef(state.run || 0);
+    const viewport = useRef<HTMLPreElement>(null);
+    const stick = useRef(true);
+    const limit = maxLines || 5000;
+
+    useEffect(() => {
+      if (state.run && state.run > current.current) {
+        current.current = state.run;
+        setLines([]);
+      }
+    }, [state.run]);
+
+    useEffect(() => {
+      if (!ws) {
+        return;
+      }
+      const socket = ws;
+      const messageHandler = (evt: Event) => {
+        const message = JSON.parse((evt as MessageEvent).data);
+        if (
+          message.type !== "LogLines" ||
+          message.log !== log ||
+          message.run < current.current
+        ) {
+          return;
+        }
+        const fresh = message.run > current.current;
+        current.current = message.run;
+        setLines((prev) =>
+          (fresh ? [] : prev).concat(message.lines || []).slice(-limit)
+        );
+      };
+      socket.addEventListener("message", messageHandler);
+      return () => socket.removeEventListener("message", messageHandler);
+    }, [log, limit]);
+
+    useEffect(() => {
+      const el = viewport.current;
+      if (el && stick.current) {
+        el.scrollTop = el.scrollHeight;
+      }
+    }, [lines]);
+
+    let status = "";
+    if (state.running) {
+      status = "Running…";
+    } else if (state.error) {
+      status = state.error;
+    } else if (state.exitCode !== undefined) {
+      status = `Exited with code ${state.exitCode}`;
+    }
+
+    return (
+      <div ref={elementRef}>
+        <pre
+          ref={viewport}
+          style={{ ...styles.viewport, height: height || 400 }}
+          onScroll={(evt) => {
+            const el = evt.currentTarget;
+            stick.current =
+              el.scrollHeight - el.scrollTop - el.clientHeight < STICK_DISTANCE;
+          }}
+        >
+          {lines.map((line, i) => (
+            <div
+              key={i}
+              style={line.stream === "stderr" ? styles.stderr : undefined}
+            >
+              {line.text}
+            </div>
+          ))}
+        </pre>
+        {status && <div style={styles.status}>{status}</div>}
+      </div>
+    );
+  });
+}
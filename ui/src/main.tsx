@@ -2,7 +2,10 @@ import { implementUtilMethod } from "@sunmao-ui/runtime";
 import React from "react";
 import ReactDOM from "react-dom";
 import App from "./App";
-import { MainOptions } from "./shared";
+import { MainOptions, resolveWsUrl, setBasePath, setCsrf } from "./shared";
+import { Socket } from "./socket";
+import { printWhenReady } from "./report";
+import { verifyCustomComponents } from "./custom";
 
 export function renderApp(options: MainOptions) {
   const {
@@ -11,21 +14,29 @@ export function renderApp(options: MainOptions) {
     modules,
     reloadWhenWsDisconnected,
     handlers,
+    handlerSpecs,
+    shortcuts,
+    clientEvents,
+    preferences,
+    hydration,
+    flags,
+    commandPalette,
     utilMethods,
     applicationPatch,
     modulesPatch,
+    basePath,
+    csrf,
+    reconnect,
+    print,
+    customComponents,
   } = options;
-  const ws = new WebSocket(wsUrl);
-  ws.onopen = () => {
-    console.log("ws connected");
-  };
-  ws.onclose = () => {
-    if (reloadWhenWsDisconnected) {
-      setTimeout(() => {
-        window.location.reload();
-      }, 1500);
-    }
-  };
+  setBasePath(basePath || "");
+  setCsrf(csrf);
+  // an empty wsUrl means a static export without any server
+  const ws = wsUrl
+    ? new Socket(resolveWsUrl(wsUrl), reloadWhenWsDisconnected, reconnect)
+    : null;
+  verifyCustomComponents(ws, customComponents);
 
   ReactDOM.render(
     <React.StrictMode>
@@ -34,6 +45,13 @@ export function renderApp(options: MainOptions) {
         modules={modules}
         ws={ws}
         handlers={handlers}
+        handlerSpecs={handlerSpecs}
+        shortcuts={shortcuts}
+        clientEvents={clientEvents}
+        preferences={preferences}
+        hydration={hydration}
+        flags={flags}
+        commandPalette={commandPalette}
         utilMethods={utilMethods?.map(
           (u) => () => implementUtilMethod(u.options)(u.impl)
         )}
@@ -43,4 +61,8 @@ export function renderApp(options: MainOptions) {
     </React.StrictMode>,
     document.getElementById("root")!
   );
+
+  if (print) {
+    printWhenReady();
+  }
 }
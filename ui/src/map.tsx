@@ -0,0 +1,393 @@
+import { implementRuntimeComponent } from "@sunmao-ui/runtime";
+import { PointerEvent, WheelEvent, useEffect, useRef, useState } from "react";
+import { Socket } from "./socket";
+
+type Marker = {
+  id: string;
+  lat: number;
+  lng: number;
+  title?: string;
+  color?: string;
+};
+
+type Layer = { id: string; geojson: any; color?: string };
+
+type LatLng = { lat: number; lng: number };
+
+const TILE = 256;
+const MAX_ZOOM = 19;
+const MAX_LAT = 85.0511;
+
+const styles = {
+  root: {
+    position: "relative",
+    overflow: "hidden",
+    background: "#e5e6eb",
+    cursor: "grab",
+    touchAction: "none",
+    userSelect: "none",
+  },
+  tile: { position: "absolute", width: TILE, height: TILE },
+  overlay: { position: "absolute", inset: 0, pointerEvents: "none" },
+  marker: {
+    position: "absolute",
+    width: 14,
+    height: 14,
+    marginLeft: -7,
+    marginTop: -7,
+    border: "2px solid #fff",
+    borderRadius: "50%",
+    boxShadow: "0 1px 3px rgba(0, 0, 0, 0.4)",
+    cursor: "pointer",
+  },
+  zoom: {
+    position: "absolute",
+    top: 8,
+    left: 8,
+    display: "flex",
+    flexDirection: "column",
+    gap: 2,
+  },
+  button: {
+    width: 28,
+    height: 28,
+    border: "1px solid #c9cdd4",
+    borderRadius: 4,
+    background: "#fff",
+    cursor: "pointer",
+  },
+  attribution: {
+    position: "absolute",
+    right: 0,
+    bottom: 0,
+    padding: "0 4px",
+    fontSize: 11,
+    background: "rgba(255, 255, 255, 0.7)",
+  },
+} as const;
+
+// web mercator, in pixels of the world at zoom
+function project({ lat, lng }: LatLng, zoom: number) {
+  const size = TILE * 2 ** zoom;
+  const clamped = Math.max(-MAX_LAT, Math.min(MAX_LAT, lat));
+  const sin = Math.sin((clamped * Math.PI) / 180);
+  return {
+    x: ((lng + 180) / 360) * size,
+    y: (0.5 - Math.log((1 + sin) / (1 - sin)) / (4 * Math.PI)) * size,
+  };
+}
+
+function unproject(x: number, y: number, zoom: number): LatLng {
+  const size = TILE * 2 ** zoom;
+  const n = Math.PI - (2 * Math.PI * y) / size;
+  return {
+    lat: (180 / Math.PI) * Math.atan(Math.sinh(n)),
+    lng: (x / size) * 360 - 180,
+  };
+}
+
+// every ring or line of a GeoJSON object as [lng, lat] lists, points come
+// back as single coordinate lines
+function lines(geojson: any): { coords: number[][]; closed: boolean }[] {
+  if (!geojson) {
+    return [];
+  }
+  switch (geojson.type) {
+    case "FeatureCollection":
+      return (geojson.features || []).flatMap(lines);
+    case "Feature":
+      return lines(geojson.geometry);
+    case "GeometryCollection":
+      return (geojson.geometries || []).flatMap(lines);
+    case "Point":
+      return [{ coords: [geojson.coordinates], closed: false }];
+    case "MultiPoint":
+    case "LineString":
+      return [{ coords: geojson.coordinates, closed: false }];
+    case "MultiLineString":
+      return geojson.coordinates.map((c: number[][]) => ({
+        coords: c,
+        closed: false,
+      }));
+    case "Polygon":
+      return geojson.coordinates.map((c: number[][]) => ({
+        coords: c,
+        closed: true,
+      }));
+    case "MultiPolygon":
+      return geojson.coordinates.flatMap((p: number[][][]) =>
+        p.map((c) => ({ coords: c, closed: true }))
+      );
+    default:
+      return [];
+  }
+}
+
+// the client of runtime.NewMap, a small tile map without dependencies
+export function mapComponent(ws: Socket | null) {
+  return implementRuntimeComponent({
+    version: "binding/v1",
+    metadata: {
+      name: "map",
+      displayName: "Map",
+      exampleProperties: {
+        map: "",
+        data: { markers: [], layers: [] },
+        center: { lat: 0, lng: 0 },
+        zoom: 2,
+        height: 400,
+        tiles: "https://tile.openstreetmap.org/{z}/{x}/{y}.png",
+        attribution: "© OpenStreetMap contributors",
+      },
+      annotations: { category: "Display" },
+      isDraggable: true,
+      isResizable: true,
+    },
+    spec: {
+      properties: {} as any,
+      state: {} as any,
+      methods: {},
+      slots: {},
+      styleSlots: ["content"],
+      events: ["onMarkerClick", "onMove"],
+    },
+  })(
+    ({
+      map,
+      data,
+      center: initialCenter,
+      zoom: initialZoom,
+      height,
+      tiles,
+      attribution,
+      mergeState,
+      callbackMap,
+      elementRef,
+    }: any) => {
+      const root = useRef<HTMLDivElement | null>(null);
+      const [width, setWidth] = useState(0);
+      const [center, setCenter] = useState<LatLng>(
+        initialCenter || { lat: 0, lng: 0 }
+      );
+      const [zoom, setZoom] = useState<number>(initialZoom ?? 2);
+      const drag = useRef<{ x: number; y: number; moved: boolean }>();
+      const h = height || 400;
+      const markers: Marker[] = data?.markers || [];
+      const layers: Layer[] = data?.layers || [];
+
+      useEffect(() => {
+        const el = root.current;
+        if (!el) {
+          return;
+        }
+        const observer = new ResizeObserver(() => setWidth(el.clientWidth));
+        observer.observe(el);
+        return () => observer.disconnect();
+      }, []);
+
+      const send = (action: string, params: any) =>
+        ws?.send(
+          JSON.stringify({
+            type: "Action",
+            handler: `${map}/${action}`,
+            params,
+          })
+        );
+
+      const origin = project(center, zoom);
+      const left = origin.x - width / 2;
+      const top = origin.y - h / 2;
+      const toScreen = (p: LatLng) => {
+        const { x, y } = project(p, zoom);
+        return { x: x - left, y: y - top };
+      };
+
+      // the server hears about a view once panning or zooming settled
+      useEffect(() => {
+        if (!width) {
+          return;
+        }
+        const sw = unproject(left, top + h, zoom);
+        const ne = unproject(left + width, top, zoom);
+        const view = {
+          lat: center.lat,
+          lng: center.lng,
+          zoom,
+          bounds: [sw.lat, sw.lng, ne.lat, ne.lng],
+        };
+        mergeState({ center, zoom, bounds: view.bounds });
+        const timer = setTimeout(() => {
+          send("move", view);
+          callbackMap?.onMove?.();
+        }, 300);
+        return () => clearTimeout(timer);
+      }, [center.lat, center.lng, zoom, width, h]);
+
+      const zoomTo = (next: number) =>
+        setZoom(Math.max(0, Math.min(MAX_ZOOM, next)));
+
+      const onPointerDown = (evt: PointerEvent) => {
+        (evt.target as Element).setPointerCapture?.(evt.pointerId);
+        drag.current = { x: evt.clientX, y: evt.clientY, moved: false };
+      };
+      const onPointerMove = (evt: PointerEvent) => {
+        const d = drag.current;
+        if (!d) {
+          return;
+        }
+        const dx = evt.clientX - d.x;
+        const dy = evt.clientY - d.y;
+        if (!d.moved && Math.abs(dx) + Math.abs(dy) < 3) {
+          return;
+        }
+        d.moved = true;
+        d.x = evt.clientX;
+        d.y = evt.clientY;
+        setCenter((c) => {
+          const p = project(c, zoom);
+          return unproject(p.x - dx, p.y - dy, zoom);
+        });
+      };
+      const onPointerUp = (evt: PointerEvent) => {
+        const d = drag.current;
+        drag.current = undefined;
+        if (!d || d.moved) {
+          return;
+        }
+        const rect = root.current!.getBoundingClientRect();
+        const at = unproject(
+          left + evt.clientX - rect.left,
+          top + evt.clientY - rect.top,
+          zoom
+        );
+        send("click", at);
+      };
+      const onWheel = (evt: WheelEvent) => {
+        zoomTo(zoom + (evt.deltaY < 0 ? 1 : -1));
+      };
+
+      const count = 2 ** zoom;
+      const tileImages = [];
+      const firstX = Math.floor(left / TILE);
+      const firstY = Math.floor(top / TILE);
+      for (let ty = firstY; ty * TILE < top + h; ty++) {
+        for (let tx = firstX; tx * TILE < left + width; tx++) {
+          if (ty < 0 || ty >= count) {
+            continue;
+          }
+          // the world repeats horizontally
+          const x = ((tx % count) + count) % count;
+          const src = (tiles as string)
+            .replace("{z}", String(zoom))
+            .replace("{x}", String(x))
+            .replace("{y}", String(ty));
+          tileImages.push(
+            <img
+              key={`${zoom}/${tx}/${ty}`}
+              src={src}
+              alt=""
+              draggable={false}
+              style={{
+                ...styles.tile,
+                left: tx * TILE - left,
+                top: ty * TILE - top,
+              }}
+            />
+          );
+        }
+      }
+
+      return (
+        <div
+          ref={(el) => {
+            root.current = el;
+            if (elementRef) {
+              elementRef.current = el;
+            }
+          }}
+          style={{ ...styles.root, height: h }}
+          onPointerDown={onPointerDown}
+          onPointerMove={onPointerMove}
+          onPointerUp={onPointerUp}
+          onWheel={onWheel}
+        >
+          {tileImages}
+          <svg style={styles.overlay} width={width} height={h}>
+            {layers.map((layer) =>
+              lines(layer.geojson).map((line, i) => {
+                const points = line.coords.map(([lng, lat]) =>
+                  toScreen({ lat, lng })
+                );
+                const color = layer.color || "#165dff";
+                if (points.length === 1) {
+                  return (
+                    <circle
+                      key={`${layer.id}/${i}`}
+                      cx={points[0].x}
+                      cy={points[0].y}
+                      r={5}
+                      fill={color}
+                    />
+                  );
+                }
+                const d =
+                  points
+                    .map((p, j) => `${j === 0 ? "M" : "L"}${p.x},${p.y}`)
+                    .join(" ") + (line.closed ? " Z" : "");
+                return (
+                  <path
+                    key={`${layer.id}/${i}`}
+                    d={d}
+                    stroke={color}
+                    strokeWidth={2}
+                    fill={line.closed ? color : "none"}
+                    fillOpacity={0.2}
+                  />
+                );
+              })
+            )}
+          </svg>
+          {markers.map((marker) => {
+            const p = toScreen(marker);
+            return (
+              <div
+                key={marker.id}
+                title={marker.title}
+                style={{
+                  ...styles.marker,
+                  left: p.x,
+                  top: p.y,
+                  background: marker.color || "#165dff",
+                }}
+                onPointerDown={(evt) => evt.stopPropagation()}
+                onPointerUp={(evt) => evt.stopPropagation()}
+                onClick={() => {
+                  mergeState({ selected: marker });
+                  send("markerClick", { marker: marker.id });
+                  callbackMap?.onMarkerClick?.();
+                }}
+              />
+            );
+          })}
+          <div style={styles.zoom}>
+            <button
+              style={styles.button}
+              onPointerDown={(evt) => evt.stopPropagation()}
+              onClick={() => zoomTo(zoom + 1)}
+            >
+              +
+            </button>
+            <button
+              style={styles.button}
+              onPointerDown={(evt) => evt.stopPropagation()}
+              onClick={() => zoomTo(zoom - 1)}
+            >
+              −
+            </button>
+          </div>
+          {attribution && <div style={styles.attribution}>{attribution}</div>}
+        </div>
+      );
+    }
+  );
+}
@@ -0,0 +1,216 @@
+import { implementRuntimeComponent } from "@sunmao-ui/runtime";
+import { ReactNode, useEffect, useState } from "react";
+import { Socket } from "./socket";
+
+type MenuItem = {
+  key: string;
+  title: string;
+  icon?: string;
+  children?: MenuItem[];
+};
+
+// the items from the root down to key, empty if key is not in the menu
+function trail(items: MenuItem[], key: string): MenuItem[] {
+  for (const item of items) {
+    if (item.key === key) {
+      return [item];
+    }
+    const below = trail(item.children || [], key);
+    if (below.length > 0) {
+      return [item, ...below];
+    }
+  }
+  return [];
+}
+
+function firstLeaf(items: MenuItem[]): MenuItem | undefined {
+  const item = items[0];
+  if (!item || !item.children?.length) {
+    return item;
+  }
+  return firstLeaf(item.children);
+}
+
+const keyOfHash = () =>
+  decodeURIComponent(window.location.hash.replace(/^#\/?/, ""));
+
+function Icon({ icon }: { icon?: string }) {
+  if (!icon) {
+    return null;
+  }
+  const style = { width: 16, height: 16, marginRight: 8, flexShrink: 0 };
+  return icon.includes("/") ? (
+    <img src={icon} alt="" style={style} />
+  ) : (
+    <span style={{ ...style, display: "inline-block" }}>{icon}</span>
+  );
+}
+
+const styles = {
+  root: { display: "flex", minHeight: "100vh" },
+  sidebar: {
+    flexShrink: 0,
+    background: "#232c3d",
+    color: "#c9cdd4",
+    transition: "width 0.2s",
+    overflow: "hidden",
+  },
+  header: {
+    display: "flex",
+    alignItems: "center",
+    justifyContent: "space-between",
+    padding: "16px",
+    color: "#fff",
+    fontWeight: 600,
+  },
+  toggle: {
+    border: "none",
+    background: "transparent",
+    color: "inherit",
+    cursor: "pointer",
+  },
+  item: {
+    display: "flex",
+    alignItems: "center",
+    padding: "8px 16px",
+    cursor: "pointer",
+    whiteSpace: "nowrap",
+  },
+  main: { flex: 1, minWidth: 0, padding: 16 },
+  crumbs: { marginBottom: 16, fontSize: 13, color: "#86909c" },
+} as const;
+
+// the client of runtime.NewNavigation, the active key is kept in the url
+// hash and exposed as state for the pages of the content slot
+export function navShellComponent(ws: Socket | null) {
+  return implementRuntimeComponent({
+    version: "binding/v1",
+    metadata: {
+      name: "navShell",
+      displayName: "Navigation Shell",
+      exampleProperties: { nav: "", title: "", menu: [] },
+      annotations: { category: "Layout" },
+      isDraggable: true,
+      isResizable: true,
+    },
+    spec: {
+      properties: {} as any,
+      state: {} as any,
+      methods: {},
+      slots: { content: { slotProps: {} as any } },
+      styleSlots: ["content"],
+      events: ["onNavigate"],
+    },
+  })(
+    ({
+      nav,
+      title,
+      menu,
+      mergeState,
+      callbackMap,
+      slotsElements,
+      elementRef,
+    }: any) => {
+      const items: MenuItem[] = Array.isArray(menu) ? menu : [];
+      const [active, setActive] = useState(keyOfHash());
+      const [collapsed, setCollapsed] = useState(false);
+      const [open, setOpen] = useState<Record<string, boolean>>({});
+
+      useEffect(() => {
+        const onHash = () => setActive(keyOfHash());
+        window.addEventListener("hashchange", onHash);
+        return () => window.removeEventListener("hashchange", onHash);
+      }, []);
+
+      // fall back to the first page once the menu arrived
+      const current =
+        trail(items, active).length > 0 ? active : firstLeaf(items)?.key || "";
+      const crumbs = trail(items, current);
+
+      useEffect(() => {
+        mergeState({ active: current, collapsed });
+      }, [current, collapsed]);
+
+      useEffect(() => {
+        if (!current) {
+          return;
+        }
+        ws?.send(
+          JSON.stringify({
+            type: "Action",
+            handler: `${nav}/navigate`,
+            params: { key: current },
+          })
+        );
+        callbackMap?.onNavigate?.();
+      }, [current, nav]);
+
+      const render = (list: MenuItem[], depth: number): ReactNode =>
+        list.map((item) => {
+          const isGroup = Boolean(item.children?.length);
+          const expanded =
+            open[item.key] ?? crumbs[depth]?.key === item.key;
+          return (
+            <div key={item.key}>
+              <div
+                role="menuitem"
+                aria-current={item.key === current ? "page" : undefined}
+                style={{
+                  ...styles.item,
+                  paddingLeft: 16 + (collapsed ? 0 : depth * 16),
+                  color: item.key === current ? "#fff" : undefined,
+                  background: item.key === current ? "#165dff" : undefined,
+                }}
+                title={item.title}
+                onClick={() => {
+                  if (isGroup) {
+                    setOpen({ ...open, [item.key]: !expanded });
+                  } else {
+                    window.location.hash = `#/${encodeURIComponent(
+                      item.key
+                    )}`;
+                  }
+                }}
+              >
+                <Icon icon={item.icon} />
+                {!collapsed && item.title}
+                {!collapsed && isGroup && (
+                  <span style={{ marginLeft: "auto" }}>
+                    {expanded ? "▾" : "▸"}
+                  </span>
+                )}
+              </div>
+              {isGroup &&
+                expanded &&
+                !collapsed &&
+                render(item.children!, depth + 1)}
+            </div>
+          );
+        });
+
+      return (
+        <div ref={elementRef} style={styles.root}>
+          <nav style={{ ...styles.sidebar, width: collapsed ? 56 : 220 }}>
+            <div style={styles.header}>
+              {!collapsed && <span>{title}</span>}
+              <button
+                style={styles.toggle}
+                aria-label={collapsed ? "Expand menu" : "Collapse menu"}
+                onClick={() => setCollapsed(!collapsed)}
+              >
+                {collapsed ? "»" : "«"}
+              </button>
+            </div>
+            {render(items, 0)}
+          </nav>
+          <main style={styles.main}>
+            <div style={styles.crumbs} aria-label="Breadcrumb">
+              {crumbs.map((item) => item.title).join(" / ")}
+            </div>
+            {slotsElements.content ? slotsElements.content({}) : null}
+          </main>
+        </div>
+      );
+    }
+  );
+}
@@ -0,0 +1,66 @@
+import { implementUtilMethod, UtilMethodFactory } from "@sunmao-ui/runtime";
+
+let audio: AudioContext | undefined;
+
+function beep() {
+  audio = audio || new AudioContext();
+  const oscillator = audio.createOscillator();
+  const gain = audio.createGain();
+  oscillator.frequency.value = 880;
+  gain.gain.value = 0.1;
+  oscillator.connect(gain);
+  gain.connect(audio.destination);
+  oscillator.start();
+  oscillator.stop(audio.currentTime + 0.2);
+}
+
+async function notify(title: string, body: string) {
+  if (!("Notification" in window)) {
+    beep();
+    return;
+  }
+  let permission = Notification.permission;
+  if (permission === "default") {
+    permission = await Notification.requestPermission();
+  }
+  if (permission !== "granted") {
+    beep();
+    return;
+  }
+  new Notification(title, { body });
+}
+
+export const beepUtilMethod: UtilMethodFactory = () =>
+  implementUtilMethod({
+    version: "binding/v1",
+    metadata: {
+      name: "beep",
+    },
+    spec: {
+      parameters: {} as any,
+    },
+  })(() => beep());
+
+export const notifyUtilMethod: UtilMethodFactory = () =>
+  implementUtilMethod({
+    version: "binding/v1",
+    metadata: {
+      name: "notify",
+    },
+    spec: {
+      parameters: {} as any,
+    },
+  })((params: any) => {
+    notify(params.title, params.body);
+  });
+
+export const focusUtilMethod: UtilMethodFactory = () =>
+  implementUtilMethod({
+    version: "binding/v1",
+    metadata: {
+      name: "focus",
+    },
+    spec: {
+      parameters: {} as any,
+    },
+  })(() => window.focus());
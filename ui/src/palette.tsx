@@ -0,0 +1,189 @@
+import { useEffect, useMemo, useRef, useState } from "react";
+import { HandlerSpec } from "./shared";
+import { matches } from "./shortcuts";
+import { Socket } from "./socket";
+
+export type CommandPaletteOptions = { keys: string };
+
+type Command = { handler: string; title: string; keywords: string[] };
+
+function commandsOf(specs?: Record<string, HandlerSpec>): Command[] {
+  return Object.keys(specs || {})
+    .filter((handler) => specs![handler].command)
+    .map((handler) => ({
+      handler,
+      title: specs![handler].command!.title,
+      keywords: specs![handler].command!.keywords || [],
+    }))
+    .sort((a, b) => a.title.localeCompare(b.title));
+}
+
+// every word of the query has to appear in the title or a keyword
+function search(commands: Command[], query: string) {
+  const words = query.toLowerCase().split(/\s+/).filter(Boolean);
+  return commands.filter((command) => {
+    const text = [command.title, ...command.keywords].join(" ").toLowerCase();
+    return words.every((word) => text.includes(word));
+  });
+}
+
+const styles = {
+  backdrop: {
+    position: "fixed",
+    inset: 0,
+    zIndex: 2000,
+    background: "rgba(0, 0, 0, 0.3)",
+    display: "flex",
+    justifyContent: "center",
+    alignItems: "flex-start",
+    paddingTop: "15vh",
+  },
+  panel: {
+    width: 560,
+    maxWidth: "90vw",
+    background: "#fff",
+    borderRadius: 8,
+    boxShadow: "0 8px 32px rgba(0, 0, 0, 0.2)",
+    overflow: "hidden",
+  },
+  input: {
+    width: "100%",
+    boxSizing: "border-box",
+    border: "none",
+    borderBottom: "1px solid #e5e6eb",
+    padding: "14px 16px",
+    fontSize: 16,
+    outline: "none",
+  },
+  list: { maxHeight: 360, overflowY: "auto", margin: 0, padding: 4 },
+  item: {
+    listStyle: "none",
+    padding: "8px 12px",
+    borderRadius: 4,
+    cursor: "pointer",
+  },
+} as const;
+
+// lists the handlers declared with runtime.Command and sends the picked
+// one as an Action, handlers pushed by the server later are added
+export function CommandPalette({
+  ws,
+  options,
+  handlerSpecs,
+}: {
+  ws: Socket | null;
+  options: CommandPaletteOptions;
+  handlerSpecs?: Record<string, HandlerSpec>;
+}) {
+  const [open, setOpen] = useState(false);
+  const [query, setQuery] = useState("");
+  const [active, setActive] = useState(0);
+  const [specs, setSpecs] = useState(handlerSpecs);
+  const inputRef = useRef<HTMLInputElement>(null);
+
+  const commands = useMemo(() => commandsOf(specs), [specs]);
+  const results = useMemo(() => search(commands, query), [commands, query]);
+
+  useEffect(() => {
+    const keyHandler = (evt: KeyboardEvent) => {
+      if (matches(options.keys, evt)) {
+        evt.preventDefault();
+        setOpen((o) => !o);
+      }
+    };
+    window.addEventListener("keydown", keyHandler);
+    return () => window.removeEventListener("keydown", keyHandler);
+  }, [options.keys]);
+
+  useEffect(() => {
+    if (!ws) {
+      return;
+    }
+    const socket = ws;
+    const messageHandler = (evt: Event) => {
+      const message = JSON.parse((evt as MessageEvent).data);
+      if (message.type === "Handlers") {
+        setSpecs(message.handlerSpecs);
+      }
+    };
+    socket.addEventListener("message", messageHandler);
+    return () => socket.removeEventListener("message", messageHandler);
+  }, [ws]);
+
+  useEffect(() => {
+    if (open) {
+      setQuery("");
+      setActive(0);
+      inputRef.current?.focus();
+    }
+  }, [open]);
+
+  const run = (command?: Command) => {
+    if (!command) {
+      return;
+    }
+    setOpen(false);
+    ws?.send(
+      JSON.stringify({
+        type: "Action",
+        handler: command.handler,
+        params: { command: command.title },
+      })
+    );
+  };
+
+  if (!open) {
+    return null;
+  }
+  return (
+    <div style={styles.backdrop} onClick={() => setOpen(false)}>
+      <div
+        style={styles.panel}
+        role="dialog"
+        aria-label="Command palette"
+        onClick={(evt) => evt.stopPropagation()}
+      >
+        <input
+          ref={inputRef}
+          style={styles.input}
+          placeholder="Type a command"
+          value={query}
+          onChange={(evt) => {
+            setQuery(evt.target.value);
+            setActive(0);
+          }}
+          onKeyDown={(evt) => {
+            if (evt.key === "ArrowDown") {
+              evt.preventDefault();
+              setActive((a) => Math.min(a + 1, results.length - 1));
+            } else if (evt.key === "ArrowUp") {
+              evt.preventDefault();
+              setActive((a) => Math.max(a - 1, 0));
+            } else if (evt.key === "Enter") {
+              run(results[active]);
+            } else if (evt.key === "Escape") {
+              setOpen(false);
+            }
+          }}
+        />
+        <ul style={styles.list} role="listbox">
+          {results.map((command, i) => (
+            <li
+              key={command.handler}
+              role="option"
+              aria-selected={i === active}
+              style={{
+                ...styles.item,
+                background: i === active ? "#f2f3f5" : undefined,
+              }}
+              onMouseEnter={() => setActive(i)}
+              onClick={() => run(command)}
+            >
+              {command.title}
+            </li>
+          ))}
+        </ul>
+      </div>
+    </div>
+  );
+}
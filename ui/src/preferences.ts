@@ -0,0 +1,85 @@
+import { implementUtilMethod, UtilMethodFactory } from "@sunmao-ui/runtime";
+import { useEffect } from "react";
+import { Socket } from "./socket";
+
+const PREFIX = "sunmao-binding:pref:";
+
+export function readPreference(key: string, def: any) {
+  const raw = localStorage.getItem(PREFIX + key);
+  if (raw === null) {
+    return def;
+  }
+  try {
+    return JSON.parse(raw);
+  } catch {
+    return def;
+  }
+}
+
+export function writePreference(ws: Socket | null, key: string, value: any) {
+  localStorage.setItem(PREFIX + key, JSON.stringify(value));
+  ws?.send(JSON.stringify({ type: "Preferences", params: { [key]: value } }));
+}
+
+export function readPreferences(preferences?: Record<string, any>) {
+  const values: Record<string, any> = {};
+  Object.keys(preferences || {}).forEach((key) => {
+    values[key] = readPreference(key, preferences![key]);
+  });
+  return values;
+}
+
+// lets components persist a preference, e.g. on a column resize
+export function setPreferenceUtilMethod(ws: Socket | null): UtilMethodFactory {
+  return () =>
+    implementUtilMethod({
+      version: "binding/v1",
+      metadata: {
+        name: "setPreference",
+      },
+      spec: {
+        parameters: {} as any,
+      },
+    })((params: any) => {
+      writePreference(ws, params.key, params.value);
+    });
+}
+
+// reports the stored preferences on every connect, before the app is
+// marked as served, and applies the ones written by the server
+export function usePreferences({
+  ws,
+  preferences,
+}: {
+  ws: Socket | null;
+  preferences?: Record<string, any>;
+}) {
+  useEffect(() => {
+    if (!ws) {
+      return;
+    }
+    const socket = ws;
+    const report = () =>
+      socket.send(
+        JSON.stringify({
+          type: "Preferences",
+          params: readPreferences(preferences),
+        })
+      );
+    const messageHandler = (evt: Event) => {
+      const message = JSON.parse((evt as MessageEvent).data);
+      if (message.type !== "Preference") {
+        return;
+      }
+      writePreference(socket, message.key, message.value);
+    };
+
+    report();
+    socket.addEventListener("reconnect", report);
+    socket.addEventListener("message", messageHandler);
+    return () => {
+      socket.removeEventListener("reconnect", report);
+      socket.removeEventListener("message", messageHandler);
+    };
+  }, [ws]);
+}
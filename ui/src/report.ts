@@ -0,0 +1,93 @@
+import { useEffect } from "react";
+import { Socket } from "./socket";
+
+// time for the report app to lay out after the first render
+const PRINT_DELAY = 500;
+
+// called by report pages, prints as soon as the app settled
+export function printWhenReady() {
+  window.addEventListener("load", () => {
+    document.fonts.ready.then(() => setTimeout(() => window.print(), PRINT_DELAY));
+  });
+}
+
+// loads PrintReport urls in a hidden frame, which prints itself
+export function usePrintReport({ ws }: { ws: Socket | null }) {
+  useEffect(() => {
+    if (!ws) {
+      return;
+    }
+    const socket = ws;
+    const messageHandler = (evt: Event) => {
+      const message = JSON.parse((evt as MessageEvent).data);
+      if (message.type !== "PrintReport") {
+        return;
+      }
+      const frame = document.createElement("iframe");
+      frame.style.position = "fixed";
+      frame.style.width = "0";
+      frame.style.height = "0";
+      frame.style.border = "0";
+      frame.src = message.url;
+      frame.onload = () => {
+        frame.contentWindow?.addEventListener("afterprint", () =>
+          frame.remove()
+        );
+      };
+      document.body.appendChild(frame);
+    };
+    socket.addEventListener("message", messageHandler);
+    return () => socket.removeEventListener("message", messageHandler);
+  }, [ws]);
+}
+
+// while printing only the region and its contents are visible, moved to
+// the top left corner of the paper
+function printStyle(componentId: string, size: string, margin: string) {
+  const region = `[data-binding-printable="${CSS.escape(componentId)}"]`;
+  const page = [size && `size: ${size};`, margin && `margin: ${margin};`]
+    .filter(Boolean)
+    .join(" ");
+  return `@media print {
+  body * { visibility: hidden; }
+  ${region}, ${region} * { visibility: visible; }
+  ${region} { position: absolute; left: 0; top: 0; width: 100%; }
+}
+${page ? `@page { ${page} }` : ""}`;
+}
+
+// prints a Printable component on its own when the server calls Print
+export function usePrint({ ws }: { ws: Socket | null }) {
+  useEffect(() => {
+    if (!ws) {
+      return;
+    }
+    const socket = ws;
+    const messageHandler = (evt: Event) => {
+      const message = JSON.parse((evt as MessageEvent).data);
+      if (message.type !== "Print") {
+        return;
+      }
+      const el = document.querySelector<HTMLElement>(
+        `[data-binding-printable="${CSS.escape(message.componentId)}"]`
+      );
+      if (!el) {
+        console.error(`component ${message.componentId} is not printable`);
+        return;
+      }
+      const style = document.createElement("style");
+      style.textContent = printStyle(
+        message.componentId,
+        el.dataset.bindingPrintSize || "",
+        el.dataset.bindingPrintMargin || ""
+      );
+      document.head.appendChild(style);
+      window.addEventListener("afterprint", () => style.remove(), {
+        once: true,
+      });
+      window.print();
+    };
+    socket.addEventListener("message", messageHandler);
+    return () => socket.removeEventListener("message", messageHandler);
+  }, [ws]);
+}
@@ -0,0 +1,84 @@
+import type { Application, ComponentSchema } from "@sunmao-ui/core";
+import { useEffect } from "react";
+import { Socket } from "./socket";
+
+export type Change = {
+  kind: "added" | "removed" | "changed";
+  componentId: string;
+  path?: string;
+  old?: any;
+  new?: any;
+};
+
+const TRAIT_PATH = /^traits\[(\d+)\]$/;
+
+function applyChange(component: ComponentSchema, change: Change) {
+  if (change.path === "type") {
+    return { ...component, type: change.new };
+  }
+  if (change.path?.startsWith("properties.")) {
+    const key = change.path.slice("properties.".length);
+    const properties: Record<string, any> = { ...component.properties };
+    if (change.new === undefined) {
+      delete properties[key];
+    } else {
+      properties[key] = change.new;
+    }
+    return { ...component, properties };
+  }
+  const trait = change.path?.match(TRAIT_PATH);
+  if (trait) {
+    // removed traits are left as holes and dropped once all changes ran
+    const traits: any[] = [...component.traits];
+    traits[Number(trait[1])] = change.new;
+    return { ...component, traits };
+  }
+  return component;
+}
+
+// applies the changes computed by sunmao.Diff on the server
+export function applyChanges(app: Application, changes: Change[]) {
+  let components = [...app.spec.components];
+  changes.forEach((change) => {
+    if (change.kind === "added") {
+      components.push(change.new);
+    } else if (change.kind === "removed") {
+      components = components.filter((c) => c.id !== change.componentId);
+    } else {
+      components = components.map((c) =>
+        c.id === change.componentId ? applyChange(c, change) : c
+      );
+    }
+  });
+  components = components.map((c) => ({
+    ...c,
+    traits: c.traits.filter((t) => t !== undefined),
+  }));
+  return { ...app, spec: { ...app.spec, components } };
+}
+
+// patches the rendered application in place on SchemaPatch, keeping the
+// state of components the change did not touch
+export function useSchemaPatch({
+  ws,
+  setApp,
+}: {
+  ws: Socket | null;
+  setApp: (update: (app: Application) => Application) => void;
+}) {
+  useEffect(() => {
+    if (!ws) {
+      return;
+    }
+    const socket = ws;
+    const messageHandler = (evt: Event) => {
+      const message = JSON.parse((evt as MessageEvent).data);
+      if (message.type !== "SchemaPatch") {
+        return;
+      }
+      setApp((app) => applyChanges(app, message.changes));
+    };
+    socket.addEventListener("message", messageHandler);
+    return () => socket.removeEventListener("message", messageHandler);
+  }, [ws]);
+}
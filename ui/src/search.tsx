@@ -0,0 +1,193 @@
+import { implementRuntimeComponent } from "@sunmao-ui/runtime";
+import { Fragment, useEffect, useRef, useState } from "react";
+import { Socket } from "./socket";
+
+type SearchResult = {
+  id: string;
+  title: string;
+  description?: string;
+  group?: string;
+  data?: any;
+};
+
+const styles = {
+  root: { position: "relative" },
+  input: {
+    width: "100%",
+    boxSizing: "border-box",
+    padding: "6px 10px",
+    border: "1px solid #e5e6eb",
+    borderRadius: 4,
+    fontSize: 14,
+  },
+  dropdown: {
+    position: "absolute",
+    top: "100%",
+    left: 0,
+    right: 0,
+    zIndex: 1000,
+    maxHeight: 360,
+    overflowY: "auto",
+    margin: "4px 0 0",
+    padding: 4,
+    background: "#fff",
+    borderRadius: 4,
+    boxShadow: "0 4px 16px rgba(0, 0, 0, 0.15)",
+  },
+  group: {
+    listStyle: "none",
+    padding: "6px 8px 2px",
+    fontSize: 12,
+    color: "#86909c",
+  },
+  item: { listStyle: "none", padding: "6px 8px", cursor: "pointer" },
+  description: { fontSize: 12, color: "#86909c" },
+  status: {
+    listStyle: "none",
+    padding: "6px 8px",
+    fontSize: 12,
+    color: "#86909c",
+  },
+} as const;
+
+// the client of runtime.Search, results of the current query stream in
+// per source until the server marks the query done
+export function searchComponent(ws: Socket | null) {
+  return implementRuntimeComponent({
+    version: "binding/v1",
+    metadata: {
+      name: "search",
+      displayName: "Search",
+      exampleProperties: { search: "", placeholder: "Search", debounce: 250 },
+      annotations: { category: "Input" },
+      isDraggable: true,
+      isResizable: true,
+    },
+    spec: {
+      properties: {} as any,
+      state: {} as any,
+      methods: {},
+      slots: {},
+      styleSlots: ["content"],
+      events: ["onSelect"],
+    },
+  })(
+    ({
+      search,
+      placeholder,
+      debounce,
+      mergeState,
+      callbackMap,
+      elementRef,
+    }: any) => {
+      const [query, setQuery] = useState("");
+      const [results, setResults] = useState<SearchResult[]>([]);
+      const [done, setDone] = useState(true);
+      const [open, setOpen] = useState(false);
+      const current = useRef("");
+
+      useEffect(() => {
+        if (!ws) {
+          return;
+        }
+        const socket = ws;
+        const messageHandler = (evt: Event) => {
+          const message = JSON.parse((evt as MessageEvent).data);
+          if (
+            message.type !== "SearchResults" ||
+            message.search !== search ||
+            message.query !== current.current
+          ) {
+            return;
+          }
+          setResults((prev) => prev.concat(message.results || []));
+          setDone(message.done);
+        };
+        socket.addEventListener("message", messageHandler);
+        return () => socket.removeEventListener("message", messageHandler);
+      }, [search]);
+
+      useEffect(() => {
+        mergeState({ query });
+        if (!query.trim()) {
+          current.current = "";
+          setResults([]);
+          setDone(true);
+          return;
+        }
+        const timer = setTimeout(() => {
+          current.current = query;
+          setResults([]);
+          setDone(false);
+          ws?.send(
+            JSON.stringify({
+              type: "Action",
+              handler: `${search}/query`,
+              params: { query },
+            })
+          );
+        }, debounce || 0);
+        return () => clearTimeout(timer);
+      }, [query, search, debounce]);
+
+      const select = (result: SearchResult) => {
+        setOpen(false);
+        mergeState({ selected: result });
+        ws?.send(
+          JSON.stringify({
+            type: "Action",
+            handler: `${search}/select`,
+            params: { result },
+          })
+        );
+        callbackMap?.onSelect?.();
+      };
+
+      let lastGroup: string | undefined;
+      return (
+        <div ref={elementRef} style={styles.root}>
+          <input
+            style={styles.input}
+            placeholder={placeholder}
+            value={query}
+            onChange={(evt) => {
+              setQuery(evt.target.value);
+              setOpen(true);
+            }}
+            onFocus={() => setOpen(true)}
+            onBlur={() => setTimeout(() => setOpen(false), 150)}
+          />
+          {open && query.trim() && (
+            <ul style={styles.dropdown} role="listbox">
+              {results.map((result) => {
+                const header = result.group && result.group !== lastGroup;
+                lastGroup = result.group;
+                return (
+                  <Fragment key={`${result.group}/${result.id}`}>
+                    {header && <li style={styles.group}>{result.group}</li>}
+                    <li
+                      role="option"
+                      style={styles.item}
+                      onMouseDown={() => select(result)}
+                    >
+                      <div>{result.title}</div>
+                      {result.description && (
+                        <div style={styles.description}>
+                          {result.description}
+                        </div>
+                      )}
+                    </li>
+                  </Fragment>
+                );
+              })}
+              {!done && <li style={styles.status}>Searching…</li>}
+              {done && results.length === 0 && (
+                <li style={styles.status}>No results</li>
+              )}
+            </ul>
+          )}
+        </div>
+      );
+    }
+  );
+}
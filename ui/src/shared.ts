@@ -8,64 +8,312 @@ import {
 } from "@sunmao-ui/runtime";
 import { useEffect } from "react";
 import * as jdp from "jsondiffpatch";
+import { ReconnectPolicy, Socket } from "./socket";
+import { bindingTraits } from "./traits";
+import { JSONSchema, validateParams } from "./validate";
+import type { CommandPaletteOptions } from "./palette";
+import { errorBoundaryComponent } from "./components";
+import { searchComponent } from "./search";
+import { navShellComponent } from "./nav";
+import { dashboardComponent } from "./dashboard";
+import { kanbanComponent } from "./kanban";
+import { calendarComponent } from "./calendar";
+import { treeComponent } from "./tree";
+import { mapComponent } from "./map";
+import { diffComponent } from "./diff";
+import { dataEditorComponent } from "./dataEditor";
+import { imageAnnotatorComponent } from "./annotate";
+import { virtualListComponent } from "./virtualList";
+import { loadMoreComponent } from "./loadMore";
+import { typeaheadComponent } from "./typeahead";
+import { dropZoneComponent } from "./dropZone";
+import { logViewComponent } from "./logView";
+import { terminalComponent } from "./terminal";
+import { CustomComponentDeclaration, customComponents } from "./custom";
+import { setPreferenceUtilMethod } from "./preferences";
+import {
+  beepUtilMethod,
+  focusUtilMethod,
+  notifyUtilMethod,
+} from "./notify";
+
+export type HandlerSpec = {
+  store?: string[];
+  params?: JSONSchema;
+  command?: { title: string; keywords?: string[] };
+  confirm?: string;
+};
+
+export type StoreGetter = () => Record<string, any>;
+
+// applies an optimistic value to the state of a ServerState locally
+export type StateSetter = (componentId: string, value: any) => void;
+
+// pickStore copies only the dotted paths out of the store, keeping their
+// nesting, so "input.value" becomes { input: { value } }
+function pickStore(store: Record<string, any>, paths: string[]) {
+  const picked: Record<string, any> = {};
+  paths.forEach((path) => {
+    const keys = path.split(".");
+    let src: any = store;
+    let dst = picked;
+    for (let i = 0; i < keys.length; i++) {
+      if (src === null || typeof src !== "object" || !(keys[i] in src)) {
+        return;
+      }
+      src = src[keys[i]];
+      if (i === keys.length - 1) {
+        dst[keys[i]] = src;
+      } else {
+        dst[keys[i]] = dst[keys[i]] || {};
+        dst = dst[keys[i]];
+      }
+    }
+  });
+  return picked;
+}
+
+// params.optimistic = { state, value } shows value in the ServerState
+// state right away, the server confirms or corrects it
+function optimisticOf(
+  params: any,
+  getStore?: StoreGetter,
+  setState?: StateSetter
+) {
+  const optimistic = params?.optimistic;
+  if (!optimistic?.state || !setState) {
+    return { params, optimistic: undefined };
+  }
+  const { optimistic: _, ...rest } = params;
+  const version = getStore?.()[optimistic.state]?.version || 0;
+  setState(optimistic.state, optimistic.value);
+  return {
+    params: rest,
+    optimistic: { state: optimistic.state, value: optimistic.value, version },
+  };
+}
+
+// fills the {field} placeholders of a handler's confirm message
+function confirmText(message: string, params: any): string {
+  return message.replace(/\{(\w+)\}/g, (placeholder, field) =>
+    params && params[field] !== undefined ? String(params[field]) : placeholder
+  );
+}
+
+export function handlerUtilMethod(
+  ws: Socket | null,
+  handler: string,
+  spec?: HandlerSpec,
+  getStore?: StoreGetter,
+  setState?: StateSetter
+): UtilMethodFactory {
+  return () =>
+    implementUtilMethod({
+      version: "binding/v1",
+      metadata: {
+        name: handler,
+      },
+      spec: {
+        // also gives the editor a form for the params
+        parameters: (spec?.params || {}) as any,
+      },
+    })((callParams) => {
+      if (spec?.params) {
+        // the server validates the params without the optimistic value
+        let checked: any = callParams;
+        if (checked && typeof checked === "object" && "optimistic" in checked) {
+          const { optimistic: _, ...rest } = checked;
+          checked = rest;
+        }
+        const invalid = validateParams(spec.params, checked);
+        if (invalid) {
+          console.error(`action ${handler} rejected: invalid_params ${invalid}`);
+          return;
+        }
+      }
+      if (
+        spec?.confirm &&
+        !window.confirm(confirmText(spec.confirm, callParams))
+      ) {
+        return;
+      }
+      const { params, optimistic } = optimisticOf(
+        callParams,
+        getStore,
+        setState
+      );
+      ws?.send(
+        JSON.stringify({
+          type: "Action",
+          handler,
+          params,
+          optimistic,
+          store:
+            spec?.store && getStore
+              ? pickStore(getStore(), spec.store)
+              : undefined,
+        })
+      );
+    });
+}
 
 export function getLibs({
   ws,
   handlers,
+  handlerSpecs,
+  getStore,
+  setState,
   utilMethods,
 }: {
-  ws: WebSocket;
+  ws: Socket | null;
   handlers: string[];
+  handlerSpecs?: Record<string, HandlerSpec>;
+  getStore?: StoreGetter;
+  setState?: StateSetter;
   utilMethods?: UtilMethodFactory[];
 }) {
   return [
     sunmaoChakraUILib,
     ArcoDesignLib,
     {
+      traits: bindingTraits(ws),
+      components: [
+        errorBoundaryComponent(ws),
+        searchComponent(ws),
+        navShellComponent(ws),
+        dashboardComponent(ws),
+        kanbanComponent(ws),
+        calendarComponent(ws),
+        treeComponent(ws),
+        mapComponent(ws),
+        diffComponent,
+        dataEditorComponent(ws),
+        imageAnnotatorComponent(ws),
+        virtualListComponent(ws),
+        loadMoreComponent(ws),
+        typeaheadComponent(ws),
+        dropZoneComponent(ws),
+        logViewComponent(ws),
+        terminalComponent(ws),
+        ...customComponents,
+      ],
       utilMethods: (utilMethods || []).concat(
-        handlers.map(
-          (handler) => () =>
-            implementUtilMethod({
-              version: "binding/v1",
-              metadata: {
-                name: handler,
-              },
-              spec: {
-                parameters: {} as any,
-              },
-            })((params) => {
-              ws.send(
-                JSON.stringify({
-                  type: "Action",
-                  handler,
-                  params,
-                })
-              );
-            })
+        setPreferenceUtilMethod(ws),
+        beepUtilMethod,
+        notifyUtilMethod,
+        focusUtilMethod,
+        logoutUtilMethod,
+        handlers.map((handler) =>
+          handlerUtilMethod(
+            ws,
+            handler,
+            handlerSpecs?.[handler],
+            getStore,
+            setState
+          )
         )
       ),
     },
   ];
 }
 
+// registers handlers the server added after the app was served, and
+// registers them again when their spec changed, e.g. after an Override
+export function useHandlers({
+  ws,
+  handlers,
+  handlerSpecs,
+  registry,
+  getStore,
+  setState,
+}: {
+  ws: Socket | null;
+  handlers: string[];
+  handlerSpecs?: Record<string, HandlerSpec>;
+  registry: ReturnType<typeof initSunmaoUI>["registry"];
+  getStore?: StoreGetter;
+  setState?: StateSetter;
+}) {
+  useEffect(() => {
+    if (!ws) {
+      return;
+    }
+    const socket = ws;
+    // handler name to its serialized spec
+    const known = new Map(
+      handlers.map((handler) => [
+        handler,
+        JSON.stringify(handlerSpecs?.[handler] ?? null),
+      ])
+    );
+    const messageHandler = (evt: Event) => {
+      const message = JSON.parse((evt as MessageEvent).data);
+      if (message.type !== "Handlers") {
+        return;
+      }
+      const current = new Set(message.handlers as string[]);
+      // removed handlers are rejected by the server, forget them so they
+      // are registered again if they come back
+      Array.from(known.keys())
+        .filter((handler) => !current.has(handler))
+        .forEach((handler) => known.delete(handler));
+      (message.handlers as string[]).forEach((handler) => {
+        const spec = JSON.stringify(message.handlerSpecs?.[handler] ?? null);
+        if (known.get(handler) === spec) {
+          return;
+        }
+        known.set(handler, spec);
+        registry.registerUtilMethod(
+          handlerUtilMethod(
+            socket,
+            handler,
+            message.handlerSpecs?.[handler],
+            getStore,
+            setState
+          )()
+        );
+      });
+    };
+    socket.addEventListener("message", messageHandler);
+    return () => socket.removeEventListener("message", messageHandler);
+  }, [registry]);
+}
+
 export type ServerMessage = {
   type: string;
   componentId: string;
   name: string;
   parameters?: any;
+  handler?: string;
+  code?: string;
+  message?: string;
+  requestId?: string;
 };
 
 export function useApiService({
   ws,
   apiService,
 }: {
-  ws: WebSocket;
+  ws: Socket | null;
   apiService: ReturnType<typeof initSunmaoUI>["apiService"];
 }) {
   useEffect(() => {
-    const messageHandler = (evt: MessageEvent) => {
+    if (!ws) {
+      return;
+    }
+    const messageHandler = (evt: Event) => {
       try {
-        const message: ServerMessage = JSON.parse(evt.data);
+        const message: ServerMessage = JSON.parse((evt as MessageEvent).data);
+        if (message.type === "Reload") {
+          window.location.reload();
+          return;
+        }
+        if (message.type === "ActionError") {
+          console.error(
+            `action ${message.handler} rejected: ${message.code} ${message.message} (request ${message.requestId})`
+          );
+          return;
+        }
         if (message.type !== "UiMethod") {
           return;
         }
@@ -78,14 +326,31 @@ export function useApiService({
         console.log("message handler", error);
       }
     };
-    ws.addEventListener("message", messageHandler);
-    return () => ws.removeEventListener("message", messageHandler);
+    const socket = ws;
+    socket.addEventListener("message", messageHandler);
+    // lets the server push initial states once the app is mounted, and
+    // again after every reconnect
+    const notifyServed = () =>
+      socket.send(JSON.stringify({ type: "AppServed" }));
+    notifyServed();
+    socket.addEventListener("reconnect", notifyServed);
+    return () => {
+      socket.removeEventListener("message", messageHandler);
+      socket.removeEventListener("reconnect", notifyServed);
+    };
   }, [apiService]);
 }
 
 export type BaseProps = {
   handlers: string[];
-  ws: WebSocket;
+  handlerSpecs?: Record<string, HandlerSpec>;
+  shortcuts?: Record<string, string>;
+  clientEvents?: boolean;
+  preferences?: Record<string, any>;
+  hydration?: Record<string, any>;
+  flags?: Record<string, any>;
+  commandPalette?: CommandPaletteOptions;
+  ws: Socket | null;
   utilMethods?: UtilMethodFactory[];
 } & Pick<
   MainOptions,
@@ -98,11 +363,61 @@ export type MainOptions = {
   wsUrl: string;
   reloadWhenWsDisconnected: boolean;
   handlers: string[];
+  handlerSpecs?: Record<string, HandlerSpec>;
+  shortcuts?: Record<string, string>;
+  clientEvents?: boolean;
+  preferences?: Record<string, any>;
+  hydration?: Record<string, any>;
+  flags?: Record<string, any>;
+  commandPalette?: CommandPaletteOptions;
   utilMethods?: { options: any; impl: any }[];
   applicationPatch?: any;
   modulesPatch?: any;
+  basePath?: string;
+  csrf?: { cookie: string; header: string };
+  reconnect?: ReconnectPolicy;
+  print?: boolean;
+  customComponents?: CustomComponentDeclaration[] | null;
 };
 
+let basePath = "";
+let csrf: MainOptions["csrf"];
+
+export function setBasePath(path: string) {
+  basePath = path;
+}
+
+// prefixes a path of the runtime with the path it is mounted under
+export function withBasePath(path: string) {
+  return `${basePath}${path}`;
+}
+
+export function setCsrf(options: MainOptions["csrf"]) {
+  csrf = options;
+}
+
+// state changing requests echo the csrf cookie back in a header
+export function csrfHeaders(): Record<string, string> {
+  if (!csrf) {
+    return {};
+  }
+  const pair = document.cookie
+    .split("; ")
+    .find((c) => c.startsWith(`${csrf!.cookie}=`));
+  return pair ? { [csrf.header]: pair.slice(csrf.cookie.length + 1) } : {};
+}
+
+// the default wsUrl assumes the app is served at the root, rebase it when
+// the runtime is mounted under a sub path
+export function resolveWsUrl(wsUrl: string) {
+  if (!basePath || !wsUrl) {
+    return wsUrl;
+  }
+  const url = new URL(wsUrl);
+  url.pathname = `${basePath}${url.pathname}`;
+  return url.toString();
+}
+
 const PREFIX = "/sunmao-binding-patch";
 
 const diffpatcher = jdp.create({
@@ -122,10 +437,11 @@ const diffpatcher = jdp.create({
 });
 
 export function saveApp(app: Application, base: Application) {
-  return fetch(`${PREFIX}/app`, {
+  return fetch(`${basePath}${PREFIX}/app`, {
     method: "put",
     headers: {
       "content-type": "application/json",
+      ...csrfHeaders(),
     },
     body: JSON.stringify({
       delta: diffpatcher.diff(base, app),
@@ -134,10 +450,11 @@ export function saveApp(app: Application, base: Application) {
 }
 
 export function saveModules(modules: Module[], base: Module[]) {
-  return fetch(`${PREFIX}/modules`, {
+  return fetch(`${basePath}${PREFIX}/modules`, {
     method: "put",
     headers: {
       "content-type": "application/json",
+      ...csrfHeaders(),
     },
     body: JSON.stringify({
       delta: diffpatcher.diff(base, modules),
@@ -145,6 +462,23 @@ export function saveModules(modules: Module[], base: Module[]) {
   });
 }
 
+// ends the session of runtime.WithPasswordAuth and shows the login page
+export const logoutUtilMethod: UtilMethodFactory = () =>
+  implementUtilMethod({
+    version: "binding/v1",
+    metadata: {
+      name: "logout",
+    },
+    spec: {
+      parameters: {} as any,
+    },
+  })(() => {
+    fetch(`${basePath}/logout`, {
+      method: "post",
+      headers: csrfHeaders(),
+    }).then(() => window.location.reload());
+  });
+
 function isEmptyDelta(delta?: jdp.Delta) {
   return !delta || Object.keys(delta).length === 0;
 }
@@ -160,3 +494,70 @@ export function patchModules(base: Module[], delta?: jdp.Delta): Module[] {
     ? base
     : diffpatcher.patch(diffpatcher.clone(base), delta!);
 }
+
+// bakes the server computed states into the state traits of the
+// ServerState components, so the first render already shows them
+export function hydrateApp(
+  app: Application,
+  hydration?: Record<string, any>
+): Application {
+  if (!hydration || Object.keys(hydration).length === 0) {
+    return app;
+  }
+  return {
+    ...app,
+    spec: {
+      ...app.spec,
+      components: app.spec.components.map((component) =>
+        component.id in hydration
+          ? {
+              ...component,
+              traits: component.traits.map((trait) =>
+                trait.type === "core/v1/state" &&
+                trait.properties.key === "state"
+                  ? {
+                      ...trait,
+                      properties: {
+                        ...trait.properties,
+                        initialValue: hydration[component.id],
+                      },
+                    }
+                  : trait
+              ),
+            }
+          : component
+      ),
+    },
+  };
+}
+
+// the server side sunmao.FlagsId
+const FLAGS_ID = "bindingFlags";
+
+// exposes feature flags to expressions as {{ bindingFlags.values.name }}
+export function withFlags(
+  app: Application,
+  flags?: Record<string, any>
+): Application {
+  if (!flags) {
+    return app;
+  }
+  const holder = {
+    id: FLAGS_ID,
+    type: "core/v1/dummy",
+    properties: {},
+    traits: [
+      {
+        type: "core/v1/state",
+        properties: { key: "values", initialValue: flags },
+      },
+    ],
+  };
+  return {
+    ...app,
+    spec: {
+      ...app.spec,
+      components: [holder as any, ...app.spec.components],
+    },
+  };
+}
@@ -0,0 +1,70 @@
+import { useEffect } from "react";
+import { Socket } from "./socket";
+
+const isMac = navigator.platform.toUpperCase().includes("MAC");
+
+export function matches(keys: string, evt: KeyboardEvent) {
+  const parts = keys.split("+");
+  const key = parts.pop();
+  const mods = new Set(
+    parts.map((m) => (m === "mod" ? (isMac ? "meta" : "ctrl") : m))
+  );
+  return (
+    key === evt.key.toLowerCase() &&
+    mods.has("ctrl") === evt.ctrlKey &&
+    mods.has("shift") === evt.shiftKey &&
+    mods.has("alt") === evt.altKey &&
+    mods.has("meta") === evt.metaKey
+  );
+}
+
+// binds the key combinations declared on the server to handler actions,
+// bindings pushed later by the server add to or remove from them
+export function useShortcuts({
+  ws,
+  shortcuts,
+}: {
+  ws: Socket | null;
+  shortcuts?: Record<string, string>;
+}) {
+  useEffect(() => {
+    if (!ws) {
+      return;
+    }
+    const socket = ws;
+    const bindings: Record<string, string> = { ...shortcuts };
+
+    const keyHandler = (evt: KeyboardEvent) => {
+      const keys = Object.keys(bindings).find((k) => matches(k, evt));
+      if (!keys) {
+        return;
+      }
+      evt.preventDefault();
+      socket.send(
+        JSON.stringify({
+          type: "Action",
+          handler: bindings[keys],
+          params: { shortcut: keys },
+        })
+      );
+    };
+    const messageHandler = (evt: Event) => {
+      const message = JSON.parse((evt as MessageEvent).data);
+      if (message.type !== "Shortcut") {
+        return;
+      }
+      if (message.handler) {
+        bindings[message.keys] = message.handler;
+      } else {
+        delete bindings[message.keys];
+      }
+    };
+
+    window.addEventListener("keydown", keyHandler);
+    socket.addEventListener("message", messageHandler);
+    return () => {
+      window.removeEventListener("keydown", keyHandler);
+      socket.removeEventListener("message", messageHandler);
+    };
+  }, [ws]);
+}
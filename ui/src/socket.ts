@@ -0,0 +1,171 @@
+export type ReconnectPolicy = {
+  maxRetries: number;
+  initialDelay: number;
+  maxDelay: number;
+  onReconnect: "reload" | "resume";
+};
+
+// time for the new process to take over the listener during a handoff
+const HANDOFF_DELAY = 500;
+const HANDOFF_RETRIES = 20;
+
+// Socket wraps a WebSocket and transparently replaces it when a reconnect
+// policy is configured, listeners stay attached across reconnects.
+export class Socket extends EventTarget {
+  private ws!: WebSocket;
+  private retries = 0;
+  private connectedOnce = false;
+  private queue: string[] = [];
+  private resumeToken?: string;
+  // identifies this page load across reconnects, unlike the connection
+  readonly tabId = Math.random().toString(36).slice(2);
+
+  constructor(
+    private url: string,
+    private reloadWhenWsDisconnected: boolean,
+    private policy?: ReconnectPolicy
+  ) {
+    super();
+    this.open();
+  }
+
+  private open() {
+    const url = new URL(this.url);
+    url.searchParams.set("tab", this.tabId);
+    const resuming = Boolean(this.resumeToken);
+    if (this.resumeToken) {
+      url.searchParams.set("resume", this.resumeToken);
+    }
+    this.ws = new WebSocket(url.toString());
+    this.ws.onopen = () => {
+      console.log("ws connected");
+      const reconnected = this.connectedOnce;
+      // a handed off connection resumes in place whatever the policy says
+      if (
+        reconnected &&
+        !resuming &&
+        this.policy?.onReconnect === "reload"
+      ) {
+        window.location.reload();
+        return;
+      }
+      this.connectedOnce = true;
+      this.retries = 0;
+      this.resumeToken = undefined;
+      this.queue.splice(0).forEach((data) => this.ws.send(data));
+      if (reconnected) {
+        this.dispatchEvent(new Event("reconnect"));
+      }
+    };
+    // the server numbers frames per connection, so stale or duplicated
+    // frames are dropped and listeners see them strictly in order
+    let lastSeq = 0;
+    const inOrder = (seq: any) => {
+      if (typeof seq === "number") {
+        if (seq <= lastSeq) {
+          return false;
+        }
+        lastSeq = seq;
+      }
+      return true;
+    };
+    this.ws.binaryType = "arraybuffer";
+    this.ws.onmessage = (evt) => {
+      // binary frames carry a length prefixed json header and raw bytes,
+      // they go to "binary" listeners as { header, body }
+      if (evt.data instanceof ArrayBuffer) {
+        const view = new DataView(evt.data);
+        const size = view.getUint32(0);
+        const header = JSON.parse(
+          new TextDecoder().decode(new Uint8Array(evt.data, 4, size))
+        );
+        if (!inOrder(header.seq)) {
+          return;
+        }
+        this.dispatchEvent(
+          new MessageEvent("binary", {
+            data: { header, body: evt.data.slice(4 + size) },
+          })
+        );
+        return;
+      }
+      const message = JSON.parse(evt.data);
+      if (!inOrder(message.seq)) {
+        return;
+      }
+      if (message.type === "Handoff") {
+        this.resumeToken = message.token;
+        return;
+      }
+      if (message.type === "Chunk") {
+        this.reassemble(message);
+        return;
+      }
+      this.dispatchEvent(new MessageEvent("message", { data: evt.data }));
+    };
+    this.ws.onclose = () => {
+      this.dispatchEvent(new Event("close"));
+      this.reconnect();
+    };
+  }
+
+  private chunks: Record<string, string[]> = {};
+
+  // oversized messages arrive as Chunk frames, listeners only see the
+  // reassembled message
+  private reassemble(chunk: {
+    id: string;
+    index: number;
+    total: number;
+    data: string;
+  }) {
+    const parts = (this.chunks[chunk.id] =
+      this.chunks[chunk.id] || new Array(chunk.total));
+    parts[chunk.index] = chunk.data;
+    for (let i = 0; i < chunk.total; i++) {
+      if (parts[i] === undefined) {
+        return;
+      }
+    }
+    delete this.chunks[chunk.id];
+    this.dispatchEvent(new MessageEvent("message", { data: parts.join("") }));
+  }
+
+  private reconnect() {
+    // the server restarts, come back to the new process right away
+    if (this.resumeToken) {
+      if (this.retries < HANDOFF_RETRIES) {
+        this.retries++;
+        setTimeout(() => this.open(), HANDOFF_DELAY);
+        return;
+      }
+      // the new process never came up, fall back to the policy
+      this.resumeToken = undefined;
+      this.retries = 0;
+    }
+    const policy = this.policy;
+    if (!policy || (policy.maxRetries > 0 && this.retries >= policy.maxRetries)) {
+      if (this.reloadWhenWsDisconnected) {
+        setTimeout(() => {
+          window.location.reload();
+        }, 1500);
+      }
+      return;
+    }
+
+    const delay = Math.min(
+      policy.initialDelay * 2 ** this.retries,
+      policy.maxDelay
+    );
+    this.retries++;
+    setTimeout(() => this.open(), delay);
+  }
+
+  send(data: string) {
+    if (this.ws.readyState === WebSocket.OPEN) {
+      this.ws.send(data);
+    } else if (this.ws.readyState === WebSocket.CONNECTING || this.policy) {
+      this.queue.push(data);
+    }
+  }
+}
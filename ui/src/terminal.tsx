@@ -0,0 +1,249 @@
+import { implementRuntimeComponent } from "@sunmao-ui/runtime";
+import { KeyboardEvent, useEffect, useRef, useState } from "react";
+import { Socket } from "./socket";
+
+type Screen = { lines: string[]; col: number };
+
+const MAX_LINES = 2000;
+const LINE_HEIGHT = 18;
+
+const styles = {
+  viewport: {
+    overflowY: "auto",
+    margin: 0,
+    padding: "8px 12px",
+    background: "#1d2129",
+    color: "#e5e6eb",
+    fontFamily: "ui-monospace, SFMono-Regular, Menlo, monospace",
+    fontSize: 13,
+    lineHeight: `${LINE_HEIGHT}px`,
+    whiteSpace: "pre",
+    outline: "none",
+  },
+  cursor: { background: "#e5e6eb", color: "#1d2129" },
+  measure: { position: "absolute", visibility: "hidden" },
+  status: { marginTop: 8, color: "#86909c" },
+} as const;
+
+const KEYS: Record<string, string> = {
+  Enter: "\r",
+  Backspace: "\x7f",
+  Tab: "\t",
+  Escape: "\x1b",
+  ArrowUp: "\x1b[A",
+  ArrowDown: "\x1b[B",
+  ArrowRight: "\x1b[C",
+  ArrowLeft: "\x1b[D",
+  Home: "\x1b[H",
+  End: "\x1b[F",
+  Delete: "\x1b[3~",
+};
+
+const CSI = /^\x1b\[([0-?]*)[ -/]*([@-~])/;
+const OSC = /^\x1b\][^\x07\x1b]*(\x07|\x1b\\)?/;
+
+// writes output to the screen, escape sequences are dropped except for
+// erasing the rest of the line, which shells use to redraw the prompt
+function write(screen: Screen, text: string) {
+  const { lines } = screen;
+  let i = 0;
+  while (i < text.length) {
+    const ch = text[i];
+    const last = lines.length - 1;
+    if (ch === "\x1b") {
+      const rest = text.slice(i, i + 64);
+      const csi = CSI.exec(rest);
+      const osc = csi ? null : OSC.exec(rest);
+      if (csi && csi[2] === "K") {
+        lines[last] = lines[last].slice(0, screen.col);
+      }
+      i += csi ? csi[0].length : osc ? osc[0].length : 2;
+      continue;
+    }
+    i++;
+    if (ch === "\n") {
+      lines.push("");
+      screen.col = 0;
+    } else if (ch === "\r") {
+      screen.col = 0;
+    } else if (ch === "\b") {
+      screen.col = Math.max(0, screen.col - 1);
+    } else if (ch === "\t") {
+      const spaces = 8 - (screen.col % 8);
+      lines[last] = lines[last].padEnd(screen.col + spaces);
+      screen.col += spaces;
+    } else if (ch >= " ") {
+      const line = lines[last].padEnd(screen.col);
+      lines[last] = line.slice(0, screen.col) + ch + line.slice(screen.col + 1);
+      screen.col++;
+    }
+  }
+  if (lines.length > MAX_LINES) {
+    lines.splice(0, lines.length - MAX_LINES);
+  }
+}
+
+function keyData(evt: KeyboardEvent): string | undefined {
+  if (evt.metaKey) {
+    return;
+  }
+  if (evt.ctrlKey && evt.key.length === 1) {
+    const code = evt.key.toLowerCase().charCodeAt(0);
+    return code >= 97 && code <= 122
+      ? String.fromCharCode(code - 96)
+      : undefined;
+  }
+  if (KEYS[evt.key]) {
+    return KEYS[evt.key];
+  }
+  return evt.key.length === 1 ? evt.key : undefined;
+}
+
+// the client of runtime.NewTerminal, output arrives base64 encoded and is
+// decoded as a stream so characters split across messages survive
+export function terminalComponent(ws: Socket | null) {
+  return implementRuntimeComponent({
+    version: "binding/v1",
+    metadata: {
+      name: "terminal",
+      displayName: "Terminal",
+      exampleProperties: {
+        terminal: "",
+        session: {},
+        params: {},
+        height: 400,
+      },
+      annotations: { category: "Display" },
+      isDraggable: true,
+      isResizable: true,
+    },
+    spec: {
+      properties: {} as any,
+      state: {} as any,
+      methods: {},
+      slots: {},
+      styleSlots: ["content"],
+      events: [],
+    },
+  })(({ terminal, session, params, height, elementRef }: any) => {
+    const screen = useRef<Screen>({ lines: [""], col: 0 });
+    const decoder = useRef(new TextDecoder());
+    const viewport = useRef<HTMLPreElement>(null);
+    const measure = useRef<HTMLSpanElement>(null);
+    const opened = useRef(false);
+    const [, setVersion] = useState(0);
+
+    const action = (name: string, actionParams: unknown) =>
+      ws?.send(
+        JSON.stringify({
+          type: "Action",
+          handler: `${terminal}/${name}`,
+          params: actionParams,
+        })
+      );
+
+    const size = () => {
+      const el = viewport.current;
+      const charWidth = measure.current?.getBoundingClientRect().width || 8;
+      if (!el) {
+        return { cols: 80, rows: 24 };
+      }
+      return {
+        cols: Math.max(20, Math.floor((el.clientWidth - 24) / charWidth)),
+        rows: Math.max(5, Math.floor((el.clientHeight - 16) / LINE_HEIGHT)),
+      };
+    };
+
+    useEffect(() => {
+      if (!ws) {
+        return;
+      }
+      const socket = ws;
+      const messageHandler = (evt: Event) => {
+        const message = JSON.parse((evt as MessageEvent).data);
+        if (
+          message.type !== "TerminalOutput" ||
+          message.terminal !== terminal
+        ) {
+          return;
+        }
+        const bytes = Uint8Array.from(atob(message.data), (c) =>
+          c.charCodeAt(0)
+        );
+        write(screen.current, decoder.current.decode(bytes, { stream: true }));
+        setVersion((v) => v + 1);
+      };
+      socket.addEventListener("message", messageHandler);
+      screen.current = { lines: [""], col: 0 };
+      decoder.current = new TextDecoder();
+      action("open", { ...size(), params: params || {} });
+
+      let timer: ReturnType<typeof setTimeout>;
+      const observer = new ResizeObserver(() => {
+        clearTimeout(timer);
+        timer = setTimeout(() => action("resize", size()), 200);
+      });
+      if (viewport.current) {
+        observer.observe(viewport.current);
+      }
+      return () => {
+        clearTimeout(timer);
+        observer.disconnect();
+        socket.removeEventListener("message", messageHandler);
+        action("close", {});
+      };
+    }, [terminal, JSON.stringify(params)]);
+
+    useEffect(() => {
+      const el = viewport.current;
+      if (el) {
+        el.scrollTop = el.scrollHeight;
+      }
+    });
+
+    const { lines, col } = screen.current;
+    const current = lines[lines.length - 1].padEnd(col + 1);
+    // the state is closed too before the first session opened
+    if (session?.open) {
+      opened.current = true;
+    }
+    const status: string = session?.error
+      ? session.error
+      : opened.current && !session?.open
+      ? "Session closed"
+      : "";
+
+    return (
+      <div ref={elementRef}>
+        <pre
+          ref={viewport}
+          tabIndex={0}
+          style={{ ...styles.viewport, height: height || 400 }}
+          onKeyDown={(evt) => {
+            const data = keyData(evt);
+            if (data !== undefined) {
+              evt.preventDefault();
+              action("input", { data });
+            }
+          }}
+          onPaste={(evt) => {
+            evt.preventDefault();
+            action("input", { data: evt.clipboardData.getData("text") });
+          }}
+        >
+          <span ref={measure} style={styles.measure}>
+            M
+          </span>
+          {lines.slice(0, -1).join("\n")}
+          {lines.length > 1 && "\n"}
+          {current.slice(0, col)}
+          <span style={session?.open ? styles.cursor : undefined}>
+            {current[col]}
+          </span>
+          {current.slice(col + 1)}
+        </pre>
+        {status && <div style={styles.status}>{status}</div>}
+      </div>
+    );
+  });
+}
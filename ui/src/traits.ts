@@ -0,0 +1,142 @@
+import { implementRuntimeTrait } from "@sunmao-ui/runtime";
+import { Socket } from "./socket";
+
+const DRAG_TYPE = "application/x-sunmao-binding";
+
+// sunmao renders every component inside a wrapper carrying its id
+function componentElement(componentId: string): HTMLElement | null {
+  return document.querySelector(`[data-sunmao-ui-id="${componentId}"]`);
+}
+
+// componentDidMount runs before the element is attached in some libs,
+// retry on the next frames until it shows up
+function withElement(
+  componentId: string,
+  fn: (el: HTMLElement) => () => void
+) {
+  let cleanup = () => {};
+  let frame = 0;
+  const attach = () => {
+    const el = componentElement(componentId);
+    if (el) {
+      cleanup = fn(el);
+    } else if (frame++ < 60) {
+      requestAnimationFrame(attach);
+    }
+  };
+  attach();
+  return () => cleanup();
+}
+
+const DraggableTrait = implementRuntimeTrait({
+  version: "binding/v1",
+  metadata: { name: "draggable", description: "drag source" },
+  spec: { properties: {} as any, state: {} as any, methods: [] },
+})(() => {
+  const cleanups: Record<string, () => void> = {};
+  return ({ componentId, payload }: any) => ({
+    props: {
+      componentDidMount: [
+        () => {
+          cleanups[componentId] = withElement(componentId, (el) => {
+            const onDragStart = (evt: DragEvent) => {
+              evt.dataTransfer?.setData(
+                DRAG_TYPE,
+                JSON.stringify({ sourceId: componentId, payload })
+              );
+            };
+            el.draggable = true;
+            el.addEventListener("dragstart", onDragStart);
+            return () => el.removeEventListener("dragstart", onDragStart);
+          });
+        },
+      ],
+      componentDidUnmount: [() => cleanups[componentId]?.()],
+    },
+  });
+});
+
+// marks the element for usePrint, the page layout travels on it as well
+const PrintableTrait = implementRuntimeTrait({
+  version: "binding/v1",
+  metadata: { name: "printable", description: "print region" },
+  spec: { properties: {} as any, state: {} as any, methods: [] },
+})(() => {
+  const cleanups: Record<string, () => void> = {};
+  return ({ componentId, size, margin }: any) => ({
+    props: {
+      componentDidMount: [
+        () => {
+          cleanups[componentId] = withElement(componentId, (el) => {
+            el.dataset.bindingPrintable = componentId;
+            el.dataset.bindingPrintSize = size || "";
+            el.dataset.bindingPrintMargin = margin || "";
+            return () => {
+              delete el.dataset.bindingPrintable;
+              delete el.dataset.bindingPrintSize;
+              delete el.dataset.bindingPrintMargin;
+            };
+          });
+        },
+      ],
+      componentDidUnmount: [() => cleanups[componentId]?.()],
+    },
+  });
+});
+
+export function droppableTrait(ws: Socket | null) {
+  return implementRuntimeTrait({
+    version: "binding/v1",
+    metadata: { name: "droppable", description: "drop target" },
+    spec: { properties: {} as any, state: {} as any, methods: [] },
+  })(() => {
+    const cleanups: Record<string, () => void> = {};
+    return ({ componentId, handler, parameters }: any) => ({
+      props: {
+        componentDidMount: [
+          () => {
+            cleanups[componentId] = withElement(componentId, (el) => {
+              const onDragOver = (evt: DragEvent) => {
+                if (evt.dataTransfer?.types.includes(DRAG_TYPE)) {
+                  evt.preventDefault();
+                }
+              };
+              const onDrop = (evt: DragEvent) => {
+                const data = evt.dataTransfer?.getData(DRAG_TYPE);
+                if (!data) {
+                  return;
+                }
+                evt.preventDefault();
+                evt.stopPropagation();
+                const { sourceId, payload } = JSON.parse(data);
+                ws?.send(
+                  JSON.stringify({
+                    type: "Action",
+                    handler,
+                    params: {
+                      sourceId,
+                      targetId: componentId,
+                      payload,
+                      parameters,
+                    },
+                  })
+                );
+              };
+              el.addEventListener("dragover", onDragOver);
+              el.addEventListener("drop", onDrop);
+              return () => {
+                el.removeEventListener("dragover", onDragOver);
+                el.removeEventListener("drop", onDrop);
+              };
+            });
+          },
+        ],
+        componentDidUnmount: [() => cleanups[componentId]?.()],
+      },
+    });
+  });
+}
+
+export function bindingTraits(ws: Socket | null) {
+  return [DraggableTrait, droppableTrait(ws), PrintableTrait];
+}
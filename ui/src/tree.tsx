@@ -0,0 +1,185 @@
+import { implementRuntimeComponent } from "@sunmao-ui/runtime";
+import { useEffect, useState } from "react";
+import { readPreference, writePreference } from "./preferences";
+import { Socket } from "./socket";
+
+type TreeNode = {
+  id: string;
+  title: string;
+  icon?: string;
+  leaf?: boolean;
+  data?: any;
+};
+
+// children by parent id, "" holds the roots
+type Loaded = Record<string, { nodes: TreeNode[]; error?: string }>;
+
+const styles = {
+  root: { fontSize: 14, userSelect: "none" },
+  row: {
+    display: "flex",
+    alignItems: "center",
+    gap: 4,
+    padding: "2px 4px",
+    borderRadius: 2,
+    cursor: "pointer",
+  },
+  toggle: { width: 16, flexShrink: 0, color: "#86909c", textAlign: "center" },
+  icon: { width: 16, height: 16, flexShrink: 0 },
+  status: { padding: "2px 4px", fontSize: 12, color: "#86909c" },
+  error: { padding: "2px 4px", fontSize: 12, color: "#f53f3f" },
+} as const;
+
+// the client of runtime.NewTree, children are requested the first time a
+// node expands and replaced whenever the server refreshes them
+export function treeComponent(ws: Socket | null) {
+  return implementRuntimeComponent({
+    version: "binding/v1",
+    metadata: {
+      name: "tree",
+      displayName: "Tree",
+      exampleProperties: { tree: "" },
+      annotations: { category: "Display" },
+      isDraggable: true,
+      isResizable: true,
+    },
+    spec: {
+      properties: {} as any,
+      state: {} as any,
+      methods: {},
+      slots: {},
+      styleSlots: ["content"],
+      events: ["onSelect"],
+    },
+  })(({ tree, mergeState, callbackMap, elementRef }: any) => {
+    const prefKey = `tree:${tree}`;
+    const [loaded, setLoaded] = useState<Loaded>({});
+    const [expanded, setExpanded] = useState<string[]>(() =>
+      readPreference(prefKey, [])
+    );
+    const [selected, setSelected] = useState<string>();
+
+    const request = (node: string) =>
+      ws?.send(
+        JSON.stringify({
+          type: "Action",
+          handler: `${tree}/children`,
+          params: { node },
+        })
+      );
+
+    useEffect(() => {
+      if (!ws) {
+        return;
+      }
+      const socket = ws;
+      const messageHandler = (evt: Event) => {
+        const message = JSON.parse((evt as MessageEvent).data);
+        if (message.type !== "TreeChildren" || message.tree !== tree) {
+          return;
+        }
+        setLoaded((prev) => ({
+          ...prev,
+          [message.node]: {
+            nodes: message.nodes || [],
+            error: message.error || undefined,
+          },
+        }));
+      };
+      socket.addEventListener("message", messageHandler);
+      // the expanded nodes of the last visit load along with the roots
+      request("");
+      expanded.forEach(request);
+      return () => socket.removeEventListener("message", messageHandler);
+    }, [tree]);
+
+    const toggle = (node: TreeNode) => {
+      const open = expanded.includes(node.id);
+      const next = open
+        ? expanded.filter((id) => id !== node.id)
+        : expanded.concat(node.id);
+      setExpanded(next);
+      writePreference(ws, prefKey, next);
+      mergeState({ expanded: next });
+      if (!open && (!loaded[node.id] || loaded[node.id].error)) {
+        request(node.id);
+      }
+    };
+
+    const select = (node: TreeNode) => {
+      setSelected(node.id);
+      mergeState({ selected: node });
+      ws?.send(
+        JSON.stringify({
+          type: "Action",
+          handler: `${tree}/select`,
+          params: { node },
+        })
+      );
+      callbackMap?.onSelect?.();
+    };
+
+    const renderLevel = (parent: string, depth: number): JSX.Element => {
+      const level = loaded[parent];
+      if (!level) {
+        return (
+          <div style={{ ...styles.status, paddingLeft: depth * 16 + 24 }}>
+            Loading…
+          </div>
+        );
+      }
+      if (level.error) {
+        return (
+          <div style={{ ...styles.error, paddingLeft: depth * 16 + 24 }}>
+            {level.error}
+          </div>
+        );
+      }
+      return (
+        <>
+          {level.nodes.map((node) => {
+            const open = !node.leaf && expanded.includes(node.id);
+            return (
+              <div key={node.id} role="treeitem" aria-expanded={open}>
+                <div
+                  style={{
+                    ...styles.row,
+                    paddingLeft: depth * 16 + 4,
+                    background: selected === node.id ? "#e8f3ff" : undefined,
+                  }}
+                  onClick={() => select(node)}
+                >
+                  <span
+                    style={styles.toggle}
+                    onClick={(evt) => {
+                      evt.stopPropagation();
+                      if (!node.leaf) {
+                        toggle(node);
+                      }
+                    }}
+                  >
+                    {node.leaf ? "" : open ? "▾" : "▸"}
+                  </span>
+                  {node.icon &&
+                    (node.icon.includes("/") ? (
+                      <img src={node.icon} alt="" style={styles.icon} />
+                    ) : (
+                      <span style={styles.icon}>{node.icon}</span>
+                    ))}
+                  <span>{node.title}</span>
+                </div>
+                {open && renderLevel(node.id, depth + 1)}
+              </div>
+            );
+          })}
+        </>
+      );
+    };
+
+    return (
+      <div ref={elementRef} style={styles.root} role="tree">
+        {renderLevel("", 0)}
+      </div>
+    );
+  });
+}
@@ -0,0 +1,226 @@
+import { implementRuntimeComponent } from "@sunmao-ui/runtime";
+import { KeyboardEvent, useEffect, useRef, useState } from "react";
+import { Socket } from "./socket";
+
+type Option = { value: string; label?: string; description?: string };
+
+const styles = {
+  root: { position: "relative" },
+  input: {
+    width: "100%",
+    boxSizing: "border-box",
+    padding: "6px 10px",
+    border: "1px solid #e5e6eb",
+    borderRadius: 4,
+    fontSize: 14,
+  },
+  dropdown: {
+    position: "absolute",
+    top: "100%",
+    left: 0,
+    right: 0,
+    zIndex: 1000,
+    maxHeight: 280,
+    overflowY: "auto",
+    margin: "4px 0 0",
+    padding: 4,
+    background: "#fff",
+    borderRadius: 4,
+    boxShadow: "0 4px 16px rgba(0, 0, 0, 0.15)",
+  },
+  item: { listStyle: "none", padding: "6px 8px", cursor: "pointer" },
+  description: { fontSize: 12, color: "#86909c" },
+  status: {
+    listStyle: "none",
+    padding: "6px 8px",
+    fontSize: 12,
+    color: "#86909c",
+  },
+} as const;
+
+// the client of runtime.NewTypeahead, replies carry the seq of their
+// query so options of a query the user typed past never show
+export function typeaheadComponent(ws: Socket | null) {
+  return implementRuntimeComponent({
+    version: "binding/v1",
+    metadata: {
+      name: "typeahead",
+      displayName: "Typeahead",
+      exampleProperties: {
+        typeahead: "",
+        placeholder: "",
+        debounce: 200,
+        minLength: 1,
+      },
+      annotations: { category: "Input" },
+      isDraggable: true,
+      isResizable: true,
+    },
+    spec: {
+      properties: {} as any,
+      state: {} as any,
+      methods: {},
+      slots: {},
+      styleSlots: ["content"],
+      events: ["onSelect"],
+    },
+  })(
+    ({
+      typeahead,
+      placeholder,
+      debounce,
+      minLength,
+      mergeState,
+      callbackMap,
+      elementRef,
+    }: any) => {
+      const [text, setText] = useState("");
+      const [options, setOptions] = useState<Option[]>([]);
+      const [loading, setLoading] = useState(false);
+      const [error, setError] = useState<string>();
+      const [open, setOpen] = useState(false);
+      const [active, setActive] = useState(0);
+      const seq = useRef(0);
+      // set while the text is the label of the picked option
+      const picked = useRef(false);
+      const min = minLength ?? 1;
+
+      useEffect(() => {
+        if (!ws) {
+          return;
+        }
+        const socket = ws;
+        const messageHandler = (evt: Event) => {
+          const message = JSON.parse((evt as MessageEvent).data);
+          if (
+            message.type !== "Suggestions" ||
+            message.typeahead !== typeahead ||
+            message.seq !== seq.current
+          ) {
+            return;
+          }
+          setOptions(message.options || []);
+          setError(message.error || undefined);
+          setLoading(false);
+          setActive(0);
+        };
+        socket.addEventListener("message", messageHandler);
+        return () => socket.removeEventListener("message", messageHandler);
+      }, [typeahead]);
+
+      const query = (prefix: string) => {
+        seq.current++;
+        setLoading(true);
+        ws?.send(
+          JSON.stringify({
+            type: "Action",
+            handler: `${typeahead}/suggest`,
+            params: { prefix, seq: seq.current },
+          })
+        );
+      };
+
+      useEffect(() => {
+        mergeState({ text });
+        if (picked.current) {
+          picked.current = false;
+          return;
+        }
+        if (text.length < min) {
+          // replies still on their way are stale now
+          seq.current++;
+          setOptions([]);
+          setLoading(false);
+          return;
+        }
+        const timer = setTimeout(() => query(text), debounce || 0);
+        return () => clearTimeout(timer);
+      }, [text, typeahead, debounce, min]);
+
+      const select = (option: Option) => {
+        picked.current = true;
+        setText(option.label || option.value);
+        setOpen(false);
+        mergeState({ value: option.value, selected: option });
+        ws?.send(
+          JSON.stringify({
+            type: "Action",
+            handler: `${typeahead}/select`,
+            params: { option },
+          })
+        );
+        callbackMap?.onSelect?.();
+      };
+
+      const onKeyDown = (evt: KeyboardEvent) => {
+        if (evt.key === "ArrowDown" || evt.key === "ArrowUp") {
+          evt.preventDefault();
+          if (options.length === 0) {
+            return;
+          }
+          setOpen(true);
+          const step = evt.key === "ArrowDown" ? 1 : -1;
+          setActive((i) => (i + step + options.length) % options.length);
+        } else if (evt.key === "Enter" && open && options[active]) {
+          evt.preventDefault();
+          select(options[active]);
+        } else if (evt.key === "Escape") {
+          setOpen(false);
+        }
+      };
+
+      return (
+        <div ref={elementRef} style={styles.root}>
+          <input
+            style={styles.input}
+            placeholder={placeholder}
+            value={text}
+            role="combobox"
+            aria-expanded={open}
+            onChange={(evt) => {
+              setText(evt.target.value);
+              setOpen(true);
+              // typing over the pick unsets it
+              mergeState({ value: undefined, selected: undefined });
+            }}
+            onKeyDown={onKeyDown}
+            onFocus={() => {
+              setOpen(true);
+              if (min === 0 && !text) {
+                query("");
+              }
+            }}
+            onBlur={() => setTimeout(() => setOpen(false), 150)}
+          />
+          {open && text.length >= min && (
+            <ul style={styles.dropdown} role="listbox">
+              {options.map((option, i) => (
+                <li
+                  key={option.value}
+                  role="option"
+                  aria-selected={i === active}
+                  style={{
+                    ...styles.item,
+                    background: i === active ? "#f2f3f5" : undefined,
+                  }}
+                  onMouseEnter={() => setActive(i)}
+                  onMouseDown={() => select(option)}
+                >
+                  <div>{option.label || option.value}</div>
+                  {option.description && (
+                    <div style={styles.description}>{option.description}</div>
+                  )}
+                </li>
+              ))}
+              {loading && <li style={styles.status}>Loading…</li>}
+              {!loading && error && <li style={styles.status}>{error}</li>}
+              {!loading && !error && options.length === 0 && (
+                <li style={styles.status}>No suggestions</li>
+              )}
+            </ul>
+          )}
+        </div>
+      );
+    }
+  );
+}
@@ -0,0 +1,92 @@
+// mirrors sunmao.ValidateSchema, so calls the server would reject with
+// invalid_params are not sent at all
+export type JSONSchema = Record<string, any>;
+
+function typeOf(value: any) {
+  if (value === null || value === undefined) {
+    return "null";
+  }
+  if (Array.isArray(value)) {
+    return "array";
+  }
+  return typeof value;
+}
+
+function matchesType(type: string, value: any) {
+  switch (type) {
+    case "integer":
+      return Number.isInteger(value);
+    case "boolean":
+    case "string":
+    case "number":
+    case "array":
+    case "object":
+    case "null":
+      return typeOf(value) === type;
+  }
+  return true;
+}
+
+// validateParams returns the first error as "path: message"
+export function validateParams(
+  schema: JSONSchema,
+  value: any,
+  path = ""
+): string | undefined {
+  const fail = (message: string) => (path ? `${path}: ${message}` : message);
+
+  if (typeof schema.type === "string" && !matchesType(schema.type, value)) {
+    return fail(`expected ${schema.type}`);
+  }
+  if (
+    Array.isArray(schema.enum) &&
+    !schema.enum.some((e: any) => String(e) === String(value))
+  ) {
+    return fail(`must be one of ${schema.enum.join(", ")}`);
+  }
+  if (typeof value === "number") {
+    if (schema.minimum !== undefined && value < schema.minimum) {
+      return fail(`must be at least ${schema.minimum}`);
+    }
+    if (schema.maximum !== undefined && value > schema.maximum) {
+      return fail(`must be at most ${schema.maximum}`);
+    }
+  }
+  if (typeof value === "string") {
+    const length = Array.from(value).length;
+    if (schema.minLength !== undefined && length < schema.minLength) {
+      return fail(`must be at least ${schema.minLength} characters`);
+    }
+    if (schema.maxLength !== undefined && length > schema.maxLength) {
+      return fail(`must be at most ${schema.maxLength} characters`);
+    }
+    if (schema.pattern && !new RegExp(schema.pattern).test(value)) {
+      return fail(`must match ${schema.pattern}`);
+    }
+  }
+  if (Array.isArray(value) && schema.items) {
+    for (let i = 0; i < value.length; i++) {
+      const err = validateParams(schema.items, value[i], `${path}/${i}`);
+      if (err) {
+        return err;
+      }
+    }
+  }
+  if (typeOf(value) === "object") {
+    for (const name of schema.required || []) {
+      if (!(name in value)) {
+        return `${path}/${name}: is required`;
+      }
+    }
+    for (const name of Object.keys(value).sort()) {
+      const sub = schema.properties?.[name] || schema.additionalProperties;
+      if (sub && typeof sub === "object") {
+        const err = validateParams(sub, value[name], `${path}/${name}`);
+        if (err) {
+          return err;
+        }
+      }
+    }
+  }
+  return undefined;
+}
@@ -0,0 +1,207 @@
+import { implementRuntimeComponent } from "@sunmao-ui/runtime";
+import { useEffect, useRef, useState } from "react";
+import { Socket } from "./socket";
+
+type VirtualItem = {
+  key: string;
+  title: string;
+  description?: string;
+  data?: any;
+};
+
+// items are requested in aligned pages, so scrolling back and forth asks
+// for each page once
+const PAGE = 100;
+// pages further than this from the viewport are dropped again
+const KEEP_PAGES = 10;
+const OVERSCAN = 10;
+
+const styles = {
+  viewport: { position: "relative", overflowY: "auto" },
+  row: {
+    position: "absolute",
+    left: 0,
+    right: 0,
+    boxSizing: "border-box",
+    display: "flex",
+    flexDirection: "column",
+    justifyContent: "center",
+    padding: "0 12px",
+    borderBottom: "1px solid #f2f3f5",
+    overflow: "hidden",
+    cursor: "pointer",
+  },
+  title: { whiteSpace: "nowrap", overflow: "hidden", textOverflow: "ellipsis" },
+  description: {
+    fontSize: 12,
+    color: "#86909c",
+    whiteSpace: "nowrap",
+    overflow: "hidden",
+    textOverflow: "ellipsis",
+  },
+  placeholder: { height: 10, width: "40%", background: "#f2f3f5" },
+} as const;
+
+// the client of runtime.NewVirtualList
+export function virtualListComponent(ws: Socket | null) {
+  return implementRuntimeComponent({
+    version: "binding/v1",
+    metadata: {
+      name: "virtualList",
+      displayName: "Virtual List",
+      exampleProperties: { list: "", itemHeight: 48, height: 480 },
+      annotations: { category: "Display" },
+      isDraggable: true,
+      isResizable: true,
+    },
+    spec: {
+      properties: {} as any,
+      state: {} as any,
+      methods: {},
+      slots: {},
+      styleSlots: ["content"],
+      events: ["onSelect"],
+    },
+  })(
+    ({
+      list,
+      itemHeight,
+      height,
+      mergeState,
+      callbackMap,
+      elementRef,
+    }: any) => {
+      const rowHeight = itemHeight || 48;
+      const viewHeight = height || 480;
+      // pages by offset, undefined while requested
+      const pages = useRef(new Map<number, VirtualItem[] | undefined>());
+      const [total, setTotal] = useState(0);
+      const [scrollTop, setScrollTop] = useState(0);
+      const [selected, setSelected] = useState<string>();
+      // bumped when pages arrive, the map itself is not state
+      const [, setLoaded] = useState(0);
+
+      const first = Math.max(0, Math.floor(scrollTop / rowHeight) - OVERSCAN);
+      const last = Math.min(
+        total,
+        Math.ceil((scrollTop + viewHeight) / rowHeight) + OVERSCAN
+      );
+
+      const request = (offset: number) => {
+        pages.current.set(offset, undefined);
+        ws?.send(
+          JSON.stringify({
+            type: "Action",
+            handler: `${list}/range`,
+            params: { offset, limit: PAGE },
+          })
+        );
+      };
+
+      useEffect(() => {
+        if (!ws) {
+          return;
+        }
+        const socket = ws;
+        const messageHandler = (evt: Event) => {
+          const message = JSON.parse((evt as MessageEvent).data);
+          if (message.list !== list) {
+            return;
+          }
+          if (message.type === "VirtualItems") {
+            pages.current.set(message.offset, message.items || []);
+            setTotal(message.total);
+            setLoaded((n) => n + 1);
+          }
+          if (message.type === "VirtualInvalidate") {
+            pages.current.clear();
+            setLoaded((n) => n + 1);
+          }
+        };
+        socket.addEventListener("message", messageHandler);
+        // the first page also tells the total
+        request(0);
+        return () => socket.removeEventListener("message", messageHandler);
+      }, [list]);
+
+      // asks for the pages in view and forgets the ones far away
+      useEffect(() => {
+        const firstPage = Math.floor(first / PAGE) * PAGE;
+        const end = Math.max(last, 1);
+        for (let offset = firstPage; offset < end; offset += PAGE) {
+          if (!pages.current.has(offset)) {
+            request(offset);
+          }
+        }
+        pages.current.forEach((_, offset) => {
+          if (Math.abs(offset - firstPage) > KEEP_PAGES * PAGE) {
+            pages.current.delete(offset);
+          }
+        });
+      });
+
+      useEffect(() => {
+        mergeState({ total });
+      }, [total]);
+
+      const itemAt = (index: number) => {
+        const page = pages.current.get(Math.floor(index / PAGE) * PAGE);
+        return page?.[index % PAGE];
+      };
+
+      const select = (item: VirtualItem) => {
+        setSelected(item.key);
+        mergeState({ selected: item });
+        ws?.send(
+          JSON.stringify({
+            type: "Action",
+            handler: `${list}/select`,
+            params: { item },
+          })
+        );
+        callbackMap?.onSelect?.();
+      };
+
+      const rows = [];
+      for (let i = first; i < last; i++) {
+        const item = itemAt(i);
+        rows.push(
+          <div
+            key={i}
+            style={{
+              ...styles.row,
+              top: i * rowHeight,
+              height: rowHeight,
+              background:
+                item && item.key === selected ? "#e8f3ff" : undefined,
+            }}
+            onClick={() => item && select(item)}
+          >
+            {item ? (
+              <>
+                <div style={styles.title}>{item.title}</div>
+                {item.description && (
+                  <div style={styles.description}>{item.description}</div>
+                )}
+              </>
+            ) : (
+              <div style={styles.placeholder} />
+            )}
+          </div>
+        );
+      }
+
+      return (
+        <div
+          ref={elementRef}
+          style={{ ...styles.viewport, height: viewHeight }}
+          onScroll={(evt) => setScrollTop(evt.currentTarget.scrollTop)}
+        >
+          <div style={{ position: "relative", height: total * rowHeight }}>
+            {rows}
+          </div>
+        </div>
+      );
+    }
+  );
+}